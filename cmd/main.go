@@ -1,10 +1,24 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"github.com/golang-migrate/migrate/v4"
@@ -12,6 +26,7 @@ import (
 	 _ "github.com/golang-migrate/migrate/v4/source/file"
 
 	"WalletApp/internal/config"
+	"WalletApp/internal/domain"
 	"WalletApp/internal/handler"
 	"WalletApp/internal/repository"
 	"WalletApp/internal/usecase"
@@ -47,12 +62,94 @@ func main() {
 	}
 
 	repo := repository.NewPostgresWalletRepository(db)
-	service := usecase.NewWalletService(repo)
+	limits := make(map[string]usecase.AmountLimits, len(cfg.OperationLimits))
+	for operationType, l := range cfg.OperationLimits {
+		limits[operationType] = usecase.AmountLimits(l)
+	}
+	var spendingLimits []domain.SpendingLimit
+	for period, l := range cfg.SpendingLimits {
+		spendingLimits = append(spendingLimits, domain.SpendingLimit{Period: period, MaxAmount: l.MaxAmount, MaxCount: l.MaxCount})
+	}
+	roundingMode, err := domain.ParseRoundingMode(cfg.RoundingMode)
+	if err != nil {
+		log.Fatalf("Invalid FX_ROUNDING_MODE: %v", err)
+	}
+	var fees domain.FeeSchedule
+	if cfg.FeeRulesFile != "" {
+		if fees, err = loadFeeSchedule(cfg.FeeRulesFile); err != nil {
+			log.Fatalf("Failed to load fee rules: %v", err)
+		}
+	}
+	var interest domain.InterestSchedule
+	if cfg.InterestRatesFile != "" {
+		if interest, err = loadInterestSchedule(cfg.InterestRatesFile); err != nil {
+			log.Fatalf("Failed to load interest rates: %v", err)
+		}
+	}
+	options := []usecase.Option{
+		usecase.WithAmountLimits(limits),
+		usecase.WithSpendingLimits(spendingLimits),
+		usecase.WithDefaultCurrency(cfg.DefaultCurrency),
+		usecase.WithExchangeRates(repository.NewPostgresExchangeRateRepository(db)),
+		usecase.WithRoundingMode(roundingMode),
+		usecase.WithQuoteTTL(cfg.QuoteTTL),
+		usecase.WithHolds(repository.NewPostgresHoldRepository(db)),
+		usecase.WithHoldTTL(cfg.HoldTTL),
+		usecase.WithOwners(repository.NewPostgresOwnerRepository(db)),
+		usecase.WithFees(fees),
+	}
+	if cfg.InterestRatesFile != "" {
+		options = append(options, usecase.WithInterest(repository.NewPostgresInterestRepository(db), interest))
+	}
+	service := usecase.NewWalletService(repo, options...)
 	logger := logrus.New()
 
+	// Разовая сверка балансов из командной строки: walletapp reconcile [-repair]
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		code := runReconcileCommand(service, os.Args[2:], os.Stdout, os.Stderr)
+		db.Close()
+		os.Exit(code)
+	}
+	// Начисление процентов из командной строки: walletapp accrue-interest [-date YYYY-MM-DD]
+	if len(os.Args) > 1 && os.Args[1] == "accrue-interest" {
+		code := runAccrueInterestCommand(service, os.Args[2:], os.Stdout, os.Stderr)
+		db.Close()
+		os.Exit(code)
+	}
+
+	if err := checkRevenueWallets(service, fees); err != nil {
+		log.Fatalf("Invalid fee rules: %v", err)
+	}
+
+	// Загрузка операций из файла: walletapp import [-dry-run] [-parallelism N] FILE
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		code := runImportCommand(service, os.Args[2:], os.Stdout, os.Stderr)
+		db.Close()
+		os.Exit(code)
+	}
+
+	// Загрузка курсов обмена из файла: каждый запуск добавляет новые версии курсов
+	if cfg.RatesFile != "" {
+		if err := loadExchangeRates(service, cfg.RatesFile); err != nil {
+			log.Fatalf("Failed to load exchange rates: %v", err)
+		}
+	}
+
+	// Фоновая очистка истёкших блокировок
+	go runHoldSweeper(service, cfg.HoldSweepInterval, logger)
+	// Фоновая сверка балансов кошельков с операциями
+	go runReconciler(service, cfg.ReconcileInterval, cfg.ReconcileRepair, logger)
+	// Фоновое начисление процентов за завершённые дни
+	if cfg.InterestRatesFile != "" {
+		go runInterestAccrual(service, cfg.InterestInterval, logger)
+	}
+
 	h := handler.NewWalletHandler(service, logger)
+	h.Idempotency = repository.NewPostgresIdempotencyRepository(db, 24*time.Hour) // ключи хранятся сутки
+	h.AdminToken = cfg.AdminToken
 
 	r := mux.NewRouter()
+	r.Use(handler.RequestIDMiddleware)
 
 	// Добавляем обработчик для корневого маршрута
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -61,10 +158,399 @@ func main() {
 	})
 
 	// Регистрация маршрутов API
-	r.HandleFunc("/api/v1/wallet", h.HandleCreateWallet).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/wallet", h.Idempotent(h.HandleCreateWallet)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/owners", h.Idempotent(h.HandleCreateOwner)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/owners/{ownerId}", h.HandleGetOwner).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/owners/{ownerId}/wallets", h.HandleListOwnerWallets).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/wallets", h.HandleListWallets).Methods(http.MethodGet)
 	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods(http.MethodGet)
-	r.HandleFunc("/api/v1/wallets/operation", h.HandleOperation).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleUpdateWallet).Methods(http.MethodPatch)
+	r.HandleFunc("/api/v1/wallets/operation", h.Idempotent(h.HandleOperation)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/wallets/operations/batch", h.Idempotent(h.HandleBatchOperation)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/wallets/{walletId}/transactions", h.HandleListTransactions).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/wallets/{walletId}/statement", h.HandleStatement).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/wallets/{walletId}/holds", h.Idempotent(h.HandleCreateHold)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/holds/{holdId}", h.HandleGetHold).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/holds/{holdId}/capture", h.Idempotent(h.HandleCaptureHold)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/holds/{holdId}/void", h.Idempotent(h.HandleVoidHold)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/ledger/summary", h.AdminOnly(h.HandleLedgerSummary)).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/fx/rates", h.HandleListExchangeRates).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/fx/quotes", h.HandleCreateQuote).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/admin/fx/rates", h.AdminOnly(h.HandleAddExchangeRates)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/admin/operations/import", h.AdminOnly(h.HandleImportOperations)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/admin/transactions/{transactionId}/reverse", h.AdminOnly(h.Idempotent(h.HandleReverseTransaction))).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/freeze", h.AdminOnly(h.HandleFreezeWallet)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/unfreeze", h.AdminOnly(h.HandleUnfreezeWallet)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/close", h.AdminOnly(h.HandleCloseWallet)).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/status-history", h.AdminOnly(h.HandleListWalletStatusChanges)).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/overdraft", h.AdminOnly(h.HandleSetOverdraftLimit)).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/tier", h.AdminOnly(h.HandleSetWalletTier)).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/admin/fees", h.AdminOnly(h.HandleGetFeeSchedule)).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/limits", h.AdminOnly(h.HandleGetSpendingLimits)).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/limits", h.AdminOnly(h.HandleSetSpendingLimits)).Methods(http.MethodPut)
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/limits/{period}", h.AdminOnly(h.HandleResetSpendingLimit)).Methods(http.MethodDelete)
 
 	log.Println("Server is running on port 8080")
 	log.Fatal(http.ListenAndServe(":8080", r))
-}
\ No newline at end of file
+}
+
+// loadExchangeRates загружает курсы обмена из CSV-файла
+func loadExchangeRates(service usecase.WalletService, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rates, err := domain.ParseExchangeRatesCSV(f)
+	if err != nil {
+		return err
+	}
+	if _, err := service.AddExchangeRates(context.Background(), rates); err != nil {
+		return err
+	}
+	log.Printf("Loaded %d exchange rates from %s", len(rates), path)
+	return nil
+}
+
+// loadFeeSchedule загружает тарифную сетку комиссий из JSON-файла
+func loadFeeSchedule(path string) (domain.FeeSchedule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return domain.FeeSchedule{}, err
+	}
+	defer f.Close()
+
+	schedule, err := domain.ParseFeeScheduleJSON(f)
+	if err != nil {
+		return domain.FeeSchedule{}, err
+	}
+	log.Printf("Loaded %d fee rules from %s", len(schedule.Rules), path)
+	return schedule, nil
+}
+
+// loadInterestSchedule загружает ставки процентов по тарифам кошельков из JSON-файла
+func loadInterestSchedule(path string) (domain.InterestSchedule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return domain.InterestSchedule{}, err
+	}
+	defer f.Close()
+
+	schedule, err := domain.ParseInterestScheduleJSON(f)
+	if err != nil {
+		return domain.InterestSchedule{}, err
+	}
+	log.Printf("Loaded %d interest rates from %s", len(schedule.Rates), path)
+	return schedule, nil
+}
+
+// checkRevenueWallets проверяет, что кошельки доходов существуют и открыты в валютах своих комиссий
+func checkRevenueWallets(service usecase.WalletService, schedule domain.FeeSchedule) error {
+	for currency, walletID := range schedule.RevenueWallets {
+		wallet, err := service.GetWallet(context.Background(), walletID)
+		if err != nil {
+			return fmt.Errorf("revenue wallet %s for %s: %w", walletID, currency, err)
+		}
+		if wallet.Currency != currency {
+			return fmt.Errorf("revenue wallet %s for %s is in %s", walletID, currency, wallet.Currency)
+		}
+		if err := wallet.CanCredit(); err != nil {
+			return fmt.Errorf("revenue wallet %s for %s: %w", walletID, currency, err)
+		}
+	}
+	return nil
+}
+
+// runHoldSweeper периодически освобождает средства блокировок с истёкшим сроком
+func runHoldSweeper(service usecase.WalletService, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		expired, err := service.ExpireHolds(context.Background())
+		if err != nil {
+			logger.WithError(err).Error("failed to expire holds")
+			continue
+		}
+		if expired > 0 {
+			logger.WithField("count", expired).Info("expired holds released")
+		}
+	}
+}
+
+// runReconciler периодически сверяет балансы кошельков с операциями и пишет расхождения в журнал;
+// при repair исправляет балансы
+func runReconciler(service usecase.WalletService, interval time.Duration, repair bool, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		report, err := service.ReconcileBalances(context.Background(), repair)
+		if err != nil {
+			logger.WithError(err).Error("failed to reconcile balances")
+			continue
+		}
+		for _, check := range report.Wallets {
+			logger.WithFields(logrus.Fields{
+				"walletId":    check.WalletID,
+				"drift":       check.Drift,
+				"ledgerDrift": check.LedgerDrift,
+				"repaired":    check.Repaired,
+				"repairError": check.RepairError,
+			}).Warn("wallet balance drift")
+		}
+		if !report.Consistent {
+			logger.WithFields(logrus.Fields{"checked": report.Checked, "drifted": report.Drifted}).Error("balances are inconsistent")
+		}
+	}
+}
+
+// runReconcileCommand выполняет разовую сверку и пишет отчёт в JSON в stdout.
+// Код выхода: 0 - расхождений нет (или все исправлены), 1 - расхождения остались, 2 - ошибка
+func runReconcileCommand(service usecase.WalletService, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	repair := flags.Bool("repair", false, "заменить wallets.balance суммой операций у кошельков с расхождением")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := service.ReconcileBalances(context.Background(), *repair)
+	if err != nil {
+		fmt.Fprintf(stderr, "reconciliation failed: %v\n", err)
+		return 2
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		fmt.Fprintf(stderr, "could not write report: %v\n", err)
+		return 2
+	}
+	if !report.Consistent {
+		return 1
+	}
+	return 0
+}
+
+// runInterestAccrual периодически начисляет проценты за дни, завершившиеся после последнего начисления
+func runInterestAccrual(service usecase.WalletService, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		runs, err := service.RunInterestAccrual(context.Background(), time.Now())
+		for _, run := range runs {
+			logger.WithFields(logrus.Fields{
+				"date":           run.Date.Format("2006-01-02"),
+				"accrued":        run.Accrued,
+				"alreadyAccrued": run.AlreadyAccrued,
+				"payouts":        len(run.Payouts),
+			}).Info("interest accrued")
+		}
+		if err != nil {
+			logger.WithError(err).Error("failed to accrue interest")
+		}
+	}
+}
+
+// runAccrueInterestCommand начисляет проценты за день -date или, без него, за все пропущенные дни
+// и пишет итоги в JSON в stdout. Код выхода: 0 - успешно, 2 - ошибка
+func runAccrueInterestCommand(service usecase.WalletService, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("accrue-interest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	date := flags.String("date", "", "день начисления YYYY-MM-DD (UTC); повторный запуск за тот же день ничего не начисляет")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var runs []domain.InterestRun
+	var err error
+	if *date != "" {
+		day, parseErr := time.Parse("2006-01-02", *date)
+		if parseErr != nil {
+			fmt.Fprintf(stderr, "invalid date %q: %v\n", *date, parseErr)
+			return 2
+		}
+		var run domain.InterestRun
+		if run, err = service.AccrueInterest(context.Background(), day); err == nil {
+			runs = []domain.InterestRun{run}
+		}
+	} else {
+		runs, err = service.RunInterestAccrual(context.Background(), time.Now())
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "interest accrual failed: %v\n", err)
+		if len(runs) == 0 {
+			return 2
+		}
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(runs); encodeErr != nil {
+		fmt.Fprintf(stderr, "could not write report: %v\n", encodeErr)
+		return 2
+	}
+	if err != nil {
+		return 2
+	}
+	return 0
+}
+
+// runImportCommand загружает операции из файла JSONL или CSV и пишет итог в JSON в stdout.
+// Прогресс сохраняется в файл контрольной точки, и повторный запуск продолжает с места остановки
+// (в том числе после Ctrl+C и сбоя процесса: ключ загрузки из контрольной точки не даёт выполнить
+// строку второй раз); отклонённые строки пишутся в файл -rejected в JSONL.
+// Код выхода: 0 - все строки выполнены, 1 - есть отклонённые строки, 2 - ошибка или загрузка прервана
+func runImportCommand(service usecase.WalletService, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "", "формат файла: jsonl или csv (по умолчанию по расширению файла)")
+	parallelism := flags.Int("parallelism", usecase.DefaultImportParallelism, "операций одновременно; строки одного кошелька выполняются по порядку")
+	dryRun := flags.Bool("dry-run", false, "только проверить строки (с учётом предыдущих строк файла), ничего не изменяя")
+	checkpointPath := flags.String("checkpoint", "", "файл контрольной точки (по умолчанию FILE.checkpoint)")
+	checkpointEvery := flags.Int("checkpoint-every", 1, "сохранять контрольную точку через столько обработанных строк")
+	rejectedPath := flags.String("rejected", "", "файл отклонённых строк (по умолчанию FILE.rejected.jsonl, при -dry-run FILE.dry-run.jsonl)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: import [flags] FILE")
+		return 2
+	}
+	if *parallelism < 1 || *parallelism > usecase.MaxImportParallelism {
+		fmt.Fprintf(stderr, "parallelism must be between 1 and %d\n", usecase.MaxImportParallelism)
+		return 2
+	}
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = domain.ImportJSONL
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			*format = domain.ImportCSV
+		}
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = path + ".checkpoint"
+	}
+	if *rejectedPath == "" {
+		*rejectedPath = path + ".rejected.jsonl"
+		if *dryRun {
+			*rejectedPath = path + ".dry-run.jsonl"
+		}
+	}
+
+	checkpoint, err := readImportCheckpoint(*checkpointPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "could not read checkpoint: %v\n", err)
+		return 2
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "could not open file: %v\n", err)
+		return 2
+	}
+	defer f.Close()
+
+	// Ключ загрузки - хэш содержимого файла и случайный id запуска с начала: продолжение возможно только
+	// для того же файла, а повторная загрузка того же файла с начала выполняет его снова
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		fmt.Fprintf(stderr, "could not read file: %v\n", err)
+		return 2
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		fmt.Fprintf(stderr, "could not read file: %v\n", err)
+		return 2
+	}
+	fileID := hex.EncodeToString(hash.Sum(nil))
+	if checkpoint.Key == "" {
+		checkpoint.Key = fileID + "-" + uuid.New().String()
+		// Ключ сохраняется до первой строки, иначе после сбоя продолжение его не узнает
+		if !*dryRun {
+			if err := writeImportCheckpoint(*checkpointPath, checkpoint); err != nil {
+				fmt.Fprintf(stderr, "could not write checkpoint: %v\n", err)
+				return 2
+			}
+		}
+	} else if !strings.HasPrefix(checkpoint.Key, fileID+"-") {
+		fmt.Fprintf(stderr, "checkpoint %s belongs to another file; remove it to import from the start\n", *checkpointPath)
+		return 2
+	}
+
+	rows, err := domain.NewOperationReader(f, *format)
+	if err != nil {
+		fmt.Fprintf(stderr, "import failed: %v\n", err)
+		return 2
+	}
+
+	// При продолжении загрузки отклонённые строки дописываются к строкам прежних запусков
+	mode := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !*dryRun && (checkpoint.Line > 0 || len(checkpoint.Done) > 0) {
+		mode = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	rejected, err := os.OpenFile(*rejectedPath, mode, 0o644)
+	if err != nil {
+		fmt.Fprintf(stderr, "could not open rejected rows file: %v\n", err)
+		return 2
+	}
+	defer rejected.Close()
+	rejectedRows := json.NewEncoder(rejected)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := usecase.ImportOperations(ctx, service, rows, usecase.ImportOptions{
+		Parallelism:     *parallelism,
+		DryRun:          *dryRun,
+		From:            checkpoint,
+		Key:             checkpoint.Key,
+		CheckpointEvery: *checkpointEvery,
+		Checkpoint: func(checkpoint domain.ImportCheckpoint) error {
+			return writeImportCheckpoint(*checkpointPath, checkpoint)
+		},
+		Reject: func(row domain.RejectedRow) error {
+			return rejectedRows.Encode(row)
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "import stopped after line %d: %v; run the command again to resume\n", report.Line, err)
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if encodeErr := encoder.Encode(report); encodeErr != nil {
+		fmt.Fprintf(stderr, "could not write report: %v\n", encodeErr)
+		return 2
+	}
+	switch {
+	case err != nil:
+		return 2
+	case report.Rejected > 0:
+		fmt.Fprintf(stderr, "%d rows rejected, see %s\n", report.Rejected, *rejectedPath)
+		return 1
+	}
+	return 0
+}
+
+// readImportCheckpoint читает контрольную точку загрузки; если файла нет - загрузка начинается сначала
+func readImportCheckpoint(path string) (domain.ImportCheckpoint, error) {
+	var checkpoint domain.ImportCheckpoint
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return checkpoint, err
+	}
+	err = json.Unmarshal(data, &checkpoint)
+	return checkpoint, err
+}
+
+// writeImportCheckpoint сохраняет контрольную точку загрузки через временный файл,
+// чтобы прерванная запись не испортила прежнюю точку
+func writeImportCheckpoint(path string, checkpoint domain.ImportCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -4,31 +4,40 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
+	"WalletApp/internal/domain"
 	"WalletApp/internal/handler"
 )
 
 // MockWalletService представляет собой мок-сервис для тестирования
 type MockWalletService struct {
 	Balances map[uuid.UUID]int64
+	Drifts   []domain.BalanceCheck // расхождения, которые находит сверка
+	Accruals []time.Time           // дни, за которые начислялись проценты
+	dryRun   bool                  // идёт DryRun: балансы восстановятся в его конце
+	Keys     map[string]bool       // ключи выполненных однократных операций
 }
 
 func NewMockWalletService() *MockWalletService {
 	return &MockWalletService{
 		Balances: make(map[uuid.UUID]int64),
+		Keys:     make(map[string]bool),
 	}
 }
 
-func (m *MockWalletService) CreateWallet(ctx context.Context) (uuid.UUID, error) {
+func (m *MockWalletService) CreateWallet(ctx context.Context, wallet domain.Wallet) (uuid.UUID, error) {
 	walletID := uuid.New()
 	m.Balances[walletID] = 0
 	return walletID, nil
@@ -37,26 +46,237 @@ func (m *MockWalletService) CreateWallet(ctx context.Context) (uuid.UUID, error)
 func (m *MockWalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
 	balance, exists := m.Balances[walletID]
 	if !exists {
-		return 0, errors.New("wallet not found")
+		return 0, domain.ErrWalletNotFound
 	}
 	return balance, nil
 }
 
-func (m *MockWalletService) PerformOperation(ctx context.Context, walletID uuid.UUID, operationType string, amount int64) error {
-	switch operationType {
+func (m *MockWalletService) GetWallet(ctx context.Context, walletID uuid.UUID) (domain.Wallet, error) {
+	balance, err := m.GetBalance(ctx, walletID)
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	return domain.Wallet{ID: walletID, Balance: balance, Currency: "RUB"}, nil
+}
+
+func (m *MockWalletService) GetBalanceAt(ctx context.Context, walletID uuid.UUID, asOf time.Time) (domain.BalanceSnapshot, error) {
+	balance, err := m.GetBalance(ctx, walletID)
+	if err != nil {
+		return domain.BalanceSnapshot{}, err
+	}
+	return domain.BalanceSnapshot{WalletID: walletID, Currency: "RUB", AsOf: asOf, Balance: balance}, nil
+}
+
+func (m *MockWalletService) WriteStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, out domain.StatementWriter) error {
+	return domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) PerformOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	if err := m.performOperation(op); err != nil {
+		return domain.OperationResult{}, err
+	}
+	return domain.OperationResult{Transactions: []domain.Transaction{}, Fees: []domain.Fee{}}, nil
+}
+
+func (m *MockWalletService) PerformOperationOnce(ctx context.Context, key string, op domain.Operation) (domain.OperationResult, error) {
+	if m.Keys[key] {
+		return domain.OperationResult{}, domain.ErrOperationApplied
+	}
+	result, err := m.PerformOperation(ctx, op)
+	if err == nil {
+		m.Keys[key] = true
+	}
+	return result, err
+}
+
+func (m *MockWalletService) PerformBatch(ctx context.Context, ops []domain.Operation) ([]domain.OperationResult, error) {
+	results := make([]domain.OperationResult, len(ops))
+	for i, op := range ops {
+		result, err := m.PerformOperation(ctx, op)
+		if err != nil {
+			return nil, &domain.BatchError{Index: i, Err: err}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+func (m *MockWalletService) DryRun(ctx context.Context, fn func(ctx context.Context) error) error {
+	balances := make(map[uuid.UUID]int64, len(m.Balances))
+	for walletID, balance := range m.Balances {
+		balances[walletID] = balance
+	}
+	m.dryRun = true
+	defer func() { m.Balances, m.dryRun = balances, false }()
+	return fn(ctx)
+}
+
+func (m *MockWalletService) DryRunOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	if m.dryRun {
+		return m.PerformOperation(ctx, op)
+	}
+	balances := make(map[uuid.UUID]int64, len(m.Balances))
+	for walletID, balance := range m.Balances {
+		balances[walletID] = balance
+	}
+	defer func() { m.Balances = balances }()
+	return m.PerformOperation(ctx, op)
+}
+
+func (m *MockWalletService) performOperation(op domain.Operation) error {
+	walletID, amount := op.WalletID, op.Amount
+	if _, exists := m.Balances[walletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	switch op.OperationType {
 	case "DEPOSIT":
 		m.Balances[walletID] += amount
 	case "WITHDRAW":
 		if m.Balances[walletID] < amount {
-			return errors.New("insufficient funds")
+			return domain.ErrInsufficientFunds
+		}
+		m.Balances[walletID] -= amount
+	case "TRANSFER":
+		if m.Balances[walletID] < amount {
+			return domain.ErrInsufficientFunds
 		}
 		m.Balances[walletID] -= amount
+		m.Balances[op.DestinationWalletID] += amount
 	default:
-		return errors.New("invalid operation type")
+		return domain.ErrInvalidOperation
 	}
 	return nil
 }
 
+func (m *MockWalletService) ListTransactions(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	return domain.TransactionPage{Transactions: []domain.Transaction{}}, nil
+}
+
+func (m *MockWalletService) ReverseTransaction(ctx context.Context, transactionID uuid.UUID, amount int64) (domain.Reversal, error) {
+	return domain.Reversal{}, domain.ErrTransactionNotFound
+}
+
+func (m *MockWalletService) AccrueInterest(ctx context.Context, date time.Time) (domain.InterestRun, error) {
+	date = domain.AccrualDate(date)
+	if !date.Before(domain.AccrualDate(time.Now())) {
+		return domain.InterestRun{}, domain.ErrInvalidAccrualDate
+	}
+	m.Accruals = append(m.Accruals, date)
+	return domain.InterestRun{Date: date, Accrued: int64(len(m.Balances)), Payouts: []domain.InterestPayout{}}, nil
+}
+
+func (m *MockWalletService) RunInterestAccrual(ctx context.Context, now time.Time) ([]domain.InterestRun, error) {
+	run, err := m.AccrueInterest(ctx, now.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+	return []domain.InterestRun{run}, nil
+}
+
+func (m *MockWalletService) ReconcileBalances(ctx context.Context, repair bool) (domain.ReconciliationReport, error) {
+	report := domain.ReconciliationReport{Checked: int64(len(m.Balances)), Repair: repair, Wallets: []domain.BalanceCheck{}, Consistent: true}
+	for _, check := range m.Drifts {
+		check.Repaired = repair
+		report.Wallets = append(report.Wallets, check)
+		report.Drifted++
+		if repair {
+			report.Repaired++
+		} else {
+			report.Consistent = false
+		}
+	}
+	return report, nil
+}
+
+func (m *MockWalletService) SetWalletTier(ctx context.Context, walletID uuid.UUID, tier string) (domain.Wallet, error) {
+	return domain.Wallet{}, domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) GetFeeSchedule(ctx context.Context) domain.FeeSchedule {
+	return domain.FeeSchedule{}
+}
+
+func (m *MockWalletService) GetLedgerSummary(ctx context.Context) ([]domain.LedgerSummary, error) {
+	return []domain.LedgerSummary{{Currency: "RUB", Balanced: true}}, nil
+}
+
+func (m *MockWalletService) AddExchangeRates(ctx context.Context, rates []domain.ExchangeRate) ([]domain.ExchangeRate, error) {
+	return rates, nil
+}
+
+func (m *MockWalletService) ListExchangeRates(ctx context.Context) ([]domain.ExchangeRate, error) {
+	return []domain.ExchangeRate{}, nil
+}
+
+func (m *MockWalletService) QuoteExchange(ctx context.Context, fromCurrency, toCurrency string, amount int64) (domain.FXQuote, error) {
+	return domain.FXQuote{}, domain.ErrRateNotFound
+}
+
+func (m *MockWalletService) CreateHold(ctx context.Context, walletID uuid.UUID, amount int64, expiresAt time.Time) (domain.Hold, error) {
+	return domain.Hold{}, domain.ErrHoldNotFound
+}
+
+func (m *MockWalletService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount int64) (domain.Hold, error) {
+	return domain.Hold{}, domain.ErrHoldNotFound
+}
+
+func (m *MockWalletService) VoidHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	return domain.Hold{}, domain.ErrHoldNotFound
+}
+
+func (m *MockWalletService) GetHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	return domain.Hold{}, domain.ErrHoldNotFound
+}
+
+func (m *MockWalletService) ExpireHolds(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (m *MockWalletService) ChangeWalletStatus(ctx context.Context, walletID uuid.UUID, status, reason, actor string) (domain.WalletStatusChange, error) {
+	return domain.WalletStatusChange{}, domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) ListWalletStatusChanges(ctx context.Context, walletID uuid.UUID) ([]domain.WalletStatusChange, error) {
+	return nil, domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) GetSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]domain.SpendingLimitStatus, error) {
+	return nil, domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) SetSpendingLimits(ctx context.Context, walletID uuid.UUID, limits []domain.SpendingLimit) ([]domain.SpendingLimitStatus, error) {
+	return nil, domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) ResetSpendingLimit(ctx context.Context, walletID uuid.UUID, period string) error {
+	return domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) CreateOwner(ctx context.Context, owner domain.Owner) (domain.Owner, error) {
+	owner.ID = uuid.New()
+	return owner, nil
+}
+
+func (m *MockWalletService) GetOwner(ctx context.Context, ownerID uuid.UUID) (domain.Owner, error) {
+	return domain.Owner{}, domain.ErrOwnerNotFound
+}
+
+func (m *MockWalletService) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]domain.Wallet, error) {
+	return nil, domain.ErrOwnerNotFound
+}
+
+func (m *MockWalletService) UpdateWallet(ctx context.Context, walletID uuid.UUID, update domain.WalletUpdate) (domain.Wallet, error) {
+	return domain.Wallet{}, domain.ErrWalletNotFound
+}
+
+func (m *MockWalletService) ListWallets(ctx context.Context, filter domain.WalletFilter) ([]domain.Wallet, error) {
+	return []domain.Wallet{}, nil
+}
+
+func (m *MockWalletService) SetOverdraftLimit(ctx context.Context, walletID uuid.UUID, limit int64) (domain.Wallet, error) {
+	return domain.Wallet{}, domain.ErrWalletNotFound
+}
+
 // Тест корневого маршрута "/"
 func TestRootHandler(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -103,7 +323,7 @@ func TestGetBalance(t *testing.T) {
 	service := NewMockWalletService()
 	h := handler.NewWalletHandler(service, logrus.New())
 
-	walletID, _ := service.CreateWallet(context.Background())
+	walletID, _ := service.CreateWallet(context.Background(), domain.Wallet{})
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String(), nil)
 	wr := httptest.NewRecorder()
@@ -125,7 +345,7 @@ func TestPerformOperation_Deposit(t *testing.T) {
 	service := NewMockWalletService()
 	h := handler.NewWalletHandler(service, logrus.New())
 
-	walletID, _ := service.CreateWallet(context.Background())
+	walletID, _ := service.CreateWallet(context.Background(), domain.Wallet{})
 
 	reqBody := map[string]interface{}{
 		"walletId":      walletID.String(),
@@ -152,8 +372,8 @@ func TestPerformOperation_Withdraw(t *testing.T) {
 	service := NewMockWalletService()
 	h := handler.NewWalletHandler(service, logrus.New())
 
-	walletID, _ := service.CreateWallet(context.Background())
-	service.PerformOperation(context.Background(), walletID, "DEPOSIT", 100)
+	walletID, _ := service.CreateWallet(context.Background(), domain.Wallet{})
+	service.PerformOperation(context.Background(), domain.Operation{WalletID: walletID, OperationType: "DEPOSIT", Amount: 100})
 
 	reqBody := map[string]interface{}{
 		"walletId":      walletID.String(),
@@ -173,4 +393,149 @@ func TestPerformOperation_Withdraw(t *testing.T) {
 
 	balanceResp := service.Balances[walletID]
 	assert.Equal(t, int64(50), balanceResp) // Проверяем баланс после вывода средств
-}
\ No newline at end of file
+}
+
+func TestRunReconcileCommand(t *testing.T) {
+	service := NewMockWalletService()
+	walletID, _ := service.CreateWallet(context.Background(), domain.Wallet{})
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runReconcileCommand(service, nil, &stdout, &stderr))
+	var report domain.ReconciliationReport
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, int64(1), report.Checked)
+	assert.True(t, report.Consistent)
+	assert.Contains(t, stdout.String(), `"wallets": []`)
+
+	// Расхождение без исправления - код выхода 1
+	service.Drifts = []domain.BalanceCheck{{WalletID: walletID, StoredBalance: 150, ExpectedBalance: 100, Drift: 50}}
+	stdout.Reset()
+	assert.Equal(t, 1, runReconcileCommand(service, nil, &stdout, &stderr))
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.False(t, report.Repair)
+	assert.Equal(t, int64(50), report.Wallets[0].Drift)
+
+	stdout.Reset()
+	assert.Equal(t, 0, runReconcileCommand(service, []string{"-repair"}, &stdout, &stderr))
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.True(t, report.Repair)
+	assert.True(t, report.Wallets[0].Repaired)
+
+	assert.Equal(t, 2, runReconcileCommand(service, []string{"-unknown"}, &stdout, &stderr))
+}
+
+func TestLoadFeeSchedule(t *testing.T) {
+	service := NewMockWalletService()
+	revenue, _ := service.CreateWallet(context.Background(), domain.Wallet{})
+
+	path := filepath.Join(t.TempDir(), "fees.json")
+	rules := `{"revenueWallets": {"RUB": "` + revenue.String() + `"}, "rules": [{"name": "withdraw", "operationType": "WITHDRAW", "flat": 100}]}`
+	assert.NoError(t, os.WriteFile(path, []byte(rules), 0o600))
+	schedule, err := loadFeeSchedule(path)
+	assert.NoError(t, err)
+	assert.Len(t, schedule.Rules, 1)
+	assert.NoError(t, checkRevenueWallets(service, schedule))
+
+	// Кошелёк доходов в другой валюте или несуществующий
+	assert.Error(t, checkRevenueWallets(service, domain.FeeSchedule{RevenueWallets: map[string]uuid.UUID{"USD": revenue}}))
+	assert.ErrorIs(t, checkRevenueWallets(service, domain.FeeSchedule{RevenueWallets: map[string]uuid.UUID{"RUB": uuid.New()}}), domain.ErrWalletNotFound)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"rules": [{"name": "withdraw", "operationType": "WITHDRAW"}]}`), 0o600))
+	_, err = loadFeeSchedule(path)
+	assert.ErrorIs(t, err, domain.ErrInvalidFeeRule)
+	_, err = loadFeeSchedule(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestRunAccrueInterestCommand(t *testing.T) {
+	service := NewMockWalletService()
+	service.CreateWallet(context.Background(), domain.Wallet{})
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runAccrueInterestCommand(service, []string{"-date", "2024-02-29"}, &stdout, &stderr))
+	var runs []domain.InterestRun
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &runs))
+	assert.Len(t, runs, 1)
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), runs[0].Date.UTC())
+	assert.Equal(t, int64(1), runs[0].Accrued)
+
+	// Без даты - начисление за пропущенные дни
+	stdout.Reset()
+	assert.Equal(t, 0, runAccrueInterestCommand(service, nil, &stdout, &stderr))
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &runs))
+	assert.Equal(t, domain.AccrualDate(time.Now()).AddDate(0, 0, -1), runs[0].Date.UTC())
+	assert.Len(t, service.Accruals, 2)
+
+	assert.Equal(t, 2, runAccrueInterestCommand(service, []string{"-date", "29.02.2024"}, &stdout, &stderr))
+	assert.Equal(t, 2, runAccrueInterestCommand(service, []string{"-date", time.Now().UTC().Format("2006-01-02")}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), domain.ErrInvalidAccrualDate.Error())
+}
+
+func TestRunImportCommand(t *testing.T) {
+	service := NewMockWalletService()
+	walletID, _ := service.CreateWallet(context.Background(), domain.Wallet{})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "operations.csv")
+	data := "walletId,operationType,amount\n" +
+		walletID.String() + ",DEPOSIT,100\n" +
+		walletID.String() + ",WITHDRAW,500\n" +
+		walletID.String() + ",WITHDRAW,30\n"
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	// Проверка: баланс не меняется, отклонённые строки в отдельном файле
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, runImportCommand(service, []string{"-dry-run", "-parallelism", "1", path}, &stdout, &stderr))
+	assert.Equal(t, int64(0), service.Balances[walletID])
+	assert.FileExists(t, path+".dry-run.jsonl")
+	assert.NoFileExists(t, path+".checkpoint")
+
+	stdout.Reset()
+	assert.Equal(t, 1, runImportCommand(service, []string{"-parallelism", "1", path}, &stdout, &stderr))
+	var report domain.ImportReport
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, domain.ImportReport{Applied: 2, Rejected: 1, Line: 4}, report)
+	assert.Equal(t, int64(70), service.Balances[walletID])
+
+	rejected, err := os.ReadFile(path + ".rejected.jsonl")
+	assert.NoError(t, err)
+	var row domain.RejectedRow
+	assert.NoError(t, json.Unmarshal(rejected, &row))
+	assert.Equal(t, 3, row.Line)
+	assert.Equal(t, walletID.String()+",WITHDRAW,500", row.Row)
+
+	checkpoint, err := readImportCheckpoint(path + ".checkpoint")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ImportCheckpoint{Key: checkpoint.Key, Line: 4, Applied: 2, Rejected: 1}, checkpoint)
+	assert.NotEmpty(t, checkpoint.Key)
+
+	// Повторный запуск продолжает с контрольной точки: строки не выполняются повторно,
+	// отклонённые строки прежнего запуска сохраняются
+	stdout.Reset()
+	assert.Equal(t, 1, runImportCommand(service, []string{"-parallelism", "1", path}, &stdout, &stderr))
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, int64(3), report.Skipped)
+	assert.Equal(t, int64(70), service.Balances[walletID])
+	rejectedAgain, _ := os.ReadFile(path + ".rejected.jsonl")
+	assert.Equal(t, rejected, rejectedAgain)
+
+	// Сбой до сохранения контрольной точки: строки обрабатываются снова, но выполненные
+	// с ключом загрузки не выполняются второй раз
+	assert.NoError(t, writeImportCheckpoint(path+".checkpoint", domain.ImportCheckpoint{Key: checkpoint.Key}))
+	stdout.Reset()
+	assert.Equal(t, 1, runImportCommand(service, []string{"-parallelism", "1", path}, &stdout, &stderr))
+	assert.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, domain.ImportReport{Applied: 2, Rejected: 1, Line: 4}, report)
+	assert.Equal(t, int64(70), service.Balances[walletID])
+
+	// Контрольная точка другого файла не подходит для продолжения
+	stderr.Reset()
+	assert.NoError(t, os.WriteFile(path, []byte(data+walletID.String()+",DEPOSIT,1\n"), 0o644))
+	assert.Equal(t, 2, runImportCommand(service, []string{path}, &stdout, &stderr))
+	assert.True(t, strings.Contains(stderr.String(), "belongs to another file"), stderr.String())
+	assert.Equal(t, int64(70), service.Balances[walletID])
+
+	assert.Equal(t, 2, runImportCommand(service, nil, &stdout, &stderr))
+	assert.Equal(t, 2, runImportCommand(service, []string{"-parallelism", "0", path}, &stdout, &stderr))
+	assert.Equal(t, 2, runImportCommand(service, []string{"-format", "xml", path}, &stdout, &stderr))
+	assert.Equal(t, 2, runImportCommand(service, []string{filepath.Join(dir, "missing.jsonl")}, &stdout, &stderr))
+}
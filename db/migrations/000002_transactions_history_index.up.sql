@@ -0,0 +1,3 @@
+-- Индекс для истории операций кошелька с keyset-пагинацией (новые записи первыми)
+CREATE INDEX IF NOT EXISTS idx_transactions_wallet_created
+    ON transactions (wallet_id, created_at DESC, id DESC);
@@ -0,0 +1,8 @@
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS related_transaction_id,
+    DROP COLUMN IF EXISTS counterparty_wallet_id;
+
+DELETE FROM transactions WHERE operation_type = 'TRANSFER';
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW'));
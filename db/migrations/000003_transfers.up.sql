@@ -0,0 +1,8 @@
+-- Переводы между кошельками: пара связанных записей (списание и зачисление)
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER'));
+
+ALTER TABLE transactions
+    ADD COLUMN counterparty_wallet_id UUID REFERENCES wallets(id),
+    ADD COLUMN related_transaction_id UUID REFERENCES transactions(id);
@@ -0,0 +1,2 @@
+-- Баланс кошелька не может быть отрицательным
+ALTER TABLE wallets ADD CONSTRAINT wallets_balance_non_negative CHECK (balance >= 0);
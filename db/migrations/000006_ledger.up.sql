@@ -0,0 +1,65 @@
+-- Двойная запись: каждая операция кошелька - проводка из записей с нулевой суммой.
+-- wallets.balance остаётся проекцией счёта кошелька и обновляется в той же транзакции БД.
+
+CREATE TABLE ledger_accounts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID UNIQUE REFERENCES wallets(id) ON DELETE CASCADE, -- счёт кошелька
+    code VARCHAR(50) UNIQUE,                                         -- системный счёт
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((wallet_id IS NULL) <> (code IS NULL))
+);
+
+CREATE TABLE journal_entries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    transaction_id UUID UNIQUE REFERENCES transactions(id) ON DELETE CASCADE,
+    description VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- amount со знаком: положительная сумма увеличивает счёт, отрицательная уменьшает
+CREATE TABLE postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES journal_entries(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES ledger_accounts(id),
+    amount BIGINT NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX idx_postings_account ON postings (account_id);
+CREATE INDEX idx_postings_entry ON postings (entry_id);
+
+-- Сумма записей проводки проверяется при фиксации транзакции, когда все записи уже вставлены
+CREATE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT COALESCE(SUM(amount), 0) FROM postings WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Системные счета: поступления извне, выплаты наружу и транзитный счёт переводов
+INSERT INTO ledger_accounts (code) VALUES ('funding'), ('payout'), ('transfer_clearing');
+
+-- Счета для существующих кошельков и входящие остатки из funding
+INSERT INTO ledger_accounts (wallet_id) SELECT id FROM wallets;
+
+CREATE TEMPORARY TABLE opening_entries AS
+    SELECT uuid_generate_v4() AS entry_id, w.id AS wallet_id, w.balance
+    FROM wallets w WHERE w.balance <> 0;
+
+INSERT INTO journal_entries (id, description)
+    SELECT entry_id, 'OPENING_BALANCE' FROM opening_entries;
+
+INSERT INTO postings (entry_id, account_id, amount)
+    SELECT o.entry_id, a.id, o.balance
+    FROM opening_entries o JOIN ledger_accounts a ON a.wallet_id = o.wallet_id
+    UNION ALL
+    SELECT o.entry_id, f.id, -o.balance
+    FROM opening_entries o CROSS JOIN ledger_accounts f WHERE f.code = 'funding';
+
+DROP TABLE opening_entries;
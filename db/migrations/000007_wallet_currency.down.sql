@@ -0,0 +1,6 @@
+DELETE FROM ledger_accounts WHERE code IS NOT NULL AND currency <> 'RUB';
+ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_code_currency_key;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_code_key UNIQUE (code);
+ALTER TABLE ledger_accounts DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE wallets DROP COLUMN IF EXISTS currency;
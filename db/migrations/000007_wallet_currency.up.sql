@@ -0,0 +1,13 @@
+-- Валюта кошелька (ISO 4217); суммы хранятся в минимальных единицах этой валюты.
+-- Существующие кошельки считаются рублёвыми.
+ALTER TABLE wallets ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'RUB' CHECK (currency ~ '^[A-Z]{3}$');
+ALTER TABLE wallets ALTER COLUMN currency DROP DEFAULT;
+
+-- Счета главной книги ведутся в валюте; системные счета заводятся отдельно для каждой валюты
+ALTER TABLE ledger_accounts ADD COLUMN currency CHAR(3);
+UPDATE ledger_accounts a SET currency = w.currency FROM wallets w WHERE a.wallet_id = w.id;
+UPDATE ledger_accounts SET currency = 'RUB' WHERE code IS NOT NULL;
+ALTER TABLE ledger_accounts ALTER COLUMN currency SET NOT NULL;
+
+ALTER TABLE ledger_accounts DROP CONSTRAINT ledger_accounts_code_key;
+ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_code_currency_key UNIQUE (code, currency);
@@ -0,0 +1,18 @@
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT COALESCE(SUM(amount), 0) FROM postings WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DELETE FROM ledger_accounts a
+    WHERE a.code = 'fx_position' AND NOT EXISTS (SELECT 1 FROM postings p WHERE p.account_id = a.id);
+
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS exchange_rate_id,
+    DROP COLUMN IF EXISTS exchange_rate;
+
+DROP TABLE IF EXISTS fx_quotes;
+DROP TABLE IF EXISTS exchange_rates;
@@ -0,0 +1,53 @@
+-- Курсы обмена: каждая загрузка курса пары создаёт новую версию, старые версии не изменяются
+CREATE TABLE exchange_rates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL CHECK (rate > 0), -- единиц quote_currency за единицу base_currency
+    version BIGINT NOT NULL,
+    effective_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (base_currency <> quote_currency),
+    UNIQUE (base_currency, quote_currency, version)
+);
+
+CREATE INDEX idx_exchange_rates_pair ON exchange_rates (base_currency, quote_currency, effective_at DESC, version DESC);
+
+-- Котировки: курс, зафиксированный на короткое время; используется одним переводом
+CREATE TABLE fx_quotes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    rate_id UUID NOT NULL REFERENCES exchange_rates(id),
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    rate NUMERIC NOT NULL,
+    amount BIGINT NOT NULL,
+    converted_amount BIGINT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Курс, по которому выполнен перевод между валютами
+ALTER TABLE transactions
+    ADD COLUMN exchange_rate NUMERIC,
+    ADD COLUMN exchange_rate_id UUID REFERENCES exchange_rates(id);
+
+-- Валютная позиция для каждой валюты, в которой уже есть системные счета
+INSERT INTO ledger_accounts (code, currency)
+    SELECT DISTINCT 'fx_position', currency FROM ledger_accounts WHERE code IS NOT NULL
+    ON CONFLICT (code, currency) DO NOTHING;
+
+-- Проводка перевода между валютами затрагивает счета двух валют,
+-- поэтому сумма записей проверяется отдельно по каждой валюте
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS TRIGGER AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM postings p JOIN ledger_accounts a ON a.id = p.account_id
+        WHERE p.entry_id = NEW.entry_id
+        GROUP BY a.currency HAVING SUM(p.amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,11 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS hold_id;
+
+DELETE FROM transactions WHERE operation_type = 'CAPTURE';
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER'));
+
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_reserved_within_balance;
+ALTER TABLE wallets DROP COLUMN IF EXISTS reserved;
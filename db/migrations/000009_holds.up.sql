@@ -0,0 +1,25 @@
+-- Зарезервированная блокировками сумма; доступный баланс = balance - reserved
+ALTER TABLE wallets ADD COLUMN reserved BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE wallets ADD CONSTRAINT wallets_reserved_within_balance CHECK (reserved >= 0 AND reserved <= balance);
+
+CREATE TABLE holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    captured_amount BIGINT NOT NULL DEFAULT 0 CHECK (captured_amount >= 0 AND captured_amount <= amount),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('ACTIVE', 'CAPTURED', 'VOIDED', 'EXPIRED')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_holds_wallet ON holds (wallet_id);
+-- Поиск истёкших блокировок фоновой очисткой
+CREATE INDEX idx_holds_active_expiry ON holds (expires_at) WHERE status = 'ACTIVE';
+
+-- Списание по блокировке
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'CAPTURE'));
+
+ALTER TABLE transactions ADD COLUMN hold_id UUID REFERENCES holds(id);
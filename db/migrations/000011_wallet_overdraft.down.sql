@@ -0,0 +1,8 @@
+-- Откат невозможен, пока есть кошельки с отрицательным балансом или резервом сверх баланса
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_reserved_within_balance;
+ALTER TABLE wallets ADD CONSTRAINT wallets_reserved_within_balance CHECK (reserved >= 0 AND reserved <= balance);
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_balance_within_overdraft;
+ALTER TABLE wallets ADD CONSTRAINT wallets_balance_non_negative CHECK (balance >= 0);
+
+ALTER TABLE wallets DROP COLUMN IF EXISTS overdraft_limit;
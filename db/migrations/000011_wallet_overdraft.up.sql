@@ -0,0 +1,10 @@
+-- Кредитная линия кошелька: баланс может уходить в минус до -overdraft_limit
+ALTER TABLE wallets ADD COLUMN overdraft_limit BIGINT NOT NULL DEFAULT 0
+    CONSTRAINT wallets_overdraft_limit_non_negative CHECK (overdraft_limit >= 0);
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_balance_non_negative;
+ALTER TABLE wallets ADD CONSTRAINT wallets_balance_within_overdraft CHECK (balance >= -overdraft_limit);
+
+-- Блокировки тоже могут использовать кредитную линию; условие записано без сложения, чтобы не переполнить BIGINT
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_reserved_within_balance;
+ALTER TABLE wallets ADD CONSTRAINT wallets_reserved_within_balance CHECK (reserved >= 0 AND reserved - overdraft_limit <= balance);
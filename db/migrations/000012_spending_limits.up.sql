@@ -0,0 +1,9 @@
+-- Лимиты расходов, заданные для отдельных кошельков; для остальных периодов действует политика по умолчанию
+CREATE TABLE wallet_spending_limits (
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    period VARCHAR(10) NOT NULL CHECK (period IN ('DAILY', 'WEEKLY', 'MONTHLY')),
+    max_amount BIGINT NOT NULL DEFAULT 0 CHECK (max_amount >= 0), -- 0 - без ограничения
+    max_count BIGINT NOT NULL DEFAULT 0 CHECK (max_count >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wallet_id, period)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_wallets_owner;
+ALTER TABLE wallets DROP COLUMN IF EXISTS created_at;
+ALTER TABLE wallets DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS owners;
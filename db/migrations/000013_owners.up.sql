@@ -0,0 +1,13 @@
+-- Владельцы (клиенты), которым принадлежат кошельки
+CREATE TABLE owners (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Кошельки без владельца остаются анонимными
+ALTER TABLE wallets ADD COLUMN owner_id UUID CONSTRAINT wallets_owner_id_fkey REFERENCES owners(id);
+ALTER TABLE wallets ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX idx_wallets_owner ON wallets (owner_id, created_at) WHERE owner_id IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_wallets_metadata;
+ALTER TABLE wallets DROP COLUMN IF EXISTS metadata;
+ALTER TABLE wallets DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,6 @@
+-- Отображаемое имя и произвольные метаданные кошелька (например, ссылки на внешние счета)
+ALTER TABLE wallets ADD COLUMN display_name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE wallets ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Поиск кошельков по меткам: metadata @> '{"tier": "gold"}'
+CREATE INDEX idx_wallets_metadata ON wallets USING GIN (metadata jsonb_path_ops);
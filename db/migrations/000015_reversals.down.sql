@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_transactions_related;
+DROP INDEX IF EXISTS idx_transactions_reversed;
+
+DELETE FROM transactions WHERE operation_type = 'REVERSAL';
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_reversal_link;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversed_transaction_id;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'CAPTURE'));
@@ -0,0 +1,13 @@
+-- Отмена (возврат) операции: компенсирующая запись REVERSAL ссылается на отменяемую, история не меняется
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'CAPTURE', 'REVERSAL'));
+
+ALTER TABLE transactions ADD COLUMN reversed_transaction_id UUID REFERENCES transactions(id);
+ALTER TABLE transactions ADD CONSTRAINT transactions_reversal_link
+    CHECK ((operation_type = 'REVERSAL') = (reversed_transaction_id IS NOT NULL));
+
+-- Подсчёт уже возвращённой суммы операции
+CREATE INDEX idx_transactions_reversed ON transactions (reversed_transaction_id) WHERE reversed_transaction_id IS NOT NULL;
+-- Поиск зачисления перевода по записи списания
+CREATE INDEX idx_transactions_related ON transactions (related_transaction_id) WHERE related_transaction_id IS NOT NULL;
@@ -0,0 +1,10 @@
+-- Зачисления комиссий ссылаются на списания, поэтому удаляются первыми
+DELETE FROM transactions WHERE operation_type = 'FEE' AND related_transaction_id IN (
+    SELECT id FROM transactions WHERE operation_type = 'FEE'
+);
+DELETE FROM transactions WHERE operation_type = 'FEE';
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'CAPTURE', 'REVERSAL'));
+
+ALTER TABLE wallets DROP COLUMN IF EXISTS tier;
@@ -0,0 +1,8 @@
+-- Тариф кошелька, по которому правила комиссий выбирают ставку
+ALTER TABLE wallets ADD COLUMN tier VARCHAR(32) NOT NULL DEFAULT 'STANDARD';
+
+-- Комиссия: пара связанных записей FEE (списание с кошелька и зачисление на кошелёк доходов).
+-- Списание ссылается на запись операции, за которую взята комиссия, зачисление - на списание
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'CAPTURE', 'REVERSAL', 'FEE'));
@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS interest_runs;
+DROP TABLE IF EXISTS interest_accruals;
+DROP INDEX IF EXISTS idx_transactions_interest;
+
+-- Проводки выплат удаляются вместе с записями; после них счёт процентов пуст
+DELETE FROM transactions WHERE operation_type = 'INTEREST';
+DELETE FROM ledger_accounts WHERE code = 'interest';
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'CAPTURE', 'REVERSAL', 'FEE'));
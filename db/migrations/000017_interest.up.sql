@@ -0,0 +1,35 @@
+-- Проценты на остатки: ежедневные начисления в долях минимальной единицы и выплаты записями INTEREST
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_operation_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_operation_type_check
+    CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW', 'TRANSFER', 'CAPTURE', 'REVERSAL', 'FEE', 'INTEREST'));
+
+-- Счёт выплаченных процентов для каждой валюты, в которой уже есть системные счета
+INSERT INTO ledger_accounts (code, currency)
+    SELECT DISTINCT 'interest', currency FROM ledger_accounts WHERE code IS NOT NULL
+    ON CONFLICT (code, currency) DO NOTHING;
+
+-- Начисление за день сохраняется один раз: повторный запуск за ту же дату его не меняет.
+-- payout_transaction_id - выплата, в которую вошло начисление
+CREATE TABLE interest_accruals (
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    accrual_date DATE NOT NULL,
+    balance BIGINT NOT NULL,                             -- остаток на конец дня
+    annual_rate NUMERIC NOT NULL,                        -- годовая ставка в процентах
+    amount NUMERIC(38, 12) NOT NULL CHECK (amount >= 0), -- в минимальных единицах валюты
+    payout_transaction_id UUID REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (wallet_id, accrual_date)
+);
+
+CREATE INDEX idx_interest_accruals_unpaid ON interest_accruals (accrual_date) WHERE payout_transaction_id IS NULL;
+
+-- Итоги начисления по дням; последний день - точка продолжения после перерыва
+CREATE TABLE interest_runs (
+    accrual_date DATE PRIMARY KEY,
+    accrued BIGINT NOT NULL,
+    paid_out BIGINT NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Выплаты процентов кошельку для расчёта суммы к выплате
+CREATE INDEX idx_transactions_interest ON transactions (wallet_id) WHERE operation_type = 'INTEREST';
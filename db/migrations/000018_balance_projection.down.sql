@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS wallets_balance_projection ON wallets;
+DROP FUNCTION IF EXISTS check_wallet_balance_source();
+DROP TRIGGER IF EXISTS postings_immutable ON postings;
+DROP FUNCTION IF EXISTS reject_posting_update();
+DROP TRIGGER IF EXISTS postings_wallet_balance ON postings;
+DROP FUNCTION IF EXISTS apply_posting_to_wallet_balance();
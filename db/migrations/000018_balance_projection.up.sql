@@ -0,0 +1,53 @@
+-- wallets.balance - проекция счёта кошелька в главной книге: её изменяет только триггер на postings,
+-- поэтому баланс всегда равен сумме записей счёта, зафиксированных в той же транзакции.
+-- Ограничения wallets_balance_within_overdraft и wallets_reserved_within_balance проверяются
+-- при обновлении проекции, так что проводка, уводящая баланс за кредитную линию, отклоняется целиком
+
+CREATE FUNCTION apply_posting_to_wallet_balance() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE wallets SET balance = balance + NEW.amount
+        WHERE id = (SELECT wallet_id FROM ledger_accounts WHERE id = NEW.account_id);
+    ELSE
+        UPDATE wallets SET balance = balance - OLD.amount
+        WHERE id = (SELECT wallet_id FROM ledger_accounts WHERE id = OLD.account_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER postings_wallet_balance
+    AFTER INSERT OR DELETE ON postings
+    FOR EACH ROW EXECUTE FUNCTION apply_posting_to_wallet_balance();
+
+-- Записи книги не меняются: исправление - новая проводка
+CREATE FUNCTION reject_posting_update() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'postings are append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER postings_immutable
+    BEFORE UPDATE ON postings
+    FOR EACH ROW EXECUTE FUNCTION reject_posting_update();
+
+-- Прямое изменение баланса (не из триггера проводок) допускается только до остатка счёта в книге:
+-- так сверка исправляет расхождение, накопленное до этой миграции, но не может создать новое
+CREATE FUNCTION check_wallet_balance_source() RETURNS TRIGGER AS $$
+BEGIN
+    IF pg_trigger_depth() = 1 AND NEW.balance <> (
+        SELECT COALESCE(SUM(p.amount), 0) FROM postings p
+        JOIN ledger_accounts a ON a.id = p.account_id
+        WHERE a.wallet_id = NEW.id
+    ) THEN
+        RAISE EXCEPTION 'wallet % balance is maintained from ledger postings', NEW.id
+            USING ERRCODE = 'integrity_constraint_violation';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER wallets_balance_projection
+    BEFORE UPDATE OF balance ON wallets
+    FOR EACH ROW WHEN (NEW.balance IS DISTINCT FROM OLD.balance)
+    EXECUTE FUNCTION check_wallet_balance_source();
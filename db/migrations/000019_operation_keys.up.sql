@@ -0,0 +1,6 @@
+-- Ключи однократных операций (например, строк загрузки из файла): ключ занимается в транзакции
+-- операции, поэтому повторное выполнение той же строки после сбоя находит его и пропускается
+CREATE TABLE operation_keys (
+    key TEXT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -3,13 +3,52 @@ package config
 import (
     "log"
     "os"
+    "strconv"
+    "time"
 
     "github.com/joho/godotenv"
 )
 
+// границы суммы одной операции; 0 - граница не задана
+type AmountLimits struct {
+    Min int64
+    Max int64
+}
+
+// лимит расходов кошелька за период; 0 - без ограничения
+type SpendingLimit struct {
+    MaxAmount int64
+    MaxCount  int64
+}
+
 // структура для хранения конфигурации приложения
 type Config struct {
     DBUrl string // URL бд
+
+    DefaultCurrency string // валюта кошельков, созданных без указания валюты (DEFAULT_CURRENCY)
+
+    OperationLimits map[string]AmountLimits // границы сумм по типам операций (<ТИП>_MIN_AMOUNT, <ТИП>_MAX_AMOUNT)
+
+    // политика лимитов расходов по периодам DAILY, WEEKLY, MONTHLY
+    // (SPENDING_<ПЕРИОД>_MAX_AMOUNT, SPENDING_<ПЕРИОД>_MAX_COUNT)
+    SpendingLimits map[string]SpendingLimit
+
+    RatesFile    string        // CSV с курсами обмена, загружаемый при старте (FX_RATES_FILE)
+    RoundingMode string        // округление сумм при конвертации (FX_ROUNDING_MODE)
+    QuoteTTL     time.Duration // время жизни котировки обмена (FX_QUOTE_TTL)
+
+    HoldTTL           time.Duration // срок блокировки средств по умолчанию (HOLD_TTL)
+    HoldSweepInterval time.Duration // период фоновой очистки истёкших блокировок (HOLD_SWEEP_INTERVAL)
+
+    ReconcileInterval time.Duration // период фоновой сверки балансов с операциями (RECONCILE_INTERVAL)
+    ReconcileRepair   bool          // исправлять расхождения при фоновой сверке (RECONCILE_REPAIR)
+
+    FeeRulesFile string // JSON с тарифной сеткой комиссий и кошельками доходов (FEE_RULES_FILE); пусто - без комиссий
+
+    InterestRatesFile string        // JSON со ставками процентов по тарифам (INTEREST_RATES_FILE); пусто - без процентов
+    InterestInterval  time.Duration // период проверки незавершённых дней начисления процентов (INTEREST_INTERVAL)
+
+    AdminToken string // токен административных методов API (ADMIN_TOKEN)
 }
 
 // LoadConfig загружает конфигурацию из файла .env или из переменных окружения
@@ -19,7 +58,91 @@ func LoadConfig() *Config {
         log.Println("Warning: .env file not found or cannot be loaded")
     }
 
+    limits := make(map[string]AmountLimits)
+    for _, operationType := range []string{"DEPOSIT", "WITHDRAW", "TRANSFER"} {
+        limits[operationType] = AmountLimits{
+            Min: getEnvInt64(operationType+"_MIN_AMOUNT", 0),
+            Max: getEnvInt64(operationType+"_MAX_AMOUNT", 0),
+        }
+    }
+
+    spendingLimits := make(map[string]SpendingLimit)
+    for _, period := range []string{"DAILY", "WEEKLY", "MONTHLY"} {
+        spendingLimits[period] = SpendingLimit{
+            MaxAmount: getEnvInt64("SPENDING_"+period+"_MAX_AMOUNT", 0),
+            MaxCount:  getEnvInt64("SPENDING_"+period+"_MAX_COUNT", 0),
+        }
+    }
+
+    defaultCurrency := os.Getenv("DEFAULT_CURRENCY")
+    if defaultCurrency == "" {
+        defaultCurrency = "RUB"
+    }
+
+    roundingMode := os.Getenv("FX_ROUNDING_MODE")
+    if roundingMode == "" {
+        roundingMode = "HALF_EVEN"
+    }
+
     return &Config{
-        DBUrl: os.Getenv("DATABASE_URL"),
+        DBUrl:             os.Getenv("DATABASE_URL"),
+        DefaultCurrency:   defaultCurrency,
+        OperationLimits:   limits,
+        SpendingLimits:    spendingLimits,
+        RatesFile:         os.Getenv("FX_RATES_FILE"),
+        RoundingMode:      roundingMode,
+        QuoteTTL:          getEnvDuration("FX_QUOTE_TTL", 30*time.Second),
+        HoldTTL:           getEnvDuration("HOLD_TTL", 7*24*time.Hour),
+        HoldSweepInterval: getEnvDuration("HOLD_SWEEP_INTERVAL", time.Minute),
+        ReconcileInterval: getEnvDuration("RECONCILE_INTERVAL", time.Hour),
+        ReconcileRepair:   getEnvBool("RECONCILE_REPAIR", false),
+        FeeRulesFile:      os.Getenv("FEE_RULES_FILE"),
+        InterestRatesFile: os.Getenv("INTEREST_RATES_FILE"),
+        InterestInterval:  getEnvDuration("INTEREST_INTERVAL", time.Hour),
+        AdminToken:        os.Getenv("ADMIN_TOKEN"),
     }
-}
\ No newline at end of file
+}
+
+// getEnvInt64 читает целое число из переменной окружения; при ошибке возвращает значение по умолчанию
+func getEnvInt64(key string, def int64) int64 {
+    value := os.Getenv(key)
+    if value == "" {
+        return def
+    }
+    n, err := strconv.ParseInt(value, 10, 64)
+    if err != nil || n < 0 {
+        log.Printf("Warning: invalid value %q for %s, using %d", value, key, def)
+        return def
+    }
+    return n
+}
+
+// getEnvBool читает логическое значение ("true", "1", "false", ...) из переменной окружения;
+// при ошибке возвращает значение по умолчанию
+func getEnvBool(key string, def bool) bool {
+    value := os.Getenv(key)
+    if value == "" {
+        return def
+    }
+    b, err := strconv.ParseBool(value)
+    if err != nil {
+        log.Printf("Warning: invalid value %q for %s, using %t", value, key, def)
+        return def
+    }
+    return b
+}
+
+// getEnvDuration читает положительную длительность (например "30s") из переменной окружения;
+// при ошибке возвращает значение по умолчанию
+func getEnvDuration(key string, def time.Duration) time.Duration {
+    value := os.Getenv(key)
+    if value == "" {
+        return def
+    }
+    d, err := time.ParseDuration(value)
+    if err != nil || d <= 0 {
+        log.Printf("Warning: invalid value %q for %s, using %s", value, key, def)
+        return def
+    }
+    return d
+}
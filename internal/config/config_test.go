@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -17,4 +18,95 @@ func TestLoadConfig(t *testing.T) {
 	if cfg.DBUrl != expectedDBUrl {
 		t.Errorf("expected DATABASE_URL to be %s, got %s", expectedDBUrl, cfg.DBUrl)
 	}
-}
\ No newline at end of file
+}
+func TestLoadConfig_OperationLimits(t *testing.T) {
+	os.Setenv("DEPOSIT_MIN_AMOUNT", "10")
+	os.Setenv("DEPOSIT_MAX_AMOUNT", "1000000")
+	os.Setenv("WITHDRAW_MAX_AMOUNT", "not-a-number")
+	defer os.Unsetenv("DEPOSIT_MIN_AMOUNT")
+	defer os.Unsetenv("DEPOSIT_MAX_AMOUNT")
+	defer os.Unsetenv("WITHDRAW_MAX_AMOUNT")
+
+	cfg := LoadConfig()
+
+	if got := cfg.OperationLimits["DEPOSIT"]; got.Min != 10 || got.Max != 1000000 {
+		t.Errorf("unexpected DEPOSIT limits: %+v", got)
+	}
+	// Некорректное значение заменяется значением по умолчанию (без ограничения)
+	if got := cfg.OperationLimits["WITHDRAW"]; got.Min != 0 || got.Max != 0 {
+		t.Errorf("unexpected WITHDRAW limits: %+v", got)
+	}
+}
+
+func TestLoadConfig_FX(t *testing.T) {
+	os.Setenv("FX_QUOTE_TTL", "2m")
+	defer os.Unsetenv("FX_QUOTE_TTL")
+
+	cfg := LoadConfig()
+
+	if cfg.QuoteTTL != 2*time.Minute {
+		t.Errorf("expected quote TTL 2m, got %s", cfg.QuoteTTL)
+	}
+	if cfg.RoundingMode != "HALF_EVEN" {
+		t.Errorf("expected default rounding mode HALF_EVEN, got %s", cfg.RoundingMode)
+	}
+
+	os.Setenv("FX_QUOTE_TTL", "-1s")
+	if cfg := LoadConfig(); cfg.QuoteTTL != 30*time.Second {
+		t.Errorf("expected default quote TTL for invalid value, got %s", cfg.QuoteTTL)
+	}
+}
+
+func TestLoadConfig_SpendingLimits(t *testing.T) {
+	os.Setenv("SPENDING_DAILY_MAX_AMOUNT", "500000")
+	os.Setenv("SPENDING_MONTHLY_MAX_COUNT", "100")
+	defer os.Unsetenv("SPENDING_DAILY_MAX_AMOUNT")
+	defer os.Unsetenv("SPENDING_MONTHLY_MAX_COUNT")
+
+	cfg := LoadConfig()
+
+	if got := cfg.SpendingLimits["DAILY"]; got.MaxAmount != 500000 || got.MaxCount != 0 {
+		t.Errorf("unexpected DAILY limit: %+v", got)
+	}
+	if got := cfg.SpendingLimits["MONTHLY"]; got.MaxCount != 100 {
+		t.Errorf("unexpected MONTHLY limit: %+v", got)
+	}
+	if got := cfg.SpendingLimits["WEEKLY"]; got.MaxAmount != 0 || got.MaxCount != 0 {
+		t.Errorf("expected WEEKLY to be unlimited, got %+v", got)
+	}
+}
+
+func TestLoadConfig_Reconcile(t *testing.T) {
+	cfg := LoadConfig()
+	if cfg.ReconcileInterval != time.Hour || cfg.ReconcileRepair {
+		t.Errorf("unexpected reconcile defaults: %s %t", cfg.ReconcileInterval, cfg.ReconcileRepair)
+	}
+
+	os.Setenv("RECONCILE_INTERVAL", "15m")
+	os.Setenv("RECONCILE_REPAIR", "true")
+	defer os.Unsetenv("RECONCILE_INTERVAL")
+	defer os.Unsetenv("RECONCILE_REPAIR")
+	if cfg := LoadConfig(); cfg.ReconcileInterval != 15*time.Minute || !cfg.ReconcileRepair {
+		t.Errorf("unexpected reconcile settings: %s %t", cfg.ReconcileInterval, cfg.ReconcileRepair)
+	}
+
+	os.Setenv("RECONCILE_REPAIR", "maybe")
+	if cfg := LoadConfig(); cfg.ReconcileRepair {
+		t.Error("expected repair to stay disabled for invalid value")
+	}
+}
+
+func TestLoadConfig_Interest(t *testing.T) {
+	cfg := LoadConfig()
+	if cfg.InterestRatesFile != "" || cfg.InterestInterval != time.Hour {
+		t.Errorf("unexpected interest defaults: %q %s", cfg.InterestRatesFile, cfg.InterestInterval)
+	}
+
+	os.Setenv("INTEREST_RATES_FILE", "interest.json")
+	os.Setenv("INTEREST_INTERVAL", "10m")
+	defer os.Unsetenv("INTEREST_RATES_FILE")
+	defer os.Unsetenv("INTEREST_INTERVAL")
+	if cfg := LoadConfig(); cfg.InterestRatesFile != "interest.json" || cfg.InterestInterval != 10*time.Minute {
+		t.Errorf("unexpected interest settings: %q %s", cfg.InterestRatesFile, cfg.InterestInterval)
+	}
+}
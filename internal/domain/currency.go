@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// валюта ISO 4217: суммы хранятся в минимальных единицах (копейках, тиынах, центах)
+type Currency struct {
+	Code       string
+	MinorUnits int // число знаков после запятой
+}
+
+// поддерживаемые валюты
+var currencies = map[string]Currency{
+	"RUB": {Code: "RUB", MinorUnits: 2},
+	"KZT": {Code: "KZT", MinorUnits: 2},
+	"USD": {Code: "USD", MinorUnits: 2},
+	"EUR": {Code: "EUR", MinorUnits: 2},
+	"JPY": {Code: "JPY", MinorUnits: 0},
+	"KWD": {Code: "KWD", MinorUnits: 3},
+}
+
+// LookupCurrency возвращает валюту по коду ISO 4217
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, ErrUnsupportedCurrency
+	}
+	return currency, nil
+}
+
+// FormatAmount переводит сумму в минимальных единицах в десятичную строку, например 12345 -> "123.45"
+func (c Currency) FormatAmount(amount int64) string {
+	sign := ""
+	digits := strconv.FormatUint(uint64(amount), 10)
+	if amount < 0 {
+		sign = "-"
+		digits = strconv.FormatUint(uint64(-(amount+1))+1, 10) // без переполнения для MinInt64
+	}
+	if c.MinorUnits == 0 {
+		return sign + digits
+	}
+	if len(digits) <= c.MinorUnits {
+		digits = strings.Repeat("0", c.MinorUnits-len(digits)+1) + digits
+	}
+	point := len(digits) - c.MinorUnits
+	return sign + digits[:point] + "." + digits[point:]
+}
@@ -1,7 +1,12 @@
 package domain
 
 import (
+	"errors"
+	"io"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -24,4 +29,576 @@ func TestWalletCreation(t *testing.T) {
 	if wallet.Balance != initialBalance {
 		t.Errorf("expected balance %d, got %d", initialBalance, wallet.Balance)
 	}
-}
\ No newline at end of file
+}
+
+func TestTransactionCursorRoundTrip(t *testing.T) {
+	cursor := TransactionCursor{
+		CreatedAt: time.Date(2026, 9, 30, 23, 59, 59, 123456000, time.UTC),
+		ID:        uuid.New(),
+	}
+
+	decoded, err := DecodeTransactionCursor(cursor.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID {
+		t.Errorf("expected %+v, got %+v", cursor, decoded)
+	}
+
+	if _, err := DecodeTransactionCursor("not-a-cursor"); err == nil {
+		t.Error("expected error for malformed cursor")
+	}
+}
+
+func TestLedgerSummaryReconcile(t *testing.T) {
+	summary := LedgerSummary{WalletLiabilities: 700, WalletBalances: 700, Funded: 1000, PaidOut: 300}
+	summary.Reconcile()
+	if !summary.Balanced {
+		t.Errorf("expected summary to be balanced: %+v", summary)
+	}
+
+	// Незавершённый перевод на транзитном счёте
+	summary.InTransit = 100
+	summary.Reconcile()
+	if summary.Balanced {
+		t.Errorf("expected summary with funds in transit to be unbalanced: %+v", summary)
+	}
+
+	// Проекция балансов разошлась с главной книгой
+	summary = LedgerSummary{WalletLiabilities: 700, WalletBalances: 650, Funded: 1000, PaidOut: 300}
+	summary.Reconcile()
+	if summary.Balanced {
+		t.Errorf("expected summary with drifted balances to be unbalanced: %+v", summary)
+	}
+
+	// Кошельки обменяли 200 на другую валюту: обязательства уменьшились на валютную позицию
+	summary = LedgerSummary{WalletLiabilities: 500, WalletBalances: 500, Funded: 1000, PaidOut: 300, FXPosition: 200}
+	summary.Reconcile()
+	if !summary.Balanced {
+		t.Errorf("expected summary with fx position to be balanced: %+v", summary)
+	}
+
+	// Выплаченные проценты увеличивают обязательства
+	summary = LedgerSummary{WalletLiabilities: 750, WalletBalances: 750, Funded: 1000, PaidOut: 300, InterestPaid: 50}
+	summary.Reconcile()
+	if !summary.Balanced {
+		t.Errorf("expected summary with interest to be balanced: %+v", summary)
+	}
+}
+
+func TestCurrencyFormatAmount(t *testing.T) {
+	rub, _ := LookupCurrency("RUB")
+	jpy, _ := LookupCurrency("jpy")
+	kwd, _ := LookupCurrency("KWD")
+
+	tests := []struct {
+		currency Currency
+		amount   int64
+		expected string
+	}{
+		{rub, 12345, "123.45"},
+		{rub, 5, "0.05"},
+		{rub, 0, "0.00"},
+		{rub, -150, "-1.50"},
+		{jpy, 1500, "1500"},
+		{kwd, 1234, "1.234"},
+		{rub, math.MinInt64, "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := tt.currency.FormatAmount(tt.amount); got != tt.expected {
+			t.Errorf("%s %d: expected %q, got %q", tt.currency.Code, tt.amount, tt.expected, got)
+		}
+	}
+
+	if _, err := LookupCurrency("XXX"); err != ErrUnsupportedCurrency {
+		t.Errorf("expected ErrUnsupportedCurrency, got %v", err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	usd, _ := LookupCurrency("USD")
+	rub, _ := LookupCurrency("RUB")
+	jpy, _ := LookupCurrency("JPY")
+
+	tests := []struct {
+		name     string
+		amount   int64
+		from, to Currency
+		rate     string
+		mode     RoundingMode
+		expected int64
+	}{
+		{"exact", 1000, usd, rub, "90", RoundHalfEven, 90000},
+		{"half up", 1, usd, rub, "0.5", RoundHalfUp, 1},
+		{"half even rounds to even", 1, usd, rub, "0.5", RoundHalfEven, 0},
+		{"half even odd", 3, usd, rub, "0.5", RoundHalfEven, 2},
+		{"down", 199, rub, usd, "0.0111", RoundDown, 2},
+		{"up", 199, rub, usd, "0.0111", RoundUp, 3},
+		{"minor units scale", 150, usd, jpy, "150.25", RoundHalfUp, 225},
+		{"minor units scale back", 225, jpy, usd, "0.0066", RoundHalfUp, 149},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.amount, tt.from, tt.to, tt.rate, tt.mode)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.expected, got)
+		}
+	}
+
+	if got := Prorate(90645, 333, 1001, RoundDown); got != 30154 {
+		t.Errorf("expected prorated amount 30154, got %d", got)
+	}
+	if got := Prorate(math.MaxInt64, 1, 2, RoundHalfUp); got != math.MaxInt64/2+1 {
+		t.Errorf("expected prorate without overflow, got %d", got)
+	}
+
+	if _, err := Convert(math.MaxInt64, usd, rub, "100", RoundDown); err == nil {
+		t.Error("expected error for converted amount out of int64 range")
+	}
+	for _, rate := range []string{"", "0", "-1.5", "abc", "1/3", "1e3"} {
+		if _, err := Convert(100, usd, rub, rate, RoundDown); err != ErrInvalidRate {
+			t.Errorf("rate %q: expected ErrInvalidRate, got %v", rate, err)
+		}
+	}
+	if _, err := ParseRoundingMode("half_up"); err != nil {
+		t.Errorf("expected rounding mode to be parsed case-insensitively, got %v", err)
+	}
+	if _, err := ParseRoundingMode("CEILING"); err == nil {
+		t.Error("expected error for unknown rounding mode")
+	}
+}
+
+func TestParseExchangeRatesCSV(t *testing.T) {
+	input := "base_currency,quote_currency,rate,effective_at\n" +
+		"USD,RUB,91.25,2026-01-01T00:00:00Z\n" +
+		"RUB,KZT, 5.4,\n"
+
+	rates, err := ParseExchangeRatesCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rates, got %d", len(rates))
+	}
+	if rates[0].BaseCurrency != "USD" || rates[0].QuoteCurrency != "RUB" || rates[0].Rate != "91.25" ||
+		!rates[0].EffectiveAt.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected first rate: %+v", rates[0])
+	}
+	if rates[1].Rate != "5.4" || !rates[1].EffectiveAt.IsZero() {
+		t.Errorf("unexpected second rate: %+v", rates[1])
+	}
+
+	if _, err := ParseExchangeRatesCSV(strings.NewReader("base,quote\nUSD,RUB\n")); err == nil {
+		t.Error("expected error for missing columns")
+	}
+	if _, err := ParseExchangeRatesCSV(strings.NewReader("base_currency,quote_currency,rate,effective_at\nUSD,RUB,1,yesterday\n")); err == nil {
+		t.Error("expected error for invalid effective_at")
+	}
+}
+func TestWalletStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{WalletActive, WalletFrozen, true},
+		{WalletActive, WalletClosed, true},
+		{WalletFrozen, WalletActive, true},
+		{WalletFrozen, WalletClosed, true},
+		{WalletActive, WalletActive, false},
+		{WalletClosed, WalletActive, false},
+		{WalletClosed, WalletFrozen, false},
+		{WalletActive, "DELETED", false},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("%s -> %s: expected %v, got %v", tt.from, tt.to, tt.allowed, got)
+		}
+	}
+
+	wallet := Wallet{Status: WalletActive, Balance: 100}
+	if err := wallet.CheckTransition(WalletClosed); err != ErrWalletNotEmpty {
+		t.Errorf("expected ErrWalletNotEmpty, got %v", err)
+	}
+	wallet = Wallet{Status: WalletFrozen, Reserved: 0}
+	if err := wallet.CheckTransition(WalletClosed); err != nil {
+		t.Errorf("expected empty wallet to be closable, got %v", err)
+	}
+
+	frozen := Wallet{Status: WalletFrozen}
+	if frozen.CanDebit() != ErrWalletFrozen || frozen.CanCredit() != nil {
+		t.Error("expected frozen wallet to accept credits and reject debits")
+	}
+	closed := Wallet{Status: WalletClosed}
+	if closed.CanDebit() != ErrWalletClosed || closed.CanCredit() != ErrWalletClosed {
+		t.Error("expected closed wallet to reject all operations")
+	}
+}
+
+func TestWalletRemainingCredit(t *testing.T) {
+	tests := []struct {
+		wallet   Wallet
+		expected int64
+	}{
+		{Wallet{Balance: 100, OverdraftLimit: 500}, 500},
+		{Wallet{Balance: -300, OverdraftLimit: 500}, 200},
+		{Wallet{Balance: 100, Reserved: 250, OverdraftLimit: 500}, 350},
+		{Wallet{Balance: -500, OverdraftLimit: 500}, 0},
+		{Wallet{Balance: 100}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.wallet.RemainingCredit(); got != tt.expected {
+			t.Errorf("%+v: expected remaining credit %d, got %d", tt.wallet, tt.expected, got)
+		}
+	}
+}
+
+func TestSpendingLimitPeriods(t *testing.T) {
+	now := time.Date(2026, 10, 15, 13, 45, 0, 0, time.UTC) // четверг
+	tests := []struct {
+		period   string
+		expected time.Time
+	}{
+		{PeriodDaily, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{PeriodWeekly, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{PeriodMonthly, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		start, err := PeriodStart(tt.period, now)
+		if err != nil || !start.Equal(tt.expected) {
+			t.Errorf("%s: expected %s, got %s; error: %v", tt.period, tt.expected, start, err)
+		}
+	}
+	// Воскресенье относится к неделе, начавшейся в понедельник
+	sunday := time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)
+	if start, _ := PeriodStart(PeriodWeekly, sunday); !start.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected week start for sunday: %s", start)
+	}
+
+	limit := SpendingLimit{Period: PeriodDaily, MaxAmount: 1000, MaxCount: 2}
+	if err := limit.Check(SpendingUsage{Amount: 400, Count: 1}, 600); err != nil {
+		t.Errorf("expected amount up to the limit to pass, got %v", err)
+	}
+	if err := limit.Check(SpendingUsage{Amount: 400, Count: 1}, 601); !errors.Is(err, ErrSpendingLimitExceeded) {
+		t.Errorf("expected ErrSpendingLimitExceeded for amount, got %v", err)
+	}
+	if err := limit.Check(SpendingUsage{Amount: 0, Count: 2}, 1); !errors.Is(err, ErrSpendingLimitExceeded) {
+		t.Errorf("expected ErrSpendingLimitExceeded for count, got %v", err)
+	}
+	invalid := SpendingLimit{Period: "daily", MaxAmount: -1}
+	if err := invalid.Validate(); !errors.Is(err, ErrInvalidSpendingLimit) || invalid.Period != PeriodDaily {
+		t.Errorf("expected ErrInvalidSpendingLimit with normalized period, got %v (%s)", err, invalid.Period)
+	}
+}
+
+func TestWalletMetadata(t *testing.T) {
+	wallet := Wallet{DisplayName: "Payroll", Metadata: map[string]string{"tier": "gold", "crm": "acc-1"}}
+	if err := wallet.ValidateDetails(); err != nil {
+		t.Fatalf("expected valid metadata, got %v", err)
+	}
+	invalid := []Wallet{
+		{DisplayName: strings.Repeat("я", MaxDisplayNameLength+1)},
+		{Metadata: map[string]string{"": "x"}},
+		{Metadata: map[string]string{"a:b": "x"}},
+		{Metadata: map[string]string{"tier": strings.Repeat("x", MaxMetadataValueLength+1)}},
+	}
+	for _, w := range invalid {
+		if err := w.ValidateDetails(); !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("expected ErrInvalidMetadata for %+v, got %v", w, err)
+		}
+	}
+
+	name, silver := " Savings ", "silver"
+	updated := WalletUpdate{DisplayName: &name, Metadata: map[string]*string{"tier": &silver, "crm": nil}}.Apply(wallet)
+	if updated.DisplayName != "Savings" || len(updated.Metadata) != 1 || updated.Metadata["tier"] != "silver" {
+		t.Errorf("unexpected updated wallet: %+v", updated)
+	}
+	if wallet.Metadata["tier"] != "gold" || wallet.Metadata["crm"] != "acc-1" {
+		t.Errorf("update must not modify original metadata: %v", wallet.Metadata)
+	}
+	if unchanged := (WalletUpdate{}).Apply(wallet); unchanged.DisplayName != "Payroll" || len(unchanged.Metadata) != 2 {
+		t.Errorf("empty update changed wallet: %+v", unchanged)
+	}
+
+	if key, value, err := ParseLabel("ref:crm:42"); err != nil || key != "ref" || value != "crm:42" {
+		t.Errorf("unexpected label: %q %q %v", key, value, err)
+	}
+	for _, label := range []string{"tier", ":gold"} {
+		if _, _, err := ParseLabel(label); !errors.Is(err, ErrInvalidMetadata) {
+			t.Errorf("expected ErrInvalidMetadata for %q, got %v", label, err)
+		}
+	}
+}
+
+func TestFeeSchedule(t *testing.T) {
+	revenue := uuid.New()
+	schedule, err := ParseFeeScheduleJSON(strings.NewReader(`{
+		"revenueWallets": {"rub": "` + revenue.String() + `"},
+		"rules": [
+			{"name": "gold withdraw", "operationType": "withdraw", "tier": "gold"},
+			{"name": "large withdraw", "operationType": "WITHDRAW", "minAmount": 100000, "percent": "0.5", "maxFee": 1000},
+			{"name": "withdraw", "operationType": "WITHDRAW", "flat": 50, "percent": "1.25", "minFee": 100, "rounding": "up"},
+			{"name": "usd transfer", "operationType": "TRANSFER", "currency": "USD", "flat": 10}
+		]
+	}`))
+	if err == nil {
+		t.Fatal("expected error for USD rule without revenue wallet")
+	}
+
+	schedule, err = ParseFeeScheduleJSON(strings.NewReader(`{
+		"revenueWallets": {"rub": "` + revenue.String() + `"},
+		"rules": [
+			{"name": "gold withdraw", "operationType": "withdraw", "tier": "gold"},
+			{"name": "large withdraw", "operationType": "WITHDRAW", "minAmount": 100000, "percent": "0.5", "maxFee": 1000},
+			{"name": "withdraw", "operationType": "WITHDRAW", "flat": 50, "percent": "1.25", "minFee": 100, "rounding": "up"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("could not parse fee schedule: %v", err)
+	}
+	if schedule.RevenueWallets["RUB"] != revenue || schedule.Rules[0].Tier != "GOLD" || schedule.Rules[1].Rounding != RoundHalfEven {
+		t.Errorf("expected codes to be normalized: %+v", schedule)
+	}
+
+	tests := []struct {
+		name     string
+		currency string
+		tier     string
+		amount   int64
+		rule     string
+		flat     int64
+		percent  int64
+		total    int64
+	}{
+		{"tier rule comes first", "RUB", "GOLD", 500000, "gold withdraw", 0, 0, 0},
+		{"band with cap", "RUB", "STANDARD", 500000, "large withdraw", 0, 2500, 1000},
+		{"band without cap", "RUB", "STANDARD", 100000, "large withdraw", 0, 500, 500},
+		{"minimum fee", "RUB", "STANDARD", 1000, "withdraw", 50, 13, 100},
+		{"rounding up", "RUB", "STANDARD", 9999, "withdraw", 50, 125, 175},
+	}
+	for _, tt := range tests {
+		rule, ok := schedule.Match("WITHDRAW", tt.currency, tt.tier, tt.amount)
+		if !ok || rule.Name != tt.rule {
+			t.Errorf("%s: expected rule %q, got %q (%t)", tt.name, tt.rule, rule.Name, ok)
+			continue
+		}
+		flat, percent, total, err := rule.Calculate(tt.amount)
+		if err != nil || flat != tt.flat || percent != tt.percent || total != tt.total {
+			t.Errorf("%s: expected %d+%d=%d, got %d+%d=%d; error:%v", tt.name, tt.flat, tt.percent, tt.total, flat, percent, total, err)
+		}
+	}
+	if _, ok := schedule.Match("DEPOSIT", "RUB", "STANDARD", 1000); ok {
+		t.Error("expected no rule for deposits")
+	}
+	if _, ok := schedule.Match("WITHDRAW", "USD", "STANDARD", 1000); ok {
+		t.Error("expected no fee in a currency without revenue wallet")
+	}
+
+	for _, invalid := range []string{
+		`{"rules": [{"name": "x", "operationType": "CAPTURE", "currency": "RUB"}], "revenueWallets": {"RUB": "` + revenue.String() + `"}}`,
+		`{"rules": [{"name": "x", "operationType": "WITHDRAW", "percent": "101"}], "revenueWallets": {"RUB": "` + revenue.String() + `"}}`,
+		`{"rules": [{"name": "x", "operationType": "WITHDRAW", "percent": "1e2"}], "revenueWallets": {"RUB": "` + revenue.String() + `"}}`,
+		`{"rules": [{"name": "x", "operationType": "WITHDRAW", "minFee": 10, "maxFee": 5}], "revenueWallets": {"RUB": "` + revenue.String() + `"}}`,
+		`{"rules": [{"name": "x", "operationType": "WITHDRAW", "minAmount": 10, "maxAmount": 5}], "revenueWallets": {"RUB": "` + revenue.String() + `"}}`,
+		`{"rules": [{"name": "x", "operationType": "WITHDRAW", "rounding": "SIDEWAYS"}], "revenueWallets": {"RUB": "` + revenue.String() + `"}}`,
+		`{"rules": [{"name": "", "operationType": "WITHDRAW"}], "revenueWallets": {"RUB": "` + revenue.String() + `"}}`,
+		`{"rules": [{"name": "x", "operationType": "WITHDRAW"}]}`,
+		`{"rules": [], "revenueWallets": {"RUB": "00000000-0000-0000-0000-000000000000"}}`,
+		`{"rules": [], "unknown": true}`,
+	} {
+		if _, err := ParseFeeScheduleJSON(strings.NewReader(invalid)); !errors.Is(err, ErrInvalidFeeRule) {
+			t.Errorf("expected ErrInvalidFeeRule for %s, got %v", invalid, err)
+		}
+	}
+
+	if _, _, _, err := (FeeRule{Percent: "100", Flat: math.MaxInt64, Rounding: RoundDown}).Calculate(1); !errors.Is(err, ErrAmountOutOfRange) {
+		t.Errorf("expected ErrAmountOutOfRange, got %v", err)
+	}
+	if tier, err := ValidateTier(" gold_2 "); err != nil || tier != "GOLD_2" {
+		t.Errorf("expected GOLD_2, got %q; error:%v", tier, err)
+	}
+	if _, err := ValidateTier("gold tier"); !errors.Is(err, ErrInvalidTier) {
+		t.Errorf("expected ErrInvalidTier, got %v", err)
+	}
+}
+
+func TestInterestSchedule(t *testing.T) {
+	schedule, err := ParseInterestScheduleJSON(strings.NewReader(`{
+		"rates": [
+			{"tier": "gold", "currency": "usd", "annualRate": "5"},
+			{"tier": "GOLD", "annualRate": "7.3"},
+			{"tier": "STANDARD", "annualRate": "0"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("could not parse interest schedule: %v", err)
+	}
+	if schedule.DayCount != 365 || schedule.Payout != PayoutMonthly || schedule.Rates[0].Tier != "GOLD" || schedule.Rates[0].Currency != "USD" {
+		t.Errorf("expected defaults and normalized codes: %+v", schedule)
+	}
+	if rate, ok := schedule.Match("GOLD", "USD"); !ok || rate.AnnualRate != "5" {
+		t.Errorf("expected USD rate 5, got %+v", rate)
+	}
+	if rate, ok := schedule.Match("GOLD", "RUB"); !ok || rate.AnnualRate != "7.3" {
+		t.Errorf("expected any-currency rate 7.3, got %+v", rate)
+	}
+	if _, ok := schedule.Match("PREMIUM", "RUB"); ok {
+		t.Error("expected no rate for PREMIUM")
+	}
+
+	tests := []struct {
+		name    string
+		balance int64
+		rate    string
+		amount  string
+		ok      bool
+	}{
+		{"whole units", 100000, "7.3", "20.000000000000", true},
+		{"sub-minor precision", 1, "7.3", "0.000200000000", true},
+		{"truncated", 1, "1", "0.000027397260", true},
+		{"too small", 1, "0.0000000001", "", false},
+		{"zero rate", 100000, "0", "", false},
+		{"negative balance", -100000, "7.3", "", false},
+		{"max balance", math.MaxInt64, "100", "25269512429739111.800000000000", true},
+	}
+	for _, tt := range tests {
+		amount, ok := schedule.DailyInterest(tt.balance, tt.rate)
+		if amount != tt.amount || ok != tt.ok {
+			t.Errorf("%s: expected %q (%t), got %q (%t)", tt.name, tt.amount, tt.ok, amount, ok)
+		}
+	}
+
+	monthEnd, midMonth := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC)
+	if !schedule.PayoutDue(monthEnd) || schedule.PayoutDue(midMonth) {
+		t.Error("expected monthly payout on the last day of month only")
+	}
+	if daily := (InterestSchedule{Payout: PayoutDaily}); !daily.PayoutDue(midMonth) {
+		t.Error("expected daily payout")
+	}
+	if date := AccrualDate(time.Date(2024, 3, 1, 1, 30, 0, 0, time.FixedZone("MSK", 3*60*60))); !date.Equal(midMonth.AddDate(0, 0, 1)) {
+		t.Errorf("expected accrual date 2024-02-29, got %s", date)
+	}
+
+	for _, invalid := range []string{
+		`{"rates": [{"tier": "GOLD", "annualRate": "101"}]}`,
+		`{"rates": [{"tier": "GOLD", "annualRate": "-1"}]}`,
+		`{"rates": [{"tier": "GOLD", "annualRate": "1e1"}]}`,
+		`{"rates": [{"tier": "GOLD", "annualRate": ""}]}`,
+		`{"rates": [], "dayCount": 100}`,
+		`{"rates": [], "payout": "YEARLY"}`,
+		`{"rates": [], "unknown": true}`,
+	} {
+		if _, err := ParseInterestScheduleJSON(strings.NewReader(invalid)); !errors.Is(err, ErrInvalidInterestRate) {
+			t.Errorf("expected ErrInvalidInterestRate for %s, got %v", invalid, err)
+		}
+	}
+	if _, err := ParseInterestScheduleJSON(strings.NewReader(`{"rates": [{"tier": "", "annualRate": "1"}]}`)); !errors.Is(err, ErrInvalidTier) {
+		t.Errorf("expected ErrInvalidTier, got %v", err)
+	}
+}
+
+func TestAccruedInterestDue(t *testing.T) {
+	tests := []struct {
+		accrued string
+		paid    int64
+		due     int64
+	}{
+		{"0.999999999999", 0, 0},
+		{"2.469000000000", 0, 2},
+		{"37.040600000000", 2, 35},
+		{"37.040600000000", 37, 0},
+		{"10.000000000000", 12, 0}, // выплачено больше начисленного на день
+	}
+	for _, tt := range tests {
+		due, err := AccruedInterest{Accrued: tt.accrued, Paid: tt.paid}.Due()
+		if err != nil || due != tt.due {
+			t.Errorf("%s - %d: expected %d, got %d; error:%v", tt.accrued, tt.paid, tt.due, due, err)
+		}
+	}
+	if _, err := (AccruedInterest{Accrued: "1e30"}).Due(); !errors.Is(err, ErrAmountOutOfRange) {
+		t.Errorf("expected ErrAmountOutOfRange, got %v", err)
+	}
+	if _, err := (AccruedInterest{Accrued: "abc"}).Due(); err == nil {
+		t.Error("expected error for malformed amount")
+	}
+}
+
+func TestOperationReader(t *testing.T) {
+	walletID, destinationID := uuid.New(), uuid.New()
+	readAll := func(t *testing.T, rows *OperationReader) []ImportRow {
+		var result []ImportRow
+		for {
+			row, err := rows.Next()
+			if err == io.EOF {
+				return result
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result = append(result, row)
+		}
+	}
+
+	// JSONL: пустые строки пропускаются, неразобранная строка возвращается с ошибкой
+	rows, err := NewOperationReader(strings.NewReader(
+		`{"walletId":"`+walletID.String()+`","operationType":"DEPOSIT","amount":100}`+"\n\n"+
+			`{"walletId":"`+walletID.String()+`","operationType":"TRANSFER","amount":5,"destinationWalletId":"`+destinationID.String()+`"}`+"\n"+
+			`{"walletId":`+"\n"), "JSONL")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := readAll(t, rows)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", result)
+	}
+	if result[0].Line != 1 || result[0].Err != nil || result[0].Operation.Amount != 100 || result[0].Operation.WalletID != walletID {
+		t.Errorf("unexpected first row: %+v", result[0])
+	}
+	if result[1].Line != 3 || result[1].Operation.DestinationWalletID != destinationID {
+		t.Errorf("unexpected second row: %+v", result[1])
+	}
+	if result[2].Line != 4 || !errors.Is(result[2].Err, ErrInvalidImport) || result[2].Raw != `{"walletId":` {
+		t.Errorf("expected malformed row 4, got %+v", result[2])
+	}
+
+	// CSV: колонки по именам заголовка, необязательные поля можно опустить
+	rows, err = NewOperationReader(strings.NewReader(
+		"Amount,walletId,operationType,destinationWalletId,currency\n"+
+			"100,"+walletID.String()+",DEPOSIT,,rub\n"+
+			"5,"+walletID.String()+",TRANSFER,"+destinationID.String()+"\n"+
+			"ten,"+walletID.String()+",WITHDRAW,,\n"+
+			"1,not-a-uuid,DEPOSIT,,\n"), ImportCSV)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result = readAll(t, rows)
+	if len(result) != 4 {
+		t.Fatalf("expected 4 rows, got %+v", result)
+	}
+	if op := result[0].Operation; result[0].Line != 2 || op.Amount != 100 || op.OperationType != "DEPOSIT" || op.Currency != "rub" {
+		t.Errorf("unexpected first row: %+v", result[0])
+	}
+	if result[1].Err != nil || result[1].Operation.DestinationWalletID != destinationID {
+		t.Errorf("unexpected second row: %+v", result[1])
+	}
+	for _, row := range result[2:] {
+		if !errors.Is(row.Err, ErrInvalidImport) {
+			t.Errorf("expected ErrInvalidImport for line %d, got %v", row.Line, row.Err)
+		}
+	}
+	if result[2].Line != 4 || result[2].Raw != "ten,"+walletID.String()+",WITHDRAW,," {
+		t.Errorf("unexpected raw row: %+v", result[2])
+	}
+
+	for _, tt := range []struct{ format, data string }{
+		{"xml", ""},
+		{ImportCSV, ""},
+		{ImportCSV, "walletId,amount\n"},
+	} {
+		if _, err := NewOperationReader(strings.NewReader(tt.data), tt.format); !errors.Is(err, ErrInvalidImport) {
+			t.Errorf("%s %q: expected ErrInvalidImport, got %v", tt.format, tt.data, err)
+		}
+	}
+}
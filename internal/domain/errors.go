@@ -0,0 +1,47 @@
+package domain
+
+import "errors"
+
+// ошибки предметной области
+var (
+	ErrInsufficientFunds   = errors.New("insufficient funds")           // недостаточно средств для списания
+	ErrWalletNotFound      = errors.New("wallet not found")             // кошелёк не существует
+	ErrInvalidOperation    = errors.New("invalid operation type")       // неизвестный тип операции
+	ErrInvalidAmount       = errors.New("invalid amount")               // сумма операции не положительная
+	ErrAmountOutOfRange    = errors.New("amount out of range")          // сумма вне допустимых границ для типа операции
+	ErrBalanceOverflow     = errors.New("balance overflow")             // баланс вышел бы за пределы int64
+	ErrInvalidDestination  = errors.New("invalid transfer destination") // получатель перевода не указан или совпадает с отправителем
+	ErrInvalidCursor       = errors.New("invalid cursor")               // курсор пагинации повреждён
+	ErrUnsupportedCurrency = errors.New("unsupported currency")         // код валюты не поддерживается
+	ErrCurrencyMismatch    = errors.New("currency mismatch")            // валюта операции не совпадает с валютой кошелька
+	ErrInvalidRate         = errors.New("invalid exchange rate")        // курс не является положительным десятичным числом
+	ErrRateNotFound        = errors.New("exchange rate not found")      // для пары валют нет действующего курса
+	ErrQuoteNotFound       = errors.New("fx quote not found")           // котировка не существует
+	ErrQuoteExpired        = errors.New("fx quote expired or used")     // срок котировки истёк или она уже использована
+	ErrQuoteMismatch       = errors.New("fx quote mismatch")            // котировка выдана на другую пару валют или сумму
+	ErrHoldNotFound        = errors.New("hold not found")               // блокировка не существует
+	ErrHoldNotActive       = errors.New("hold is not active")           // блокировка уже списана, отменена или истекла
+	ErrWalletFrozen        = errors.New("wallet is frozen")             // списания с замороженного кошелька запрещены
+	ErrWalletClosed        = errors.New("wallet is closed")             // операции с закрытым кошельком запрещены
+	ErrWalletNotEmpty      = errors.New("wallet is not empty")          // закрыть можно только кошелёк без средств и блокировок
+	ErrOverdraftInUse      = errors.New("overdraft limit is in use")    // новый лимит меньше уже использованного кредита
+	ErrOwnerNotFound       = errors.New("owner not found")              // владелец кошелька не существует
+	ErrInvalidOwner        = errors.New("invalid owner")                // у владельца не указано имя
+	ErrInvalidMetadata     = errors.New("invalid wallet metadata")      // слишком длинное имя, неверный ключ или метка
+	ErrTransactionNotFound = errors.New("transaction not found")        // операция не существует
+	ErrNotReversible       = errors.New("transaction not reversible")   // отмену нельзя отменить
+	ErrAlreadyReversed     = errors.New("transaction already reversed") // сумма операции уже возвращена полностью
+	ErrInvalidPeriod       = errors.New("invalid period")               // начало периода не раньше его конца
+	ErrInvalidFeeRule      = errors.New("invalid fee rule")             // правило комиссии неполное или противоречивое
+	ErrInvalidTier         = errors.New("invalid wallet tier")          // тариф кошелька пуст или содержит недопустимые символы
+	ErrInvalidInterestRate = errors.New("invalid interest rate")        // ставка процентов вне 0-100% или неверный тариф
+	ErrInvalidAccrualDate  = errors.New("invalid accrual date")         // день начисления процентов ещё не закончился
+	ErrInvalidBatch        = errors.New("invalid batch")                // пакет операций пуст или превышает допустимый размер
+	ErrInvalidImport       = errors.New("invalid import file")          // неизвестный формат файла загрузки или неверная строка
+
+	ErrInvalidStatusTransition = errors.New("invalid wallet status transition") // переход между статусами кошелька запрещён
+	ErrSpendingLimitExceeded   = errors.New("spending limit exceeded")          // списание превысило лимит расходов за период
+	ErrInvalidSpendingLimit    = errors.New("invalid spending limit")           // неизвестный период или отрицательная граница лимита
+	ErrRefundExceedsAmount     = errors.New("refund exceeds original amount")   // возврат больше невозвращённого остатка операции
+	ErrOperationApplied        = errors.New("operation already applied")        // операция с этим ключом уже выполнена
+)
@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// курс обмена: сколько единиц QuoteCurrency стоит одна единица BaseCurrency.
+// Курсы версионируются: новая загрузка курса пары создаёт следующую версию
+type ExchangeRate struct {
+	ID            uuid.UUID `json:"id"`
+	BaseCurrency  string    `json:"baseCurrency"`
+	QuoteCurrency string    `json:"quoteCurrency"`
+	Rate          string    `json:"rate"` // десятичная строка, например "89.4512"
+	Version       int64     `json:"version"`
+	EffectiveAt   time.Time `json:"effectiveAt"` // курс действует с этого момента до появления более нового
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// котировка обмена: курс, зафиксированный для суммы до ExpiresAt
+type FXQuote struct {
+	ID              uuid.UUID `json:"id"`
+	FromCurrency    string    `json:"fromCurrency"`
+	ToCurrency      string    `json:"toCurrency"`
+	Rate            string    `json:"rate"`
+	RateID          uuid.UUID `json:"rateId"`
+	Amount          int64     `json:"amount"`          // в минимальных единицах FromCurrency
+	ConvertedAmount int64     `json:"convertedAmount"` // в минимальных единицах ToCurrency
+	ExpiresAt       time.Time `json:"expiresAt"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// интерфейс хранилища курсов обмена и котировок
+type ExchangeRateRepository interface {
+	// AddRates сохраняет курсы, присваивая каждому следующую версию его пары, и заполняет ID, Version и CreatedAt
+	AddRates(ctx context.Context, rates []ExchangeRate) error
+	// GetRate возвращает курс пары, действующий в момент at; если его нет - ErrRateNotFound
+	GetRate(ctx context.Context, baseCurrency, quoteCurrency string, at time.Time) (ExchangeRate, error)
+	// ListRates возвращает действующие в момент at курсы всех пар
+	ListRates(ctx context.Context, at time.Time) ([]ExchangeRate, error)
+	// CreateQuote сохраняет котировку, заполняя ID и CreatedAt
+	CreateQuote(ctx context.Context, quote *FXQuote) error
+	// UseQuote помечает котировку использованной и возвращает её; котировка используется один раз.
+	// Для неизвестной котировки ErrQuoteNotFound, для использованной или просроченной на момент at - ErrQuoteExpired
+	UseQuote(ctx context.Context, quoteID uuid.UUID, at time.Time) (FXQuote, error)
+}
+
+// режим округления суммы после конвертации
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "HALF_UP"   // половина округляется от нуля
+	RoundHalfEven RoundingMode = "HALF_EVEN" // половина округляется к чётному (банковское округление)
+	RoundDown     RoundingMode = "DOWN"      // отбрасывание дробной части
+	RoundUp       RoundingMode = "UP"        // любая дробная часть округляется от нуля
+)
+
+// ParseRoundingMode возвращает режим округления по имени (без учёта регистра)
+func ParseRoundingMode(s string) (RoundingMode, error) {
+	mode := RoundingMode(strings.ToUpper(s))
+	switch mode {
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown rounding mode %q", s)
+}
+
+// ParseRate разбирает положительный курс из десятичной строки
+func ParseRate(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, "/eE") {
+		return nil, ErrInvalidRate
+	}
+	rate, ok := new(big.Rat).SetString(s)
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	return rate, nil
+}
+
+// Convert пересчитывает сумму в минимальных единицах валюты from в минимальные единицы to
+// по курсу rate с округлением mode. Результат вне int64 - ErrAmountOutOfRange
+func Convert(amount int64, from, to Currency, rate string, mode RoundingMode) (int64, error) {
+	r, err := ParseRate(rate)
+	if err != nil {
+		return 0, err
+	}
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), r)
+	scale := new(big.Rat).SetFrac(pow10(to.MinorUnits), pow10(from.MinorUnits))
+	value.Mul(value, scale)
+
+	rounded := round(value, mode)
+	if !rounded.IsInt64() {
+		return 0, fmt.Errorf("%w: converted amount does not fit", ErrAmountOutOfRange)
+	}
+	return rounded.Int64(), nil
+}
+
+// Prorate возвращает долю part/whole от суммы total с округлением mode,
+// например зачисление перевода между валютами, соответствующее частичному возврату
+func Prorate(total, part, whole int64, mode RoundingMode) int64 {
+	value := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(total), big.NewInt(part)), big.NewInt(whole))
+	return round(value, mode).Int64()
+}
+
+// Функция округляет дробь до целого в режиме mode
+func round(value *big.Rat, mode RoundingMode) *big.Int {
+	num := new(big.Int).Abs(value.Num())
+	den := value.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	if rem.Sign() != 0 {
+		// сравнение остатка с половиной делителя: 2*rem <=> den
+		half := new(big.Int).Lsh(rem, 1).Cmp(den)
+		increment := false
+		switch mode {
+		case RoundUp:
+			increment = true
+		case RoundHalfUp:
+			increment = half >= 0
+		case RoundHalfEven:
+			increment = half > 0 || (half == 0 && quo.Bit(0) == 1)
+		}
+		if increment {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	if value.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return quo
+}
+
+// Функция возвращает 10^n
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// ParseExchangeRatesCSV читает курсы из CSV с заголовком base_currency,quote_currency,rate[,effective_at];
+// effective_at в формате RFC 3339, пустое значение - курс действует с момента загрузки
+func ParseExchangeRatesCSV(r io.Reader) ([]ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"base_currency", "quote_currency", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv column %s is missing", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rates []ExchangeRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read csv line %d: %w", line, err)
+		}
+		rate := ExchangeRate{
+			BaseCurrency:  field(record, "base_currency"),
+			QuoteCurrency: field(record, "quote_currency"),
+			Rate:          field(record, "rate"),
+		}
+		if v := field(record, "effective_at"); v != "" {
+			if rate.EffectiveAt, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, fmt.Errorf("csv line %d: invalid effective_at %q", line, v)
+			}
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
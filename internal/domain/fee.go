@@ -0,0 +1,208 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const DefaultWalletTier = "STANDARD" // тариф кошелька, если он не назначен
+
+// тариф кошелька: латинские буквы, цифры и подчёркивание, до 32 символов
+var tierPattern = regexp.MustCompile(`^[A-Z0-9_]{1,32}$`)
+
+// операции, для которых можно задать комиссию
+var feeOperationTypes = map[string]bool{"DEPOSIT": true, "WITHDRAW": true, "TRANSFER": true}
+
+// правило комиссии; пустые поля отбора подходят к любому значению, нулевые границы не ограничивают.
+// Комиссия = Flat + Percent% от суммы операции (с округлением Rounding), затем ограничивается MinFee и MaxFee
+type FeeRule struct {
+	Name          string       `json:"name"`                // показывается в расшифровке комиссии
+	OperationType string       `json:"operationType"`       // DEPOSIT, WITHDRAW или TRANSFER
+	Currency      string       `json:"currency,omitempty"`  // валюта кошелька, списывающего комиссию
+	Tier          string       `json:"tier,omitempty"`      // тариф кошелька
+	MinAmount     int64        `json:"minAmount,omitempty"` // сумма операции не меньше MinAmount
+	MaxAmount     int64        `json:"maxAmount,omitempty"` // сумма операции не больше MaxAmount
+	Flat          int64        `json:"flat,omitempty"`      // фиксированная часть в минимальных единицах валюты
+	Percent       string       `json:"percent,omitempty"`   // процент от суммы операции, десятичная строка ("1.5")
+	MinFee        int64        `json:"minFee,omitempty"`
+	MaxFee        int64        `json:"maxFee,omitempty"`
+	Rounding      RoundingMode `json:"rounding,omitempty"` // округление процентной части; по умолчанию HALF_EVEN
+}
+
+// тарифная сетка: правила проверяются по порядку, применяется первое подходящее
+type FeeSchedule struct {
+	RevenueWallets map[string]uuid.UUID `json:"revenueWallets"` // кошелёк доходов для каждой валюты комиссий
+	Rules          []FeeRule            `json:"rules"`
+}
+
+// расшифровка комиссии, списанной за операцию
+type Fee struct {
+	Rule            string    `json:"rule"`
+	Amount          int64     `json:"amount"`
+	Currency        string    `json:"currency"`
+	Flat            int64     `json:"flat"`       // фиксированная часть
+	Percentage      int64     `json:"percentage"` // процентная часть после округления
+	WalletID        uuid.UUID `json:"walletId"`   // кошелёк, с которого списана комиссия
+	RevenueWalletID uuid.UUID `json:"revenueWalletId"`
+	TransactionID   uuid.UUID `json:"transactionId"` // запись списания комиссии
+}
+
+// результат операции с кошельком
+type OperationResult struct {
+	Transactions []Transaction `json:"transactions"` // записи операции без комиссий; у перевода - списание и зачисление
+	Fees         []Fee         `json:"fees"`
+}
+
+// ValidateTier приводит тариф к верхнему регистру и проверяет его
+func ValidateTier(tier string) (string, error) {
+	tier = strings.ToUpper(strings.TrimSpace(tier))
+	if !tierPattern.MatchString(tier) {
+		return "", fmt.Errorf("%w %q", ErrInvalidTier, tier)
+	}
+	return tier, nil
+}
+
+// ParseFeeScheduleJSON читает и проверяет тарифную сетку в формате JSON
+func ParseFeeScheduleJSON(r io.Reader) (FeeSchedule, error) {
+	var schedule FeeSchedule
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&schedule); err != nil {
+		return FeeSchedule{}, fmt.Errorf("%w: %v", ErrInvalidFeeRule, err)
+	}
+	if err := schedule.Validate(); err != nil {
+		return FeeSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// Validate проверяет правила и приводит коды к верхнему регистру; для каждой валюты,
+// упомянутой в правилах, должен быть задан кошелёк доходов
+func (s *FeeSchedule) Validate() error {
+	revenue := make(map[string]uuid.UUID, len(s.RevenueWallets))
+	for code, walletID := range s.RevenueWallets {
+		currency, err := LookupCurrency(code)
+		if err != nil {
+			return err
+		}
+		if walletID == uuid.Nil {
+			return fmt.Errorf("%w: revenue wallet for %s is not set", ErrInvalidFeeRule, currency.Code)
+		}
+		revenue[currency.Code] = walletID
+	}
+	s.RevenueWallets = revenue
+
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		if err := rule.normalize(); err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+		if rule.Currency == "" && len(s.RevenueWallets) == 0 {
+			return fmt.Errorf("rule %d (%s): %w: no revenue wallets", i+1, rule.Name, ErrInvalidFeeRule)
+		}
+		if _, ok := s.RevenueWallets[rule.Currency]; rule.Currency != "" && !ok {
+			return fmt.Errorf("rule %d (%s): %w: no revenue wallet for %s", i+1, rule.Name, ErrInvalidFeeRule, rule.Currency)
+		}
+	}
+	return nil
+}
+
+// Метод проверяет правило и заполняет значения по умолчанию
+func (r *FeeRule) normalize() error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.OperationType = strings.ToUpper(r.OperationType)
+	if r.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidFeeRule)
+	}
+	if !feeOperationTypes[r.OperationType] {
+		return fmt.Errorf("%w: operation type %q", ErrInvalidFeeRule, r.OperationType)
+	}
+	if r.Currency != "" {
+		currency, err := LookupCurrency(r.Currency)
+		if err != nil {
+			return err
+		}
+		r.Currency = currency.Code
+	}
+	if r.Tier != "" {
+		tier, err := ValidateTier(r.Tier)
+		if err != nil {
+			return err
+		}
+		r.Tier = tier
+	}
+	if r.MinAmount < 0 || r.MaxAmount < 0 || (r.MaxAmount > 0 && r.MaxAmount < r.MinAmount) {
+		return fmt.Errorf("%w: amount band %d-%d", ErrInvalidFeeRule, r.MinAmount, r.MaxAmount)
+	}
+	if r.Flat < 0 || r.MinFee < 0 || r.MaxFee < 0 || (r.MaxFee > 0 && r.MaxFee < r.MinFee) {
+		return fmt.Errorf("%w: negative amount or max fee below min fee", ErrInvalidFeeRule)
+	}
+	if r.Percent != "" {
+		percent, ok := new(big.Rat).SetString(strings.TrimSpace(r.Percent))
+		if !ok || strings.ContainsAny(r.Percent, "/eE") || percent.Sign() < 0 || percent.Cmp(big.NewRat(100, 1)) > 0 {
+			return fmt.Errorf("%w: percent %q", ErrInvalidFeeRule, r.Percent)
+		}
+		r.Percent = strings.TrimSpace(r.Percent)
+	}
+	if r.Rounding == "" {
+		r.Rounding = RoundHalfEven
+	}
+	mode, err := ParseRoundingMode(string(r.Rounding))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFeeRule, err)
+	}
+	r.Rounding = mode
+	return nil
+}
+
+// Match возвращает первое правило для операции operationType на сумму amount
+// с кошелька в валюте currency с тарифом tier. Комиссия берётся только в валютах с кошельком доходов
+func (s FeeSchedule) Match(operationType, currency, tier string, amount int64) (FeeRule, bool) {
+	if _, ok := s.RevenueWallets[currency]; !ok {
+		return FeeRule{}, false
+	}
+	for _, rule := range s.Rules {
+		if rule.OperationType != operationType ||
+			(rule.Currency != "" && rule.Currency != currency) ||
+			(rule.Tier != "" && rule.Tier != tier) ||
+			amount < rule.MinAmount ||
+			(rule.MaxAmount > 0 && amount > rule.MaxAmount) {
+			continue
+		}
+		return rule, true
+	}
+	return FeeRule{}, false
+}
+
+// Calculate рассчитывает комиссию с суммы amount: фиксированную и процентную части и итог
+// после ограничений MinFee и MaxFee. Итог вне int64 - ErrAmountOutOfRange
+func (r FeeRule) Calculate(amount int64) (flat, percentage, total int64, err error) {
+	if r.Percent != "" {
+		percent, _ := new(big.Rat).SetString(r.Percent)
+		value := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), percent)
+		value.Quo(value, big.NewRat(100, 1))
+		rounded := round(value, r.Rounding)
+		if !rounded.IsInt64() {
+			return 0, 0, 0, fmt.Errorf("%w: fee does not fit", ErrAmountOutOfRange)
+		}
+		percentage = rounded.Int64()
+	}
+	sum := new(big.Int).Add(big.NewInt(r.Flat), big.NewInt(percentage))
+	if !sum.IsInt64() {
+		return 0, 0, 0, fmt.Errorf("%w: fee does not fit", ErrAmountOutOfRange)
+	}
+	total = sum.Int64()
+	if total < r.MinFee {
+		total = r.MinFee
+	}
+	if r.MaxFee > 0 && total > r.MaxFee {
+		total = r.MaxFee
+	}
+	return r.Flat, percentage, total, nil
+}
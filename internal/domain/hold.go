@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// статусы блокировки средств
+const (
+	HoldActive   = "ACTIVE"   // средства зарезервированы
+	HoldCaptured = "CAPTURED" // списана вся сумма или её часть, остаток освобождён
+	HoldVoided   = "VOIDED"   // блокировка отменена, средства освобождены
+	HoldExpired  = "EXPIRED"  // срок блокировки истёк, средства освобождены
+)
+
+// блокировка (авторизация) средств кошелька: сумма исключается из доступного баланса,
+// но остаётся на счёте до списания (capture) или освобождения (void, истечение срока)
+type Hold struct {
+	ID             uuid.UUID `json:"id"`
+	WalletID       uuid.UUID `json:"walletId"`
+	Amount         int64     `json:"amount"`         // зарезервированная сумма в минимальных единицах валюты кошелька
+	CapturedAmount int64     `json:"capturedAmount"` // списанная сумма
+	Status         string    `json:"status"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// интерфейс хранилища блокировок; вызовы с контекстом из WithinTransaction кошельков
+// выполняются в той же транзакции
+type HoldRepository interface {
+	// CreateHold сохраняет блокировку, заполняя ID, CreatedAt и UpdatedAt
+	CreateHold(ctx context.Context, hold *Hold) error
+	// GetHold возвращает блокировку; для неизвестной - ErrHoldNotFound
+	GetHold(ctx context.Context, holdID uuid.UUID) (Hold, error)
+	// LockHold возвращает блокировку, блокируя её строку до конца транзакции
+	LockHold(ctx context.Context, holdID uuid.UUID) (Hold, error)
+	// UpdateHold сохраняет статус и списанную сумму, обновляя UpdatedAt
+	UpdateHold(ctx context.Context, hold *Hold) error
+	// LockExpiredHolds блокирует до limit активных блокировок с истёкшим на момент at сроком,
+	// пропуская уже заблокированные другими транзакциями
+	LockExpiredHolds(ctx context.Context, at time.Time, limit int) ([]Hold, error)
+	// GetActiveHoldUsage возвращает сумму и число активных блокировок кошелька, созданных начиная с since
+	GetActiveHoldUsage(ctx context.Context, walletID uuid.UUID, since time.Time) (SpendingUsage, error)
+}
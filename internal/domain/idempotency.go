@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// сохранённый результат запроса с заголовком Idempotency-Key
+type IdempotencyRecord struct {
+	Key         string
+	Fingerprint string // хеш метода, пути и тела исходного запроса
+	Completed   bool   // false, пока исходный запрос ещё выполняется
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// интерфейс хранилища ключей идемпотентности
+type IdempotencyRepository interface {
+	// WithinTransaction выполняет fn в транзакции БД, общей с хранилищами кошельков: ключ, занятый
+	// Reserve, и ответ, сохранённый Complete, фиксируются или откатываются вместе с операцией запроса
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// Reserve занимает ключ для нового запроса до конца транзакции. Если ключ уже занят, возвращает
+	// существующую запись и false; для запроса, который ещё выполняется, запись не завершена
+	Reserve(ctx context.Context, key, fingerprint string) (IdempotencyRecord, bool, error)
+	// Complete сохраняет ответ, полученный на запрос с этим ключом; вне транзакции Reserve
+	// занимает свободный ключ сразу с ответом, уже сохранённый ответ не меняет
+	Complete(ctx context.Context, key, fingerprint string, statusCode int, contentType string, body []byte) error
+}
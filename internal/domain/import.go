@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ImportJSONL = "jsonl" // по объекту операции в формате запроса операции на строку
+	ImportCSV   = "csv"   // заголовок с именами полей операции, затем по операции на строку
+
+	maxImportLine = 1 << 20 // наибольшая длина строки JSONL
+)
+
+// строка файла загрузки операций
+type ImportRow struct {
+	Line      int       // номер строки в файле (с единицы)
+	Operation Operation // операция строки, если она разобрана
+	Raw       string    // исходный текст строки
+	Err       error     // строку не удалось разобрать; такая строка отклоняется
+}
+
+// отклонённая строка загрузки
+type RejectedRow struct {
+	Line  int    `json:"line"`
+	Row   string `json:"row"`   // исходный текст строки
+	Error string `json:"error"` // причина отклонения
+}
+
+// контрольная точка загрузки: строки по Line включительно и строки Done обработаны
+// (выполнены или отклонены), повторный запуск с этой точки их пропускает
+type ImportCheckpoint struct {
+	Key      string `json:"key,omitempty"` // ключ загрузки, с которым выполнялись строки
+	Line     int    `json:"line"`
+	Done     []int  `json:"done,omitempty"` // обработанные строки после Line, пока не обработаны предыдущие
+	Applied  int64  `json:"applied"`        // выполнено операций с начала загрузки
+	Rejected int64  `json:"rejected"`       // отклонено строк с начала загрузки
+}
+
+// итог загрузки операций
+type ImportReport struct {
+	DryRun   bool  `json:"dryRun"`
+	Applied  int64 `json:"applied"`  // выполнено (при проверке - прошло проверку) операций с начала загрузки
+	Rejected int64 `json:"rejected"` // отклонено строк с начала загрузки
+	Skipped  int64 `json:"skipped"`  // строк, обработанных прежним запуском
+	Line     int   `json:"line"`     // все строки по эту включительно обработаны
+}
+
+// чтение операций из файла загрузки по одной строке
+type OperationReader struct {
+	next func() (ImportRow, error)
+}
+
+// NewOperationReader возвращает чтение файла в формате ImportJSONL или ImportCSV.
+// Для CSV сразу читается заголовок: колонки walletId, operationType и amount обязательны,
+// currency, destinationWalletId и quoteId - нет; регистр имён не важен
+func NewOperationReader(r io.Reader, format string) (*OperationReader, error) {
+	switch strings.ToLower(format) {
+	case ImportJSONL:
+		return newJSONLReader(r), nil
+	case ImportCSV:
+		return newCSVReader(r)
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidImport, format)
+}
+
+// Next возвращает следующую строку; после последней - io.EOF. Строка, которую не удалось
+// разобрать, возвращается с Err; ошибка самого Next означает, что файл дальше не читается
+func (r *OperationReader) Next() (ImportRow, error) {
+	return r.next()
+}
+
+// Функция чтения JSONL: пустые строки пропускаются, поля как в запросе операции
+func newJSONLReader(r io.Reader) *OperationReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	line := 0
+	return &OperationReader{next: func() (ImportRow, error) {
+		for scanner.Scan() {
+			line++
+			raw := strings.TrimSpace(scanner.Text())
+			if raw == "" {
+				continue
+			}
+			row := ImportRow{Line: line, Raw: raw}
+			if err := json.Unmarshal([]byte(raw), &row.Operation); err != nil {
+				row.Err = fmt.Errorf("%w: %v", ErrInvalidImport, err)
+			}
+			return row, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return ImportRow{}, fmt.Errorf("read line %d: %w", line+1, err)
+		}
+		return ImportRow{}, io.EOF
+	}}
+}
+
+// Функция чтения CSV с заголовком
+func newCSVReader(r io.Reader) (*OperationReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: read csv header: %v", ErrInvalidImport, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"walletid", "operationtype", "amount"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: csv column %s is missing", ErrInvalidImport, name)
+		}
+	}
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	return &OperationReader{next: func() (ImportRow, error) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return ImportRow{}, io.EOF
+		}
+		if err != nil { // незакрытые кавычки: границы следующих строк не определить
+			return ImportRow{}, fmt.Errorf("read csv: %w", err)
+		}
+		row := ImportRow{Raw: csvLine(record)}
+		row.Line, _ = reader.FieldPos(0)
+		row.Operation, row.Err = parseCSVOperation(func(name string) string { return field(record, name) })
+		return row, nil
+	}}, nil
+}
+
+// Функция собирает операцию из полей строки CSV
+func parseCSVOperation(field func(name string) string) (Operation, error) {
+	op := Operation{
+		OperationType: field("operationtype"),
+		Currency:      field("currency"),
+	}
+	var err error
+	if op.WalletID, err = uuid.Parse(field("walletid")); err != nil {
+		return Operation{}, fmt.Errorf("%w: invalid walletId %q", ErrInvalidImport, field("walletid"))
+	}
+	if op.Amount, err = strconv.ParseInt(field("amount"), 10, 64); err != nil {
+		return Operation{}, fmt.Errorf("%w: invalid amount %q", ErrInvalidImport, field("amount"))
+	}
+	if v := field("destinationwalletid"); v != "" {
+		if op.DestinationWalletID, err = uuid.Parse(v); err != nil {
+			return Operation{}, fmt.Errorf("%w: invalid destinationWalletId %q", ErrInvalidImport, v)
+		}
+	}
+	if v := field("quoteid"); v != "" {
+		quoteID, err := uuid.Parse(v)
+		if err != nil {
+			return Operation{}, fmt.Errorf("%w: invalid quoteId %q", ErrInvalidImport, v)
+		}
+		op.QuoteID = &quoteID
+	}
+	return op, nil
+}
+
+// Функция возвращает запись CSV одной строкой для файла отклонённых строк
+func csvLine(record []string) string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(record)
+	w.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
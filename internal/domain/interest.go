@@ -0,0 +1,215 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	InterestScale = 12 // знаков после запятой в начислениях процентов (доли минимальной единицы)
+
+	PayoutDaily   = "DAILY"   // выплата начисленных процентов каждый день
+	PayoutMonthly = "MONTHLY" // выплата в последний день месяца
+
+	defaultDayCount = 365 // дней в году для дневной ставки по умолчанию
+)
+
+// годовая ставка процентов для тарифа (продукта) кошелька
+type InterestRate struct {
+	Tier       string `json:"tier"`               // тариф кошелька
+	Currency   string `json:"currency,omitempty"` // пусто - любая валюта
+	AnnualRate string `json:"annualRate"`         // годовая ставка в процентах, десятичная строка ("4.5")
+}
+
+// ставки процентов: проверяются по порядку, применяется первая подходящая
+type InterestSchedule struct {
+	Rates    []InterestRate `json:"rates"`
+	DayCount int            `json:"dayCount,omitempty"` // дней в году; дневная ставка = годовая / DayCount
+	Payout   string         `json:"payout,omitempty"`   // DAILY или MONTHLY (по умолчанию)
+}
+
+// остаток кошелька на конец дня по главной книге
+type EndOfDayBalance struct {
+	WalletID uuid.UUID
+	Currency string
+	Tier     string
+	Status   string
+	Balance  int64
+}
+
+// начисление процентов кошельку за один день
+type InterestAccrual struct {
+	WalletID   uuid.UUID `json:"walletId"`
+	Date       time.Time `json:"date"`       // день начисления (полночь UTC)
+	Balance    int64     `json:"balance"`    // остаток на конец дня
+	AnnualRate string    `json:"annualRate"` // применённая годовая ставка
+	Amount     string    `json:"amount"`     // сумма в минимальных единицах с InterestScale знаками
+}
+
+// начисленные и выплаченные проценты кошелька
+type AccruedInterest struct {
+	WalletID uuid.UUID
+	Accrued  string // сумма начислений в минимальных единицах с дробной частью
+	Paid     int64  // сумма выплат INTEREST
+}
+
+// выплата процентов кошельку
+type InterestPayout struct {
+	WalletID      uuid.UUID `json:"walletId"`
+	Currency      string    `json:"currency"`
+	Amount        int64     `json:"amount"`
+	TransactionID uuid.UUID `json:"transactionId"`
+}
+
+// итог начисления процентов за день
+type InterestRun struct {
+	Date           time.Time        `json:"date"`
+	Accrued        int64            `json:"accrued"`        // новых начислений
+	AlreadyAccrued int64            `json:"alreadyAccrued"` // начислений, сделанных за этот день прежним запуском
+	Payouts        []InterestPayout `json:"payouts"`        // выплаты, сделанные этим запуском
+	CompletedAt    time.Time        `json:"completedAt"`
+}
+
+// интерфейс хранилища начислений процентов. Вызовы с контекстом из WithinTransaction
+// кошельков выполняются в той же транзакции
+type InterestRepository interface {
+	// StreamEndOfDayBalances вызывает fn для каждого кошелька в порядке id с остатком по главной книге
+	// на момент end (не включая); ошибка fn прерывает чтение
+	StreamEndOfDayBalances(ctx context.Context, end time.Time, fn func(EndOfDayBalance) error) error
+	// CreateInterestAccruals сохраняет начисления, пропуская уже сохранённые за тот же день;
+	// возвращает число сохранённых
+	CreateInterestAccruals(ctx context.Context, accruals []InterestAccrual) (int64, error)
+	// ListInterestPayees возвращает кошельки с невыплаченными начислениями по день through включительно
+	ListInterestPayees(ctx context.Context, through time.Time) ([]uuid.UUID, error)
+	// GetAccruedInterest возвращает сумму начислений кошелька по день through включительно и сумму всех выплат
+	GetAccruedInterest(ctx context.Context, walletID uuid.UUID, through time.Time) (AccruedInterest, error)
+	// MarkInterestPaid связывает невыплаченные начисления по день through включительно с записью выплаты
+	MarkInterestPaid(ctx context.Context, walletID uuid.UUID, through time.Time, transactionID uuid.UUID) error
+	// GetLastInterestRun возвращает последний завершённый день начисления; если запусков не было - нулевое время
+	GetLastInterestRun(ctx context.Context) (time.Time, error)
+	// SaveInterestRun сохраняет итог начисления за день, прибавляя его к итогу прежнего запуска за тот же день
+	SaveInterestRun(ctx context.Context, run InterestRun) error
+}
+
+// AccrualDate возвращает день начисления, содержащий момент t: полночь UTC
+func AccrualDate(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// ParseInterestScheduleJSON читает и проверяет ставки процентов в формате JSON
+func ParseInterestScheduleJSON(r io.Reader) (InterestSchedule, error) {
+	var schedule InterestSchedule
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&schedule); err != nil {
+		return InterestSchedule{}, fmt.Errorf("%w: %v", ErrInvalidInterestRate, err)
+	}
+	if err := schedule.Validate(); err != nil {
+		return InterestSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// Validate проверяет ставки, приводит коды к верхнему регистру и заполняет значения по умолчанию
+func (s *InterestSchedule) Validate() error {
+	if s.DayCount == 0 {
+		s.DayCount = defaultDayCount
+	}
+	if s.DayCount != 360 && s.DayCount != 365 && s.DayCount != 366 {
+		return fmt.Errorf("%w: day count %d", ErrInvalidInterestRate, s.DayCount)
+	}
+	s.Payout = strings.ToUpper(s.Payout)
+	if s.Payout == "" {
+		s.Payout = PayoutMonthly
+	}
+	if s.Payout != PayoutDaily && s.Payout != PayoutMonthly {
+		return fmt.Errorf("%w: payout %q", ErrInvalidInterestRate, s.Payout)
+	}
+
+	for i := range s.Rates {
+		rate := &s.Rates[i]
+		tier, err := ValidateTier(rate.Tier)
+		if err != nil {
+			return fmt.Errorf("rate %d: %w", i+1, err)
+		}
+		rate.Tier = tier
+		if rate.Currency != "" {
+			currency, err := LookupCurrency(rate.Currency)
+			if err != nil {
+				return fmt.Errorf("rate %d: %w", i+1, err)
+			}
+			rate.Currency = currency.Code
+		}
+		rate.AnnualRate = strings.TrimSpace(rate.AnnualRate)
+		value, ok := new(big.Rat).SetString(rate.AnnualRate)
+		if !ok || strings.ContainsAny(rate.AnnualRate, "/eE") || value.Sign() < 0 || value.Cmp(big.NewRat(100, 1)) > 0 {
+			return fmt.Errorf("rate %d: %w: annual rate %q", i+1, ErrInvalidInterestRate, rate.AnnualRate)
+		}
+	}
+	return nil
+}
+
+// Match возвращает первую ставку для кошелька с тарифом tier в валюте currency
+func (s InterestSchedule) Match(tier, currency string) (InterestRate, bool) {
+	for _, rate := range s.Rates {
+		if rate.Tier == tier && (rate.Currency == "" || rate.Currency == currency) {
+			return rate, true
+		}
+	}
+	return InterestRate{}, false
+}
+
+// PayoutDue сообщает, выплачиваются ли начисленные проценты после начисления за день date
+func (s InterestSchedule) PayoutDue(date time.Time) bool {
+	if s.Payout == PayoutDaily {
+		return true
+	}
+	return date.AddDate(0, 0, 1).Day() == 1
+}
+
+// DailyInterest рассчитывает начисление за день на остаток balance по годовой ставке annualRate (в процентах):
+// balance * annualRate / 100 / DayCount, с отбрасыванием знаков после InterestScale.
+// Если начислять нечего (остаток не положительный или сумма меньше 10^-InterestScale) - ok = false
+func (s InterestSchedule) DailyInterest(balance int64, annualRate string) (amount string, ok bool) {
+	rate, valid := new(big.Rat).SetString(annualRate)
+	if balance <= 0 || !valid {
+		return "", false
+	}
+	dayCount := s.DayCount
+	if dayCount == 0 {
+		dayCount = defaultDayCount
+	}
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(balance), rate)
+	value.Quo(value, new(big.Rat).SetInt64(int64(100*dayCount)))
+	scale := pow10(InterestScale)
+	scaled := round(value.Mul(value, new(big.Rat).SetInt(scale)), RoundDown)
+	if scaled.Sign() <= 0 {
+		return "", false
+	}
+	return new(big.Rat).SetFrac(scaled, scale).FloatString(InterestScale), true
+}
+
+// Due возвращает сумму к выплате: целая часть начислений за вычетом уже выплаченного.
+// Дробная часть остаётся в начислениях до следующей выплаты
+func (a AccruedInterest) Due() (int64, error) {
+	accrued, ok := new(big.Rat).SetString(a.Accrued)
+	if !ok {
+		return 0, fmt.Errorf("invalid accrued interest %q", a.Accrued)
+	}
+	due := new(big.Int).Sub(round(accrued, RoundDown), big.NewInt(a.Paid))
+	if !due.IsInt64() {
+		return 0, fmt.Errorf("%w: interest does not fit", ErrAmountOutOfRange)
+	}
+	if due.Sign() < 0 {
+		return 0, nil
+	}
+	return due.Int64(), nil
+}
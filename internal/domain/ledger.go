@@ -0,0 +1,37 @@
+package domain
+
+// коды системных счетов главной книги; у каждой валюты свой набор системных счетов
+const (
+	AccountFunding          = "funding"           // поступления извне (депозиты)
+	AccountPayout           = "payout"            // выплаты наружу (снятия)
+	AccountTransferClearing = "transfer_clearing" // транзитный счёт переводов между кошельками
+	AccountFXPosition       = "fx_position"       // валютная позиция: суммы, обменянные при переводах между валютами
+	AccountInterest         = "interest"          // проценты, выплаченные на остатки кошельков
+)
+
+// описание проводки входящего остатка, перенесённого при создании главной книги (миграция 000006);
+// у таких проводок нет записи в transactions, в выписке они идут операцией этого типа
+const EntryOpeningBalance = "OPENING_BALANCE"
+
+// сводка по главной книге в одной валюте для сверки обязательств
+type LedgerSummary struct {
+	Currency          string `json:"currency"`
+	WalletLiabilities int64  `json:"walletLiabilities"` // сумма записей по счетам кошельков
+	WalletBalances    int64  `json:"walletBalances"`    // сумма wallets.balance (проекция)
+	Funded            int64  `json:"funded"`            // всего поступило через funding
+	PaidOut           int64  `json:"paidOut"`           // всего выплачено через payout
+	InterestPaid      int64  `json:"interestPaid"`      // всего выплачено процентов через interest
+	InTransit         int64  `json:"inTransit"`         // остаток транзитного счёта, после завершения переводов 0
+	FXPosition        int64  `json:"fxPosition"`        // остаток валютной позиции: получено от кошельков при обмене минус выдано им
+	UnbalancedEntries int64  `json:"unbalancedEntries"` // число проводок с ненулевой суммой записей
+	Balanced          bool   `json:"balanced"`
+}
+
+// Reconcile проверяет, что обязательства перед кошельками равны поступлениям и процентам
+// за вычетом выплат и валютной позиции, а проекция балансов совпадает с главной книгой
+func (s *LedgerSummary) Reconcile() {
+	s.Balanced = s.UnbalancedEntries == 0 &&
+		s.InTransit == 0 &&
+		s.WalletLiabilities == s.Funded+s.InterestPaid-s.PaidOut-s.FXPosition &&
+		s.WalletBalances == s.WalletLiabilities
+}
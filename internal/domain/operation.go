@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// запрос на операцию с кошельком
+type Operation struct {
+	WalletID            uuid.UUID  `json:"walletId"`
+	OperationType       string     `json:"operationType"`
+	Amount              int64      `json:"amount"`                        // в минимальных единицах валюты кошелька
+	Currency            string     `json:"currency,omitempty"`            // если указана, должна совпадать с валютой кошелька
+	DestinationWalletID uuid.UUID  `json:"destinationWalletId,omitempty"` // только для TRANSFER
+	QuoteID             *uuid.UUID `json:"quoteId,omitempty"`             // котировка для перевода между валютами; без неё берётся текущий курс
+}
+
+// ошибка операции пакета: операция с номером Index (с нуля) не выполнена, весь пакет откатён.
+// errors.Is и errors.As видят исходную ошибку операции
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// владелец (клиент), которому принадлежат кошельки
+type Owner struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// интерфейс хранилища владельцев кошельков
+type OwnerRepository interface {
+	// CreateOwner сохраняет владельца, заполняя ID и CreatedAt
+	CreateOwner(ctx context.Context, owner *Owner) error
+	// GetOwner возвращает владельца; для неизвестного - ErrOwnerNotFound
+	GetOwner(ctx context.Context, ownerID uuid.UUID) (Owner, error)
+}
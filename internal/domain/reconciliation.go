@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// сверка баланса кошелька с его операциями
+type BalanceCheck struct {
+	WalletID        uuid.UUID `json:"walletId"`
+	Currency        string    `json:"currency"`
+	StoredBalance   int64     `json:"storedBalance"`   // wallets.balance
+	OpeningBalance  int64     `json:"openingBalance"`  // входящий остаток, перенесённый при создании главной книги
+	TransactionsSum int64     `json:"transactionsSum"` // сумма записей кошелька в transactions
+	LedgerBalance   int64     `json:"ledgerBalance"`   // остаток счёта кошелька в главной книге
+	ExpectedBalance int64     `json:"expectedBalance"` // OpeningBalance + TransactionsSum
+	Drift           int64     `json:"drift"`           // StoredBalance - ExpectedBalance
+	LedgerDrift     int64     `json:"ledgerDrift"`     // LedgerBalance - ExpectedBalance
+	Repaired        bool      `json:"repaired"`        // wallets.balance исправлен на ExpectedBalance
+	RepairError     string    `json:"repairError,omitempty"`
+}
+
+// Reconcile рассчитывает ожидаемый баланс и расхождения с ним
+func (c *BalanceCheck) Reconcile() {
+	c.ExpectedBalance = c.OpeningBalance + c.TransactionsSum
+	c.Drift = c.StoredBalance - c.ExpectedBalance
+	c.LedgerDrift = c.LedgerBalance - c.ExpectedBalance
+}
+
+// Consistent сообщает, что баланс и главная книга совпадают с операциями кошелька
+func (c BalanceCheck) Consistent() bool {
+	return c.Drift == 0 && c.LedgerDrift == 0
+}
+
+// отчёт о сверке балансов всех кошельков
+type ReconciliationReport struct {
+	StartedAt  time.Time      `json:"startedAt"`
+	FinishedAt time.Time      `json:"finishedAt"`
+	Repair     bool           `json:"repair"`     // запуск с исправлением расхождений
+	Checked    int64          `json:"checked"`    // проверено кошельков
+	Drifted    int64          `json:"drifted"`    // кошельков с расхождением
+	Repaired   int64          `json:"repaired"`   // кошельков с исправленным балансом
+	Wallets    []BalanceCheck `json:"wallets"`    // только кошельки с расхождением
+	Consistent bool           `json:"consistent"` // расхождений не осталось
+}
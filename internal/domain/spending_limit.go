@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// периоды лимитов расходов; окна считаются по календарю в UTC
+const (
+	PeriodDaily   = "DAILY"   // с полуночи текущих суток
+	PeriodWeekly  = "WEEKLY"  // с полуночи понедельника текущей недели
+	PeriodMonthly = "MONTHLY" // с полуночи первого числа текущего месяца
+)
+
+// SpendingPeriods перечисляет периоды лимитов от короткого к длинному
+var SpendingPeriods = []string{PeriodDaily, PeriodWeekly, PeriodMonthly}
+
+// лимит расходов кошелька за период: сумма списаний и число списывающих операций; 0 - без ограничения
+type SpendingLimit struct {
+	Period    string `json:"period"`
+	MaxAmount int64  `json:"maxAmount"` // в минимальных единицах валюты кошелька
+	MaxCount  int64  `json:"maxCount"`
+}
+
+// использование лимита: списания кошелька с начала окна
+type SpendingUsage struct {
+	Amount int64 `json:"amount"` // сумма списаний в минимальных единицах валюты
+	Count  int64 `json:"count"`  // число списывающих операций
+}
+
+// действующий лимит кошелька вместе с его использованием в текущем окне
+type SpendingLimitStatus struct {
+	SpendingLimit
+	Override    bool          `json:"override"` // лимит задан для кошелька, а не взят из политики по умолчанию
+	WindowStart time.Time     `json:"windowStart"`
+	Used        SpendingUsage `json:"used"`
+}
+
+// Validate проверяет период (без учёта регистра, приводя его к верхнему) и неотрицательность границ
+func (l *SpendingLimit) Validate() error {
+	l.Period = strings.ToUpper(l.Period)
+	if _, err := PeriodStart(l.Period, time.Time{}); err != nil {
+		return err
+	}
+	if l.MaxAmount < 0 || l.MaxCount < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidSpendingLimit)
+	}
+	return nil
+}
+
+// Unlimited сообщает, что лимит ничего не ограничивает
+func (l SpendingLimit) Unlimited() bool {
+	return l.MaxAmount == 0 && l.MaxCount == 0
+}
+
+// Check проверяет, что списание amount при использовании usage укладывается в лимит;
+// при превышении возвращает ErrSpendingLimitExceeded
+func (l SpendingLimit) Check(usage SpendingUsage, amount int64) error {
+	if l.MaxAmount > 0 && amount > l.MaxAmount-usage.Amount {
+		return fmt.Errorf("%w: %s amount limit %d, already spent %d",
+			ErrSpendingLimitExceeded, strings.ToLower(l.Period), l.MaxAmount, usage.Amount)
+	}
+	if l.MaxCount > 0 && usage.Count >= l.MaxCount {
+		return fmt.Errorf("%w: %s operation limit %d reached",
+			ErrSpendingLimitExceeded, strings.ToLower(l.Period), l.MaxCount)
+	}
+	return nil
+}
+
+// PeriodStart возвращает начало окна периода, содержащего момент now
+func PeriodStart(period string, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodDaily:
+		return day, nil
+	case PeriodWeekly:
+		// неделя начинается с понедельника (ISO 8601)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)), nil
+	case PeriodMonthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: unknown period %q", ErrInvalidSpendingLimit, period)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// заголовок выписки по кошельку за период [From, To)
+type StatementHeader struct {
+	WalletID       uuid.UUID `json:"walletId"`
+	Currency       string    `json:"currency"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	OpeningBalance int64     `json:"openingBalance"` // баланс по главной книге на начало периода
+}
+
+// строка выписки: операция и баланс после неё
+type StatementLine struct {
+	Transaction
+	Balance int64 `json:"balance"`
+}
+
+// итог выписки
+type StatementSummary struct {
+	ClosingBalance int64 `json:"closingBalance"` // баланс на конец периода
+	TotalCredits   int64 `json:"totalCredits"`   // сумма зачислений за период
+	TotalDebits    int64 `json:"totalDebits"`    // сумма списаний за период (без знака)
+	Count          int64 `json:"count"`          // число операций
+}
+
+// получатель выписки; строки передаются по одной, чтобы выписку за большой период
+// можно было отдавать потоком, не загружая в память
+type StatementWriter interface {
+	Begin(header StatementHeader) error
+	Line(line StatementLine) error
+	End(summary StatementSummary) error
+}
@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// запись об операции с кошельком (таблица transactions)
+type Transaction struct {
+	ID            uuid.UUID `json:"id"`
+	WalletID      uuid.UUID `json:"walletId"`
+	Amount        int64     `json:"amount"` // сумма со знаком: положительная при зачислении, отрицательная при списании
+	OperationType string    `json:"operationType"`
+	CreatedAt     time.Time `json:"createdAt"`
+
+	CounterpartyWalletID *uuid.UUID `json:"counterpartyWalletId,omitempty"` // второй кошелёк перевода
+	RelatedTransactionID *uuid.UUID `json:"relatedTransactionId,omitempty"` // связанная запись (списание для зачисления перевода)
+
+	ExchangeRate   *string    `json:"exchangeRate,omitempty"`   // курс перевода между валютами (валюта отправителя -> получателя)
+	ExchangeRateID *uuid.UUID `json:"exchangeRateId,omitempty"` // версия курса, по которой выполнена конвертация
+
+	HoldID *uuid.UUID `json:"holdId,omitempty"` // блокировка, по которой выполнено списание (CAPTURE)
+
+	ReversedTransactionID *uuid.UUID `json:"reversedTransactionId,omitempty"` // операция, которую компенсирует запись REVERSAL
+}
+
+// результат отмены (возврата) операции
+type Reversal struct {
+	TransactionID uuid.UUID     `json:"transactionId"` // отменяемая операция; для перевода - запись списания
+	Amount        int64         `json:"amount"`        // возвращённая сумма в валюте отменяемой записи
+	Remaining     int64         `json:"remaining"`     // сумма, которую ещё можно вернуть
+	Transactions  []Transaction `json:"transactions"`  // компенсирующие записи REVERSAL
+}
+
+// курсор keyset-пагинации: позиция последней записи на странице
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode возвращает непрозрачное строковое представление курсора
+func (c TransactionCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeTransactionCursor разбирает курсор, полученный от Encode
+func DecodeTransactionCursor(s string) (TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return TransactionCursor{}, ErrInvalidCursor
+	}
+	return TransactionCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// фильтр истории операций кошелька; записи возвращаются от новых к старым
+type TransactionFilter struct {
+	WalletID      uuid.UUID
+	OperationType string             // пустая строка - любые операции
+	From          *time.Time         // created_at >= From
+	To            *time.Time         // created_at < To
+	After         *TransactionCursor // продолжить после этой записи
+	Limit         int
+}
+
+// страница истории операций
+type TransactionPage struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"nextCursor,omitempty"` // пусто, если записей больше нет
+}
@@ -1,9 +1,112 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// статусы кошелька
+const (
+	WalletActive = "ACTIVE" // операции разрешены
+	WalletFrozen = "FROZEN" // зачисления разрешены, списания запрещены
+	WalletClosed = "CLOSED" // любые операции запрещены; статус окончательный
+)
+
+// допустимые переходы между статусами кошелька
+var walletTransitions = map[string][]string{
+	WalletActive: {WalletFrozen, WalletClosed},
+	WalletFrozen: {WalletActive, WalletClosed},
+}
 
 // модель
 type Wallet struct {
-	ID      uuid.UUID `json:"id"`
-	Balance int64     `json:"balance"`
-}
\ No newline at end of file
+	ID       uuid.UUID `json:"id"`
+	Balance  int64     `json:"balance"`  // баланс по главной книге в минимальных единицах валюты
+	Reserved int64     `json:"reserved"` // сумма активных блокировок
+	Currency string    `json:"currency"` // код ISO 4217
+	Status   string    `json:"status"`
+
+	OverdraftLimit int64  `json:"overdraftLimit"` // кредитная линия: баланс может уходить в минус до -OverdraftLimit
+	Tier           string `json:"tier"`           // тариф, по которому рассчитываются комиссии
+
+	OwnerID   *uuid.UUID `json:"ownerId,omitempty"` // владелец кошелька; nil - анонимный кошелёк
+	CreatedAt time.Time  `json:"createdAt"`
+
+	DisplayName string            `json:"displayName,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"` // произвольные пары ключ-значение, например ссылки на внешние счета
+}
+
+// баланс кошелька на момент времени, рассчитанный по главной книге
+type BalanceSnapshot struct {
+	WalletID uuid.UUID `json:"walletId"`
+	Currency string    `json:"currency"`
+	AsOf     time.Time `json:"asOf"`
+	Balance  int64     `json:"balance"` // баланс по главной книге (блокировки не учитываются)
+}
+
+// запись о смене статуса кошелька
+type WalletStatusChange struct {
+	ID         uuid.UUID `json:"id"`
+	WalletID   uuid.UUID `json:"walletId"`
+	FromStatus string    `json:"fromStatus"`
+	ToStatus   string    `json:"toStatus"`
+	Reason     string    `json:"reason"`
+	Actor      string    `json:"actor"` // кто изменил статус
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// CanTransition сообщает, разрешён ли переход кошелька из статуса from в статус to
+func CanTransition(from, to string) bool {
+	for _, allowed := range walletTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckTransition проверяет переход в статус to; закрыть можно только кошелёк без средств и блокировок
+func (w Wallet) CheckTransition(to string) error {
+	if !CanTransition(w.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, w.Status, to)
+	}
+	if to == WalletClosed && (w.Balance != 0 || w.Reserved != 0) {
+		return ErrWalletNotEmpty
+	}
+	return nil
+}
+
+// CanDebit проверяет, что с кошелька можно списывать и резервировать средства
+func (w Wallet) CanDebit() error {
+	switch w.Status {
+	case WalletFrozen:
+		return ErrWalletFrozen
+	case WalletClosed:
+		return ErrWalletClosed
+	}
+	return nil
+}
+
+// CanCredit проверяет, что на кошелёк можно зачислять средства
+func (w Wallet) CanCredit() error {
+	if w.Status == WalletClosed {
+		return ErrWalletClosed
+	}
+	return nil
+}
+
+// Available возвращает доступный баланс: баланс за вычетом заблокированных средств.
+// При использовании кредитной линии значение отрицательное
+func (w Wallet) Available() int64 {
+	return w.Balance - w.Reserved
+}
+
+// RemainingCredit возвращает неиспользованную часть кредитной линии
+func (w Wallet) RemainingCredit() int64 {
+	if available := w.Available(); available < 0 {
+		return w.OverdraftLimit + available
+	}
+	return w.OverdraftLimit
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ограничения на отображаемое имя и метаданные кошелька
+const (
+	MaxDisplayNameLength   = 255 // символов
+	MaxMetadataKeys        = 50
+	MaxMetadataKeyLength   = 64  // символов
+	MaxMetadataValueLength = 512 // символов
+)
+
+// изменение отображаемого имени и метаданных кошелька (PATCH);
+// nil-поле не меняется, ключ метаданных со значением nil удаляется
+type WalletUpdate struct {
+	DisplayName *string            `json:"displayName"`
+	Metadata    map[string]*string `json:"metadata"`
+}
+
+// фильтр списка кошельков
+type WalletFilter struct {
+	Labels map[string]string // кошелёк должен содержать все пары ключ-значение в метаданных
+	Limit  int
+}
+
+// ValidateDetails проверяет отображаемое имя и метаданные кошелька
+func (w Wallet) ValidateDetails() error {
+	if utf8.RuneCountInString(w.DisplayName) > MaxDisplayNameLength {
+		return fmt.Errorf("%w: display name longer than %d characters", ErrInvalidMetadata, MaxDisplayNameLength)
+	}
+	if len(w.Metadata) > MaxMetadataKeys {
+		return fmt.Errorf("%w: more than %d keys", ErrInvalidMetadata, MaxMetadataKeys)
+	}
+	for key, value := range w.Metadata {
+		if err := validateMetadataKey(key); err != nil {
+			return err
+		}
+		if utf8.RuneCountInString(value) > MaxMetadataValueLength {
+			return fmt.Errorf("%w: value of %q longer than %d characters", ErrInvalidMetadata, key, MaxMetadataValueLength)
+		}
+	}
+	return nil
+}
+
+// Apply возвращает кошелёк с применённым изменением; исходные метаданные не меняются
+func (u WalletUpdate) Apply(w Wallet) Wallet {
+	if u.DisplayName != nil {
+		w.DisplayName = strings.TrimSpace(*u.DisplayName)
+	}
+	if len(u.Metadata) > 0 {
+		metadata := make(map[string]string, len(w.Metadata)+len(u.Metadata))
+		for key, value := range w.Metadata {
+			metadata[key] = value
+		}
+		for key, value := range u.Metadata {
+			if value == nil {
+				delete(metadata, key)
+				continue
+			}
+			metadata[key] = *value
+		}
+		w.Metadata = metadata
+	}
+	return w
+}
+
+// ParseLabel разбирает метку вида "ключ:значение"; значение может содержать двоеточия
+func ParseLabel(label string) (key, value string, err error) {
+	key, value, found := strings.Cut(label, ":")
+	if !found {
+		return "", "", fmt.Errorf("%w: label %q must look like key:value", ErrInvalidMetadata, label)
+	}
+	if err := validateMetadataKey(key); err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+// Функция проверяет ключ метаданных: непустой, без двоеточия (разделителя меток) и не длиннее предела
+func validateMetadataKey(key string) error {
+	if key == "" || strings.Contains(key, ":") || utf8.RuneCountInString(key) > MaxMetadataKeyLength {
+		return fmt.Errorf("%w: invalid key %q", ErrInvalidMetadata, key)
+	}
+	return nil
+}
@@ -2,13 +2,92 @@ package domain
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-// интерфейс для работы с кошельками
+// интерфейс для работы с кошельками;
+// методы, работающие с одним кошельком, возвращают ErrWalletNotFound, если его нет.
+// Баланс кошелька - проекция его счёта в главной книге: он меняется только записью операции
+// через CreateTransaction и всегда равен сумме проводок счёта
 type WalletRepository interface {
 	GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error)
-	UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) error
-	CreateWallet(ctx context.Context) (uuid.UUID, error)
+	// GetBalanceAt возвращает баланс кошелька по главной книге на момент at включительно
+	GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (int64, error)
+	// ReserveBalance атомарно проверяет доступный баланс с учётом кредитной линии и резервирует amount;
+	// при нехватке средств возвращает ErrInsufficientFunds и ничего не меняет
+	ReserveBalance(ctx context.Context, walletID uuid.UUID, amount int64) error
+	// ReleaseReserve освобождает ранее зарезервированную сумму
+	ReleaseReserve(ctx context.Context, walletID uuid.UUID, amount int64) error
+	// GetWallet возвращает кошелёк с балансом, резервом, валютой, статусом и кредитным лимитом
+	GetWallet(ctx context.Context, walletID uuid.UUID) (Wallet, error)
+	// SetOverdraftLimit устанавливает кредитный лимит кошелька
+	SetOverdraftLimit(ctx context.Context, walletID uuid.UUID, limit int64) error
+	// SetWalletTier назначает кошельку тариф комиссий
+	SetWalletTier(ctx context.Context, walletID uuid.UUID, tier string) error
+	// CreateWallet создаёт активный кошелёк в валюте wallet.Currency, принадлежащий wallet.OwnerID,
+	// с именем wallet.DisplayName и метаданными wallet.Metadata;
+	// для неизвестного владельца возвращает ErrOwnerNotFound
+	CreateWallet(ctx context.Context, wallet Wallet) (uuid.UUID, error)
+	// ListWalletsByOwner возвращает кошельки владельца в порядке создания
+	ListWalletsByOwner(ctx context.Context, ownerID uuid.UUID) ([]Wallet, error)
+	// ListWallets возвращает до filter.Limit кошельков с метками filter.Labels в порядке создания
+	ListWallets(ctx context.Context, filter WalletFilter) ([]Wallet, error)
+	// UpdateWalletDetails заменяет отображаемое имя и метаданные кошелька
+	UpdateWalletDetails(ctx context.Context, walletID uuid.UUID, displayName string, metadata map[string]string) error
+	// ChangeWalletStatus устанавливает кошельку статус change.ToStatus и сохраняет запись о смене,
+	// заполняя ID и CreatedAt
+	ChangeWalletStatus(ctx context.Context, change *WalletStatusChange) error
+	// ListWalletStatusChanges возвращает историю смены статусов кошелька от старых к новым
+	ListWalletStatusChanges(ctx context.Context, walletID uuid.UUID) ([]WalletStatusChange, error)
+	// GetSpendingLimits возвращает лимиты расходов, заданные для кошелька (без политики по умолчанию)
+	GetSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]SpendingLimit, error)
+	// SetSpendingLimit задаёт кошельку лимит на период limit.Period, заменяя прежний
+	SetSpendingLimit(ctx context.Context, walletID uuid.UUID, limit SpendingLimit) error
+	// DeleteSpendingLimit удаляет лимит кошелька на период; дальше действует политика по умолчанию
+	DeleteSpendingLimit(ctx context.Context, walletID uuid.UUID, period string) error
+	// GetSpendingUsage возвращает сумму и число списаний кошелька начиная с момента since (без отмен операций и комиссий)
+	GetSpendingUsage(ctx context.Context, walletID uuid.UUID, since time.Time) (SpendingUsage, error)
+	// LockWallets блокирует строки кошельков до конца транзакции в порядке id,
+	// чтобы встречные переводы не приводили к взаимоблокировке
+	LockWallets(ctx context.Context, walletIDs ...uuid.UUID) error
+
+	// CreateTransaction сохраняет запись об операции и сбалансированную проводку
+	// в главной книге, заполняя ID и CreatedAt; баланс кошелька изменяется на tx.Amount.
+	// Если списание превышает доступный остаток (без резерва, с учётом кредитной линии),
+	// возвращает ErrInsufficientFunds, при переполнении баланса - ErrBalanceOverflow, и ничего не меняет
+	CreateTransaction(ctx context.Context, tx *Transaction) error
+	// LockTransaction блокирует запись об операции до конца транзакции и возвращает её;
+	// для несуществующей записи ErrTransactionNotFound
+	LockTransaction(ctx context.Context, transactionID uuid.UUID) (Transaction, error)
+	// LockTransferCredit блокирует и возвращает запись зачисления перевода по id записи списания
+	LockTransferCredit(ctx context.Context, debitID uuid.UUID) (Transaction, error)
+	// ClaimOperationKey занимает ключ однократной операции до конца транзакции; если ключ уже
+	// занят зафиксированной транзакцией, возвращает ErrOperationApplied
+	ClaimOperationKey(ctx context.Context, key string) error
+	// GetReversedAmount возвращает сумму, уже возвращённую компенсирующими записями операции (без знака)
+	GetReversedAmount(ctx context.Context, transactionID uuid.UUID) (int64, error)
+	// ListTransactions возвращает до filter.Limit операций кошелька от новых к старым
+	ListTransactions(ctx context.Context, filter TransactionFilter) ([]Transaction, error)
+	// StreamTransactions вызывает fn для каждой записи счёта кошелька в главной книге с временем проводки
+	// в [from, to) в порядке записи, не загружая их в память; ошибка fn прерывает чтение. Кроме операций
+	// передаются входящие остатки EntryOpeningBalance, поэтому сумма записей сходится с GetBalanceAt
+	StreamTransactions(ctx context.Context, walletID uuid.UUID, from, to time.Time, fn func(Transaction) error) error
+	// GetLedgerSummary возвращает итоги главной книги по каждой валюте
+	GetLedgerSummary(ctx context.Context) ([]LedgerSummary, error)
+	// StreamBalanceChecks вызывает fn для каждого кошелька в порядке id с его балансом, суммой операций
+	// и остатком в главной книге, прочитанными одним запросом; ошибка fn прерывает чтение
+	StreamBalanceChecks(ctx context.Context, fn func(BalanceCheck) error) error
+	// GetBalanceCheck возвращает те же данные для одного кошелька
+	GetBalanceCheck(ctx context.Context, walletID uuid.UUID) (BalanceCheck, error)
+	// SetBalance заменяет wallets.balance при исправлении расхождений сверкой;
+	// принимается только остаток счёта кошелька в главной книге
+	SetBalance(ctx context.Context, walletID uuid.UUID, balance int64) error
+	// WithinTransaction выполняет fn в одной транзакции БД: все вызовы репозитория
+	// с переданным в fn контекстом либо фиксируются вместе, либо откатываются
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// WithinSnapshot выполняет fn в транзакции только для чтения с уровнем REPEATABLE READ: все чтения
+	// с переданным в fn контекстом видят один снимок БД; внутри открытой транзакции использует её
+	WithinSnapshot(ctx context.Context, fn func(ctx context.Context) error) error
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// AdminOnly пропускает к административному обработчику только запросы с заголовком
+// Authorization: Bearer <AdminToken>; если токен не задан, административные методы отключены
+func (h *WalletHandler) AdminOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.AdminToken == "" {
+			writeProblem(w, r, http.StatusForbidden, CodeForbidden, "Admin API is disabled")
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeProblem(w, r, http.StatusUnauthorized, CodeUnauthorized, "Invalid admin token")
+			return
+		}
+		next(w, r)
+	}
+}
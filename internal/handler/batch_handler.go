@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"WalletApp/internal/domain"
+)
+
+// Метод для обработки пакета операций: {"operations": [{walletId, operationType, amount, ...}, ...]}.
+// Операции выполняются по порядку и все вместе: при ошибке любой из них не выполняется ни одна,
+// а в ответе с ошибкой поле leg указывает номер операции. В ответе результаты операций в том же порядке
+func (h *WalletHandler) HandleBatchOperation(w http.ResponseWriter, r *http.Request) {
+	h.Semaphore <- struct{}{}
+	defer func() { <-h.Semaphore }()
+
+	var request struct {
+		Operations []domain.Operation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	results, err := h.Service.PerformBatch(r.Context(), request.Operations)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Status  string                   `json:"status"`
+		Results []domain.OperationResult `json:"results"`
+	}{"success", results})
+}
@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование пакета операций: результаты по порядку, откат пакета и номер ошибочной операции
+func TestHandleBatchOperation(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/operations/batch", h.HandleBatchOperation).Methods("POST")
+
+	from, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	to, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	mockSvc.balances[from] = 1000
+
+	batch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operations/batch", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := batch(`{"operations":[
+		{"walletId":"` + from.String() + `","operationType":"WITHDRAW","amount":100},
+		{"walletId":"` + from.String() + `","operationType":"TRANSFER","amount":300,"destinationWalletId":"` + to.String() + `"},
+		{"walletId":"` + to.String() + `","operationType":"DEPOSIT","amount":50}]}`)
+	var response struct {
+		Status  string                   `json:"status"`
+		Results []domain.OperationResult `json:"results"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response.Status != "success" || len(response.Results) != 3 {
+		t.Fatalf("expected 200 with 3 results, got %d %+v", w.Code, response)
+	}
+	if tx := response.Results[2].Transactions[0]; tx.WalletID != to || tx.Amount != 50 {
+		t.Errorf("expected results in request order, got %+v", response.Results)
+	}
+	if mockSvc.balances[from] != 600 || mockSvc.balances[to] != 350 {
+		t.Errorf("unexpected balances %d, %d", mockSvc.balances[from], mockSvc.balances[to])
+	}
+
+	// Вторая операция не проходит - первая тоже откатывается
+	w = batch(`{"operations":[
+		{"walletId":"` + to.String() + `","operationType":"DEPOSIT","amount":100},
+		{"walletId":"` + from.String() + `","operationType":"WITHDRAW","amount":10000}]}`)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || problem.Code != handler.CodeInsufficientFunds || problem.Leg == nil || *problem.Leg != 1 {
+		t.Errorf("expected 400 %q for leg 1, got %d %+v", handler.CodeInsufficientFunds, w.Code, problem)
+	}
+	if mockSvc.balances[from] != 600 || mockSvc.balances[to] != 350 {
+		t.Errorf("expected batch to be rolled back, got balances %d, %d", mockSvc.balances[from], mockSvc.balances[to])
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedType string
+	}{
+		{"Empty batch", `{"operations":[]}`, http.StatusBadRequest, handler.CodeInvalidBatch},
+		{"Invalid body", `{"operations":{}}`, http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"Unknown operation", `{"operations":[{"walletId":"` + from.String() + `","operationType":"EXCHANGE","amount":1}]}`, http.StatusBadRequest, handler.CodeInvalidOperation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := batch(tt.body)
+			var problem handler.Problem
+			json.NewDecoder(w.Body).Decode(&problem)
+			if w.Code != tt.expectedCode || problem.Code != tt.expectedType {
+				t.Errorf("expected %d %q, got %d %q", tt.expectedCode, tt.expectedType, w.Code, problem.Code)
+			}
+		})
+	}
+}
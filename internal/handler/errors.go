@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// машиночитаемые коды ошибок API
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeInvalidWalletID      = "invalid_wallet_id"
+	CodeInvalidHoldID        = "invalid_hold_id"
+	CodeInvalidOwnerID       = "invalid_owner_id"
+	CodeInvalidOwner         = "invalid_owner"
+	CodeOwnerNotFound        = "owner_not_found"
+	CodeInvalidMetadata      = "invalid_metadata"
+	CodeInvalidTransactionID = "invalid_transaction_id"
+	CodeTransactionNotFound  = "transaction_not_found"
+	CodeNotReversible        = "not_reversible"
+	CodeAlreadyReversed      = "already_reversed"
+	CodeRefundExceedsAmount  = "refund_exceeds_amount"
+	CodeInvalidPeriod        = "invalid_period"
+	CodeInvalidTier          = "invalid_tier"
+	CodeInvalidOperation     = "invalid_operation"
+	CodeInvalidAmount        = "invalid_amount"
+	CodeAmountOutOfRange     = "amount_out_of_range"
+	CodeBalanceOverflow      = "balance_overflow"
+	CodeInvalidDestination   = "invalid_destination"
+	CodeInvalidCursor        = "invalid_cursor"
+	CodeUnsupportedCurrency  = "unsupported_currency"
+	CodeCurrencyMismatch     = "currency_mismatch"
+	CodeInvalidRate          = "invalid_rate"
+	CodeRateNotFound         = "rate_not_found"
+	CodeQuoteNotFound        = "quote_not_found"
+	CodeQuoteExpired         = "quote_expired"
+	CodeQuoteMismatch        = "quote_mismatch"
+	CodeHoldNotFound         = "hold_not_found"
+	CodeHoldNotActive        = "hold_not_active"
+	CodeInsufficientFunds    = "insufficient_funds"
+	CodeWalletNotFound       = "wallet_not_found"
+	CodeWalletFrozen         = "wallet_frozen"
+	CodeWalletClosed         = "wallet_closed"
+	CodeWalletNotEmpty       = "wallet_not_empty"
+	CodeOverdraftInUse       = "overdraft_in_use"
+	CodeSpendingLimit        = "spending_limit_exceeded"
+	CodeInvalidSpendingLimit = "invalid_spending_limit"
+	CodeInvalidTransition    = "invalid_status_transition"
+	CodeInvalidBatch         = "invalid_batch"
+	CodeInvalidImport        = "invalid_import"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeRequestInProgress    = "request_in_progress"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeInternal             = "internal_error"
+)
+
+// тело ответа с ошибкой в стиле RFC 7807 (problem details)
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"requestId"`
+	Leg       *int   `json:"leg,omitempty"` // номер (с нуля) операции пакета, из-за которой пакет отклонён
+}
+
+// соответствие ошибок предметной области кодам ответа
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrInsufficientFunds, http.StatusBadRequest, CodeInsufficientFunds},
+	{domain.ErrWalletNotFound, http.StatusNotFound, CodeWalletNotFound},
+	{domain.ErrOwnerNotFound, http.StatusNotFound, CodeOwnerNotFound},
+	{domain.ErrInvalidOwner, http.StatusBadRequest, CodeInvalidOwner},
+	{domain.ErrInvalidMetadata, http.StatusBadRequest, CodeInvalidMetadata},
+	{domain.ErrTransactionNotFound, http.StatusNotFound, CodeTransactionNotFound},
+	{domain.ErrNotReversible, http.StatusUnprocessableEntity, CodeNotReversible},
+	{domain.ErrAlreadyReversed, http.StatusConflict, CodeAlreadyReversed},
+	{domain.ErrRefundExceedsAmount, http.StatusUnprocessableEntity, CodeRefundExceedsAmount},
+	{domain.ErrInvalidPeriod, http.StatusBadRequest, CodeInvalidPeriod},
+	{domain.ErrInvalidTier, http.StatusBadRequest, CodeInvalidTier},
+	{domain.ErrInvalidOperation, http.StatusBadRequest, CodeInvalidOperation},
+	{domain.ErrInvalidAmount, http.StatusBadRequest, CodeInvalidAmount},
+	{domain.ErrAmountOutOfRange, http.StatusBadRequest, CodeAmountOutOfRange},
+	{domain.ErrBalanceOverflow, http.StatusUnprocessableEntity, CodeBalanceOverflow},
+	{domain.ErrInvalidDestination, http.StatusBadRequest, CodeInvalidDestination},
+	{domain.ErrInvalidCursor, http.StatusBadRequest, CodeInvalidCursor},
+	{domain.ErrUnsupportedCurrency, http.StatusBadRequest, CodeUnsupportedCurrency},
+	{domain.ErrCurrencyMismatch, http.StatusBadRequest, CodeCurrencyMismatch},
+	{domain.ErrInvalidRate, http.StatusBadRequest, CodeInvalidRate},
+	{domain.ErrRateNotFound, http.StatusUnprocessableEntity, CodeRateNotFound},
+	{domain.ErrQuoteNotFound, http.StatusNotFound, CodeQuoteNotFound},
+	{domain.ErrQuoteExpired, http.StatusConflict, CodeQuoteExpired},
+	{domain.ErrQuoteMismatch, http.StatusBadRequest, CodeQuoteMismatch},
+	{domain.ErrHoldNotFound, http.StatusNotFound, CodeHoldNotFound},
+	{domain.ErrHoldNotActive, http.StatusConflict, CodeHoldNotActive},
+	{domain.ErrWalletFrozen, http.StatusConflict, CodeWalletFrozen},
+	{domain.ErrWalletClosed, http.StatusConflict, CodeWalletClosed},
+	{domain.ErrWalletNotEmpty, http.StatusConflict, CodeWalletNotEmpty},
+	{domain.ErrOverdraftInUse, http.StatusConflict, CodeOverdraftInUse},
+	{domain.ErrSpendingLimitExceeded, http.StatusUnprocessableEntity, CodeSpendingLimit},
+	{domain.ErrInvalidSpendingLimit, http.StatusBadRequest, CodeInvalidSpendingLimit},
+	{domain.ErrInvalidStatusTransition, http.StatusConflict, CodeInvalidTransition},
+	{domain.ErrInvalidBatch, http.StatusBadRequest, CodeInvalidBatch},
+	{domain.ErrInvalidImport, http.StatusBadRequest, CodeInvalidImport},
+}
+
+type requestIDKey struct{}
+
+// RequestIDMiddleware присваивает запросу идентификатор (из X-Request-ID или новый)
+// и возвращает его в заголовке ответа
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+	})
+}
+
+// Функция возвращает идентификатор запроса; вне RequestIDMiddleware создаёт новый
+func requestID(w http.ResponseWriter, r *http.Request) string {
+	if id, ok := r.Context().Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	id := uuid.NewString()
+	w.Header().Set(RequestIDHeader, id)
+	return id
+}
+
+// Функция для отправки ошибки в формате application/problem+json
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	sendProblem(w, r, Problem{Status: status, Code: code, Detail: detail})
+}
+
+// Функция дополняет problem общими полями и отправляет его
+func sendProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	problem.Type = "about:blank"
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = r.URL.Path
+	problem.RequestID = requestID(w, r)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// Метод для отправки ошибки сервиса: ошибки предметной области получают свой код,
+// остальные логируются и возвращаются как 500 без подробностей
+func (h *WalletHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, detail := h.describeError(w, r, err)
+	problem := Problem{Status: status, Code: code, Detail: detail}
+	var batchErr *domain.BatchError
+	if errors.As(err, &batchErr) {
+		problem.Leg = &batchErr.Index
+	}
+	sendProblem(w, r, problem)
+}
+
+// Метод возвращает код ответа, код и описание ошибки сервиса; ошибка вне таблицы
+// domainErrors логируется, а клиенту описывается как внутренняя
+func (h *WalletHandler) describeError(w http.ResponseWriter, r *http.Request, err error) (int, string, string) {
+	for _, e := range domainErrors {
+		if errors.Is(err, e.err) {
+			return e.status, e.code, err.Error()
+		}
+	}
+	h.Logger.WithError(err).WithField("requestId", requestID(w, r)).Error("request failed")
+	return http.StatusInternalServerError, CodeInternal, "Internal server error"
+}
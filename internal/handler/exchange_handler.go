@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"time"
+
+	"WalletApp/internal/domain"
+)
+
+// курс в запросе на загрузку; rate принимается числом или строкой
+type exchangeRateRequest struct {
+	BaseCurrency  string      `json:"baseCurrency"`
+	QuoteCurrency string      `json:"quoteCurrency"`
+	Rate          json.Number `json:"rate"`
+	EffectiveAt   *time.Time  `json:"effectiveAt"` // RFC 3339; если не указан, курс действует с момента загрузки
+}
+
+// Метод для загрузки курсов (административный): JSON {"rates": [...]} или CSV
+// с заголовком base_currency,quote_currency,rate[,effective_at] при Content-Type: text/csv
+func (h *WalletHandler) HandleAddExchangeRates(w http.ResponseWriter, r *http.Request) {
+	var rates []domain.ExchangeRate
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		parsed, err := domain.ParseExchangeRatesCSV(r.Body)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+			return
+		}
+		rates = parsed
+	} else {
+		var request struct {
+			Rates []exchangeRateRequest `json:"rates"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+			return
+		}
+		for _, rate := range request.Rates {
+			converted := domain.ExchangeRate{
+				BaseCurrency:  rate.BaseCurrency,
+				QuoteCurrency: rate.QuoteCurrency,
+				Rate:          rate.Rate.String(),
+			}
+			if rate.EffectiveAt != nil {
+				converted.EffectiveAt = *rate.EffectiveAt
+			}
+			rates = append(rates, converted)
+		}
+	}
+
+	saved, err := h.Service.AddExchangeRates(r.Context(), rates)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string][]domain.ExchangeRate{"rates": saved})
+}
+
+// Метод для получения действующих курсов всех пар
+func (h *WalletHandler) HandleListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.Service.ListExchangeRates(r.Context())
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]domain.ExchangeRate{"rates": rates})
+}
+
+// Метод для получения котировки обмена: {"fromCurrency", "toCurrency", "amount"}.
+// Идентификатор котировки передаётся в quoteId перевода, пока она не истекла
+func (h *WalletHandler) HandleCreateQuote(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		FromCurrency string `json:"fromCurrency"`
+		ToCurrency   string `json:"toCurrency"`
+		Amount       int64  `json:"amount"` // в минимальных единицах fromCurrency
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	quote, err := h.Service.QuoteExchange(r.Context(), request.FromCurrency, request.ToCurrency, request.Amount)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(quote)
+}
@@ -0,0 +1,123 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Функция создаёт маршрутизатор с обработчиками курсов обмена
+func newExchangeRouter(h *handler.WalletHandler) *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/fx/rates", h.HandleListExchangeRates).Methods("GET")
+	r.HandleFunc("/api/v1/fx/quotes", h.HandleCreateQuote).Methods("POST")
+	r.HandleFunc("/api/v1/admin/fx/rates", h.AdminOnly(h.HandleAddExchangeRates)).Methods("POST")
+	return r
+}
+
+// Тестирование доступа к административной загрузке курсов
+func TestAdminOnly(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	r := newExchangeRouter(h)
+
+	body := `{"rates":[{"baseCurrency":"USD","quoteCurrency":"RUB","rate":90.5}]}`
+	tests := []struct {
+		name     string
+		token    string
+		header   string
+		expected int
+	}{
+		{"disabled without token", "", "Bearer secret", http.StatusForbidden},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer wrong", http.StatusUnauthorized},
+		{"valid token", "secret", "Bearer secret", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		h.AdminToken = tt.token
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/fx/rates", bytes.NewBufferString(body))
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, w.Code)
+		}
+	}
+	if len(mockSvc.rates) != 1 || mockSvc.rates[0].Rate != "90.5" {
+		t.Errorf("expected rate 90.5 to be loaded once, got %+v", mockSvc.rates)
+	}
+}
+
+// Тестирование загрузки курсов из CSV
+func TestHandleAddExchangeRates_CSV(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	h.AdminToken = "secret"
+	r := newExchangeRouter(h)
+
+	csv := "base_currency,quote_currency,rate,effective_at\nUSD,RUB,91.2,2026-01-01T00:00:00Z\nUSD,KZT,470,\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/fx/rates", bytes.NewBufferString(csv))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(mockSvc.rates) != 2 || mockSvc.rates[1].QuoteCurrency != "KZT" {
+		t.Errorf("unexpected loaded rates: %+v", mockSvc.rates)
+	}
+
+	// Некорректный курс
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/fx/rates", bytes.NewBufferString("base_currency,quote_currency,rate\nUSD,RUB,-1\n"))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "text/csv")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || problem.Code != handler.CodeInvalidRate {
+		t.Errorf("expected 400 invalid_rate, got %d %q", w.Code, problem.Code)
+	}
+}
+
+// Тестирование получения котировки
+func TestHandleCreateQuote(t *testing.T) {
+	h := handler.NewWalletHandler(newMockWalletService(), logrus.New())
+	r := newExchangeRouter(h)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/fx/quotes", bytes.NewBufferString(`{"fromCurrency":"USD","toCurrency":"RUB","amount":1000}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var quote domain.FXQuote
+	json.NewDecoder(w.Body).Decode(&quote)
+	if quote.ConvertedAmount != 90000 || quote.Rate != "90" || quote.ExpiresAt.IsZero() {
+		t.Errorf("unexpected quote: %+v", quote)
+	}
+
+	// Для пары без курса
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/fx/quotes", bytes.NewBufferString(`{"fromCurrency":"EUR","toCurrency":"RUB","amount":1000}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusUnprocessableEntity || problem.Code != handler.CodeRateNotFound {
+		t.Errorf("expected 422 rate_not_found, got %d %q", w.Code, problem.Code)
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Метод для обработки назначения тарифа комиссий кошельку: {"tier": "GOLD"};
+// в ответе баланс кошелька с новым тарифом
+func (h *WalletHandler) HandleSetWalletTier(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := parseWalletID(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Tier string `json:"tier"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	wallet, err := h.Service.SetWalletTier(r.Context(), walletID, request.Tier)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	response, err := newBalanceResponse(wallet)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Метод для обработки запроса тарифной сетки комиссий: правила в порядке применения и кошельки доходов
+func (h *WalletHandler) HandleGetFeeSchedule(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Service.GetFeeSchedule(r.Context()))
+}
@@ -0,0 +1,110 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование назначения тарифа кошельку и выдачи тарифной сетки
+func TestHandleSetWalletTier(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	h.AdminToken = "secret"
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/tier", h.AdminOnly(h.HandleSetWalletTier)).Methods("PUT")
+	r.HandleFunc("/api/v1/admin/fees", h.AdminOnly(h.HandleGetFeeSchedule)).Methods("GET")
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	setTier := func(walletID uuid.UUID, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/wallets/"+walletID.String()+"/tier", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := setTier(walletID, `{"tier":"gold"}`)
+	var balance map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&balance)
+	if w.Code != http.StatusOK || balance["tier"] != "GOLD" {
+		t.Errorf("expected 200 with tier GOLD, got %d %v", w.Code, balance)
+	}
+
+	tests := []struct {
+		name         string
+		walletID     uuid.UUID
+		body         string
+		expectedCode int
+		expectedType string
+	}{
+		{"Invalid tier", walletID, `{"tier":"gold tier"}`, http.StatusBadRequest, handler.CodeInvalidTier},
+		{"Empty tier", walletID, `{}`, http.StatusBadRequest, handler.CodeInvalidTier},
+		{"Invalid body", walletID, `{`, http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"Unknown wallet", uuid.New(), `{"tier":"GOLD"}`, http.StatusNotFound, handler.CodeWalletNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := setTier(tt.walletID, tt.body)
+			var problem handler.Problem
+			json.NewDecoder(w.Body).Decode(&problem)
+			if w.Code != tt.expectedCode || problem.Code != tt.expectedType {
+				t.Errorf("expected %d %q, got %d %q", tt.expectedCode, tt.expectedType, w.Code, problem.Code)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/fees", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without token, got %d", w.Code)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var schedule domain.FeeSchedule
+	json.NewDecoder(w.Body).Decode(&schedule)
+	if w.Code != http.StatusOK || len(schedule.Rules) != 1 || schedule.Rules[0].Name != "withdraw" {
+		t.Errorf("unexpected fee schedule response: %d %+v", w.Code, schedule)
+	}
+}
+
+// Тестирование расшифровки комиссии в ответе на операцию
+func TestHandleOperation_Fees(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	mockSvc.fees = []domain.Fee{{Rule: "deposit", Amount: 15, Currency: "RUB", Flat: 10, Percentage: 5, WalletID: walletID, RevenueWalletID: uuid.New(), TransactionID: uuid.New()}}
+
+	body := `{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":500}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operation", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	h.HandleOperation(w, req)
+
+	var response struct {
+		Status       string               `json:"status"`
+		Transactions []domain.Transaction `json:"transactions"`
+		Fees         []domain.Fee         `json:"fees"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response.Status != "success" {
+		t.Fatalf("expected 200 success, got %d %q", w.Code, response.Status)
+	}
+	if len(response.Transactions) != 1 || response.Transactions[0].Amount != 500 {
+		t.Errorf("unexpected transactions: %+v", response.Transactions)
+	}
+	if len(response.Fees) != 1 || response.Fees[0] != mockSvc.fees[0] {
+		t.Errorf("unexpected fees: %+v", response.Fees)
+	}
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Метод для обработки создания блокировки средств: {"amount", "expiresAt"}; без expiresAt - срок по умолчанию
+func (h *WalletHandler) HandleCreateHold(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil || walletID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidWalletID, "Invalid wallet ID")
+		return
+	}
+
+	var request struct {
+		Amount    int64      `json:"amount"`
+		ExpiresAt *time.Time `json:"expiresAt"` // RFC 3339
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	var expiresAt time.Time
+	if request.ExpiresAt != nil {
+		expiresAt = *request.ExpiresAt
+	}
+
+	hold, err := h.Service.CreateHold(r.Context(), walletID, request.Amount, expiresAt)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// Метод для обработки запроса блокировки по id
+func (h *WalletHandler) HandleGetHold(w http.ResponseWriter, r *http.Request) {
+	holdID, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.Service.GetHold(r.Context(), holdID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// Метод для обработки списания по блокировке: {"amount"}; без тела или amount списывается вся сумма
+func (h *WalletHandler) HandleCaptureHold(w http.ResponseWriter, r *http.Request) {
+	holdID, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Amount int64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	hold, err := h.Service.CaptureHold(r.Context(), holdID, request.Amount)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// Метод для обработки отмены блокировки
+func (h *WalletHandler) HandleVoidHold(w http.ResponseWriter, r *http.Request) {
+	holdID, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.Service.VoidHold(r.Context(), holdID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hold)
+}
+
+// Функция извлекает id блокировки из URL; при ошибке отправляет ответ 400
+func parseHoldID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	holdID, err := uuid.Parse(mux.Vars(r)["holdId"])
+	if err != nil || holdID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidHoldID, "Invalid hold ID")
+		return uuid.Nil, false
+	}
+	return holdID, true
+}
@@ -0,0 +1,90 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование создания, списания и отмены блокировок
+func TestHoldHandlers(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods("GET")
+	r.HandleFunc("/api/v1/wallets/{walletId}/holds", h.HandleCreateHold).Methods("POST")
+	r.HandleFunc("/api/v1/holds/{holdId}", h.HandleGetHold).Methods("GET")
+	r.HandleFunc("/api/v1/holds/{holdId}/capture", h.HandleCaptureHold).Methods("POST")
+	r.HandleFunc("/api/v1/holds/{holdId}/void", h.HandleVoidHold).Methods("POST")
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	mockSvc.balances[walletID] = 1000
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/"+walletID.String()+"/holds",
+		bytes.NewBufferString(`{"amount":300,"expiresAt":"2099-01-01T00:00:00Z"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var hold domain.Hold
+	json.NewDecoder(w.Body).Decode(&hold)
+	if hold.Amount != 300 || hold.Status != domain.HoldActive || hold.ExpiresAt.Year() != 2099 {
+		t.Errorf("unexpected hold: %+v", hold)
+	}
+
+	// Баланс показывает доступную сумму и баланс по главной книге отдельно
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var balance map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&balance)
+	if balance["available"] != float64(700) || balance["ledger"] != float64(1000) || balance["reserved"] != float64(300) ||
+		balance["balance"] != float64(700) || balance["ledgerDecimal"] != "10.00" {
+		t.Errorf("unexpected balance response: %v", balance)
+	}
+
+	// Списание без тела - на всю сумму
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/holds/"+hold.ID.String()+"/capture", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	json.NewDecoder(w.Body).Decode(&hold)
+	if w.Code != http.StatusOK || hold.Status != domain.HoldCaptured || hold.CapturedAmount != 300 {
+		t.Errorf("unexpected capture response %d: %+v", w.Code, hold)
+	}
+
+	// Повторная отмена списанной блокировки
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/holds/"+hold.ID.String()+"/void", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusConflict || problem.Code != handler.CodeHoldNotActive {
+		t.Errorf("expected 409 hold_not_active, got %d %q", w.Code, problem.Code)
+	}
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/v1/holds/" + uuid.NewString(), http.StatusNotFound},
+		{"/api/v1/holds/not-a-uuid", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expected, w.Code)
+		}
+	}
+}
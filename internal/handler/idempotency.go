@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"WalletApp/internal/domain"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// ошибка, которой откатывается транзакция запроса, завершившегося ошибкой
+var errRequestFailed = errors.New("request failed")
+
+// обёртка над ResponseWriter, которая копит код ответа и тело, пока транзакция запроса
+// не зафиксирована; заголовки пишутся сразу в исходный ResponseWriter
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(statusCode int) {
+	if rec.statusCode == 0 {
+		rec.statusCode = statusCode
+	}
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.statusCode == 0 {
+		rec.statusCode = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
+
+// Метод для передачи накопленного ответа клиенту
+func (rec *responseRecorder) flush() {
+	if rec.statusCode == 0 {
+		return
+	}
+	rec.ResponseWriter.WriteHeader(rec.statusCode)
+	rec.ResponseWriter.Write(rec.body.Bytes())
+}
+
+// Idempotent оборачивает обработчик POST-запроса поддержкой заголовка Idempotency-Key.
+// Повтор с тем же ключом и телом получает сохранённый ответ без повторного выполнения,
+// повтор с тем же ключом и другим телом отклоняется. Ключ занимается, обработчик выполняется
+// и успешный ответ сохраняется в одной транзакции БД, поэтому операция не может зафиксироваться
+// без ответа: после сбоя процесса или ошибки сервера откатываются и она, и ключ
+func (h *WalletHandler) Idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || h.Idempotency == nil {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid idempotency key")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Отпечаток запроса: метод, путь и тело
+		sum := sha256.New()
+		sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+		sum.Write(body)
+		fingerprint := hex.EncodeToString(sum.Sum(nil))
+
+		// Запрос доводится до конца и ответ сохраняется, даже если клиент уже отключился
+		ctx := context.WithoutCancel(r.Context())
+
+		var (
+			record   domain.IdempotencyRecord
+			reserved bool
+			rec      = &responseRecorder{ResponseWriter: w}
+		)
+		err = h.Idempotency.WithinTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			record, reserved, err = h.Idempotency.Reserve(ctx, key, fingerprint)
+			if err != nil || !reserved {
+				return err
+			}
+			next(rec, r.WithContext(ctx))
+			// Изменения запроса, завершившегося ошибкой, откатываются вместе с ключом
+			if rec.statusCode == 0 || rec.statusCode >= http.StatusBadRequest {
+				return errRequestFailed
+			}
+			return h.Idempotency.Complete(ctx, key, fingerprint, rec.statusCode, w.Header().Get("Content-Type"), rec.body.Bytes())
+		})
+		switch {
+		case errors.Is(err, errRequestFailed):
+			// Ответ на ошибку клиента сохраняется уже после отката; ошибки сервера не сохраняем:
+			// клиент может повторить запрос с тем же ключом
+			if rec.statusCode >= http.StatusBadRequest && rec.statusCode < http.StatusInternalServerError {
+				if err := h.Idempotency.Complete(ctx, key, fingerprint, rec.statusCode, w.Header().Get("Content-Type"), rec.body.Bytes()); err != nil {
+					h.Logger.WithError(err).Error("failed to store idempotent response")
+				}
+			}
+			rec.flush()
+		case err != nil:
+			// Транзакция не зафиксирована: ни операция, ни ответ не сохранены
+			h.writeError(w, r, err)
+		case reserved:
+			rec.flush()
+		case !record.Completed:
+			writeProblem(w, r, http.StatusConflict, CodeRequestInProgress, "Request with this idempotency key is in progress")
+		case record.Fingerprint != fingerprint:
+			writeProblem(w, r, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency key reused with a different request")
+		default:
+			if record.ContentType != "" {
+				w.Header().Set("Content-Type", record.ContentType)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(record.StatusCode)
+			w.Write(record.Body)
+		}
+	}
+}
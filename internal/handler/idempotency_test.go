@@ -0,0 +1,140 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// хранилище ключей идемпотентности в памяти
+type mockIdempotencyRepository struct {
+	records      map[string]domain.IdempotencyRecord
+	failComplete bool // имитация ошибки сохранения ответа
+}
+
+func newMockIdempotencyRepository() *mockIdempotencyRepository {
+	return &mockIdempotencyRepository{records: make(map[string]domain.IdempotencyRecord)}
+}
+
+// WithinTransaction восстанавливает ключи, если fn вернула ошибку
+func (m *mockIdempotencyRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	records := make(map[string]domain.IdempotencyRecord, len(m.records))
+	for key, record := range m.records {
+		records[key] = record
+	}
+	if err := fn(ctx); err != nil {
+		m.records = records
+		return err
+	}
+	return nil
+}
+
+func (m *mockIdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string) (domain.IdempotencyRecord, bool, error) {
+	if record, exists := m.records[key]; exists {
+		return record, false, nil
+	}
+	record := domain.IdempotencyRecord{Key: key, Fingerprint: fingerprint}
+	m.records[key] = record
+	return record, true, nil
+}
+
+func (m *mockIdempotencyRepository) Complete(ctx context.Context, key, fingerprint string, statusCode int, contentType string, body []byte) error {
+	if m.failComplete {
+		return errors.New("update failed")
+	}
+	record, exists := m.records[key]
+	if exists && (record.Completed || record.Fingerprint != fingerprint) {
+		return nil
+	}
+	m.records[key] = domain.IdempotencyRecord{
+		Key: key, Fingerprint: fingerprint, Completed: true, StatusCode: statusCode, ContentType: contentType, Body: body,
+	}
+	return nil
+}
+
+func TestIdempotentOperation(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	store := newMockIdempotencyRepository()
+	h.Idempotency = store
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	operation := h.Idempotent(h.HandleOperation)
+
+	send := func(key, operationType string, amount int64) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"walletId":      walletID.String(),
+			"operationType": operationType,
+			"amount":        amount,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operation", bytes.NewBuffer(body))
+		if key != "" {
+			req.Header.Set(handler.IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		operation(w, req)
+		return w
+	}
+
+	first := send("key-1", "DEPOSIT", 100)
+	if first.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", first.Code)
+	}
+
+	// Повтор с тем же ключом возвращает сохранённый ответ и не выполняет операцию повторно
+	retry := send("key-1", "DEPOSIT", 100)
+	if retry.Code != http.StatusOK || retry.Header().Get(handler.IdempotentReplayedHeader) != "true" {
+		t.Errorf("expected replayed 200 response, got %d (replayed=%q)", retry.Code, retry.Header().Get(handler.IdempotentReplayedHeader))
+	}
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("expected replayed body %q, got %q", first.Body.String(), retry.Body.String())
+	}
+	if mockSvc.balances[walletID] != 100 {
+		t.Errorf("expected balance 100 after retry, got %d", mockSvc.balances[walletID])
+	}
+
+	// Тот же ключ с другим телом отклоняется
+	if w := send("key-1", "DEPOSIT", 200); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for reused key, got %d", w.Code)
+	}
+
+	// Ключ, запрос по которому ещё выполняется в другой транзакции
+	store.records["key-2"] = domain.IdempotencyRecord{Key: "key-2", Fingerprint: store.records["key-1"].Fingerprint}
+	if w := send("key-2", "DEPOSIT", 100); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for in-progress key, got %d", w.Code)
+	}
+
+	// Без ключа каждый запрос выполняется
+	send("", "DEPOSIT", 100)
+	send("", "DEPOSIT", 100)
+	if mockSvc.balances[walletID] != 300 {
+		t.Errorf("expected balance 300, got %d", mockSvc.balances[walletID])
+	}
+
+	// Ошибка клиента сохраняется и повторяется без выполнения операции
+	if w := send("key-3", "WITHDRAW", 1000); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if w := send("key-3", "WITHDRAW", 1000); w.Code != http.StatusBadRequest || w.Header().Get(handler.IdempotentReplayedHeader) != "true" {
+		t.Errorf("expected replayed 400 response, got %d", w.Code)
+	}
+
+	// Если ответ не удалось сохранить, транзакция запроса откатывается (мок сервиса этого не умеет,
+	// поэтому проверка последняя): клиент получает ошибку сервера, а ключ остаётся свободным для повтора
+	store.failComplete = true
+	if w := send("key-4", "DEPOSIT", 100); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 when response is not stored, got %d", w.Code)
+	}
+	store.failComplete = false
+	if _, exists := store.records["key-4"]; exists {
+		t.Error("expected key to be released with the rolled back request")
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"WalletApp/internal/domain"
+	"WalletApp/internal/usecase"
+)
+
+const maxImportBodySize = 64 << 20 // наибольший размер файла загрузки операций
+
+// Метод для обработки загрузки операций из файла в теле запроса (JSONL или CSV, строки как в запросе операции).
+// Параметры: format = jsonl или csv (по умолчанию по Content-Type: text/csv - csv, иначе jsonl),
+// parallelism - операций одновременно, dryRun=true - только проверить строки (с учётом
+// предыдущих строк файла), after - пропустить строки по эту включительно (продолжение прерванной
+// загрузки с поля line ответа), importId - обязательный без dryRun идентификатор загрузки, одинаковый
+// при всех её запросах: строки, уже выполненные прерванным или повторённым запросом, не выполняются снова.
+// Отклонённые строки возвращаются в rejectedRows. Если загрузка прервалась, status = "stopped",
+// а line указывает, по какую строку файл обработан
+func (h *WalletHandler) HandleImportOperations(w http.ResponseWriter, r *http.Request) {
+	h.Semaphore <- struct{}{}
+	defer func() { <-h.Semaphore }()
+
+	query := r.URL.Query()
+	opts := usecase.ImportOptions{Key: query.Get("importId")}
+	for _, p := range []struct {
+		name  string
+		value *int
+	}{{"parallelism", &opts.Parallelism}, {"after", &opts.From.Line}} {
+		if v := query.Get(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid "+p.name)
+				return
+			}
+			*p.value = n
+		}
+	}
+	if opts.Parallelism > usecase.MaxImportParallelism {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid parallelism")
+		return
+	}
+	if v := query.Get("dryRun"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid dryRun")
+			return
+		}
+		opts.DryRun = dryRun
+	}
+	// Без идентификатора повтор запроса после обрыва связи выполнил бы строки второй раз
+	if opts.Key == "" && !opts.DryRun {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Missing importId")
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = domain.ImportJSONL
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+			format = domain.ImportCSV
+		}
+	}
+	rows, err := domain.NewOperationReader(http.MaxBytesReader(w, r.Body, maxImportBodySize), format)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	response := struct {
+		Status string `json:"status"`
+		domain.ImportReport
+		Error        string               `json:"error,omitempty"`  // код ошибки, прервавшей загрузку
+		Detail       string               `json:"detail,omitempty"` // описание ошибки
+		RejectedRows []domain.RejectedRow `json:"rejectedRows"`
+	}{Status: "success", RejectedRows: []domain.RejectedRow{}}
+	opts.Reject = func(row domain.RejectedRow) error {
+		response.RejectedRows = append(response.RejectedRows, row)
+		return nil
+	}
+
+	response.ImportReport, err = usecase.ImportOperations(r.Context(), h.Service, rows, opts)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		response.Status, response.Error, response.Detail = "stopped", CodeInvalidRequest, "Request body too large"
+	case err != nil:
+		response.Status = "stopped"
+		_, response.Error, response.Detail = h.describeError(w, r, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,111 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование загрузки операций: CSV и JSONL, проверка без изменений, пропуск обработанных строк
+func TestHandleImportOperations(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	h.AdminToken = "secret"
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/admin/operations/import", h.AdminOnly(h.HandleImportOperations)).Methods("POST")
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	importOperations := func(query, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/operations/import"+query, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type response struct {
+		Status string `json:"status"`
+		domain.ImportReport
+		Error        string               `json:"error"`
+		RejectedRows []domain.RejectedRow `json:"rejectedRows"`
+	}
+
+	csvFile := "walletId,operationType,amount\n" +
+		walletID.String() + ",DEPOSIT,100\n" +
+		walletID.String() + ",WITHDRAW,500\n" +
+		walletID.String() + ",WITHDRAW,30\n"
+
+	// Проверка: ничего не меняется, строки видят результаты предыдущих строк файла
+	w := importOperations("?dryRun=true&parallelism=4", "text/csv", csvFile)
+	var result response
+	json.NewDecoder(w.Body).Decode(&result)
+	if w.Code != http.StatusOK || !result.DryRun || result.Applied != 2 || result.Rejected != 1 || mockSvc.balances[walletID] != 0 {
+		t.Errorf("unexpected dry run response %d %+v, balance %d", w.Code, result, mockSvc.balances[walletID])
+	}
+
+	w = importOperations("?importId=file-1&parallelism=1", "text/csv; charset=utf-8", csvFile)
+	result = response{}
+	json.NewDecoder(w.Body).Decode(&result)
+	if w.Code != http.StatusOK || result.Status != "success" || result.Applied != 2 || result.Rejected != 1 || result.Line != 4 {
+		t.Fatalf("unexpected import response %d %+v", w.Code, result)
+	}
+	if len(result.RejectedRows) != 1 || result.RejectedRows[0].Line != 3 || result.RejectedRows[0].Row != walletID.String()+",WITHDRAW,500" {
+		t.Errorf("unexpected rejected rows: %+v", result.RejectedRows)
+	}
+	if mockSvc.balances[walletID] != 70 {
+		t.Errorf("expected balance 70, got %d", mockSvc.balances[walletID])
+	}
+
+	// Продолжение: строки по after включительно пропускаются
+	jsonl := `{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":1}` + "\n" +
+		`{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":10}` + "\n"
+	w = importOperations("?importId=file-2&after=1&parallelism=1", "application/x-ndjson", jsonl)
+	result = response{}
+	json.NewDecoder(w.Body).Decode(&result)
+	if w.Code != http.StatusOK || result.Skipped != 1 || result.Applied != 1 || mockSvc.balances[walletID] != 80 {
+		t.Errorf("unexpected resumed import response %d %+v, balance %d", w.Code, result, mockSvc.balances[walletID])
+	}
+
+	// Строки, уже выполненные загрузкой с тем же importId, при повторе не выполняются
+	for i := 0; i < 2; i++ {
+		w = importOperations("?importId=file-3&parallelism=1", "application/x-ndjson", jsonl)
+		result = response{}
+		json.NewDecoder(w.Body).Decode(&result)
+		if w.Code != http.StatusOK || result.Applied != 2 || mockSvc.balances[walletID] != 91 {
+			t.Errorf("unexpected import response %d %+v, balance %d", w.Code, result, mockSvc.balances[walletID])
+		}
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		body         string
+		expectedCode int
+		expectedType string
+	}{
+		{"Missing importId", "", jsonl, http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"Unknown format", "?importId=file-4&format=xml", jsonl, http.StatusBadRequest, handler.CodeInvalidImport},
+		{"Missing CSV column", "?importId=file-4&format=csv", "walletId,amount\n", http.StatusBadRequest, handler.CodeInvalidImport},
+		{"Invalid parallelism", "?parallelism=1000", jsonl, http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"Invalid dryRun", "?dryRun=maybe", jsonl, http.StatusBadRequest, handler.CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := importOperations(tt.query, "", tt.body)
+			var problem handler.Problem
+			json.NewDecoder(w.Body).Decode(&problem)
+			if w.Code != tt.expectedCode || problem.Code != tt.expectedType {
+				t.Errorf("expected %d %q, got %d %q", tt.expectedCode, tt.expectedType, w.Code, problem.Code)
+			}
+		})
+	}
+}
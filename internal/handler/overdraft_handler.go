@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Метод для обработки установки кредитной линии кошелька: {"limit"} в минимальных единицах валюты;
+// в ответе баланс кошелька с новым лимитом и остатком кредита
+func (h *WalletHandler) HandleSetOverdraftLimit(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil || walletID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidWalletID, "Invalid wallet ID")
+		return
+	}
+
+	var request struct {
+		Limit *int64 `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Limit == nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	wallet, err := h.Service.SetOverdraftLimit(r.Context(), walletID, *request.Limit)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	response, err := newBalanceResponse(wallet)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
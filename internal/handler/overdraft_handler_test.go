@@ -0,0 +1,65 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование установки кредитной линии и отражения остатка кредита в балансе
+func TestHandleSetOverdraftLimit(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	h.AdminToken = "secret"
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods("GET")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/overdraft", h.AdminOnly(h.HandleSetOverdraftLimit)).Methods("PUT")
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	setLimit := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/wallets/"+walletID.String()+"/overdraft", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := setLimit(`{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without limit, got %d", w.Code)
+	}
+
+	w := setLimit(`{"limit":50000}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	// Списание в пределах кредитной линии уводит баланс в минус
+	if _, err := mockSvc.PerformOperation(context.Background(), domain.Operation{WalletID: walletID, OperationType: "WITHDRAW", Amount: 12000}); err != nil {
+		t.Fatalf("could not withdraw: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var balance map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&balance)
+	if balance["balance"] != float64(-12000) || balance["overdraftLimit"] != float64(50000) ||
+		balance["remainingCredit"] != float64(38000) || balance["remainingCreditDecimal"] != "380.00" {
+		t.Errorf("unexpected balance response: %v", balance)
+	}
+
+	w = setLimit(`{"limit":10000}`)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusConflict || problem.Code != handler.CodeOverdraftInUse {
+		t.Errorf("expected 409 overdraft_in_use, got %d %q", w.Code, problem.Code)
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"WalletApp/internal/domain"
+)
+
+// Метод для обработки регистрации владельца кошельков: {"name", "email"}
+func (h *WalletHandler) HandleCreateOwner(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	owner, err := h.Service.CreateOwner(r.Context(), domain.Owner{Name: request.Name, Email: request.Email})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(owner)
+}
+
+// Метод для обработки запроса владельца по id
+func (h *WalletHandler) HandleGetOwner(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := parseOwnerID(w, r)
+	if !ok {
+		return
+	}
+
+	owner, err := h.Service.GetOwner(r.Context(), ownerID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(owner)
+}
+
+// Метод для обработки запроса всех кошельков владельца с их балансами
+func (h *WalletHandler) HandleListOwnerWallets(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := parseOwnerID(w, r)
+	if !ok {
+		return
+	}
+
+	wallets, err := h.Service.ListOwnerWallets(r.Context(), ownerID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	response := struct {
+		OwnerID uuid.UUID         `json:"ownerId"`
+		Wallets []balanceResponse `json:"wallets"`
+	}{OwnerID: ownerID, Wallets: make([]balanceResponse, 0, len(wallets))}
+	for _, wallet := range wallets {
+		balance, err := newBalanceResponse(wallet)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		response.Wallets = append(response.Wallets, balance)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Функция извлекает id владельца из URL; при ошибке отправляет ответ 400
+func parseOwnerID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	ownerID, err := uuid.Parse(mux.Vars(r)["ownerId"])
+	if err != nil || ownerID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidOwnerID, "Invalid owner ID")
+		return uuid.Nil, false
+	}
+	return ownerID, true
+}
@@ -0,0 +1,81 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование регистрации владельца, создания его кошельков и их списка
+func TestOwnerHandlers(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallet", h.HandleCreateWallet).Methods("POST")
+	r.HandleFunc("/api/v1/owners", h.HandleCreateOwner).Methods("POST")
+	r.HandleFunc("/api/v1/owners/{ownerId}", h.HandleGetOwner).Methods("GET")
+	r.HandleFunc("/api/v1/owners/{ownerId}/wallets", h.HandleListOwnerWallets).Methods("GET")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPost, "/api/v1/owners", `{"email":"x@example.com"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without name, got %d", w.Code)
+	}
+	w := do(http.MethodPost, "/api/v1/owners", `{"name":"Acme LLC"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var owner domain.Owner
+	json.NewDecoder(w.Body).Decode(&owner)
+
+	if w := do(http.MethodGet, "/api/v1/owners/"+owner.ID.String(), ""); w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	for _, currency := range []string{"RUB", "USD"} {
+		w := do(http.MethodPost, "/api/v1/wallet", `{"currency":"`+currency+`","ownerId":"`+owner.ID.String()+`"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status 201, got %d", w.Code)
+		}
+	}
+	w = do(http.MethodPost, "/api/v1/wallet", `{"ownerId":"`+uuid.NewString()+`"}`)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusNotFound || problem.Code != handler.CodeOwnerNotFound {
+		t.Errorf("expected 404 owner_not_found, got %d %q", w.Code, problem.Code)
+	}
+
+	w = do(http.MethodGet, "/api/v1/owners/"+owner.ID.String()+"/wallets", "")
+	var response struct {
+		OwnerID uuid.UUID                `json:"ownerId"`
+		Wallets []map[string]interface{} `json:"wallets"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response.OwnerID != owner.ID || len(response.Wallets) != 2 {
+		t.Fatalf("unexpected owner wallets response: %d %+v", w.Code, response)
+	}
+	if response.Wallets[0]["currency"] != "RUB" || response.Wallets[1]["currency"] != "USD" ||
+		response.Wallets[0]["ownerId"] != owner.ID.String() || response.Wallets[0]["balance"] != float64(0) {
+		t.Errorf("unexpected wallets: %v", response.Wallets)
+	}
+
+	if w := do(http.MethodGet, "/api/v1/owners/not-a-uuid/wallets", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid owner id, got %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/v1/owners/"+uuid.NewString()+"/wallets", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown owner, got %d", w.Code)
+	}
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Метод для обработки отмены (возврата) операции; тело необязательно: {"amount": 500}.
+// Без суммы возвращается весь невозвращённый остаток операции
+func (h *WalletHandler) HandleReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := uuid.Parse(mux.Vars(r)["transactionId"])
+	if err != nil || transactionID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidTransactionID, "Invalid transaction ID")
+		return
+	}
+
+	var request struct {
+		Amount int64 `json:"amount"` // в минимальных единицах валюты отменяемой записи
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	reversal, err := h.Service.ReverseTransaction(r.Context(), transactionID, request.Amount)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reversal)
+}
@@ -0,0 +1,73 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование административной отмены операции: частичный возврат, превышение и повтор
+func TestHandleReverseTransaction(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	h.AdminToken = "secret"
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/admin/transactions/{transactionId}/reverse", h.AdminOnly(h.HandleReverseTransaction)).Methods("POST")
+	transactionID := uuid.New()
+	mockSvc.refundable[transactionID] = 1000
+	reverse := func(id, body string) (*httptest.ResponseRecorder, handler.Problem) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/transactions/"+id+"/reverse", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var problem handler.Problem
+		if w.Code >= 400 {
+			json.NewDecoder(w.Body).Decode(&problem)
+		}
+		return w, problem
+	}
+
+	w, _ := reverse(transactionID.String(), `{"amount":400}`)
+	var reversal domain.Reversal
+	json.NewDecoder(w.Body).Decode(&reversal)
+	if w.Code != http.StatusCreated || reversal.Amount != 400 || reversal.Remaining != 600 || len(reversal.Transactions) != 1 {
+		t.Fatalf("unexpected reversal: %d %+v", w.Code, reversal)
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+		code   string
+	}{
+		{"exceeds remaining", transactionID.String(), `{"amount":601}`, http.StatusUnprocessableEntity, handler.CodeRefundExceedsAmount},
+		{"invalid id", "not-a-uuid", "", http.StatusBadRequest, handler.CodeInvalidTransactionID},
+		{"invalid body", transactionID.String(), `{"amount":"x"}`, http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"unknown transaction", uuid.NewString(), "", http.StatusNotFound, handler.CodeTransactionNotFound},
+		{"rest without body", transactionID.String(), "", http.StatusCreated, ""},
+		{"already reversed", transactionID.String(), "", http.StatusConflict, handler.CodeAlreadyReversed},
+	}
+	for _, tt := range tests {
+		w, problem := reverse(tt.id, tt.body)
+		if w.Code != tt.status || problem.Code != tt.code {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.name, tt.status, tt.code, w.Code, problem.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/transactions/"+transactionID.String()+"/reverse", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without admin token, got %d", w.Code)
+	}
+}
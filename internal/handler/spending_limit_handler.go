@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"WalletApp/internal/domain"
+)
+
+// ответ со списком действующих лимитов кошелька
+type spendingLimitsResponse struct {
+	Limits []domain.SpendingLimitStatus `json:"limits"`
+}
+
+// Метод для обработки запроса действующих лимитов расходов кошелька и их использования
+func (h *WalletHandler) HandleGetSpendingLimits(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := parseWalletID(w, r)
+	if !ok {
+		return
+	}
+
+	limits, err := h.Service.GetSpendingLimits(r.Context(), walletID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spendingLimitsResponse{limits})
+}
+
+// Метод для обработки установки лимитов кошелька:
+// {"limits": [{"period", "maxAmount", "maxCount"}]}; 0 - без ограничения
+func (h *WalletHandler) HandleSetSpendingLimits(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := parseWalletID(w, r)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Limits []domain.SpendingLimit `json:"limits"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || len(request.Limits) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	limits, err := h.Service.SetSpendingLimits(r.Context(), walletID, request.Limits)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(spendingLimitsResponse{limits})
+}
+
+// Метод для обработки сброса лимита кошелька на период к политике по умолчанию
+func (h *WalletHandler) HandleResetSpendingLimit(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := parseWalletID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.ResetSpendingLimit(r.Context(), walletID, mux.Vars(r)["period"]); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Функция извлекает id кошелька из URL; при ошибке отправляет ответ 400
+func parseWalletID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil || walletID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidWalletID, "Invalid wallet ID")
+		return uuid.Nil, false
+	}
+	return walletID, true
+}
@@ -0,0 +1,83 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование установки лимитов расходов и ответа при их превышении
+func TestSpendingLimitHandlers(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	h.AdminToken = "secret"
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/operation", h.HandleOperation).Methods("POST")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/limits", h.AdminOnly(h.HandleGetSpendingLimits)).Methods("GET")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/limits", h.AdminOnly(h.HandleSetSpendingLimits)).Methods("PUT")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/limits/{period}", h.AdminOnly(h.HandleResetSpendingLimit)).Methods("DELETE")
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	mockSvc.balances[walletID] = 10000
+	admin := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/admin/wallets/"+walletID.String()+"/limits"+path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	withdraw := func(amount int64) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"walletId": walletID, "operationType": "WITHDRAW", "amount": amount})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operation", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := admin(http.MethodPut, "", `{"limits":[{"period":"yearly","maxAmount":100}]}`)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || problem.Code != handler.CodeInvalidSpendingLimit {
+		t.Errorf("expected 400 invalid_spending_limit, got %d %q", w.Code, problem.Code)
+	}
+
+	w = admin(http.MethodPut, "", `{"limits":[{"period":"daily","maxAmount":1000,"maxCount":5}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+
+	if w := withdraw(600); w.Code != http.StatusOK {
+		t.Fatalf("expected withdraw within limit to succeed, got %d", w.Code)
+	}
+	w = withdraw(500)
+	problem = handler.Problem{}
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusUnprocessableEntity || problem.Code != handler.CodeSpendingLimit {
+		t.Errorf("expected 422 spending_limit_exceeded, got %d %q", w.Code, problem.Code)
+	}
+
+	w = admin(http.MethodGet, "", "")
+	var response struct {
+		Limits []domain.SpendingLimitStatus `json:"limits"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response.Limits) != 1 || response.Limits[0].Period != domain.PeriodDaily || response.Limits[0].Used.Amount != 600 {
+		t.Errorf("unexpected limits response: %+v", response)
+	}
+
+	if w := admin(http.MethodDelete, "/DAILY", ""); w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if w := withdraw(500); w.Code != http.StatusOK {
+		t.Errorf("expected withdraw after reset to succeed, got %d", w.Code)
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"WalletApp/internal/domain"
+)
+
+// форматы выписки
+const (
+	StatementJSON = "json"
+	StatementCSV  = "csv"
+	StatementText = "txt"
+)
+
+// Метод для обработки запроса выписки по кошельку.
+// Параметры: from и to (RFC 3339, период [from, to)), format = json (по умолчанию), csv или txt.
+// Выписка отдаётся потоком; если ошибка случилась после начала ответа, ответ обрывается
+func (h *WalletHandler) HandleStatement(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := parseWalletID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	var from, to time.Time
+	for _, p := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v, err := time.Parse(time.RFC3339, query.Get(p.name))
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidPeriod, "Invalid or missing "+p.name)
+			return
+		}
+		*p.value = v
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = StatementJSON
+	}
+	out, ok := newStatementWriter(format, w)
+	if !ok {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Unsupported statement format "+strconv.Quote(format))
+		return
+	}
+
+	if err := h.Service.WriteStatement(r.Context(), walletID, from, to, out); err != nil {
+		if !out.started() {
+			h.writeError(w, r, err)
+			return
+		}
+		h.Logger.WithError(err).WithField("requestId", requestID(w, r)).Error("statement stream aborted")
+		panic(http.ErrAbortHandler) // клиент должен увидеть обрыв, а не усечённую выписку
+	}
+}
+
+// выписка, которую пишут в ответ HTTP
+type statementResponse interface {
+	domain.StatementWriter
+	started() bool
+}
+
+// Функция возвращает запись выписки в формате format
+func newStatementWriter(format string, w http.ResponseWriter) (statementResponse, bool) {
+	base := statementBase{w: w, format: format}
+	switch format {
+	case StatementJSON:
+		return &jsonStatement{statementBase: base}, true
+	case StatementCSV:
+		return &csvStatement{statementBase: base, csv: csv.NewWriter(w)}, true
+	case StatementText:
+		return &textStatement{statementBase: base}, true
+	}
+	return nil, false
+}
+
+// общая часть записи выписки: заголовки ответа и валюта для форматирования сумм
+type statementBase struct {
+	w        http.ResponseWriter
+	format   string
+	currency domain.Currency
+	to       time.Time // конец периода для строки исходящего остатка
+	begun    bool
+}
+
+var statementContentTypes = map[string]string{
+	StatementJSON: "application/json",
+	StatementCSV:  "text/csv; charset=utf-8",
+	StatementText: "text/plain; charset=utf-8",
+}
+
+// Метод отправляет заголовки ответа; после него ошибку уже нельзя вернуть кодом ответа
+func (b *statementBase) begin(header domain.StatementHeader) error {
+	currency, err := domain.LookupCurrency(header.Currency)
+	if err != nil {
+		return err
+	}
+	b.currency, b.to, b.begun = currency, header.To, true
+	filename := fmt.Sprintf("statement-%s-%s.%s", header.WalletID, header.From.UTC().Format("20060102"), b.format)
+	b.w.Header().Set("Content-Type", statementContentTypes[b.format])
+	b.w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	b.w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (b *statementBase) started() bool {
+	return b.begun
+}
+
+// Функция возвращает строковое представление необязательного id
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// выписка в JSON: {"walletId", ..., "transactions": [...], "closingBalance", ...}
+type jsonStatement struct {
+	statementBase
+	lines int
+}
+
+func (s *jsonStatement) Begin(header domain.StatementHeader) error {
+	if err := s.begin(header); err != nil {
+		return err
+	}
+	head, err := json.Marshal(struct {
+		domain.StatementHeader
+		OpeningBalanceDecimal string `json:"openingBalanceDecimal"`
+	}{header, s.currency.FormatAmount(header.OpeningBalance)})
+	if err != nil {
+		return err
+	}
+	// Открытый объект заголовка дополняется массивом операций и итогом
+	_, err = fmt.Fprintf(s.w, `%s,"transactions":[`, head[:len(head)-1])
+	return err
+}
+
+func (s *jsonStatement) Line(line domain.StatementLine) error {
+	item, err := json.Marshal(struct {
+		domain.StatementLine
+		AmountDecimal  string `json:"amountDecimal"`
+		BalanceDecimal string `json:"balanceDecimal"`
+	}{line, s.currency.FormatAmount(line.Amount), s.currency.FormatAmount(line.Balance)})
+	if err != nil {
+		return err
+	}
+	if s.lines > 0 {
+		if _, err := io.WriteString(s.w, ","); err != nil {
+			return err
+		}
+	}
+	s.lines++
+	_, err = s.w.Write(item)
+	return err
+}
+
+func (s *jsonStatement) End(summary domain.StatementSummary) error {
+	tail, err := json.Marshal(struct {
+		domain.StatementSummary
+		ClosingBalanceDecimal string `json:"closingBalanceDecimal"`
+	}{summary, s.currency.FormatAmount(summary.ClosingBalance)})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "],%s\n", tail[1:])
+	return err
+}
+
+// выписка в CSV: строки OPENING_BALANCE и CLOSING_BALANCE обрамляют операции; суммы в единицах валюты
+type csvStatement struct {
+	statementBase
+	csv *csv.Writer
+}
+
+func (s *csvStatement) Begin(header domain.StatementHeader) error {
+	if err := s.begin(header); err != nil {
+		return err
+	}
+	s.csv.Write([]string{"date", "transactionId", "operationType", "amount", "balance", "currency",
+		"counterpartyWalletId", "relatedTransactionId", "reversedTransactionId"})
+	return s.writeBalance(header.From, "OPENING_BALANCE", header.OpeningBalance)
+}
+
+func (s *csvStatement) Line(line domain.StatementLine) error {
+	s.csv.Write([]string{
+		line.CreatedAt.UTC().Format(time.RFC3339Nano),
+		line.ID.String(),
+		line.OperationType,
+		s.currency.FormatAmount(line.Amount),
+		s.currency.FormatAmount(line.Balance),
+		s.currency.Code,
+		optionalID(line.CounterpartyWalletID),
+		optionalID(line.RelatedTransactionID),
+		optionalID(line.ReversedTransactionID),
+	})
+	return s.csv.Error()
+}
+
+func (s *csvStatement) End(summary domain.StatementSummary) error {
+	return s.writeBalance(s.to, "CLOSING_BALANCE", summary.ClosingBalance)
+}
+
+// Метод пишет строку остатка на момент date и отправляет накопленные строки
+func (s *csvStatement) writeBalance(date time.Time, kind string, balance int64) error {
+	s.csv.Write([]string{date.UTC().Format(time.RFC3339Nano), "", kind, "", s.currency.FormatAmount(balance), s.currency.Code, "", "", ""})
+	s.csv.Flush()
+	return s.csv.Error()
+}
+
+// выписка простым текстом с колонками фиксированной ширины
+type textStatement struct {
+	statementBase
+}
+
+const textStatementRow = "%-30s  %-10s  %20s  %20s  %s\n"
+
+func (s *textStatement) Begin(header domain.StatementHeader) error {
+	if err := s.begin(header); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(s.w, "Statement for wallet %s (%s)\nPeriod: %s - %s\nOpening balance: %s\n\n"+textStatementRow,
+		header.WalletID, s.currency.Code,
+		header.From.UTC().Format(time.RFC3339), header.To.UTC().Format(time.RFC3339),
+		s.currency.FormatAmount(header.OpeningBalance),
+		"Date", "Operation", "Amount", "Balance", "Transaction")
+	return err
+}
+
+func (s *textStatement) Line(line domain.StatementLine) error {
+	_, err := fmt.Fprintf(s.w, textStatementRow,
+		line.CreatedAt.UTC().Format(time.RFC3339Nano), line.OperationType,
+		s.currency.FormatAmount(line.Amount), s.currency.FormatAmount(line.Balance), line.ID)
+	return err
+}
+
+func (s *textStatement) End(summary domain.StatementSummary) error {
+	_, err := fmt.Fprintf(s.w, "\nTransactions: %d\nTotal credits: %s\nTotal debits: %s\nClosing balance: %s\n",
+		summary.Count, s.currency.FormatAmount(summary.TotalCredits), s.currency.FormatAmount(summary.TotalDebits),
+		s.currency.FormatAmount(summary.ClosingBalance))
+	return err
+}
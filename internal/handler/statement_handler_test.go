@@ -0,0 +1,164 @@
+package handler_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Функция создаёт мок с кошельком и двумя операциями для выписки
+func newStatementFixture(t *testing.T) (*mockWalletService, *mux.Router, uuid.UUID) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	walletID, _ := mockSvc.CreateWallet(nil, domain.Wallet{Currency: "USD"})
+	mockSvc.balances[walletID] = 10000
+	at := time.Date(2026, 9, 10, 12, 0, 0, 0, time.UTC)
+	mockSvc.statement = []domain.Transaction{
+		{ID: uuid.New(), WalletID: walletID, Amount: 2550, OperationType: "DEPOSIT", CreatedAt: at},
+		{ID: uuid.New(), WalletID: walletID, Amount: -1000, OperationType: "WITHDRAW", CreatedAt: at.Add(time.Hour)},
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/{walletId}/statement", h.HandleStatement).Methods("GET")
+	return mockSvc, r, walletID
+}
+
+const statementPeriod = "from=2026-09-01T00:00:00Z&to=2026-10-01T00:00:00Z"
+
+func getStatement(r *mux.Router, walletID uuid.UUID, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/statement?"+query, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandleStatement_JSON(t *testing.T) {
+	mockSvc, r, walletID := newStatementFixture(t)
+
+	w := getStatement(r, walletID, statementPeriod)
+	var statement struct {
+		WalletID              uuid.UUID `json:"walletId"`
+		Currency              string    `json:"currency"`
+		OpeningBalance        int64     `json:"openingBalance"`
+		OpeningBalanceDecimal string    `json:"openingBalanceDecimal"`
+		Transactions          []struct {
+			ID             uuid.UUID `json:"id"`
+			Amount         int64     `json:"amount"`
+			Balance        int64     `json:"balance"`
+			BalanceDecimal string    `json:"balanceDecimal"`
+		} `json:"transactions"`
+		ClosingBalance        int64  `json:"closingBalance"`
+		ClosingBalanceDecimal string `json:"closingBalanceDecimal"`
+		Count                 int64  `json:"count"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&statement); err != nil {
+		t.Fatalf("statement is not valid JSON: %v", err)
+	}
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if statement.WalletID != walletID || statement.Currency != "USD" || statement.OpeningBalanceDecimal != "100.00" {
+		t.Errorf("unexpected header: %+v", statement)
+	}
+	if len(statement.Transactions) != 2 || statement.Transactions[0].ID != mockSvc.statement[0].ID ||
+		statement.Transactions[0].BalanceDecimal != "125.50" || statement.Transactions[1].Balance != 11550 {
+		t.Errorf("unexpected transactions: %+v", statement.Transactions)
+	}
+	if statement.ClosingBalance != 11550 || statement.ClosingBalanceDecimal != "115.50" || statement.Count != 2 {
+		t.Errorf("unexpected summary: %+v", statement)
+	}
+
+	// Пустой период - валидный JSON с пустым массивом
+	mockSvc.statement = nil
+	w = getStatement(r, walletID, statementPeriod)
+	if !strings.Contains(w.Body.String(), `"transactions":[]`) || !json.Valid(w.Body.Bytes()) {
+		t.Errorf("unexpected empty statement: %s", w.Body.String())
+	}
+}
+
+func TestHandleStatement_CSV(t *testing.T) {
+	_, r, walletID := newStatementFixture(t)
+
+	w := getStatement(r, walletID, statementPeriod+"&format=csv")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") ||
+		!strings.Contains(w.Header().Get("Content-Disposition"), "statement-"+walletID.String()+"-20260901.csv") {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("statement is not valid CSV: %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("expected header, opening, 2 transactions and closing rows, got %d", len(records))
+	}
+	if records[1][2] != "OPENING_BALANCE" || records[1][4] != "100.00" {
+		t.Errorf("unexpected opening row: %v", records[1])
+	}
+	if records[2][2] != "DEPOSIT" || records[2][3] != "25.50" || records[2][4] != "125.50" || records[3][3] != "-10.00" {
+		t.Errorf("unexpected transaction rows: %v %v", records[2], records[3])
+	}
+	if records[4][0] != "2026-10-01T00:00:00Z" || records[4][2] != "CLOSING_BALANCE" || records[4][4] != "115.50" {
+		t.Errorf("unexpected closing row: %v", records[4])
+	}
+}
+
+func TestHandleStatement_Text(t *testing.T) {
+	_, r, walletID := newStatementFixture(t)
+
+	w := getStatement(r, walletID, statementPeriod+"&format=txt")
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("unexpected response: %d %v", w.Code, w.Header())
+	}
+	for _, expected := range []string{"Opening balance: 100.00", "WITHDRAW", "-10.00", "Transactions: 2", "Closing balance: 115.50"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in statement:\n%s", expected, body)
+		}
+	}
+}
+
+func TestHandleStatement_Errors(t *testing.T) {
+	mockSvc, r, walletID := newStatementFixture(t)
+
+	tests := []struct {
+		name     string
+		walletID uuid.UUID
+		query    string
+		status   int
+		code     string
+	}{
+		{"missing from", walletID, "to=2026-10-01T00:00:00Z", http.StatusBadRequest, handler.CodeInvalidPeriod},
+		{"invalid to", walletID, "from=2026-09-01T00:00:00Z&to=october", http.StatusBadRequest, handler.CodeInvalidPeriod},
+		{"reversed period", walletID, "from=2026-10-01T00:00:00Z&to=2026-09-01T00:00:00Z", http.StatusBadRequest, handler.CodeInvalidPeriod},
+		{"unknown format", walletID, statementPeriod + "&format=pdf", http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"unknown wallet", uuid.New(), statementPeriod, http.StatusNotFound, handler.CodeWalletNotFound},
+	}
+	for _, tt := range tests {
+		w := getStatement(r, tt.walletID, tt.query)
+		var problem handler.Problem
+		json.NewDecoder(w.Body).Decode(&problem)
+		if w.Code != tt.status || problem.Code != tt.code {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.name, tt.status, tt.code, w.Code, problem.Code)
+		}
+	}
+
+	// Ошибка после начала ответа обрывает соединение, а не отдаёт усечённую выписку
+	mockSvc.streamErr = errors.New("connection reset")
+	defer func() {
+		if recovered := recover(); recovered != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler, got %v", recovered)
+		}
+	}()
+	getStatement(r, walletID, statementPeriod)
+}
@@ -2,11 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
 	"WalletApp/internal/usecase"
 )
 
@@ -15,6 +19,9 @@ type WalletHandler struct {
 	Service usecase.WalletService // Сервис для выполнения операций с кошельками
 	Logger  *logrus.Logger // логгер
 
+	Idempotency domain.IdempotencyRepository // Хранилище ключей идемпотентности (nil - заголовок Idempotency-Key игнорируется)
+	AdminToken  string                       // Токен административных методов (пустой - методы отключены)
+
 	Semaphore chan struct{} // Семафор для ограничения параллельных запросов
 }
 
@@ -27,12 +34,29 @@ func NewWalletHandler(service usecase.WalletService, logger *logrus.Logger) *Wal
 	}
 }
 
-// Метод для обработки создания нового кошелька
+// Метод для обработки создания нового кошелька; тело запроса необязательно:
+// {"currency": "KZT", "ownerId": "...", "displayName": "...", "metadata": {"tier": "gold"}}
 func (h *WalletHandler) HandleCreateWallet(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Currency    string            `json:"currency"`    // код ISO 4217, по умолчанию валюта сервиса
+		OwnerID     *uuid.UUID        `json:"ownerId"`     // владелец кошелька; без него кошелёк анонимный
+		DisplayName string            `json:"displayName"` // отображаемое имя
+		Metadata    map[string]string `json:"metadata"`    // произвольные пары ключ-значение
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
 	ctx := r.Context()
-	walletID, err := h.Service.CreateWallet(ctx) // Вызов метода create
+	walletID, err := h.Service.CreateWallet(ctx, domain.Wallet{ // Вызов метода create
+		Currency:    request.Currency,
+		OwnerID:     request.OwnerID,
+		DisplayName: request.DisplayName,
+		Metadata:    request.Metadata,
+	})
 	if err != nil {
-		http.Error(w, "Failed to create wallet", http.StatusInternalServerError) // Возврат ошибки 500 при неудаче
+		h.writeError(w, r, err)
 		return
 	}
 
@@ -42,25 +66,140 @@ func (h *WalletHandler) HandleCreateWallet(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(response) // Кодирование ответа в JSON и отправка клиенту
 }
 
-// Метод для обработки запроса на получение баланса кошелька
+// Метод для обработки запроса на получение баланса кошелька;
+// с параметром asOf (RFC 3339) возвращается баланс на этот момент
 func (h *WalletHandler) HandleGetBalance(w http.ResponseWriter, r *http.Request) {
 	walletIDParam := mux.Vars(r)["walletId"] // Извлечение id кошелька из URL
 	walletID, err := uuid.Parse(walletIDParam) // Парсинг id кошелька из строки
 
 	if err != nil || walletID == uuid.Nil {
-		http.Error(w, "Invalid wallet ID", http.StatusBadRequest) // Возврат ошибки 400 при некорректном id
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidWalletID, "Invalid wallet ID") // Возврат ошибки 400 при некорректном id
+		return
+	}
+	if v := r.URL.Query().Get("asOf"); v != "" {
+		asOf, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid asOf")
+			return
+		}
+		h.writeBalanceAt(w, r, walletID, asOf)
 		return
 	}
 
 	ctx := r.Context()
-	balance, err := h.Service.GetBalance(ctx, walletID)
+	wallet, err := h.Service.GetWallet(ctx, walletID)
+
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	response, err := newBalanceResponse(wallet)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Метод отправляет баланс кошелька на момент asOf
+func (h *WalletHandler) writeBalanceAt(w http.ResponseWriter, r *http.Request, walletID uuid.UUID, asOf time.Time) {
+	snapshot, err := h.Service.GetBalanceAt(r.Context(), walletID, asOf)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	currency, err := domain.LookupCurrency(snapshot.Currency)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		WalletID       uuid.UUID `json:"walletId"`
+		AsOf           time.Time `json:"asOf"`
+		Balance        int64     `json:"balance"` // баланс по главной книге на момент asOf
+		BalanceDecimal string    `json:"balanceDecimal"`
+		Currency       string    `json:"currency"`
+	}{snapshot.WalletID, snapshot.AsOf, snapshot.Balance, currency.FormatAmount(snapshot.Balance), currency.Code})
+}
+
+// Метод для обработки изменения имени и метаданных кошелька (PATCH):
+// {"displayName": "...", "metadata": {"tier": "gold", "legacy": null}}; ключ со значением null удаляется
+func (h *WalletHandler) HandleUpdateWallet(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := parseWalletID(w, r)
+	if !ok {
+		return
+	}
+
+	var update domain.WalletUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	wallet, err := h.Service.UpdateWallet(r.Context(), walletID, update)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	response, err := newBalanceResponse(wallet)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
 
+// Метод для обработки поиска кошельков по меткам.
+// Параметры: label=ключ:значение (можно несколько, нужны все) и limit
+func (h *WalletHandler) HandleListWallets(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.WalletFilter{Labels: map[string]string{}}
+	for _, label := range query["label"] {
+		key, value, err := domain.ParseLabel(label)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		if existing, ok := filter.Labels[key]; ok && existing != value {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidMetadata, "Conflicting labels for key "+key)
+			return
+		}
+		filter.Labels[key] = value
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+
+	wallets, err := h.Service.ListWallets(r.Context(), filter)
 	if err != nil {
-		http.Error(w, "Error retrieving balance", http.StatusInternalServerError) // Возврат ошибки 500 при неудаче
+		h.writeError(w, r, err)
 		return
 	}
+	response := struct {
+		Wallets []balanceResponse `json:"wallets"`
+	}{Wallets: make([]balanceResponse, 0, len(wallets))}
+	for _, wallet := range wallets {
+		balance, err := newBalanceResponse(wallet)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		response.Wallets = append(response.Wallets, balance)
+	}
 
-	json.NewEncoder(w).Encode(map[string]int64{"balance": balance})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
 }
 
 // Метод для обработки операций (депозит/снятие) с кошельком
@@ -68,32 +207,151 @@ func (h *WalletHandler) HandleOperation(w http.ResponseWriter, r *http.Request)
 	h.Semaphore <- struct{}{} // Захватываем слот для выполнения запроса
 	defer func() { <-h.Semaphore }() // Освобождаем слот после завершения
 
-	var request struct {
-		WalletId      uuid.UUID `json:"walletId"`
-		OperationType string    `json:"operationType"`
-		Amount        int64     `json:"amount"`
-	}
+	var request domain.Operation // walletId, operationType, amount, currency и destinationWalletId для TRANSFER
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
         return
     }
 
-	err := h.Service.PerformOperation(r.Context(), request.WalletId, request.OperationType, request.Amount)
+	result, err := h.Service.PerformOperation(r.Context(), request)
 
 	if err != nil {
-        if err.Error() == "insufficient funds" {
-            http.Error(w, "Insufficient funds", http.StatusBadRequest) // Возврат ошибки 400 при недостатке средств
-            return
-        }
-        if err.Error() == "invalid operation type" { 
-            http.Error(w, "Invalid operation type", http.StatusBadRequest) // Возврат ошибки 400 при неверном типе операции
-            return
-        }
-        http.Error(w, "Error performing operation", http.StatusInternalServerError) // Возврат ошибки 500 при неудаче выполнения операции
+        h.writeError(w, r, err) // Код ответа определяется типом ошибки (insufficient_funds, wallet_not_found и т.д.)
         return
     }
 
+    w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusOK)
-    json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+    // Записи операции и расшифровка комиссий
+    json.NewEncoder(w).Encode(struct {
+        Status string `json:"status"`
+        domain.OperationResult
+    }{"success", result})
+}
+
+// Метод для обработки запроса истории операций кошелька.
+// Параметры: limit, cursor, operationType, from и to (RFC 3339)
+func (h *WalletHandler) HandleListTransactions(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := parseWalletID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filter := domain.TransactionFilter{
+		WalletID:      walletID,
+		OperationType: query.Get("operationType"),
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid limit")
+			return
+		}
+		filter.Limit = limit
+	}
+	if v := query.Get("cursor"); v != "" {
+		cursor, err := domain.DecodeTransactionCursor(v)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		filter.After = &cursor
+	}
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid from")
+			return
+		}
+		filter.From = &from
+	}
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid to")
+			return
+		}
+		filter.To = &to
+	}
+
+	page, err := h.Service.ListTransactions(r.Context(), filter)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// Метод для обработки запроса сводки по главной книге (для финансовой сверки); маршрут доступен только администратору
+func (h *WalletHandler) HandleLedgerSummary(w http.ResponseWriter, r *http.Request) {
+	summaries, err := h.Service.GetLedgerSummary(r.Context())
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	balanced := true
+	for _, summary := range summaries {
+		balanced = balanced && summary.Balanced
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"balanced":   balanced,
+		"currencies": summaries,
+	})
+}
+
+// ответ с балансом кошелька; суммы в минимальных единицах валюты
+type balanceResponse struct {
+	WalletID       uuid.UUID `json:"walletId"`
+	Balance        int64     `json:"balance"`        // доступный баланс (совпадает с available)
+	Available      int64     `json:"available"`      // баланс за вычетом заблокированных средств
+	Ledger         int64     `json:"ledger"`         // баланс по главной книге, включая заблокированные средства
+	Reserved       int64     `json:"reserved"`       // сумма активных блокировок
+	Currency       string    `json:"currency"`       // код ISO 4217
+	Status         string    `json:"status"`         // ACTIVE, FROZEN или CLOSED
+	Tier           string    `json:"tier,omitempty"` // тариф комиссий
+	BalanceDecimal string    `json:"balanceDecimal"` // доступный баланс в единицах валюты, например "123.45"
+	LedgerDecimal  string    `json:"ledgerDecimal"`  // баланс по главной книге в единицах валюты
+
+	OverdraftLimit         int64  `json:"overdraftLimit"`         // кредитная линия кошелька
+	RemainingCredit        int64  `json:"remainingCredit"`        // неиспользованная часть кредитной линии
+	RemainingCreditDecimal string `json:"remainingCreditDecimal"` // неиспользованный кредит в единицах валюты
+
+	OwnerID     *uuid.UUID        `json:"ownerId,omitempty"`     // владелец кошелька
+	DisplayName string            `json:"displayName,omitempty"` // отображаемое имя
+	Metadata    map[string]string `json:"metadata,omitempty"`    // метаданные кошелька
+}
+
+// Функция формирует ответ с балансом, масштабируя суммы по валюте кошелька
+func newBalanceResponse(wallet domain.Wallet) (balanceResponse, error) {
+	currency, err := domain.LookupCurrency(wallet.Currency)
+	if err != nil {
+		return balanceResponse{}, err
+	}
+	return balanceResponse{
+		WalletID:       wallet.ID,
+		Balance:        wallet.Available(),
+		Available:      wallet.Available(),
+		Ledger:         wallet.Balance,
+		Reserved:       wallet.Reserved,
+		Currency:       currency.Code,
+		Status:         wallet.Status,
+		Tier:           wallet.Tier,
+		BalanceDecimal: currency.FormatAmount(wallet.Available()),
+		LedgerDecimal:  currency.FormatAmount(wallet.Balance),
+
+		OverdraftLimit:         wallet.OverdraftLimit,
+		RemainingCredit:        wallet.RemainingCredit(),
+		RemainingCreditDecimal: currency.FormatAmount(wallet.RemainingCredit()),
+
+		OwnerID:     wallet.OwnerID,
+		DisplayName: wallet.DisplayName,
+		Metadata:    wallet.Metadata,
+	}, nil
 }
\ No newline at end of file
@@ -7,56 +7,627 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-	"errors"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
 	"WalletApp/internal/handler"
 )
 
 type mockWalletService struct {
-	balances map[uuid.UUID]int64 // балансы кошельков по id
+	balances   map[uuid.UUID]int64  // балансы кошельков по id
+	currencies map[uuid.UUID]string // валюты кошельков по id
+
+	lastFilter domain.TransactionFilter             // фильтр последнего запроса истории
+	rates      []domain.ExchangeRate                // последние загруженные курсы
+	holds      map[uuid.UUID]domain.Hold            // блокировки по id
+	statuses   map[uuid.UUID]string                 // статусы кошельков; отсутствие записи - ACTIVE
+	changes    []domain.WalletStatusChange          // история смены статусов
+	overdrafts map[uuid.UUID]int64                  // кредитные лимиты кошельков
+	limits     map[uuid.UUID][]domain.SpendingLimit // лимиты расходов кошельков
+	usage      map[uuid.UUID]domain.SpendingUsage   // списания кошельков в текущем окне
+	owners     map[uuid.UUID]domain.Owner           // владельцы по id
+	ownerOf    map[uuid.UUID]uuid.UUID              // владельцы кошельков
+	walletIDs  []uuid.UUID                          // кошельки в порядке создания
+	details    map[uuid.UUID]domain.Wallet          // имена и метаданные кошельков
+	refundable map[uuid.UUID]int64                  // невозвращённые суммы операций по id
+	lastAsOf   time.Time                            // момент последнего запроса баланса на дату
+	statement  []domain.Transaction                 // операции выписки; входящий остаток - текущий баланс
+	streamErr  error                                // ошибка, возникающая после первой строки выписки
+	fees       []domain.Fee                         // комиссии, возвращаемые каждой операцией
+	tiers      map[uuid.UUID]string                 // тарифы кошельков; отсутствие записи - STANDARD
+	dryRun     bool                                 // идёт DryRun: состояние восстановится в его конце
+	keys       map[string]bool                      // ключи выполненных однократных операций
 }
 
 // экземпляр
 func newMockWalletService() *mockWalletService {
 	return &mockWalletService{
-		balances: make(map[uuid.UUID]int64),
+		balances:   make(map[uuid.UUID]int64),
+		currencies: make(map[uuid.UUID]string),
+		holds:      make(map[uuid.UUID]domain.Hold),
+		statuses:   make(map[uuid.UUID]string),
+		overdrafts: make(map[uuid.UUID]int64),
+		limits:     make(map[uuid.UUID][]domain.SpendingLimit),
+		usage:      make(map[uuid.UUID]domain.SpendingUsage),
+		owners:     make(map[uuid.UUID]domain.Owner),
+		ownerOf:    make(map[uuid.UUID]uuid.UUID),
+		details:    make(map[uuid.UUID]domain.Wallet),
+		refundable: make(map[uuid.UUID]int64),
+	}
+}
+
+// Метод для выполнения операций (депозит/снятие/перевод) с кошельком
+func (m *mockWalletService) PerformOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	if err := m.performOperation(op); err != nil {
+		return domain.OperationResult{}, err
+	}
+	amount := op.Amount
+	if op.OperationType != "DEPOSIT" {
+		amount = -amount
+	}
+	fees := m.fees
+	if fees == nil {
+		fees = []domain.Fee{}
+	}
+	tx := domain.Transaction{ID: uuid.New(), WalletID: op.WalletID, Amount: amount, OperationType: op.OperationType}
+	return domain.OperationResult{Transactions: []domain.Transaction{tx}, Fees: fees}, nil
+}
+
+// Метод для однократного выполнения операции по ключу
+func (m *mockWalletService) PerformOperationOnce(ctx context.Context, key string, op domain.Operation) (domain.OperationResult, error) {
+	if m.keys[key] {
+		return domain.OperationResult{}, domain.ErrOperationApplied
+	}
+	result, err := m.PerformOperation(ctx, op)
+	if err != nil {
+		return domain.OperationResult{}, err
+	}
+	if m.keys == nil {
+		m.keys = make(map[string]bool)
+	}
+	m.keys[key] = true
+	return result, nil
+}
+
+// Метод для выполнения пакета операций: при ошибке балансы и расходы восстанавливаются
+func (m *mockWalletService) PerformBatch(ctx context.Context, ops []domain.Operation) ([]domain.OperationResult, error) {
+	if len(ops) == 0 {
+		return nil, domain.ErrInvalidBatch
+	}
+	balances := make(map[uuid.UUID]int64, len(m.balances))
+	for walletID, balance := range m.balances {
+		balances[walletID] = balance
+	}
+	usage := make(map[uuid.UUID]domain.SpendingUsage, len(m.usage))
+	for walletID, u := range m.usage {
+		usage[walletID] = u
+	}
+	results := make([]domain.OperationResult, len(ops))
+	for i, op := range ops {
+		result, err := m.PerformOperation(ctx, op)
+		if err != nil {
+			m.balances, m.usage = balances, usage
+			return nil, &domain.BatchError{Index: i, Err: err}
+		}
+		results[i] = result
 	}
+	return results, nil
 }
 
-// Метод для выполнения операций (депозит/снятие) с кошельком
-func (m *mockWalletService) PerformOperation(ctx context.Context, walletID uuid.UUID, operationType string, amount int64) error {
-	if operationType == "DEPOSIT" { // // Проверка на (депозит)
+// Метод для проверки нескольких операций: балансы и расходы восстанавливаются после fn
+func (m *mockWalletService) DryRun(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.dryRun {
+		return fn(ctx)
+	}
+	balances := make(map[uuid.UUID]int64, len(m.balances))
+	for walletID, balance := range m.balances {
+		balances[walletID] = balance
+	}
+	usage := make(map[uuid.UUID]domain.SpendingUsage, len(m.usage))
+	for walletID, u := range m.usage {
+		usage[walletID] = u
+	}
+	m.dryRun = true
+	defer func() { m.balances, m.usage, m.dryRun = balances, usage, false }()
+	return fn(ctx)
+}
+
+// Метод для проверки операции: балансы и расходы восстанавливаются после выполнения
+func (m *mockWalletService) DryRunOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	if m.dryRun {
+		return m.PerformOperation(ctx, op)
+	}
+	balances := make(map[uuid.UUID]int64, len(m.balances))
+	for walletID, balance := range m.balances {
+		balances[walletID] = balance
+	}
+	usage := make(map[uuid.UUID]domain.SpendingUsage, len(m.usage))
+	for walletID, u := range m.usage {
+		usage[walletID] = u
+	}
+	defer func() { m.balances, m.usage = balances, usage }()
+	return m.PerformOperation(ctx, op)
+}
+
+func (m *mockWalletService) performOperation(op domain.Operation) error {
+	walletID, amount := op.WalletID, op.Amount
+	if _, exists := m.balances[walletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	if op.Currency != "" && op.Currency != m.currencies[walletID] {
+		return domain.ErrCurrencyMismatch
+	}
+	if op.OperationType == "DEPOSIT" { // // Проверка на (депозит)
 		m.balances[walletID] += amount
 		return nil
 	}
-	if operationType == "WITHDRAW" { // Проверка на (снятие)
+	if op.OperationType == "WITHDRAW" { // Проверка на (снятие)
+		if m.balances[walletID]+m.overdrafts[walletID] < amount {
+			return domain.ErrInsufficientFunds
+		}
+		usage := m.usage[walletID]
+		for _, limit := range m.limits[walletID] {
+			if err := limit.Check(usage, amount); err != nil {
+				return err
+			}
+		}
+		m.balances[walletID] -= amount
+		m.usage[walletID] = domain.SpendingUsage{Amount: usage.Amount + amount, Count: usage.Count + 1}
+		return nil
+	}
+	if op.OperationType == "TRANSFER" { // Проверка на (перевод)
+		toWalletID := op.DestinationWalletID
+		if toWalletID == uuid.Nil || toWalletID == walletID {
+			return domain.ErrInvalidDestination
+		}
+		if _, exists := m.balances[toWalletID]; !exists {
+			return domain.ErrWalletNotFound
+		}
 		if m.balances[walletID] < amount {
-			return errors.New("insufficient funds")
+			return domain.ErrInsufficientFunds
 		}
 		m.balances[walletID] -= amount
+		m.balances[toWalletID] += amount
 		return nil
 	}
-	return errors.New("invalid operation type")
+	return domain.ErrInvalidOperation
 }
 
 // Метод для получения текущего баланса кошелька
 func (m *mockWalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
 	balance, exists := m.balances[walletID]
 	if !exists {
-		return 0, errors.New("wallet not found")
+		return 0, domain.ErrWalletNotFound
 	}
 	return balance, nil
 }
 
+// Метод для получения кошелька с балансом и валютой
+func (m *mockWalletService) GetWallet(ctx context.Context, walletID uuid.UUID) (domain.Wallet, error) {
+	balance, exists := m.balances[walletID]
+	if !exists {
+		return domain.Wallet{}, domain.ErrWalletNotFound
+	}
+	wallet := domain.Wallet{
+		ID:             walletID,
+		Balance:        balance,
+		Currency:       m.currencies[walletID],
+		Status:         domain.WalletActive,
+		OverdraftLimit: m.overdrafts[walletID],
+		Tier:           domain.DefaultWalletTier,
+	}
+	if tier, ok := m.tiers[walletID]; ok {
+		wallet.Tier = tier
+	}
+	if ownerID, ok := m.ownerOf[walletID]; ok {
+		wallet.OwnerID = &ownerID
+	}
+	wallet.DisplayName, wallet.Metadata = m.details[walletID].DisplayName, m.details[walletID].Metadata
+	if status, ok := m.statuses[walletID]; ok {
+		wallet.Status = status
+	}
+	for _, hold := range m.holds {
+		if hold.WalletID == walletID && hold.Status == domain.HoldActive {
+			wallet.Reserved += hold.Amount
+		}
+	}
+	return wallet, nil
+}
+
 // Метод для создания нового кошелька и возврата его id
-func (m *mockWalletService) CreateWallet(ctx context.Context) (uuid.UUID, error) {
+func (m *mockWalletService) CreateWallet(ctx context.Context, wallet domain.Wallet) (uuid.UUID, error) {
+	if wallet.Currency == "" {
+		wallet.Currency = "RUB"
+	}
+	c, err := domain.LookupCurrency(wallet.Currency)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if wallet.OwnerID != nil {
+		if _, exists := m.owners[*wallet.OwnerID]; !exists {
+			return uuid.Nil, domain.ErrOwnerNotFound
+		}
+	}
+	if err := wallet.ValidateDetails(); err != nil {
+		return uuid.Nil, err
+	}
 	newID := uuid.New()
+	m.details[newID] = domain.Wallet{DisplayName: wallet.DisplayName, Metadata: wallet.Metadata}
 	m.balances[newID] = 0 // Начальный баланс = 0
+	m.currencies[newID] = c.Code
+	if wallet.OwnerID != nil {
+		m.ownerOf[newID] = *wallet.OwnerID
+	}
+	m.walletIDs = append(m.walletIDs, newID)
 	return newID, nil
 }
 
+// Метод для получения баланса на момент времени; запоминает момент и возвращает текущий баланс
+func (m *mockWalletService) GetBalanceAt(ctx context.Context, walletID uuid.UUID, asOf time.Time) (domain.BalanceSnapshot, error) {
+	m.lastAsOf = asOf
+	wallet, err := m.GetWallet(ctx, walletID)
+	if err != nil {
+		return domain.BalanceSnapshot{}, err
+	}
+	return domain.BalanceSnapshot{WalletID: walletID, Currency: wallet.Currency, AsOf: asOf, Balance: wallet.Balance}, nil
+}
+
+// Метод для выписки: передаёт заранее заданные операции с нарастающим балансом
+func (m *mockWalletService) WriteStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, out domain.StatementWriter) error {
+	if !from.Before(to) {
+		return domain.ErrInvalidPeriod
+	}
+	wallet, err := m.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	header := domain.StatementHeader{WalletID: walletID, Currency: wallet.Currency, From: from, To: to, OpeningBalance: wallet.Balance}
+	if err := out.Begin(header); err != nil {
+		return err
+	}
+	summary := domain.StatementSummary{ClosingBalance: wallet.Balance}
+	for _, tx := range m.statement {
+		summary.ClosingBalance += tx.Amount
+		summary.Count++
+		if err := out.Line(domain.StatementLine{Transaction: tx, Balance: summary.ClosingBalance}); err != nil {
+			return err
+		}
+		if m.streamErr != nil {
+			return m.streamErr
+		}
+	}
+	return out.End(summary)
+}
+
+// Метод для изменения имени и метаданных кошелька
+func (m *mockWalletService) UpdateWallet(ctx context.Context, walletID uuid.UUID, update domain.WalletUpdate) (domain.Wallet, error) {
+	wallet, err := m.GetWallet(ctx, walletID)
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	wallet = update.Apply(wallet)
+	if err := wallet.ValidateDetails(); err != nil {
+		return domain.Wallet{}, err
+	}
+	m.details[walletID] = domain.Wallet{DisplayName: wallet.DisplayName, Metadata: wallet.Metadata}
+	return wallet, nil
+}
+
+// Метод для поиска кошельков по меткам в порядке создания
+func (m *mockWalletService) ListWallets(ctx context.Context, filter domain.WalletFilter) ([]domain.Wallet, error) {
+	var wallets []domain.Wallet
+	for _, walletID := range m.walletIDs {
+		wallet, _ := m.GetWallet(ctx, walletID)
+		matches := true
+		for key, value := range filter.Labels {
+			if stored, ok := wallet.Metadata[key]; !ok || stored != value {
+				matches = false
+			}
+		}
+		if matches {
+			wallets = append(wallets, wallet)
+		}
+	}
+	return wallets, nil
+}
+
+// Метод для регистрации владельца
+func (m *mockWalletService) CreateOwner(ctx context.Context, owner domain.Owner) (domain.Owner, error) {
+	if owner.Name == "" {
+		return domain.Owner{}, domain.ErrInvalidOwner
+	}
+	owner.ID = uuid.New()
+	m.owners[owner.ID] = owner
+	return owner, nil
+}
+
+// Метод для получения владельца
+func (m *mockWalletService) GetOwner(ctx context.Context, ownerID uuid.UUID) (domain.Owner, error) {
+	owner, exists := m.owners[ownerID]
+	if !exists {
+		return domain.Owner{}, domain.ErrOwnerNotFound
+	}
+	return owner, nil
+}
+
+// Метод для получения кошельков владельца в порядке создания
+func (m *mockWalletService) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]domain.Wallet, error) {
+	if _, err := m.GetOwner(ctx, ownerID); err != nil {
+		return nil, err
+	}
+	var wallets []domain.Wallet
+	for _, walletID := range m.walletIDs {
+		if m.ownerOf[walletID] == ownerID {
+			wallet, _ := m.GetWallet(ctx, walletID)
+			wallets = append(wallets, wallet)
+		}
+	}
+	return wallets, nil
+}
+
+// Метод для получения истории операций; запоминает фильтр для проверки
+func (m *mockWalletService) ListTransactions(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	m.lastFilter = filter
+	if filter.OperationType == "INVALID" {
+		return domain.TransactionPage{}, domain.ErrInvalidOperation
+	}
+	if _, exists := m.balances[filter.WalletID]; !exists {
+		return domain.TransactionPage{}, domain.ErrWalletNotFound
+	}
+	return domain.TransactionPage{Transactions: []domain.Transaction{}}, nil
+}
+
+// Метод для отмены операции: проверяет невозвращённый остаток так же, как сервис
+func (m *mockWalletService) ReverseTransaction(ctx context.Context, transactionID uuid.UUID, amount int64) (domain.Reversal, error) {
+	remaining, exists := m.refundable[transactionID]
+	if !exists {
+		return domain.Reversal{}, domain.ErrTransactionNotFound
+	}
+	if remaining == 0 {
+		return domain.Reversal{}, domain.ErrAlreadyReversed
+	}
+	if amount == 0 {
+		amount = remaining
+	}
+	if amount > remaining {
+		return domain.Reversal{}, domain.ErrRefundExceedsAmount
+	}
+	m.refundable[transactionID] = remaining - amount
+	return domain.Reversal{
+		TransactionID: transactionID,
+		Amount:        amount,
+		Remaining:     remaining - amount,
+		Transactions: []domain.Transaction{
+			{ID: uuid.New(), Amount: amount, OperationType: "REVERSAL", ReversedTransactionID: &transactionID},
+		},
+	}, nil
+}
+
+// Метод для получения сводки по главной книге
+func (m *mockWalletService) ReconcileBalances(ctx context.Context, repair bool) (domain.ReconciliationReport, error) {
+	return domain.ReconciliationReport{Checked: int64(len(m.balances)), Repair: repair, Wallets: []domain.BalanceCheck{}, Consistent: true}, nil
+}
+
+func (m *mockWalletService) AccrueInterest(ctx context.Context, date time.Time) (domain.InterestRun, error) {
+	return domain.InterestRun{Date: domain.AccrualDate(date), Payouts: []domain.InterestPayout{}}, nil
+}
+
+func (m *mockWalletService) RunInterestAccrual(ctx context.Context, now time.Time) ([]domain.InterestRun, error) {
+	return []domain.InterestRun{}, nil
+}
+
+func (m *mockWalletService) GetLedgerSummary(ctx context.Context) ([]domain.LedgerSummary, error) {
+	var total int64
+	for _, balance := range m.balances {
+		total += balance
+	}
+	summary := domain.LedgerSummary{Currency: "RUB", WalletLiabilities: total, WalletBalances: total, Funded: total}
+	summary.Reconcile()
+	return []domain.LedgerSummary{summary}, nil
+}
+
+// Метод для загрузки курсов; запоминает последние загруженные курсы
+func (m *mockWalletService) AddExchangeRates(ctx context.Context, rates []domain.ExchangeRate) ([]domain.ExchangeRate, error) {
+	for _, rate := range rates {
+		if _, err := domain.ParseRate(rate.Rate); err != nil {
+			return nil, err
+		}
+	}
+	m.rates = rates
+	return rates, nil
+}
+
+// Метод для получения действующих курсов
+func (m *mockWalletService) ListExchangeRates(ctx context.Context) ([]domain.ExchangeRate, error) {
+	return m.rates, nil
+}
+
+// Метод для получения котировки по фиксированному курсу 90
+func (m *mockWalletService) QuoteExchange(ctx context.Context, fromCurrency, toCurrency string, amount int64) (domain.FXQuote, error) {
+	if fromCurrency != "USD" || toCurrency != "RUB" {
+		return domain.FXQuote{}, domain.ErrRateNotFound
+	}
+	return domain.FXQuote{
+		ID:              uuid.New(),
+		FromCurrency:    fromCurrency,
+		ToCurrency:      toCurrency,
+		Rate:            "90",
+		Amount:          amount,
+		ConvertedAmount: amount * 90,
+		ExpiresAt:       time.Now().Add(30 * time.Second),
+	}, nil
+}
+
+// Метод для создания блокировки без проверки остатка
+func (m *mockWalletService) CreateHold(ctx context.Context, walletID uuid.UUID, amount int64, expiresAt time.Time) (domain.Hold, error) {
+	if _, exists := m.balances[walletID]; !exists {
+		return domain.Hold{}, domain.ErrWalletNotFound
+	}
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(time.Hour)
+	}
+	hold := domain.Hold{ID: uuid.New(), WalletID: walletID, Amount: amount, Status: domain.HoldActive, ExpiresAt: expiresAt}
+	m.holds[hold.ID] = hold
+	return hold, nil
+}
+
+// Метод для списания по блокировке
+func (m *mockWalletService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount int64) (domain.Hold, error) {
+	hold, err := m.GetHold(ctx, holdID)
+	if err != nil {
+		return domain.Hold{}, err
+	}
+	if hold.Status != domain.HoldActive {
+		return domain.Hold{}, domain.ErrHoldNotActive
+	}
+	if amount == 0 {
+		amount = hold.Amount
+	}
+	hold.Status, hold.CapturedAmount = domain.HoldCaptured, amount
+	m.balances[hold.WalletID] -= amount
+	m.holds[holdID] = hold
+	return hold, nil
+}
+
+// Метод для отмены блокировки
+func (m *mockWalletService) VoidHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	hold, err := m.GetHold(ctx, holdID)
+	if err != nil {
+		return domain.Hold{}, err
+	}
+	if hold.Status != domain.HoldActive {
+		return domain.Hold{}, domain.ErrHoldNotActive
+	}
+	hold.Status = domain.HoldVoided
+	m.holds[holdID] = hold
+	return hold, nil
+}
+
+// Метод для получения блокировки
+func (m *mockWalletService) GetHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	hold, exists := m.holds[holdID]
+	if !exists {
+		return domain.Hold{}, domain.ErrHoldNotFound
+	}
+	return hold, nil
+}
+
+// Метод для освобождения истёкших блокировок
+func (m *mockWalletService) ExpireHolds(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+// Метод для смены статуса кошелька
+func (m *mockWalletService) ChangeWalletStatus(ctx context.Context, walletID uuid.UUID, status, reason, actor string) (domain.WalletStatusChange, error) {
+	wallet, err := m.GetWallet(ctx, walletID)
+	if err != nil {
+		return domain.WalletStatusChange{}, err
+	}
+	if err := wallet.CheckTransition(status); err != nil {
+		return domain.WalletStatusChange{}, err
+	}
+	change := domain.WalletStatusChange{
+		ID:         uuid.New(),
+		WalletID:   walletID,
+		FromStatus: wallet.Status,
+		ToStatus:   status,
+		Reason:     reason,
+		Actor:      actor,
+		CreatedAt:  time.Now(),
+	}
+	m.statuses[walletID] = status
+	m.changes = append(m.changes, change)
+	return change, nil
+}
+
+// Метод для получения лимитов расходов кошелька
+func (m *mockWalletService) GetSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]domain.SpendingLimitStatus, error) {
+	if _, exists := m.balances[walletID]; !exists {
+		return nil, domain.ErrWalletNotFound
+	}
+	statuses := []domain.SpendingLimitStatus{}
+	for _, limit := range m.limits[walletID] {
+		statuses = append(statuses, domain.SpendingLimitStatus{SpendingLimit: limit, Override: true, Used: m.usage[walletID]})
+	}
+	return statuses, nil
+}
+
+// Метод для установки лимитов расходов кошелька
+func (m *mockWalletService) SetSpendingLimits(ctx context.Context, walletID uuid.UUID, limits []domain.SpendingLimit) ([]domain.SpendingLimitStatus, error) {
+	if _, exists := m.balances[walletID]; !exists {
+		return nil, domain.ErrWalletNotFound
+	}
+	for i := range limits {
+		if err := limits[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	m.limits[walletID] = limits
+	return m.GetSpendingLimits(ctx, walletID)
+}
+
+// Метод для сброса лимита расходов кошелька
+func (m *mockWalletService) ResetSpendingLimit(ctx context.Context, walletID uuid.UUID, period string) error {
+	var kept []domain.SpendingLimit
+	for _, limit := range m.limits[walletID] {
+		if limit.Period != period {
+			kept = append(kept, limit)
+		}
+	}
+	m.limits[walletID] = kept
+	return nil
+}
+
+// Метод для установки кредитного лимита
+func (m *mockWalletService) SetOverdraftLimit(ctx context.Context, walletID uuid.UUID, limit int64) (domain.Wallet, error) {
+	wallet, err := m.GetWallet(ctx, walletID)
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	if wallet.Available() < -limit {
+		return domain.Wallet{}, domain.ErrOverdraftInUse
+	}
+	m.overdrafts[walletID] = limit
+	wallet.OverdraftLimit = limit
+	return wallet, nil
+}
+
+func (m *mockWalletService) SetWalletTier(ctx context.Context, walletID uuid.UUID, tier string) (domain.Wallet, error) {
+	tier, err := domain.ValidateTier(tier)
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	if _, exists := m.balances[walletID]; !exists {
+		return domain.Wallet{}, domain.ErrWalletNotFound
+	}
+	if m.tiers == nil {
+		m.tiers = make(map[uuid.UUID]string)
+	}
+	m.tiers[walletID] = tier
+	return m.GetWallet(ctx, walletID)
+}
+
+func (m *mockWalletService) GetFeeSchedule(ctx context.Context) domain.FeeSchedule {
+	return domain.FeeSchedule{RevenueWallets: map[string]uuid.UUID{}, Rules: []domain.FeeRule{{Name: "withdraw", OperationType: "WITHDRAW", Flat: 100}}}
+}
+
+// Метод для получения истории смены статусов кошелька
+func (m *mockWalletService) ListWalletStatusChanges(ctx context.Context, walletID uuid.UUID) ([]domain.WalletStatusChange, error) {
+	if _, exists := m.balances[walletID]; !exists {
+		return nil, domain.ErrWalletNotFound
+	}
+	var result []domain.WalletStatusChange
+	for _, change := range m.changes {
+		if change.WalletID == walletID {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
 // Тестирование функции обработки операций с кошельком
 func TestHandleOperation(t *testing.T) {
 	logger := logrus.New()
@@ -64,7 +635,7 @@ func TestHandleOperation(t *testing.T) {
 	h := handler.NewWalletHandler(mockSvc, logger)
 
 	ctx := context.Background()
-	walletID, _ := mockSvc.CreateWallet(ctx) // Создаем новый кошелёк
+	walletID, _ := mockSvc.CreateWallet(ctx, domain.Wallet{}) // Создаем новый кошелёк
 
 	tests := []struct {
 		name           string
@@ -105,4 +676,377 @@ func TestHandleOperation(t *testing.T) {
 	        }
 	    })
     }
-}
\ No newline at end of file
+}
+
+// Тестирование разбора параметров запроса истории операций
+func TestHandleListTransactions(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/{walletId}/transactions", h.HandleListTransactions).Methods(http.MethodGet)
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	cursor := domain.TransactionCursor{CreatedAt: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), ID: uuid.New()}
+
+	tests := []struct {
+		name         string
+		walletID     string
+		query        string
+		expectedCode int
+		expectedType string
+	}{
+		{"No filters", walletID.String(), "", http.StatusOK, ""},
+		{"Unknown wallet", uuid.NewString(), "", http.StatusNotFound, handler.CodeWalletNotFound},
+		{"Invalid wallet ID", "not-a-uuid", "", http.StatusBadRequest, handler.CodeInvalidWalletID},
+		{"Nil wallet ID", uuid.Nil.String(), "", http.StatusBadRequest, handler.CodeInvalidWalletID},
+		{"All filters", walletID.String(), "?limit=10&operationType=DEPOSIT&from=2026-09-01T00:00:00Z&to=2026-10-01T00:00:00Z&cursor=" + cursor.Encode(), http.StatusOK, ""},
+		{"Invalid limit", walletID.String(), "?limit=abc", http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"Negative limit", walletID.String(), "?limit=-1", http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"Invalid cursor", walletID.String(), "?cursor=garbage", http.StatusBadRequest, handler.CodeInvalidCursor},
+		{"Invalid from", walletID.String(), "?from=yesterday", http.StatusBadRequest, handler.CodeInvalidRequest},
+		{"Invalid operation type", walletID.String(), "?operationType=INVALID", http.StatusBadRequest, handler.CodeInvalidOperation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+tt.walletID+"/transactions"+tt.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			var problem handler.Problem
+			json.NewDecoder(w.Body).Decode(&problem)
+			if w.Code != tt.expectedCode || problem.Code != tt.expectedType {
+				t.Errorf("expected %d %q, got %d %q", tt.expectedCode, tt.expectedType, w.Code, problem.Code)
+			}
+		})
+	}
+
+	// Проверяем, что последний успешный запрос с фильтрами разобран полностью
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"/transactions?limit=10&operationType=WITHDRAW&from=2026-09-01T00:00:00Z&cursor="+cursor.Encode(), nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	f := mockSvc.lastFilter
+	if f.WalletID != walletID || f.Limit != 10 || f.OperationType != "WITHDRAW" {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+	if f.From == nil || !f.From.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) || f.To != nil {
+		t.Errorf("unexpected time range: from=%v to=%v", f.From, f.To)
+	}
+	if f.After == nil || f.After.ID != cursor.ID {
+		t.Errorf("expected cursor to be decoded, got %+v", f.After)
+	}
+}
+
+// Тестирование перевода через обработчик операций
+func TestHandleOperation_Transfer(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	ctx := context.Background()
+	from, _ := mockSvc.CreateWallet(ctx, domain.Wallet{})
+	to, _ := mockSvc.CreateWallet(ctx, domain.Wallet{})
+	mockSvc.balances[from] = 500
+
+	tests := []struct {
+		name         string
+		destination  uuid.UUID
+		amount       int64
+		expectedCode int
+	}{
+		{"Valid transfer", to, 200, http.StatusOK},
+		{"Insufficient funds", to, 1000, http.StatusBadRequest},
+		{"Missing destination", uuid.Nil, 100, http.StatusBadRequest},
+		{"Unknown destination", uuid.New(), 100, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]interface{}{
+				"walletId":            from.String(),
+				"operationType":       "TRANSFER",
+				"amount":              tt.amount,
+				"destinationWalletId": tt.destination.String(),
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operation", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			h.HandleOperation(w, req)
+
+			if w.Code != tt.expectedCode {
+				t.Errorf("expected status %d, got %d", tt.expectedCode, w.Code)
+			}
+		})
+	}
+
+	if mockSvc.balances[from] != 300 || mockSvc.balances[to] != 200 {
+		t.Errorf("expected balances 300 and 200, got %d and %d", mockSvc.balances[from], mockSvc.balances[to])
+	}
+}
+// Тестирование формата ответа с ошибкой
+func TestErrorResponseFormat(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.Use(handler.RequestIDMiddleware)
+	r.HandleFunc("/api/v1/wallets/operation", h.HandleOperation).Methods(http.MethodPost)
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	body, _ := json.Marshal(map[string]interface{}{
+		"walletId":      walletID.String(),
+		"operationType": "WITHDRAW",
+		"amount":        100,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operation", bytes.NewBuffer(body))
+	req.Header.Set(handler.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("expected problem+json content type, got %q", ct)
+	}
+
+	var problem handler.Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("could not decode error body: %v", err)
+	}
+	if problem.Code != handler.CodeInsufficientFunds || problem.Status != http.StatusBadRequest || problem.Detail == "" {
+		t.Errorf("unexpected problem: %+v", problem)
+	}
+	if problem.RequestID != "req-42" || w.Header().Get(handler.RequestIDHeader) != "req-42" {
+		t.Errorf("expected request ID req-42, got %q", problem.RequestID)
+	}
+
+	// Некорректное тело запроса получает собственный код и сгенерированный идентификатор запроса
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operation", bytes.NewBufferString("{"))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || problem.Code != handler.CodeInvalidRequest || problem.RequestID == "" {
+		t.Errorf("unexpected response for malformed body: %d %+v", w.Code, problem)
+	}
+}
+
+
+// Тестирование ответов для несуществующего кошелька
+func TestUnknownWallet(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/operation", h.HandleOperation).Methods(http.MethodPost)
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods(http.MethodGet)
+
+	unknownID := uuid.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+unknownID.String(), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for balance of unknown wallet, got %d", w.Code)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"walletId":      unknownID.String(),
+		"operationType": "DEPOSIT",
+		"amount":        100,
+	})
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/wallets/operation", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for deposit to unknown wallet, got %d", w.Code)
+	}
+	if _, exists := mockSvc.balances[unknownID]; exists {
+		t.Error("deposit to unknown wallet must not create a balance")
+	}
+}
+
+// Тестирование сводки по главной книге
+func TestHandleLedgerSummary(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	mockSvc.balances[walletID] = 250
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/ledger/summary", nil)
+	w := httptest.NewRecorder()
+	h.HandleLedgerSummary(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var response struct {
+		Balanced   bool                   `json:"balanced"`
+		Currencies []domain.LedgerSummary `json:"currencies"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("could not decode summary: %v", err)
+	}
+	if !response.Balanced || len(response.Currencies) != 1 || response.Currencies[0].WalletLiabilities != 250 {
+		t.Errorf("unexpected summary: %+v", response)
+	}
+}
+
+// Тестирование создания кошелька в заданной валюте и ответа с балансом
+func TestHandleCreateWallet_Currency(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallet", h.HandleCreateWallet).Methods("POST")
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods("GET")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewBufferString(`{"currency":"kwd"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var created map[string]uuid.UUID
+	json.NewDecoder(w.Body).Decode(&created)
+	mockSvc.balances[created["walletId"]] = 12345
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+created["walletId"].String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var balance map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&balance)
+	if balance["currency"] != "KWD" || balance["balanceDecimal"] != "12.345" || balance["balance"] != float64(12345) {
+		t.Errorf("unexpected balance response: %v", balance)
+	}
+
+	// Без тела запроса кошелёк создаётся в валюте по умолчанию
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/wallet", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201 without body, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/wallet", bytes.NewBufferString(`{"currency":"XXX"}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || problem.Code != handler.CodeUnsupportedCurrency {
+		t.Errorf("expected 400 unsupported_currency, got %d %q", w.Code, problem.Code)
+	}
+}
+// Тестирование имени и метаданных кошелька: создание, PATCH и поиск по меткам
+func TestWalletMetadataHandlers(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallet", h.HandleCreateWallet).Methods("POST")
+	r.HandleFunc("/api/v1/wallets", h.HandleListWallets).Methods("GET")
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods("GET")
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleUpdateWallet).Methods("PATCH")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type walletResponse struct {
+		WalletID    uuid.UUID         `json:"walletId"`
+		DisplayName string            `json:"displayName"`
+		Metadata    map[string]string `json:"metadata"`
+	}
+
+	w := do(http.MethodPost, "/api/v1/wallet", `{"displayName":"Payroll","metadata":{"tier":"gold","crm":"acc-1"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+	var created map[string]uuid.UUID
+	json.NewDecoder(w.Body).Decode(&created)
+	gold := created["walletId"]
+	do(http.MethodPost, "/api/v1/wallet", `{"metadata":{"tier":"silver"}}`)
+
+	w = do(http.MethodPost, "/api/v1/wallet", `{"metadata":{"a:b":"c"}}`)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusBadRequest || problem.Code != handler.CodeInvalidMetadata {
+		t.Errorf("expected 400 invalid_metadata, got %d %q", w.Code, problem.Code)
+	}
+
+	var wallet walletResponse
+	json.NewDecoder(do(http.MethodGet, "/api/v1/wallets/"+gold.String(), "").Body).Decode(&wallet)
+	if wallet.DisplayName != "Payroll" || wallet.Metadata["crm"] != "acc-1" {
+		t.Errorf("unexpected wallet: %+v", wallet)
+	}
+
+	w = do(http.MethodPatch, "/api/v1/wallets/"+gold.String(), `{"displayName":"Payroll EUR","metadata":{"crm":null,"ref":"x:1"}}`)
+	wallet = walletResponse{}
+	json.NewDecoder(w.Body).Decode(&wallet)
+	if w.Code != http.StatusOK || wallet.DisplayName != "Payroll EUR" || wallet.Metadata["crm"] != "" ||
+		wallet.Metadata["tier"] != "gold" || wallet.Metadata["ref"] != "x:1" {
+		t.Errorf("unexpected PATCH response: %d %+v", w.Code, wallet)
+	}
+	if w := do(http.MethodPatch, "/api/v1/wallets/"+uuid.NewString(), `{"displayName":"x"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown wallet, got %d", w.Code)
+	}
+
+	var list struct {
+		Wallets []walletResponse `json:"wallets"`
+	}
+	w = do(http.MethodGet, "/api/v1/wallets?label=tier:gold&label=ref:x:1", "")
+	json.NewDecoder(w.Body).Decode(&list)
+	if w.Code != http.StatusOK || len(list.Wallets) != 1 || list.Wallets[0].WalletID != gold {
+		t.Errorf("unexpected wallets by label: %d %+v", w.Code, list)
+	}
+	list.Wallets = nil
+	json.NewDecoder(do(http.MethodGet, "/api/v1/wallets", "").Body).Decode(&list)
+	if len(list.Wallets) != 2 {
+		t.Errorf("expected all wallets without labels, got %d", len(list.Wallets))
+	}
+
+	for _, query := range []string{"label=tier", "label=tier:gold&label=tier:silver", "limit=0"} {
+		if w := do(http.MethodGet, "/api/v1/wallets?"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// Тестирование запроса баланса на момент времени
+func TestHandleGetBalance_AsOf(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{Currency: "USD"})
+	mockSvc.balances[walletID] = 12345
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods("GET")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String()+"?asOf=2026-09-30T23:59:59Z", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&response)
+	if w.Code != http.StatusOK || response["balance"] != float64(12345) || response["balanceDecimal"] != "123.45" ||
+		response["asOf"] != "2026-09-30T23:59:59Z" || response["currency"] != "USD" {
+		t.Errorf("unexpected response: %d %v", w.Code, response)
+	}
+	if expected := time.Date(2026, 9, 30, 23, 59, 59, 0, time.UTC); !mockSvc.lastAsOf.Equal(expected) {
+		t.Errorf("expected asOf %s to reach the service, got %s", expected, mockSvc.lastAsOf)
+	}
+
+	for query, status := range map[string]int{
+		"/api/v1/wallets/" + walletID.String() + "?asOf=yesterday":           http.StatusBadRequest,
+		"/api/v1/wallets/" + uuid.NewString() + "?asOf=2026-09-30T23:59:59Z": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, query, nil))
+		if w.Code != status {
+			t.Errorf("%s: expected status %d, got %d", query, status, w.Code)
+		}
+	}
+}
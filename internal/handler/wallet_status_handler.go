@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"WalletApp/internal/domain"
+)
+
+// Метод для обработки заморозки кошелька: {"reason", "actor"}
+func (h *WalletHandler) HandleFreezeWallet(w http.ResponseWriter, r *http.Request) {
+	h.changeWalletStatus(w, r, domain.WalletFrozen)
+}
+
+// Метод для обработки разморозки кошелька: {"reason", "actor"}
+func (h *WalletHandler) HandleUnfreezeWallet(w http.ResponseWriter, r *http.Request) {
+	h.changeWalletStatus(w, r, domain.WalletActive)
+}
+
+// Метод для обработки закрытия кошелька: {"reason", "actor"}; закрыть можно только пустой кошелёк
+func (h *WalletHandler) HandleCloseWallet(w http.ResponseWriter, r *http.Request) {
+	h.changeWalletStatus(w, r, domain.WalletClosed)
+}
+
+// Метод для обработки запроса истории смены статусов кошелька
+func (h *WalletHandler) HandleListWalletStatusChanges(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil || walletID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidWalletID, "Invalid wallet ID")
+		return
+	}
+
+	changes, err := h.Service.ListWalletStatusChanges(r.Context(), walletID)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Changes []domain.WalletStatusChange `json:"changes"`
+	}{changes})
+}
+
+// Метод переводит кошелёк в статус status; причина и автор изменения обязательны
+func (h *WalletHandler) changeWalletStatus(w http.ResponseWriter, r *http.Request, status string) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil || walletID == uuid.Nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidWalletID, "Invalid wallet ID")
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+		Actor  string `json:"actor"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+		return
+	}
+	request.Reason, request.Actor = strings.TrimSpace(request.Reason), strings.TrimSpace(request.Actor)
+	if request.Reason == "" || request.Actor == "" {
+		writeProblem(w, r, http.StatusBadRequest, CodeInvalidRequest, "reason and actor are required")
+		return
+	}
+
+	change, err := h.Service.ChangeWalletStatus(r.Context(), walletID, status, request.Reason, request.Actor)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
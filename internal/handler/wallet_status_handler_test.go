@@ -0,0 +1,96 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/handler"
+)
+
+// Тестирование заморозки, разморозки и закрытия кошелька через админские эндпоинты
+func TestWalletStatusHandlers(t *testing.T) {
+	mockSvc := newMockWalletService()
+	h := handler.NewWalletHandler(mockSvc, logrus.New())
+	h.AdminToken = "secret"
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/wallets/{walletId}", h.HandleGetBalance).Methods("GET")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/freeze", h.AdminOnly(h.HandleFreezeWallet)).Methods("POST")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/unfreeze", h.AdminOnly(h.HandleUnfreezeWallet)).Methods("POST")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/close", h.AdminOnly(h.HandleCloseWallet)).Methods("POST")
+	r.HandleFunc("/api/v1/admin/wallets/{walletId}/status-history", h.AdminOnly(h.HandleListWalletStatusChanges)).Methods("GET")
+
+	walletID, _ := mockSvc.CreateWallet(context.Background(), domain.Wallet{})
+	mockSvc.balances[walletID] = 500
+	admin := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/admin/wallets/"+walletID.String()+path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Без токена администратора
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/wallets/"+walletID.String()+"/freeze",
+		bytes.NewBufferString(`{"reason":"fraud","actor":"ops"}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status 401 without token, got %d", w.Code)
+	}
+
+	// Причина и автор обязательны
+	if w := admin(http.MethodPost, "/freeze", `{"reason":"fraud"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without actor, got %d", w.Code)
+	}
+
+	w = admin(http.MethodPost, "/freeze", `{"reason":"fraud","actor":"ops"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var change domain.WalletStatusChange
+	json.NewDecoder(w.Body).Decode(&change)
+	if change.FromStatus != domain.WalletActive || change.ToStatus != domain.WalletFrozen || change.Reason != "fraud" || change.Actor != "ops" {
+		t.Errorf("unexpected status change: %+v", change)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/wallets/"+walletID.String(), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var balance map[string]interface{}
+	json.NewDecoder(w.Body).Decode(&balance)
+	if balance["status"] != domain.WalletFrozen {
+		t.Errorf("expected status FROZEN in balance response, got %v", balance["status"])
+	}
+
+	// Повторная заморозка - недопустимый переход, закрытие непустого кошелька запрещено
+	if w := admin(http.MethodPost, "/freeze", `{"reason":"again","actor":"ops"}`); w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 for repeated freeze, got %d", w.Code)
+	}
+	w = admin(http.MethodPost, "/close", `{"reason":"closing","actor":"ops"}`)
+	var problem handler.Problem
+	json.NewDecoder(w.Body).Decode(&problem)
+	if w.Code != http.StatusConflict || problem.Code != handler.CodeWalletNotEmpty {
+		t.Errorf("expected 409 wallet_not_empty, got %d %q", w.Code, problem.Code)
+	}
+
+	if w := admin(http.MethodPost, "/unfreeze", `{"reason":"cleared","actor":"ops"}`); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for unfreeze, got %d", w.Code)
+	}
+
+	w = admin(http.MethodGet, "/status-history", "")
+	var history struct {
+		Changes []domain.WalletStatusChange `json:"changes"`
+	}
+	json.NewDecoder(w.Body).Decode(&history)
+	if w.Code != http.StatusOK || len(history.Changes) != 2 || history.Changes[1].ToStatus != domain.WalletActive {
+		t.Errorf("unexpected status history: %d %+v", w.Code, history)
+	}
+}
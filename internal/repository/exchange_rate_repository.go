@@ -0,0 +1,126 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "time"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+)
+
+// структура PostgresExchangeRateRepository для хранения курсов обмена и котировок в Postgres.
+// Вызовы с контекстом из WithinTransaction кошельков выполняются в той же транзакции
+type PostgresExchangeRateRepository struct {
+    db *sql.DB
+}
+
+// экземпляр
+func NewPostgresExchangeRateRepository(db *sql.DB) *PostgresExchangeRateRepository {
+    return &PostgresExchangeRateRepository{db: db}
+}
+
+// Метод для сохранения новых версий курсов; версии пары выдаются под блокировкой таблицы
+func (r *PostgresExchangeRateRepository) AddRates(ctx context.Context, rates []domain.ExchangeRate) error {
+    return withinTransaction(ctx, r.db, func(ctx context.Context) error {
+        conn := connFrom(ctx, r.db)
+        if _, err := conn.ExecContext(ctx, "LOCK TABLE exchange_rates IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+            return err
+        }
+        for i := range rates {
+            rate := &rates[i]
+            if rate.ID == uuid.Nil {
+                rate.ID = uuid.New()
+            }
+            err := conn.QueryRowContext(ctx,
+                `INSERT INTO exchange_rates (id, base_currency, quote_currency, rate, version, effective_at)
+                 SELECT $1::uuid, $2::char(3), $3::char(3), $4::numeric, COALESCE(MAX(version), 0) + 1, $5::timestamptz
+                 FROM exchange_rates WHERE base_currency = $2::char(3) AND quote_currency = $3::char(3)
+                 RETURNING version, created_at`,
+                rate.ID, rate.BaseCurrency, rate.QuoteCurrency, rate.Rate, rate.EffectiveAt,
+            ).Scan(&rate.Version, &rate.CreatedAt)
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// Метод для получения курса пары, действующего в момент at: самого позднего по effective_at, затем по версии
+func (r *PostgresExchangeRateRepository) GetRate(ctx context.Context, baseCurrency, quoteCurrency string, at time.Time) (domain.ExchangeRate, error) {
+    rate := domain.ExchangeRate{BaseCurrency: baseCurrency, QuoteCurrency: quoteCurrency}
+    err := connFrom(ctx, r.db).QueryRowContext(ctx,
+        `SELECT id, rate, version, effective_at, created_at FROM exchange_rates
+         WHERE base_currency = $1 AND quote_currency = $2 AND effective_at <= $3
+         ORDER BY effective_at DESC, version DESC LIMIT 1`,
+        baseCurrency, quoteCurrency, at,
+    ).Scan(&rate.ID, &rate.Rate, &rate.Version, &rate.EffectiveAt, &rate.CreatedAt)
+    if err == sql.ErrNoRows {
+        return domain.ExchangeRate{}, domain.ErrRateNotFound
+    }
+    return rate, err
+}
+
+// Метод для получения действующих в момент at курсов всех пар
+func (r *PostgresExchangeRateRepository) ListRates(ctx context.Context, at time.Time) ([]domain.ExchangeRate, error) {
+    rows, err := connFrom(ctx, r.db).QueryContext(ctx,
+        `SELECT DISTINCT ON (base_currency, quote_currency)
+             id, base_currency, quote_currency, rate, version, effective_at, created_at
+         FROM exchange_rates WHERE effective_at <= $1
+         ORDER BY base_currency, quote_currency, effective_at DESC, version DESC`, at)
+    if err != nil {
+        return nil, err
+    }
+    rates := []domain.ExchangeRate{}
+    err = scanRows(rows, func() error {
+        var rate domain.ExchangeRate
+        if err := rows.Scan(&rate.ID, &rate.BaseCurrency, &rate.QuoteCurrency, &rate.Rate,
+            &rate.Version, &rate.EffectiveAt, &rate.CreatedAt); err != nil {
+            return err
+        }
+        rates = append(rates, rate)
+        return nil
+    })
+    return rates, err
+}
+
+// Метод для сохранения котировки
+func (r *PostgresExchangeRateRepository) CreateQuote(ctx context.Context, quote *domain.FXQuote) error {
+    if quote.ID == uuid.Nil {
+        quote.ID = uuid.New()
+    }
+    return connFrom(ctx, r.db).QueryRowContext(ctx,
+        `INSERT INTO fx_quotes (id, rate_id, from_currency, to_currency, rate, amount, converted_amount, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at`,
+        quote.ID, quote.RateID, quote.FromCurrency, quote.ToCurrency, quote.Rate, quote.Amount,
+        quote.ConvertedAmount, quote.ExpiresAt,
+    ).Scan(&quote.CreatedAt)
+}
+
+// Метод для однократного использования котировки: отметка used_at ставится одним UPDATE,
+// поэтому два параллельных перевода не могут использовать одну котировку
+func (r *PostgresExchangeRateRepository) UseQuote(ctx context.Context, quoteID uuid.UUID, at time.Time) (domain.FXQuote, error) {
+    quote := domain.FXQuote{ID: quoteID}
+    err := connFrom(ctx, r.db).QueryRowContext(ctx,
+        `UPDATE fx_quotes SET used_at = $2 WHERE id = $1 AND used_at IS NULL AND expires_at > $2
+         RETURNING rate_id, from_currency, to_currency, rate, amount, converted_amount, expires_at, created_at`,
+        quoteID, at,
+    ).Scan(&quote.RateID, &quote.FromCurrency, &quote.ToCurrency, &quote.Rate, &quote.Amount,
+        &quote.ConvertedAmount, &quote.ExpiresAt, &quote.CreatedAt)
+    if err != sql.ErrNoRows {
+        return quote, err
+    }
+
+    var exists bool
+    err = connFrom(ctx, r.db).QueryRowContext(ctx,
+        "SELECT EXISTS (SELECT 1 FROM fx_quotes WHERE id = $1)", quoteID).Scan(&exists)
+    if err != nil {
+        return domain.FXQuote{}, err
+    }
+    if !exists {
+        return domain.FXQuote{}, domain.ErrQuoteNotFound
+    }
+    return domain.FXQuote{}, domain.ErrQuoteExpired
+}
@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "WalletApp/internal/domain"
+    "WalletApp/internal/repository"
+    "WalletApp/internal/usecase"
+)
+
+func TestPostgresExchangeRateRepository(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	rates := repository.NewPostgresExchangeRateRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	first := []domain.ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "KZT", Rate: "470.5", EffectiveAt: now.Add(-time.Minute)}}
+	if err := rates.AddRates(ctx, first); err != nil {
+	    t.Fatalf("could not add rates: %v", err)
+	}
+	second := []domain.ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "KZT", Rate: "471.25", EffectiveAt: now.Add(-time.Second)}}
+	if err := rates.AddRates(ctx, second); err != nil {
+	    t.Fatalf("could not add rates: %v", err)
+	}
+	if second[0].Version != first[0].Version+1 {
+	    t.Errorf("expected version %d, got %d", first[0].Version+1, second[0].Version)
+	}
+
+	// Действующий курс - последний по времени начала действия
+	rate, err := rates.GetRate(ctx, "USD", "KZT", now)
+	if err != nil || rate.ID != second[0].ID || rate.Rate != "471.25" {
+	    t.Errorf("expected current rate 471.25, got %+v; error:%v", rate, err)
+	}
+	rate, err = rates.GetRate(ctx, "USD", "KZT", now.Add(-30*time.Second))
+	if err != nil || rate.ID != first[0].ID {
+	    t.Errorf("expected earlier rate at earlier time, got %+v; error:%v", rate, err)
+	}
+	if _, err := rates.GetRate(ctx, "KZT", "JPY", now); !errors.Is(err, domain.ErrRateNotFound) {
+	    t.Errorf("expected ErrRateNotFound, got %v", err)
+	}
+
+	// Котировка используется один раз
+	quote := &domain.FXQuote{FromCurrency: "USD", ToCurrency: "KZT", Rate: rate.Rate, RateID: rate.ID,
+	    Amount: 100, ConvertedAmount: 47125, ExpiresAt: now.Add(time.Minute)}
+	if err := rates.CreateQuote(ctx, quote); err != nil {
+	    t.Fatalf("could not create quote: %v", err)
+	}
+	used, err := rates.UseQuote(ctx, quote.ID, now)
+	if err != nil || used.ConvertedAmount != 47125 || used.Rate != "471.25" {
+	    t.Errorf("unexpected used quote: %+v; error:%v", used, err)
+	}
+	if _, err := rates.UseQuote(ctx, quote.ID, now); !errors.Is(err, domain.ErrQuoteExpired) {
+	    t.Errorf("expected ErrQuoteExpired for reused quote, got %v", err)
+	}
+}
+
+// Перевод между валютами записывает курс и оставляет главную книгу сбалансированной в каждой валюте
+func TestCrossCurrencyTransferLedger(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	rates := repository.NewPostgresExchangeRateRepository(db)
+	svc := usecase.NewWalletService(repo, usecase.WithExchangeRates(rates))
+	ctx := context.Background()
+
+	usd, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	kzt, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "KZT"})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: usd, OperationType: usecase.DEPOSIT, Amount: 1000}); err != nil {
+	    t.Fatalf("could not deposit: %v", err)
+	}
+	if _, err := svc.AddExchangeRates(ctx, []domain.ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "KZT", Rate: "500"}}); err != nil {
+	    t.Fatalf("could not add rate: %v", err)
+	}
+
+	quote, err := svc.QuoteExchange(ctx, "USD", "KZT", 200)
+	if err != nil {
+	    t.Fatalf("could not get quote: %v", err)
+	}
+	op := domain.Operation{WalletID: usd, OperationType: usecase.TRANSFER, Amount: 200, DestinationWalletID: kzt, QuoteID: &quote.ID}
+	if _, err := svc.PerformOperation(ctx, op); err != nil {
+	    t.Fatalf("could not transfer: %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, kzt); balance != 100000 {
+	    t.Errorf("expected 100000 KZT, got %d", balance)
+	}
+
+	page, err := svc.ListTransactions(ctx, domain.TransactionFilter{WalletID: kzt})
+	if err != nil || len(page.Transactions) != 1 {
+	    t.Fatalf("expected one transaction; error:%v", err)
+	}
+	credit := page.Transactions[0]
+	if credit.ExchangeRate == nil || *credit.ExchangeRate != "500" || credit.ExchangeRateID == nil || *credit.ExchangeRateID != quote.RateID {
+	    t.Errorf("expected credit to record rate 500, got %v %v", credit.ExchangeRate, credit.ExchangeRateID)
+	}
+
+	summaries, err := svc.GetLedgerSummary(ctx)
+	if err != nil {
+	    t.Fatalf("could not get ledger summary: %v", err)
+	}
+	for _, currency := range []string{"USD", "KZT"} {
+	    if summary := summaryFor(summaries, currency); summary.UnbalancedEntries != 0 || summary.InTransit != 0 {
+	        t.Errorf("unexpected %s summary: %+v", currency, summary)
+	    }
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "time"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+)
+
+// структура PostgresHoldRepository для хранения блокировок средств в Postgres.
+// Вызовы с контекстом из WithinTransaction кошельков выполняются в той же транзакции
+type PostgresHoldRepository struct {
+    db *sql.DB
+}
+
+// экземпляр
+func NewPostgresHoldRepository(db *sql.DB) *PostgresHoldRepository {
+    return &PostgresHoldRepository{db: db}
+}
+
+const holdColumns = "id, wallet_id, amount, captured_amount, status, expires_at, created_at, updated_at"
+
+// Функция читает блокировку из строки результата с колонками holdColumns
+func scanHold(row interface{ Scan(dest ...interface{}) error }) (domain.Hold, error) {
+    var hold domain.Hold
+    err := row.Scan(&hold.ID, &hold.WalletID, &hold.Amount, &hold.CapturedAmount, &hold.Status,
+        &hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt)
+    return hold, err
+}
+
+// Метод для сохранения новой блокировки
+func (r *PostgresHoldRepository) CreateHold(ctx context.Context, hold *domain.Hold) error {
+    if hold.ID == uuid.Nil {
+        hold.ID = uuid.New()
+    }
+    return connFrom(ctx, r.db).QueryRowContext(ctx,
+        `INSERT INTO holds (id, wallet_id, amount, captured_amount, status, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at, updated_at`,
+        hold.ID, hold.WalletID, hold.Amount, hold.CapturedAmount, hold.Status, hold.ExpiresAt,
+    ).Scan(&hold.CreatedAt, &hold.UpdatedAt)
+}
+
+// Метод для получения блокировки по id
+func (r *PostgresHoldRepository) GetHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+    hold, err := scanHold(connFrom(ctx, r.db).QueryRowContext(ctx,
+        "SELECT "+holdColumns+" FROM holds WHERE id = $1", holdID))
+    if err == sql.ErrNoRows {
+        return domain.Hold{}, domain.ErrHoldNotFound
+    }
+    return hold, err
+}
+
+// Метод для получения блокировки с блокировкой строки (SELECT ... FOR UPDATE); должен вызываться внутри WithinTransaction
+func (r *PostgresHoldRepository) LockHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+    hold, err := scanHold(connFrom(ctx, r.db).QueryRowContext(ctx,
+        "SELECT "+holdColumns+" FROM holds WHERE id = $1 FOR UPDATE", holdID))
+    if err == sql.ErrNoRows {
+        return domain.Hold{}, domain.ErrHoldNotFound
+    }
+    return hold, err
+}
+
+// Метод для сохранения статуса и списанной суммы блокировки
+func (r *PostgresHoldRepository) UpdateHold(ctx context.Context, hold *domain.Hold) error {
+    err := connFrom(ctx, r.db).QueryRowContext(ctx,
+        `UPDATE holds SET status = $2, captured_amount = $3, updated_at = CURRENT_TIMESTAMP
+         WHERE id = $1 RETURNING updated_at`,
+        hold.ID, hold.Status, hold.CapturedAmount,
+    ).Scan(&hold.UpdatedAt)
+    if err == sql.ErrNoRows {
+        return domain.ErrHoldNotFound
+    }
+    return err
+}
+
+// Метод для выборки истёкших активных блокировок; SKIP LOCKED позволяет нескольким
+// экземплярам приложения очищать блокировки параллельно, не мешая друг другу
+func (r *PostgresHoldRepository) LockExpiredHolds(ctx context.Context, at time.Time, limit int) ([]domain.Hold, error) {
+    rows, err := connFrom(ctx, r.db).QueryContext(ctx,
+        "SELECT "+holdColumns+` FROM holds WHERE status = $1 AND expires_at <= $2
+         ORDER BY expires_at LIMIT $3 FOR UPDATE SKIP LOCKED`,
+        domain.HoldActive, at, limit)
+    if err != nil {
+        return nil, err
+    }
+    var holds []domain.Hold
+    err = scanRows(rows, func() error {
+        hold, err := scanHold(rows)
+        if err != nil {
+            return err
+        }
+        holds = append(holds, hold)
+        return nil
+    })
+    return holds, err
+}
+
+// Метод для расчёта суммы и числа активных блокировок кошелька, созданных в окне лимита расходов
+func (r *PostgresHoldRepository) GetActiveHoldUsage(ctx context.Context, walletID uuid.UUID, since time.Time) (domain.SpendingUsage, error) {
+    var usage domain.SpendingUsage
+    err := connFrom(ctx, r.db).QueryRowContext(ctx,
+        `SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM holds
+         WHERE wallet_id = $1 AND status = $2 AND created_at >= $3`,
+        walletID, domain.HoldActive, since,
+    ).Scan(&usage.Amount, &usage.Count)
+    return usage, err
+}
@@ -0,0 +1,84 @@
+package repository_test
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "WalletApp/internal/domain"
+    "WalletApp/internal/repository"
+    "WalletApp/internal/usecase"
+)
+
+func TestPostgresHoldRepository(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	holds := repository.NewPostgresHoldRepository(db)
+	svc := usecase.NewWalletService(repo, usecase.WithHolds(holds))
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000}); err != nil {
+	    t.Fatalf("could not deposit: %v", err)
+	}
+
+	hold, err := svc.CreateHold(ctx, walletID, 600, time.Time{})
+	if err != nil {
+	    t.Fatalf("could not create hold: %v", err)
+	}
+	if wallet, _ := repo.GetWallet(ctx, walletID); wallet.Balance != 1000 || wallet.Reserved != 600 {
+	    t.Errorf("expected balance 1000 with 600 reserved, got %+v", wallet)
+	}
+
+	// Списание не затрагивает зарезервированные средства
+	err = repo.CreateTransaction(ctx, &domain.Transaction{WalletID: walletID, Amount: -500, OperationType: usecase.WITHDRAW})
+	if !errors.Is(err, domain.ErrInsufficientFunds) {
+	    t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	if _, err := svc.CaptureHold(ctx, hold.ID, 200); err != nil {
+	    t.Fatalf("could not capture hold: %v", err)
+	}
+	stored, err := holds.GetHold(ctx, hold.ID)
+	if err != nil || stored.Status != domain.HoldCaptured || stored.CapturedAmount != 200 {
+	    t.Errorf("unexpected stored hold: %+v; error:%v", stored, err)
+	}
+	if wallet, _ := repo.GetWallet(ctx, walletID); wallet.Balance != 800 || wallet.Reserved != 0 {
+	    t.Errorf("expected balance 800 without reserve, got %+v", wallet)
+	}
+
+	// Истёкшая блокировка освобождается очисткой
+	expiring, err := svc.CreateHold(ctx, walletID, 300, time.Now().Add(time.Second))
+	if err != nil {
+	    t.Fatalf("could not create hold: %v", err)
+	}
+	if _, err := db.Exec("UPDATE holds SET expires_at = now() - interval '1 minute' WHERE id = $1", expiring.ID); err != nil {
+	    t.Fatalf("could not expire hold: %v", err)
+	}
+	if _, err := svc.ExpireHolds(ctx); err != nil {
+	    t.Fatalf("could not expire holds: %v", err)
+	}
+	if stored, _ := holds.GetHold(ctx, expiring.ID); stored.Status != domain.HoldExpired {
+	    t.Errorf("expected hold to be expired, got %s", stored.Status)
+	}
+	if wallet, _ := repo.GetWallet(ctx, walletID); wallet.Reserved != 0 {
+	    t.Errorf("expected reserve to be released, got %d", wallet.Reserved)
+	}
+
+	// В использование лимитов входят только активные блокировки, созданные с начала окна
+	if _, err := svc.CreateHold(ctx, walletID, 50, time.Time{}); err != nil {
+	    t.Fatalf("could not create hold: %v", err)
+	}
+	if usage, err := holds.GetActiveHoldUsage(ctx, walletID, time.Now().Add(-time.Hour)); err != nil || usage.Amount != 50 || usage.Count != 1 {
+	    t.Errorf("expected one active hold of 50, got %+v; error:%v", usage, err)
+	}
+	if usage, err := holds.GetActiveHoldUsage(ctx, walletID, time.Now().Add(time.Hour)); err != nil || usage.Count != 0 {
+	    t.Errorf("expected no holds after the window start, got %+v; error:%v", usage, err)
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "time"
+
+    "WalletApp/internal/domain"
+)
+
+// структура PostgresIdempotencyRepository для хранения ключей идемпотентности в Postgres
+type PostgresIdempotencyRepository struct {
+    db  *sql.DB
+    ttl time.Duration // после истечения срока ключ можно использовать повторно
+}
+
+// экземпляр
+func NewPostgresIdempotencyRepository(db *sql.DB, ttl time.Duration) *PostgresIdempotencyRepository {
+    return &PostgresIdempotencyRepository{db: db, ttl: ttl}
+}
+
+// Метод для выполнения fn в транзакции; хранилища кошельков с тем же db используют её из контекста
+func (r *PostgresIdempotencyRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+    return withinTransaction(ctx, r.db, fn)
+}
+
+// Метод для резервирования ключа. Ключ выполняющегося запроса защищён рекомендательной блокировкой
+// транзакции: конкурентный запрос с тем же ключом не ждёт, а получает незавершённую запись.
+// Блокировка и незафиксированная запись исчезают вместе с транзакцией, в том числе при сбое процесса
+func (r *PostgresIdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string) (domain.IdempotencyRecord, bool, error) {
+    conn := r.conn(ctx)
+    var locked bool
+    if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_xact_lock(hashtextextended($1, 0))", key).Scan(&locked); err != nil {
+        return domain.IdempotencyRecord{}, false, err
+    }
+    if !locked {
+        return domain.IdempotencyRecord{Key: key}, false, nil
+    }
+
+    // Просроченный ключ удаляем, чтобы его можно было занять заново; срок считается по часам БД.
+    // Незавершённые записи могли остаться только от прежних версий, где ключ фиксировался отдельно
+    _, err := conn.ExecContext(ctx,
+        "DELETE FROM idempotency_keys WHERE key = $1 AND (created_at < now() - make_interval(secs => $2) OR NOT completed)",
+        key, r.ttl.Seconds())
+    if err != nil {
+        return domain.IdempotencyRecord{}, false, err
+    }
+
+    res, err := conn.ExecContext(ctx,
+        "INSERT INTO idempotency_keys (key, fingerprint) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING",
+        key, fingerprint)
+    if err != nil {
+        return domain.IdempotencyRecord{}, false, err
+    }
+    inserted, err := res.RowsAffected()
+    if err != nil {
+        return domain.IdempotencyRecord{}, false, err
+    }
+    if inserted == 1 {
+        return domain.IdempotencyRecord{Key: key, Fingerprint: fingerprint}, true, nil
+    }
+
+    var (
+        record      = domain.IdempotencyRecord{Key: key}
+        statusCode  sql.NullInt64
+        contentType sql.NullString
+    )
+    err = conn.QueryRowContext(ctx,
+        "SELECT fingerprint, completed, status_code, content_type, body, created_at FROM idempotency_keys WHERE key = $1", key,
+    ).Scan(&record.Fingerprint, &record.Completed, &statusCode, &contentType, &record.Body, &record.CreatedAt)
+    if err != nil {
+        return domain.IdempotencyRecord{}, false, err
+    }
+    record.StatusCode = int(statusCode.Int64)
+    record.ContentType = contentType.String
+    return record, false, nil
+}
+
+// Метод для сохранения ответа на запрос: запись, занятая в той же транзакции, завершается,
+// а свободный ключ занимается сразу с ответом
+func (r *PostgresIdempotencyRepository) Complete(ctx context.Context, key, fingerprint string, statusCode int, contentType string, body []byte) error {
+    _, err := r.conn(ctx).ExecContext(ctx,
+        `INSERT INTO idempotency_keys (key, fingerprint, completed, status_code, content_type, body)
+         VALUES ($1, $2, TRUE, $3, $4, $5)
+         ON CONFLICT (key) DO UPDATE SET completed = TRUE, status_code = $3, content_type = $4, body = $5
+         WHERE NOT idempotency_keys.completed AND idempotency_keys.fingerprint = $2`,
+        key, fingerprint, statusCode, contentType, body)
+    return err
+}
+
+// Метод возвращает транзакцию из контекста, если она есть, иначе пул соединений
+func (r *PostgresIdempotencyRepository) conn(ctx context.Context) executor {
+    return connFrom(ctx, r.db)
+}
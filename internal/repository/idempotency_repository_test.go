@@ -0,0 +1,77 @@
+package repository_test
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/repository"
+)
+
+func TestPostgresIdempotencyRepository(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresIdempotencyRepository(db, time.Hour)
+	ctx := context.Background()
+	key := uuid.NewString()
+
+	err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+	    if _, reserved, err := repo.Reserve(ctx, key, "fp"); err != nil || !reserved {
+	        t.Fatalf("expected key to be reserved; reserved:%v error:%v", reserved, err)
+	    }
+	    // Запрос вне транзакции видит ключ занятым, но ещё не завершённым
+	    record, reserved, err := repo.Reserve(context.Background(), key, "fp")
+	    if err != nil || reserved || record.Completed {
+	        t.Fatalf("expected in-progress record; reserved:%v completed:%v error:%v", reserved, record.Completed, err)
+	    }
+	    return repo.Complete(ctx, key, "fp", 200, "application/json", []byte(`{"status":"success"}`))
+	})
+	if err != nil {
+	    t.Fatalf("could not complete key: %v", err)
+	}
+	record, reserved, err := repo.Reserve(ctx, key, "fp")
+	if err != nil || reserved || !record.Completed || record.StatusCode != 200 || string(record.Body) != `{"status":"success"}` {
+	    t.Fatalf("unexpected stored record: %+v; error:%v", record, err)
+	}
+	// Сохранённый ответ не перезаписывается
+	if err := repo.Complete(ctx, key, "fp", 400, "application/json", nil); err != nil {
+	    t.Fatalf("could not complete key: %v", err)
+	}
+	if record, _, _ := repo.Reserve(ctx, key, "fp"); record.StatusCode != 200 {
+	    t.Errorf("expected stored response to be kept, got status %d", record.StatusCode)
+	}
+
+	// Откат транзакции освобождает ключ; ответ можно сохранить и без транзакции
+	other := uuid.NewString()
+	rollback := errors.New("rollback")
+	err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+	    if _, reserved, err := repo.Reserve(ctx, other, "fp"); err != nil || !reserved {
+	        t.Fatalf("expected key to be reserved; reserved:%v error:%v", reserved, err)
+	    }
+	    return rollback
+	})
+	if !errors.Is(err, rollback) {
+	    t.Fatalf("expected rollback error, got %v", err)
+	}
+	if err := repo.Complete(ctx, other, "fp", 400, "application/problem+json", nil); err != nil {
+	    t.Fatalf("could not complete released key: %v", err)
+	}
+	if record, reserved, err := repo.Reserve(ctx, other, "fp"); err != nil || reserved || !record.Completed || record.StatusCode != 400 {
+	    t.Fatalf("unexpected stored record: %+v; reserved:%v error:%v", record, reserved, err)
+	}
+
+	// Срок хранения отсчитывается по часам БД
+	if _, err := db.Exec("UPDATE idempotency_keys SET created_at = now() - interval '2 hours' WHERE key = $1", key); err != nil {
+	    t.Fatalf("could not age key: %v", err)
+	}
+	if _, reserved, err := repo.Reserve(ctx, key, "other"); err != nil || !reserved {
+	    t.Fatalf("expected expired key to be reserved again; reserved:%v error:%v", reserved, err)
+	}
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "time"
+
+    "github.com/google/uuid"
+    "github.com/lib/pq"
+
+    "WalletApp/internal/domain"
+)
+
+// дни начисления передаются и читаются строками, чтобы часовой пояс сессии не сдвигал дату
+const dateLayout = "2006-01-02"
+
+// структура PostgresInterestRepository для хранения начислений процентов в Postgres.
+// Вызовы с контекстом из WithinTransaction кошельков выполняются в той же транзакции
+type PostgresInterestRepository struct {
+    db *sql.DB
+}
+
+// экземпляр
+func NewPostgresInterestRepository(db *sql.DB) *PostgresInterestRepository {
+    return &PostgresInterestRepository{db: db}
+}
+
+// Метод для чтения остатков всех кошельков на момент end по главной книге; строки передаются в fn по мере чтения
+func (r *PostgresInterestRepository) StreamEndOfDayBalances(ctx context.Context, end time.Time, fn func(domain.EndOfDayBalance) error) error {
+    rows, err := connFrom(ctx, r.db).QueryContext(ctx,
+        `SELECT w.id, w.currency, w.tier, w.status,
+             COALESCE((SELECT SUM(p.amount) FROM postings p JOIN journal_entries e ON e.id = p.entry_id
+                       WHERE p.account_id = a.id AND e.created_at < $1), 0)
+         FROM wallets w JOIN ledger_accounts a ON a.wallet_id = w.id
+         ORDER BY w.id`, end)
+    if err != nil {
+        return err
+    }
+    return scanRows(rows, func() error {
+        var balance domain.EndOfDayBalance
+        if err := rows.Scan(&balance.WalletID, &balance.Currency, &balance.Tier, &balance.Status, &balance.Balance); err != nil {
+            return err
+        }
+        return fn(balance)
+    })
+}
+
+// Метод для сохранения начислений одним запросом; начисление за уже обработанный день пропускается
+func (r *PostgresInterestRepository) CreateInterestAccruals(ctx context.Context, accruals []domain.InterestAccrual) (int64, error) {
+    if len(accruals) == 0 {
+        return 0, nil
+    }
+    walletIDs := make([]string, len(accruals))
+    dates := make([]string, len(accruals))
+    balances := make([]int64, len(accruals))
+    rates := make([]string, len(accruals))
+    amounts := make([]string, len(accruals))
+    for i, accrual := range accruals {
+        walletIDs[i] = accrual.WalletID.String()
+        dates[i] = accrual.Date.Format(dateLayout)
+        balances[i], rates[i], amounts[i] = accrual.Balance, accrual.AnnualRate, accrual.Amount
+    }
+
+    res, err := connFrom(ctx, r.db).ExecContext(ctx,
+        `INSERT INTO interest_accruals (wallet_id, accrual_date, balance, annual_rate, amount)
+         SELECT * FROM unnest($1::uuid[], $2::date[], $3::bigint[], $4::numeric[], $5::numeric[])
+         ON CONFLICT (wallet_id, accrual_date) DO NOTHING`,
+        pq.Array(walletIDs), pq.Array(dates), pq.Array(balances), pq.Array(rates), pq.Array(amounts))
+    if err != nil {
+        return 0, mapError(err)
+    }
+    return res.RowsAffected()
+}
+
+// Метод для получения кошельков с невыплаченными начислениями
+func (r *PostgresInterestRepository) ListInterestPayees(ctx context.Context, through time.Time) ([]uuid.UUID, error) {
+    rows, err := connFrom(ctx, r.db).QueryContext(ctx,
+        `SELECT DISTINCT wallet_id FROM interest_accruals
+         WHERE payout_transaction_id IS NULL AND accrual_date <= $1::date
+         ORDER BY wallet_id`, through.Format(dateLayout))
+    if err != nil {
+        return nil, err
+    }
+    walletIDs := []uuid.UUID{}
+    err = scanRows(rows, func() error {
+        var walletID uuid.UUID
+        if err := rows.Scan(&walletID); err != nil {
+            return err
+        }
+        walletIDs = append(walletIDs, walletID)
+        return nil
+    })
+    return walletIDs, err
+}
+
+// Метод для получения суммы начислений и выплат процентов кошелька
+func (r *PostgresInterestRepository) GetAccruedInterest(ctx context.Context, walletID uuid.UUID, through time.Time) (domain.AccruedInterest, error) {
+    interest := domain.AccruedInterest{WalletID: walletID}
+    err := connFrom(ctx, r.db).QueryRowContext(ctx,
+        `SELECT
+             (SELECT COALESCE(SUM(amount), 0)::text FROM interest_accruals WHERE wallet_id = $1 AND accrual_date <= $2::date),
+             (SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE wallet_id = $1 AND operation_type = 'INTEREST')`,
+        walletID, through.Format(dateLayout),
+    ).Scan(&interest.Accrued, &interest.Paid)
+    return interest, err
+}
+
+// Метод для связывания невыплаченных начислений кошелька с записью выплаты
+func (r *PostgresInterestRepository) MarkInterestPaid(ctx context.Context, walletID uuid.UUID, through time.Time, transactionID uuid.UUID) error {
+    _, err := connFrom(ctx, r.db).ExecContext(ctx,
+        `UPDATE interest_accruals SET payout_transaction_id = $3
+         WHERE wallet_id = $1 AND accrual_date <= $2::date AND payout_transaction_id IS NULL`,
+        walletID, through.Format(dateLayout), transactionID)
+    return err
+}
+
+// Метод для получения последнего завершённого дня начисления
+func (r *PostgresInterestRepository) GetLastInterestRun(ctx context.Context) (time.Time, error) {
+    var date sql.NullString
+    err := connFrom(ctx, r.db).QueryRowContext(ctx,
+        "SELECT to_char(MAX(accrual_date), 'YYYY-MM-DD') FROM interest_runs").Scan(&date)
+    if err != nil || !date.Valid {
+        return time.Time{}, err
+    }
+    return time.Parse(dateLayout, date.String)
+}
+
+// Метод для сохранения итога начисления за день
+func (r *PostgresInterestRepository) SaveInterestRun(ctx context.Context, run domain.InterestRun) error {
+    _, err := connFrom(ctx, r.db).ExecContext(ctx,
+        `INSERT INTO interest_runs (accrual_date, accrued, paid_out, completed_at) VALUES ($1::date, $2, $3, $4)
+         ON CONFLICT (accrual_date) DO UPDATE
+         SET accrued = interest_runs.accrued + EXCLUDED.accrued, paid_out = interest_runs.paid_out + EXCLUDED.paid_out,
+             completed_at = EXCLUDED.completed_at`,
+        run.Date.Format(dateLayout), run.Accrued, len(run.Payouts), run.CompletedAt)
+    return err
+}
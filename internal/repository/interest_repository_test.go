@@ -0,0 +1,127 @@
+package repository_test
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "WalletApp/internal/domain"
+    "WalletApp/internal/repository"
+    "WalletApp/internal/usecase"
+)
+
+func TestPostgresInterestRepository(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	interest := repository.NewPostgresInterestRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000}); err != nil {
+	    t.Fatalf("could not deposit: %v", err)
+	}
+
+	// Остаток на конец дня по главной книге: до пополнения 0, после - 1000
+	balances := make(map[time.Time]int64)
+	for _, end := range []time.Time{time.Now().Add(-time.Hour), time.Now().Add(time.Hour)} {
+	    err := interest.StreamEndOfDayBalances(ctx, end, func(balance domain.EndOfDayBalance) error {
+	        if balance.WalletID == walletID {
+	            if balance.Currency != "RUB" || balance.Tier != domain.DefaultWalletTier || balance.Status != domain.WalletActive {
+	                t.Errorf("unexpected wallet in end of day balance: %+v", balance)
+	            }
+	            balances[end] = balance.Balance
+	        }
+	        return nil
+	    })
+	    if err != nil {
+	        t.Fatalf("could not stream balances: %v", err)
+	    }
+	}
+	if len(balances) != 2 {
+	    t.Fatalf("expected wallet in both streams, got %v", balances)
+	}
+	for end, balance := range balances {
+	    if (end.Before(time.Now()) && balance != 0) || (end.After(time.Now()) && balance != 1000) {
+	        t.Errorf("unexpected balance %d at %s", balance, end)
+	    }
+	}
+
+	day1, day2 := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC)
+	accruals := []domain.InterestAccrual{
+	    {WalletID: walletID, Date: day1, Balance: 1000, AnnualRate: "54.75", Amount: "1.500000000000"},
+	    {WalletID: walletID, Date: day2, Balance: 1000, AnnualRate: "27.375", Amount: "0.750000000001"},
+	}
+	if created, err := interest.CreateInterestAccruals(ctx, accruals); err != nil || created != 2 {
+	    t.Fatalf("expected 2 accruals, got %d; error:%v", created, err)
+	}
+	// Повторное сохранение за те же дни пропускается
+	if created, err := interest.CreateInterestAccruals(ctx, accruals); err != nil || created != 0 {
+	    t.Errorf("expected no new accruals, got %d; error:%v", created, err)
+	}
+
+	payees, err := interest.ListInterestPayees(ctx, day1)
+	if err != nil {
+	    t.Fatalf("could not list payees: %v", err)
+	}
+	var found bool
+	for _, id := range payees {
+	    found = found || id == walletID
+	}
+	if !found {
+	    t.Errorf("expected wallet among payees, got %v", payees)
+	}
+	accrued, err := interest.GetAccruedInterest(ctx, walletID, day2)
+	if err != nil || accrued.Accrued != "2.250000000001" || accrued.Paid != 0 {
+	    t.Errorf("unexpected accrued interest: %+v; error:%v", accrued, err)
+	}
+
+	summaries, _ := repo.GetLedgerSummary(ctx)
+	before := summaryFor(summaries, "RUB")
+
+	// Выплата: зачисление, запись INTEREST и отметка начислений в одной транзакции
+	err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+	    tx := &domain.Transaction{WalletID: walletID, Amount: 2, OperationType: usecase.INTEREST}
+	    if err := repo.CreateTransaction(ctx, tx); err != nil {
+	        return err
+	    }
+	    return interest.MarkInterestPaid(ctx, walletID, day2, tx.ID)
+	})
+	if err != nil {
+	    t.Fatalf("could not pay interest: %v", err)
+	}
+	if accrued, _ := interest.GetAccruedInterest(ctx, walletID, day2); accrued.Paid != 2 {
+	    t.Errorf("expected paid interest 2, got %+v", accrued)
+	}
+	payees, _ = interest.ListInterestPayees(ctx, day2)
+	for _, id := range payees {
+	    if id == walletID {
+	        t.Error("expected paid wallet to be left out of payees")
+	    }
+	}
+
+	summaries, _ = repo.GetLedgerSummary(ctx)
+	after := summaryFor(summaries, "RUB")
+	if after.InterestPaid-before.InterestPaid != 2 || after.WalletLiabilities-before.WalletLiabilities != 2 || after.UnbalancedEntries != 0 {
+	    t.Errorf("unexpected ledger change: %+v -> %+v", before, after)
+	}
+
+	// Итоги повторного запуска за день прибавляются
+	for i := 0; i < 2; i++ {
+	    if err := interest.SaveInterestRun(ctx, domain.InterestRun{Date: day2, Accrued: 1, CompletedAt: time.Now()}); err != nil {
+	        t.Fatalf("could not save run: %v", err)
+	    }
+	}
+	var total int64
+	if err := db.QueryRow("SELECT accrued FROM interest_runs WHERE accrual_date = '2000-01-02'").Scan(&total); err != nil || total < 2 {
+	    t.Errorf("expected accumulated run total, got %d; error:%v", total, err)
+	}
+	if last, err := interest.GetLastInterestRun(ctx); err != nil || last.Before(day2) {
+	    t.Errorf("expected last run not before %s, got %s; error:%v", day2, last, err)
+	}
+}
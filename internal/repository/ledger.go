@@ -0,0 +1,214 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "sort"
+    "time"
+
+    "github.com/google/uuid"
+    "github.com/lib/pq"
+
+    "WalletApp/internal/domain"
+)
+
+// системный счёт, с которым корреспондирует счёт кошелька, для каждого типа операции
+var contraAccounts = map[string]string{
+    "DEPOSIT":  domain.AccountFunding,
+    "WITHDRAW": domain.AccountPayout,
+    "TRANSFER": domain.AccountTransferClearing,
+    "CAPTURE":  domain.AccountPayout,
+    "FEE":      domain.AccountTransferClearing, // комиссия - перевод на кошелёк доходов в той же валюте
+    "INTEREST": domain.AccountInterest,
+}
+
+// Метод для записи проводки по операции: счёт кошелька изменяется на tx.Amount,
+// системный счёт - на противоположную сумму. Вызывается внутри транзакции БД
+func (r *PostgresWalletRepository) postJournalEntry(ctx context.Context, tx *domain.Transaction) error {
+    operationType := tx.OperationType
+    // Компенсирующая запись проводится по тем же счетам, что и отменяемая операция
+    if operationType == "REVERSAL" && tx.ReversedTransactionID != nil {
+        err := r.conn(ctx).QueryRowContext(ctx,
+            "SELECT operation_type FROM transactions WHERE id = $1", *tx.ReversedTransactionID).Scan(&operationType)
+        if err != nil {
+            return err
+        }
+    }
+    contra, ok := contraAccounts[operationType]
+    if !ok {
+        return fmt.Errorf("no ledger account for operation type %s", tx.OperationType)
+    }
+    // Перевод между валютами не проходит через транзитный счёт: каждая сторона
+    // корреспондирует с валютной позицией своей валюты
+    if tx.ExchangeRate != nil {
+        contra = domain.AccountFXPosition
+    }
+
+    var entryID string
+    err := r.conn(ctx).QueryRowContext(ctx,
+        "INSERT INTO journal_entries (transaction_id, description, created_at) VALUES ($1, $2, $3) RETURNING id",
+        tx.ID, tx.OperationType, tx.CreatedAt,
+    ).Scan(&entryID)
+    if err != nil {
+        return err
+    }
+
+    // Системный счёт берётся в валюте кошелька
+    res, err := r.conn(ctx).ExecContext(ctx,
+        `INSERT INTO postings (entry_id, account_id, amount)
+         SELECT $1::uuid, id, $2::bigint FROM ledger_accounts WHERE wallet_id = $3
+         UNION ALL
+         SELECT $1::uuid, a.id, -$2::bigint FROM ledger_accounts a
+         JOIN wallets w ON w.currency = a.currency
+         WHERE w.id = $3 AND a.code = $4`,
+        entryID, tx.Amount, tx.WalletID, contra)
+    if err != nil {
+        return err
+    }
+    rows, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows != 2 {
+        return fmt.Errorf("ledger account missing for wallet %s or %s", tx.WalletID, contra)
+    }
+    return nil
+}
+
+// Метод для расчёта баланса кошелька на момент at по записям его счёта в главной книге.
+// В отличие от transactions книга содержит и входящие остатки, перенесённые при её создании
+func (r *PostgresWalletRepository) GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (int64, error) {
+    var balance int64
+    err := r.conn(ctx).QueryRowContext(ctx,
+        `SELECT COALESCE(SUM(p.amount), 0) FROM postings p
+         JOIN ledger_accounts a ON a.id = p.account_id
+         JOIN journal_entries e ON e.id = p.entry_id
+         WHERE a.wallet_id = $1 AND e.created_at <= $2`, walletID, at,
+    ).Scan(&balance)
+    return balance, err
+}
+
+// Метод для создания системных счетов валюты, если их ещё нет
+func (r *PostgresWalletRepository) ensureSystemAccounts(ctx context.Context, currency string) error {
+    codes := []string{domain.AccountFunding, domain.AccountPayout, domain.AccountTransferClearing, domain.AccountFXPosition,
+        domain.AccountInterest}
+    _, err := r.conn(ctx).ExecContext(ctx,
+        `INSERT INTO ledger_accounts (code, currency) SELECT unnest($1::text[]), $2
+         ON CONFLICT (code, currency) DO NOTHING`,
+        pq.Array(codes), currency)
+    return err
+}
+
+// Метод для получения сводки по главной книге отдельно по каждой валюте. Запросы выполняются
+// в одном снимке БД: операция, зафиксированная между ними, не нарушает сходимость сводки
+func (r *PostgresWalletRepository) GetLedgerSummary(ctx context.Context) ([]domain.LedgerSummary, error) {
+    var result []domain.LedgerSummary
+    err := r.WithinSnapshot(ctx, func(ctx context.Context) error {
+        var err error
+        result, err = r.ledgerSummary(ctx)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return result, nil
+}
+
+// Метод для расчёта сводки по главной книге запросами в транзакции из ctx
+func (r *PostgresWalletRepository) ledgerSummary(ctx context.Context) ([]domain.LedgerSummary, error) {
+    summaries := make(map[string]*domain.LedgerSummary)
+    get := func(currency string) *domain.LedgerSummary {
+        if summaries[currency] == nil {
+            summaries[currency] = &domain.LedgerSummary{Currency: currency}
+        }
+        return summaries[currency]
+    }
+
+    rows, err := r.conn(ctx).QueryContext(ctx,
+        `SELECT a.currency,
+             COALESCE(SUM(p.amount) FILTER (WHERE a.wallet_id IS NOT NULL), 0),
+             COALESCE(-SUM(p.amount) FILTER (WHERE a.code = $1), 0),
+             COALESCE(SUM(p.amount) FILTER (WHERE a.code = $2), 0),
+             COALESCE(SUM(p.amount) FILTER (WHERE a.code = $3), 0),
+             COALESCE(SUM(p.amount) FILTER (WHERE a.code = $4), 0),
+             COALESCE(-SUM(p.amount) FILTER (WHERE a.code = $5), 0)
+         FROM postings p JOIN ledger_accounts a ON a.id = p.account_id
+         GROUP BY a.currency`,
+        domain.AccountFunding, domain.AccountPayout, domain.AccountTransferClearing, domain.AccountFXPosition,
+        domain.AccountInterest)
+    if err != nil {
+        return nil, err
+    }
+    err = scanRows(rows, func() error {
+        var currency string
+        var liabilities, funded, paidOut, inTransit, fxPosition, interestPaid int64
+        if err := rows.Scan(&currency, &liabilities, &funded, &paidOut, &inTransit, &fxPosition, &interestPaid); err != nil {
+            return err
+        }
+        summary := get(currency)
+        summary.WalletLiabilities, summary.Funded, summary.PaidOut, summary.InTransit = liabilities, funded, paidOut, inTransit
+        summary.FXPosition, summary.InterestPaid = fxPosition, interestPaid
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err = r.conn(ctx).QueryContext(ctx, "SELECT currency, SUM(balance) FROM wallets GROUP BY currency")
+    if err != nil {
+        return nil, err
+    }
+    err = scanRows(rows, func() error {
+        var currency string
+        var balance int64
+        if err := rows.Scan(&currency, &balance); err != nil {
+            return err
+        }
+        get(currency).WalletBalances = balance
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    // Проводка должна быть сбалансирована в каждой валюте отдельно
+    rows, err = r.conn(ctx).QueryContext(ctx,
+        `SELECT currency, COUNT(*) FROM (
+             SELECT p.entry_id, a.currency FROM postings p JOIN ledger_accounts a ON a.id = p.account_id
+             GROUP BY p.entry_id, a.currency HAVING SUM(p.amount) <> 0
+         ) unbalanced GROUP BY currency`)
+    if err != nil {
+        return nil, err
+    }
+    err = scanRows(rows, func() error {
+        var currency string
+        var count int64
+        if err := rows.Scan(&currency, &count); err != nil {
+            return err
+        }
+        get(currency).UnbalancedEntries = count
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    result := make([]domain.LedgerSummary, 0, len(summaries))
+    for _, summary := range summaries {
+        result = append(result, *summary)
+    }
+    sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+    return result, nil
+}
+
+// Функция обходит строки результата, вызывая scan для каждой, и закрывает их
+func scanRows(rows *sql.Rows, scan func() error) error {
+    defer rows.Close()
+    for rows.Next() {
+        if err := scan(); err != nil {
+            return err
+        }
+    }
+    return rows.Err()
+}
\ No newline at end of file
@@ -0,0 +1,385 @@
+package repository_test
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "reflect"
+    "testing"
+    "time"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+    "WalletApp/internal/repository"
+    "WalletApp/internal/usecase"
+)
+
+func TestLedgerPostings(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	summaries, err := repo.GetLedgerSummary(ctx)
+	if err != nil {
+	    t.Fatalf("could not get ledger summary: %v", err)
+	}
+	before := summaryFor(summaries, "RUB")
+
+	a, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	b, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.DEPOSIT, Amount: 1000}); err != nil {
+	    t.Fatalf("could not deposit: %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.WITHDRAW, Amount: 300}); err != nil {
+	    t.Fatalf("could not withdraw: %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.TRANSFER, Amount: 200, DestinationWalletID: b}); err != nil {
+	    t.Fatalf("could not transfer: %v", err)
+	}
+
+	summaries, err = repo.GetLedgerSummary(ctx)
+	if err != nil {
+	    t.Fatalf("could not get ledger summary: %v", err)
+	}
+	after := summaryFor(summaries, "RUB")
+	if after.Funded-before.Funded != 1000 || after.PaidOut-before.PaidOut != 300 {
+	    t.Errorf("unexpected funding/payout change: %+v -> %+v", before, after)
+	}
+	if after.WalletLiabilities-before.WalletLiabilities != 700 || after.InTransit != before.InTransit {
+	    t.Errorf("unexpected liabilities change: %+v -> %+v", before, after)
+	}
+	if after.UnbalancedEntries != 0 {
+	    t.Errorf("expected no unbalanced entries, got %d", after.UnbalancedEntries)
+	}
+
+	// Счёт кошелька в главной книге совпадает с проекцией wallets.balance
+	var ledgerBalance int64
+	err = db.QueryRow(
+	    "SELECT COALESCE(SUM(p.amount), 0) FROM postings p JOIN ledger_accounts a ON a.id = p.account_id WHERE a.wallet_id = $1", a,
+	).Scan(&ledgerBalance)
+	if err != nil || ledgerBalance != 500 {
+	    t.Errorf("expected ledger balance 500, got %d; error:%v", ledgerBalance, err)
+	}
+}
+
+// Несбалансированная проводка отклоняется при фиксации транзакции
+func TestLedgerRejectsUnbalancedEntry(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	tx, err := db.Begin()
+	if err != nil {
+	    t.Fatalf("could not begin transaction: %v", err)
+	}
+	entryID := uuid.New()
+	if _, err := tx.Exec("INSERT INTO journal_entries (id, description) VALUES ($1, 'TEST')", entryID); err != nil {
+	    t.Fatalf("could not insert entry: %v", err)
+	}
+	_, err = tx.Exec(
+	    "INSERT INTO postings (entry_id, account_id, amount) SELECT $1, id, 100 FROM ledger_accounts WHERE wallet_id = $2",
+	    entryID, walletID)
+	if err != nil {
+	    t.Fatalf("could not insert posting: %v", err)
+	}
+	if err := tx.Commit(); err == nil {
+	    t.Fatal("expected commit of unbalanced entry to fail")
+	}
+}
+
+// Функция возвращает сводку по валюте (нулевую, если операций в ней не было)
+func summaryFor(summaries []domain.LedgerSummary, currency string) domain.LedgerSummary {
+	for _, summary := range summaries {
+	    if summary.Currency == currency {
+	        return summary
+	    }
+	}
+	return domain.LedgerSummary{Currency: currency}
+}
+
+// Компенсирующие записи проводятся по счетам отменяемых операций, повторный возврат сверх суммы отклоняется
+func TestLedgerReversals(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	summaries, err := repo.GetLedgerSummary(ctx)
+	if err != nil {
+	    t.Fatalf("could not get ledger summary: %v", err)
+	}
+	before := summaryFor(summaries, "RUB")
+
+	a, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	b, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.DEPOSIT, Amount: 1000})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.TRANSFER, Amount: 400, DestinationWalletID: b})
+
+	history, _ := repo.ListTransactions(ctx, domain.TransactionFilter{WalletID: a, Limit: 10})
+	transfer, deposit := history[0], history[1]
+	if _, err := svc.ReverseTransaction(ctx, deposit.ID, 100); err != nil {
+	    t.Fatalf("could not reverse deposit: %v", err)
+	}
+	if _, err := svc.ReverseTransaction(ctx, transfer.ID, 0); err != nil {
+	    t.Fatalf("could not reverse transfer: %v", err)
+	}
+	if _, err := svc.ReverseTransaction(ctx, transfer.ID, 0); !errors.Is(err, domain.ErrAlreadyReversed) {
+	    t.Errorf("expected ErrAlreadyReversed, got %v", err)
+	}
+	if _, err := svc.ReverseTransaction(ctx, deposit.ID, 901); !errors.Is(err, domain.ErrRefundExceedsAmount) {
+	    t.Errorf("expected ErrRefundExceedsAmount, got %v", err)
+	}
+
+	summaries, err = repo.GetLedgerSummary(ctx)
+	if err != nil {
+	    t.Fatalf("could not get ledger summary: %v", err)
+	}
+	after := summaryFor(summaries, "RUB")
+	if after.Funded-before.Funded != 900 || after.InTransit != before.InTransit || after.UnbalancedEntries != 0 {
+	    t.Errorf("unexpected ledger change: %+v -> %+v", before, after)
+	}
+	if wallet, _ := repo.GetWallet(ctx, a); wallet.Balance != 900 {
+	    t.Errorf("expected balance 900, got %d", wallet.Balance)
+	}
+
+	reversals, _ := repo.ListTransactions(ctx, domain.TransactionFilter{WalletID: a, OperationType: usecase.REVERSAL, Limit: 10})
+	if len(reversals) != 2 || *reversals[0].ReversedTransactionID != transfer.ID || *reversals[1].ReversedTransactionID != deposit.ID {
+	    t.Errorf("unexpected reversal history: %+v", reversals)
+	}
+}
+
+// Баланс на момент времени считается по проводкам, созданным не позже этого момента
+func TestGetBalanceAt(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000})
+	history, _ := repo.ListTransactions(ctx, domain.TransactionFilter{WalletID: walletID, Limit: 1})
+	afterDeposit := history[0].CreatedAt
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 400})
+
+	if balance, err := repo.GetBalanceAt(ctx, walletID, afterDeposit.Add(-time.Microsecond)); err != nil || balance != 0 {
+	    t.Errorf("expected 0 before deposit, got %d; error:%v", balance, err)
+	}
+	if balance, _ := repo.GetBalanceAt(ctx, walletID, afterDeposit); balance != 1000 {
+	    t.Errorf("expected 1000 right after deposit, got %d", balance)
+	}
+	if balance, _ := repo.GetBalanceAt(ctx, walletID, time.Now().Add(time.Hour)); balance != 600 {
+	    t.Errorf("expected current balance 600, got %d", balance)
+	}
+}
+
+func TestStreamTransactions(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	for _, amount := range []int64{100, 200, 300} {
+	    svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: amount})
+	}
+	history, _ := repo.ListTransactions(ctx, domain.TransactionFilter{WalletID: walletID, Limit: 3})
+	// история отсортирована от новых к старым
+	from, to := history[1].CreatedAt, history[0].CreatedAt
+
+	var amounts []int64
+	err = repo.StreamTransactions(ctx, walletID, from, to, func(tx domain.Transaction) error {
+	    amounts = append(amounts, tx.Amount)
+	    return nil
+	})
+	if err != nil || len(amounts) != 1 || amounts[0] != 200 {
+	    t.Errorf("expected only the second deposit in [from, to), got %v; error:%v", amounts, err)
+	}
+
+	stop := errors.New("stop")
+	err = repo.StreamTransactions(ctx, walletID, time.Time{}, time.Now().Add(time.Hour), func(tx domain.Transaction) error {
+	    return stop
+	})
+	if !errors.Is(err, stop) {
+	    t.Errorf("expected callback error to be returned, got %v", err)
+	}
+
+	// Операции одной транзакции БД (одно время created_at) идут в порядке записи,
+	// входящий остаток без записи в transactions тоже попадает в выписку
+	batchWallet, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	_, err = svc.PerformBatch(ctx, []domain.Operation{
+	    {WalletID: batchWallet, OperationType: usecase.DEPOSIT, Amount: 100},
+	    {WalletID: batchWallet, OperationType: usecase.WITHDRAW, Amount: 70},
+	    {WalletID: batchWallet, OperationType: usecase.DEPOSIT, Amount: 5},
+	    {WalletID: batchWallet, OperationType: usecase.WITHDRAW, Amount: 30},
+	})
+	if err != nil {
+	    t.Fatalf("could not perform batch: %v", err)
+	}
+	_, err = db.Exec(`WITH e AS (INSERT INTO journal_entries (description) VALUES ($2) RETURNING id)
+	    INSERT INTO postings (entry_id, account_id, amount)
+	    SELECT e.id, a.id, 40 FROM e, ledger_accounts a WHERE a.wallet_id = $1
+	    UNION ALL
+	    SELECT e.id, f.id, -40 FROM e, ledger_accounts f WHERE f.code = 'funding' AND f.currency = 'RUB'`,
+	    batchWallet, domain.EntryOpeningBalance)
+	if err != nil {
+	    t.Fatalf("could not post opening balance: %v", err)
+	}
+	var lines []string
+	err = repo.StreamTransactions(ctx, batchWallet, time.Time{}, time.Now().Add(time.Hour), func(tx domain.Transaction) error {
+	    lines = append(lines, fmt.Sprintf("%s %d", tx.OperationType, tx.Amount))
+	    return nil
+	})
+	expected := []string{"DEPOSIT 100", "WITHDRAW -70", "DEPOSIT 5", "WITHDRAW -30", "OPENING_BALANCE 40"}
+	if err != nil || !reflect.DeepEqual(lines, expected) {
+	    t.Errorf("expected %v, got %v; error:%v", expected, lines, err)
+	}
+}
+
+func TestWithinSnapshot(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if err := record(ctx, repo, walletID, "DEPOSIT", 100); err != nil {
+	    t.Fatalf("could not record deposit: %v", err)
+	}
+
+	// Операция, зафиксированная после первого чтения снимка, ему не видна
+	err = repo.WithinSnapshot(ctx, func(snapshot context.Context) error {
+	    before, err := repo.GetBalanceAt(snapshot, walletID, time.Now().Add(time.Hour))
+	    if err != nil {
+	        return err
+	    }
+	    if err := record(ctx, repo, walletID, "DEPOSIT", 50); err != nil {
+	        return err
+	    }
+	    after, err := repo.GetBalanceAt(snapshot, walletID, time.Now().Add(time.Hour))
+	    if err != nil {
+	        return err
+	    }
+	    var count int
+	    err = repo.StreamTransactions(snapshot, walletID, time.Time{}, time.Now().Add(time.Hour), func(domain.Transaction) error {
+	        count++
+	        return nil
+	    })
+	    if before != 100 || after != 100 || count != 1 {
+	        t.Errorf("expected snapshot balance 100 and 1 record, got %d, %d and %d", before, after, count)
+	    }
+	    return err
+	})
+	if err != nil {
+	    t.Fatalf("snapshot: %v", err)
+	}
+
+	// Снимок только для чтения
+	err = repo.WithinSnapshot(ctx, func(snapshot context.Context) error {
+	    return record(snapshot, repo, walletID, "DEPOSIT", 1)
+	})
+	if err == nil {
+	    t.Errorf("expected write in read-only snapshot to fail")
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 150 {
+	    t.Errorf("expected balance 150, got %d", balance)
+	}
+}
+
+func TestLedgerFees(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	revenue, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc := usecase.NewWalletService(repo, usecase.WithFees(domain.FeeSchedule{
+	    RevenueWallets: map[string]uuid.UUID{"RUB": revenue},
+	    Rules:          []domain.FeeRule{{Name: "withdraw", OperationType: usecase.WITHDRAW, Tier: "STANDARD", Flat: 25, Rounding: domain.RoundHalfEven}},
+	}))
+
+	summaries, err := repo.GetLedgerSummary(ctx)
+	if err != nil {
+	    t.Fatalf("could not get ledger summary: %v", err)
+	}
+	before := summaryFor(summaries, "RUB")
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000}); err != nil {
+	    t.Fatalf("could not deposit: %v", err)
+	}
+	result, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 300})
+	if err != nil || len(result.Fees) != 1 || result.Fees[0].Amount != 25 {
+	    t.Fatalf("expected fee 25, got %+v; error:%v", result, err)
+	}
+
+	walletBalance, _ := repo.GetBalance(ctx, walletID)
+	revenueBalance, _ := repo.GetBalance(ctx, revenue)
+	if walletBalance != 675 || revenueBalance != 25 {
+	    t.Errorf("expected balances 675 and 25, got %d and %d", walletBalance, revenueBalance)
+	}
+
+	// Комиссия перемещает деньги между кошельками: обязательства не меняются, проводки сбалансированы
+	summaries, err = repo.GetLedgerSummary(ctx)
+	if err != nil {
+	    t.Fatalf("could not get ledger summary: %v", err)
+	}
+	after := summaryFor(summaries, "RUB")
+	if after.WalletLiabilities-before.WalletLiabilities != 700 || after.InTransit != before.InTransit || after.UnbalancedEntries != 0 {
+	    t.Errorf("unexpected ledger change: %+v -> %+v", before, after)
+	}
+
+	// Комиссия не входит в расход по лимитам
+	usage, err := repo.GetSpendingUsage(ctx, walletID, time.Now().Add(-time.Hour))
+	if err != nil || usage.Amount != 300 {
+	    t.Errorf("expected spending usage 300, got %+v; error:%v", usage, err)
+	}
+
+	// Тариф сохраняется, и правило STANDARD к кошельку больше не применяется
+	if err := repo.SetWalletTier(ctx, walletID, "GOLD"); err != nil {
+	    t.Fatalf("could not set tier: %v", err)
+	}
+	if wallet, _ := repo.GetWallet(ctx, walletID); wallet.Tier != "GOLD" {
+	    t.Errorf("expected tier GOLD, got %q", wallet.Tier)
+	}
+	if result, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 100}); err != nil || len(result.Fees) != 0 {
+	    t.Errorf("expected withdraw without fees, got %+v; error:%v", result, err)
+	}
+	if err := repo.SetWalletTier(ctx, uuid.New(), "GOLD"); !errors.Is(err, domain.ErrWalletNotFound) {
+	    t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+)
+
+// структура PostgresOwnerRepository для хранения владельцев кошельков в Postgres
+type PostgresOwnerRepository struct {
+    db *sql.DB
+}
+
+// экземпляр
+func NewPostgresOwnerRepository(db *sql.DB) *PostgresOwnerRepository {
+    return &PostgresOwnerRepository{db: db}
+}
+
+// Метод для сохранения нового владельца
+func (r *PostgresOwnerRepository) CreateOwner(ctx context.Context, owner *domain.Owner) error {
+    if owner.ID == uuid.Nil {
+        owner.ID = uuid.New()
+    }
+    return connFrom(ctx, r.db).QueryRowContext(ctx,
+        "INSERT INTO owners (id, name, email) VALUES ($1, $2, NULLIF($3, '')) RETURNING created_at",
+        owner.ID, owner.Name, owner.Email,
+    ).Scan(&owner.CreatedAt)
+}
+
+// Метод для получения владельца по id
+func (r *PostgresOwnerRepository) GetOwner(ctx context.Context, ownerID uuid.UUID) (domain.Owner, error) {
+    owner := domain.Owner{ID: ownerID}
+    err := connFrom(ctx, r.db).QueryRowContext(ctx,
+        "SELECT name, COALESCE(email, ''), created_at FROM owners WHERE id = $1", ownerID,
+    ).Scan(&owner.Name, &owner.Email, &owner.CreatedAt)
+    if err == sql.ErrNoRows {
+        return domain.Owner{}, domain.ErrOwnerNotFound
+    }
+    return owner, err
+}
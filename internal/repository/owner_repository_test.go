@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+    "WalletApp/internal/repository"
+)
+
+func TestPostgresOwnerRepository(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	owners := repository.NewPostgresOwnerRepository(db)
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+
+	owner := domain.Owner{Name: "Acme LLC"}
+	if err := owners.CreateOwner(ctx, &owner); err != nil {
+	    t.Fatalf("could not create owner: %v", err)
+	}
+	if stored, err := owners.GetOwner(ctx, owner.ID); err != nil || stored.Name != "Acme LLC" || stored.Email != "" {
+	    t.Errorf("unexpected stored owner: %+v; error:%v", stored, err)
+	}
+	if _, err := owners.GetOwner(ctx, uuid.New()); !errors.Is(err, domain.ErrOwnerNotFound) {
+	    t.Errorf("expected ErrOwnerNotFound, got %v", err)
+	}
+
+	first, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB", OwnerID: &owner.ID})
+	second, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD", OwnerID: &owner.ID})
+	if _, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"}); err != nil {
+	    t.Fatalf("could not create anonymous wallet: %v", err)
+	}
+	missing := uuid.New()
+	if _, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB", OwnerID: &missing}); !errors.Is(err, domain.ErrOwnerNotFound) {
+	    t.Errorf("expected ErrOwnerNotFound, got %v", err)
+	}
+
+	wallets, err := repo.ListWalletsByOwner(ctx, owner.ID)
+	if err != nil || len(wallets) != 2 {
+	    t.Fatalf("expected 2 owner wallets, got %d; error:%v", len(wallets), err)
+	}
+	if wallets[0].ID != first || wallets[1].ID != second || wallets[1].Currency != "USD" || *wallets[0].OwnerID != owner.ID {
+	    t.Errorf("unexpected owner wallets: %+v", wallets)
+	}
+}
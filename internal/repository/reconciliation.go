@@ -0,0 +1,74 @@
+package repository
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+)
+
+// Баланс, входящий остаток, сумма операций и остаток счёта в книге читаются одним запросом,
+// поэтому параллельные операции не дают ложных расхождений. Входящие остатки - проводки
+// без записи в transactions, перенесённые при создании главной книги
+const balanceCheckQuery = `SELECT w.id, w.currency, w.balance,
+     COALESCE((SELECT SUM(p.amount) FROM postings p JOIN journal_entries e ON e.id = p.entry_id
+               WHERE p.account_id = a.id AND e.transaction_id IS NULL), 0),
+     COALESCE((SELECT SUM(t.amount) FROM transactions t WHERE t.wallet_id = w.id), 0),
+     COALESCE((SELECT SUM(p.amount) FROM postings p WHERE p.account_id = a.id), 0)
+ FROM wallets w LEFT JOIN ledger_accounts a ON a.wallet_id = w.id`
+
+// Функция читает сверку баланса из строки результата balanceCheckQuery
+func scanBalanceCheck(row interface{ Scan(dest ...interface{}) error }) (domain.BalanceCheck, error) {
+    var check domain.BalanceCheck
+    err := row.Scan(&check.WalletID, &check.Currency, &check.StoredBalance,
+        &check.OpeningBalance, &check.TransactionsSum, &check.LedgerBalance)
+    if err != nil {
+        return domain.BalanceCheck{}, err
+    }
+    check.Reconcile()
+    return check, nil
+}
+
+// Метод для сверки балансов всех кошельков: строки передаются в fn по мере чтения
+func (r *PostgresWalletRepository) StreamBalanceChecks(ctx context.Context, fn func(domain.BalanceCheck) error) error {
+    rows, err := r.conn(ctx).QueryContext(ctx, balanceCheckQuery+" ORDER BY w.id")
+    if err != nil {
+        return err
+    }
+    return scanRows(rows, func() error {
+        check, err := scanBalanceCheck(rows)
+        if err != nil {
+            return err
+        }
+        return fn(check)
+    })
+}
+
+// Метод для сверки баланса одного кошелька
+func (r *PostgresWalletRepository) GetBalanceCheck(ctx context.Context, walletID uuid.UUID) (domain.BalanceCheck, error) {
+    check, err := scanBalanceCheck(r.conn(ctx).QueryRowContext(ctx, balanceCheckQuery+" WHERE w.id = $1", walletID))
+    if errors.Is(err, sql.ErrNoRows) {
+        return domain.BalanceCheck{}, domain.ErrWalletNotFound
+    }
+    return check, err
+}
+
+// Метод для замены баланса кошелька при исправлении расхождения; триггер wallets_balance_projection
+// отклоняет любое значение, кроме остатка счёта кошелька в главной книге
+func (r *PostgresWalletRepository) SetBalance(ctx context.Context, walletID uuid.UUID, balance int64) error {
+    res, err := r.conn(ctx).ExecContext(ctx, "UPDATE wallets SET balance = $1 WHERE id = $2", balance, walletID)
+    if err != nil {
+        return mapError(err)
+    }
+    rows, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return domain.ErrWalletNotFound
+    }
+    return nil
+}
@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+    "context"
+    "errors"
+    "testing"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+    "WalletApp/internal/repository"
+    "WalletApp/internal/usecase"
+)
+
+func TestReconcileBalances(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	clean, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	drifted, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	for _, id := range []uuid.UUID{clean, drifted} {
+	    svc.PerformOperation(ctx, domain.Operation{WalletID: id, OperationType: usecase.DEPOSIT, Amount: 1000})
+	}
+	// Расхождение, накопленное до того, как баланс стал проекцией книги: триггеры отключены
+	tx, err := db.Begin()
+	if err != nil {
+	    t.Fatalf("could not begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("SET LOCAL session_replication_role = replica"); err != nil {
+	    t.Fatalf("could not disable triggers: %v", err)
+	}
+	if _, err := tx.Exec("UPDATE wallets SET balance = balance + 25 WHERE id = $1", drifted); err != nil {
+	    t.Fatalf("could not update balance: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+	    t.Fatalf("could not commit: %v", err)
+	}
+
+	check, err := repo.GetBalanceCheck(ctx, drifted)
+	if err != nil || check.StoredBalance != 1025 || check.TransactionsSum != 1000 || check.LedgerBalance != 1000 || check.Drift != 25 {
+	    t.Errorf("unexpected balance check: %+v; error:%v", check, err)
+	}
+	if _, err := repo.GetBalanceCheck(ctx, uuid.New()); !errors.Is(err, domain.ErrWalletNotFound) {
+	    t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+
+	report, err := svc.ReconcileBalances(ctx, true)
+	if err != nil {
+	    t.Fatalf("could not reconcile: %v", err)
+	}
+	var found bool
+	for _, check := range report.Wallets {
+	    if check.WalletID == clean {
+	        t.Errorf("expected consistent wallet to be left out of the report: %+v", check)
+	    }
+	    found = found || (check.WalletID == drifted && check.Repaired && check.Drift == 25)
+	}
+	if !found {
+	    t.Errorf("expected drifted wallet to be repaired, got %+v", report.Wallets)
+	}
+	if balance, _ := repo.GetBalance(ctx, drifted); balance != 1000 {
+	    t.Errorf("expected repaired balance 1000, got %d", balance)
+	}
+	// Исправить баланс можно только на остаток книги
+	if err := repo.SetBalance(ctx, drifted, 1025); err == nil {
+	    t.Error("expected balance different from the ledger to be rejected")
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+    "context"
+    "time"
+
+    "github.com/google/uuid"
+
+    "WalletApp/internal/domain"
+)
+
+// Метод для получения лимитов расходов, заданных для кошелька
+func (r *PostgresWalletRepository) GetSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]domain.SpendingLimit, error) {
+    rows, err := r.conn(ctx).QueryContext(ctx,
+        "SELECT period, max_amount, max_count FROM wallet_spending_limits WHERE wallet_id = $1", walletID)
+    if err != nil {
+        return nil, err
+    }
+    var limits []domain.SpendingLimit
+    err = scanRows(rows, func() error {
+        var limit domain.SpendingLimit
+        if err := rows.Scan(&limit.Period, &limit.MaxAmount, &limit.MaxCount); err != nil {
+            return err
+        }
+        limits = append(limits, limit)
+        return nil
+    })
+    return limits, err
+}
+
+// Метод для установки лимита кошелька на период; существующий лимит на тот же период заменяется
+func (r *PostgresWalletRepository) SetSpendingLimit(ctx context.Context, walletID uuid.UUID, limit domain.SpendingLimit) error {
+    _, err := r.conn(ctx).ExecContext(ctx,
+        `INSERT INTO wallet_spending_limits (wallet_id, period, max_amount, max_count)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (wallet_id, period) DO UPDATE
+         SET max_amount = EXCLUDED.max_amount, max_count = EXCLUDED.max_count, updated_at = CURRENT_TIMESTAMP`,
+        walletID, limit.Period, limit.MaxAmount, limit.MaxCount)
+    return err
+}
+
+// Метод для удаления лимита кошелька на период
+func (r *PostgresWalletRepository) DeleteSpendingLimit(ctx context.Context, walletID uuid.UUID, period string) error {
+    _, err := r.conn(ctx).ExecContext(ctx,
+        "DELETE FROM wallet_spending_limits WHERE wallet_id = $1 AND period = $2", walletID, period)
+    return err
+}
+
+// Метод для подсчёта списаний кошелька начиная с since: учитываются все записи с отрицательной суммой
+// (снятия, исходящие переводы, списания по блокировкам), кроме отмен операций и комиссий. Использует индекс истории операций
+func (r *PostgresWalletRepository) GetSpendingUsage(ctx context.Context, walletID uuid.UUID, since time.Time) (domain.SpendingUsage, error) {
+    var usage domain.SpendingUsage
+    err := r.conn(ctx).QueryRowContext(ctx,
+        `SELECT COALESCE(SUM(-amount), 0), COUNT(*) FROM transactions
+         WHERE wallet_id = $1 AND created_at >= $2 AND amount < 0 AND operation_type NOT IN ('REVERSAL', 'FEE')`, walletID, since,
+    ).Scan(&usage.Amount, &usage.Count)
+    return usage, err
+}
@@ -0,0 +1,89 @@
+package repository_test
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+
+    "WalletApp/internal/domain"
+    "WalletApp/internal/repository"
+    "WalletApp/internal/usecase"
+)
+
+// Параллельные списания не превышают дневной лимит: использование окна считается под блокировкой кошелька
+func TestConcurrentWithdrawalsWithinSpendingLimit(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(20)
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	const (
+	    dailyLimit = 50
+	    workers    = 200
+	)
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000}); err != nil {
+	    t.Fatalf("could not deposit: %v", err)
+	}
+	if _, err := svc.SetSpendingLimits(ctx, walletID, []domain.SpendingLimit{{Period: domain.PeriodDaily, MaxAmount: dailyLimit}}); err != nil {
+	    t.Fatalf("could not set spending limit: %v", err)
+	}
+
+	var (
+	    wg        sync.WaitGroup
+	    succeeded int64
+	)
+	for i := 0; i < workers; i++ {
+	    wg.Add(1)
+	    go func() {
+	        defer wg.Done()
+	        _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 1})
+	        switch {
+	        case err == nil:
+	            atomic.AddInt64(&succeeded, 1)
+	        case errors.Is(err, domain.ErrSpendingLimitExceeded):
+	        default:
+	            t.Errorf("unexpected error: %v", err)
+	        }
+	    }()
+	}
+	wg.Wait()
+
+	if succeeded != dailyLimit {
+	    t.Errorf("expected %d successful withdrawals, got %d", dailyLimit, succeeded)
+	}
+	usage, err := repo.GetSpendingUsage(ctx, walletID, mustPeriodStart(t, domain.PeriodDaily))
+	if err != nil || usage.Amount != dailyLimit || usage.Count != dailyLimit {
+	    t.Errorf("unexpected spending usage: %+v; error:%v", usage, err)
+	}
+
+	limits, err := repo.GetSpendingLimits(ctx, walletID)
+	if err != nil || len(limits) != 1 || limits[0].MaxAmount != dailyLimit {
+	    t.Errorf("unexpected stored limits: %+v; error:%v", limits, err)
+	}
+	if err := repo.DeleteSpendingLimit(ctx, walletID, domain.PeriodDaily); err != nil {
+	    t.Fatalf("could not delete spending limit: %v", err)
+	}
+	if limits, _ := repo.GetSpendingLimits(ctx, walletID); len(limits) != 0 {
+	    t.Errorf("expected no stored limits, got %+v", limits)
+	}
+}
+
+// Функция возвращает начало текущего окна периода
+func mustPeriodStart(t *testing.T, period string) time.Time {
+	start, err := domain.PeriodStart(period, time.Now())
+	if err != nil {
+	    t.Fatalf("could not compute period start: %v", err)
+	}
+	return start
+}
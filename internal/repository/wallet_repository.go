@@ -3,8 +3,16 @@ package repository
 import (
     "context"
     "database/sql"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
 
     "github.com/google/uuid"
+    "github.com/lib/pq"
+
+    "WalletApp/internal/domain"
 )
 
 // структура PostgresWalletRepository для работы с кошельками в Postgres
@@ -17,25 +25,545 @@ func NewPostgresWalletRepository(db *sql.DB) *PostgresWalletRepository {
     return &PostgresWalletRepository{db: db}
 }
 
-// Метод для получения баланса кошелька по id
+// общий интерфейс *sql.DB и *sql.Tx
+type executor interface {
+    ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+    QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+    QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// коды ошибок Postgres (SQLSTATE)
+const (
+    pgNumericOutOfRange   = "22003" // переполнение BIGINT
+    pgCheckViolation      = "23514" // нарушение CHECK-ограничения
+    pgForeignKeyViolation = "23503" // ссылка на несуществующую запись
+)
+
+// Функция переводит ошибки Postgres в ошибки предметной области
+func mapError(err error) error {
+    var pqErr *pq.Error
+    if !errors.As(err, &pqErr) {
+        return err
+    }
+    switch {
+    case pqErr.Code == pgNumericOutOfRange:
+        return domain.ErrBalanceOverflow
+    case pqErr.Code == pgCheckViolation && (pqErr.Constraint == "wallets_balance_within_overdraft" ||
+        pqErr.Constraint == "wallets_reserved_within_balance"):
+        return domain.ErrInsufficientFunds
+    case pqErr.Code == pgForeignKeyViolation && pqErr.Constraint == "wallets_owner_id_fkey":
+        return domain.ErrOwnerNotFound
+    case pqErr.Code == pgForeignKeyViolation && pqErr.Constraint == "transactions_wallet_id_fkey":
+        return domain.ErrWalletNotFound
+    }
+    return err
+}
+
+// ключ контекста для текущей транзакции
+type txKey struct{}
+
+// Функция возвращает транзакцию из контекста, если она есть, иначе пул соединений db
+func connFrom(ctx context.Context, db *sql.DB) executor {
+    if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+        return tx
+    }
+    return db
+}
+
+// Метод возвращает транзакцию из контекста, если она есть, иначе пул соединений
+func (r *PostgresWalletRepository) conn(ctx context.Context) executor {
+    return connFrom(ctx, r.db)
+}
+
+// Метод для выполнения fn в транзакции; вложенный вызов использует уже открытую транзакцию
+func (r *PostgresWalletRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+    return withinTransaction(ctx, r.db, fn)
+}
+
+// Метод для выполнения fn в читающей транзакции REPEATABLE READ: запросы fn видят
+// состояние БД на момент первого из них, даже если параллельно фиксируются операции
+func (r *PostgresWalletRepository) WithinSnapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+    return beginTransaction(ctx, r.db, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}, fn)
+}
+
+// Функция выполняет fn в транзакции db или в транзакции, уже открытой в контексте
+func withinTransaction(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+    return beginTransaction(ctx, db, nil, fn)
+}
+
+// Функция выполняет fn в новой транзакции db с параметрами opts или в транзакции, уже открытой в контексте
+func beginTransaction(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+    if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+        return fn(ctx)
+    }
+
+    tx, err := db.BeginTx(ctx, opts)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback() // после Commit вызов ничего не делает
+
+    if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// Метод для получения баланса кошелька по id; для несуществующего кошелька ErrWalletNotFound.
+// wallets.balance - проекция счёта кошелька в главной книге, которую ведёт триггер на postings
+// (миграция 000018), поэтому чтение не суммирует проводки
 func (r *PostgresWalletRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
     var balance int64
-    err := r.db.QueryRowContext(ctx, "SELECT balance FROM wallets WHERE id = $1", walletID).Scan(&balance)
+    err := r.conn(ctx).QueryRowContext(ctx, "SELECT balance FROM wallets WHERE id = $1", walletID).Scan(&balance)
     if err == sql.ErrNoRows {
-        return 0, nil
+        return 0, domain.ErrWalletNotFound
     }
     return balance, err
 }
 
-// Метод для обновления баланса кошелька по id
-func (r *PostgresWalletRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) error {
-    _, err := r.db.ExecContext(ctx, "UPDATE wallets SET balance = balance + $1 WHERE id = $2", amount, walletID)
-    return err
+// Метод для резервирования суммы под блокировку: проверка доступного остатка и резервирование выполняются
+// одним UPDATE, поэтому параллельные блокировки не резервируют больше доступного
+func (r *PostgresWalletRepository) ReserveBalance(ctx context.Context, walletID uuid.UUID, amount int64) error {
+    res, err := r.conn(ctx).ExecContext(ctx,
+        "UPDATE wallets SET reserved = reserved + $1 WHERE id = $2 AND balance - reserved >= $1 - overdraft_limit", amount, walletID)
+    if err != nil {
+        return mapError(err)
+    }
+    rows, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return r.debitFailure(ctx, walletID)
+    }
+    return nil
 }
 
-// Метод для создания нового кошелька и возврата id
-func (r *PostgresWalletRepository) CreateWallet(ctx context.Context) (uuid.UUID, error) {
-    walletID := uuid.New()
-    _, err := r.db.ExecContext(ctx, "INSERT INTO wallets (id, balance) VALUES ($1, $2)", walletID, 0)
+// Метод для освобождения зарезервированной суммы
+func (r *PostgresWalletRepository) ReleaseReserve(ctx context.Context, walletID uuid.UUID, amount int64) error {
+    res, err := r.conn(ctx).ExecContext(ctx,
+        "UPDATE wallets SET reserved = reserved - $1 WHERE id = $2", amount, walletID)
+    if err != nil {
+        return err
+    }
+    rows, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return domain.ErrWalletNotFound
+    }
+    return nil
+}
+
+// Метод определяет причину несработавшего списания: кошелька нет или не хватает средств
+func (r *PostgresWalletRepository) debitFailure(ctx context.Context, walletID uuid.UUID) error {
+    var exists bool
+    err := r.conn(ctx).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM wallets WHERE id = $1)", walletID).Scan(&exists)
+    if err != nil {
+        return err
+    }
+    if !exists {
+        return domain.ErrWalletNotFound
+    }
+    return domain.ErrInsufficientFunds
+}
+
+// Метод для блокировки кошельков (SELECT ... FOR UPDATE) в порядке id; должен вызываться внутри WithinTransaction
+func (r *PostgresWalletRepository) LockWallets(ctx context.Context, walletIDs ...uuid.UUID) error {
+    ids := make([]string, len(walletIDs))
+    for i, id := range walletIDs {
+        ids[i] = id.String()
+    }
+
+    rows, err := r.conn(ctx).QueryContext(ctx,
+        "SELECT id FROM wallets WHERE id = ANY($1::uuid[]) ORDER BY id FOR UPDATE", pq.Array(ids))
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    locked := make(map[string]bool, len(ids))
+    for rows.Next() {
+        var id string
+        if err := rows.Scan(&id); err != nil {
+            return err
+        }
+        locked[id] = true
+    }
+    if err := rows.Err(); err != nil {
+        return err
+    }
+    for _, id := range ids {
+        if !locked[id] {
+            return domain.ErrWalletNotFound
+        }
+    }
+    return nil
+}
+
+// Метод для создания нового активного кошелька и возврата id
+func (r *PostgresWalletRepository) CreateWallet(ctx context.Context, wallet domain.Wallet) (uuid.UUID, error) {
+    walletID, currency := uuid.New(), wallet.Currency
+    metadata, err := encodeMetadata(wallet.Metadata)
+    if err != nil {
+        return uuid.Nil, err
+    }
+    err = r.WithinTransaction(ctx, func(ctx context.Context) error {
+        _, err := r.conn(ctx).ExecContext(ctx,
+            `INSERT INTO wallets (id, balance, currency, status, owner_id, display_name, metadata)
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+            walletID, 0, currency, domain.WalletActive, wallet.OwnerID, wallet.DisplayName, metadata)
+        if err != nil {
+            return mapError(err)
+        }
+        // Счёт кошелька в главной книге и системные счета его валюты, если их ещё нет
+        _, err = r.conn(ctx).ExecContext(ctx,
+            "INSERT INTO ledger_accounts (wallet_id, currency) VALUES ($1, $2)", walletID, currency)
+        if err != nil {
+            return err
+        }
+        return r.ensureSystemAccounts(ctx, currency)
+    })
     return walletID, err
+}
+
+const walletColumns = "id, balance, reserved, currency, status, overdraft_limit, owner_id, created_at, display_name, metadata, tier"
+
+// Функция читает кошелёк из строки результата с колонками walletColumns
+func scanWallet(row interface{ Scan(dest ...interface{}) error }) (domain.Wallet, error) {
+    var (
+        wallet   domain.Wallet
+        ownerID  uuid.NullUUID
+        metadata []byte
+    )
+    err := row.Scan(&wallet.ID, &wallet.Balance, &wallet.Reserved, &wallet.Currency, &wallet.Status,
+        &wallet.OverdraftLimit, &ownerID, &wallet.CreatedAt, &wallet.DisplayName, &metadata, &wallet.Tier)
+    if err != nil {
+        return domain.Wallet{}, err
+    }
+    if ownerID.Valid {
+        wallet.OwnerID = &ownerID.UUID
+    }
+    if err := json.Unmarshal(metadata, &wallet.Metadata); err != nil {
+        return domain.Wallet{}, fmt.Errorf("decode wallet metadata: %w", err)
+    }
+    if len(wallet.Metadata) == 0 {
+        wallet.Metadata = nil
+    }
+    return wallet, nil
+}
+
+// Функция кодирует метаданные кошелька для колонки JSONB; nil сохраняется как пустой объект
+func encodeMetadata(metadata map[string]string) ([]byte, error) {
+    if metadata == nil {
+        metadata = map[string]string{}
+    }
+    return json.Marshal(metadata)
+}
+
+// Метод для получения кошелька с балансом, резервом, валютой, статусом, кредитным лимитом и владельцем по id
+func (r *PostgresWalletRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (domain.Wallet, error) {
+    wallet, err := scanWallet(r.conn(ctx).QueryRowContext(ctx,
+        "SELECT "+walletColumns+" FROM wallets WHERE id = $1", walletID))
+    if err == sql.ErrNoRows {
+        return domain.Wallet{}, domain.ErrWalletNotFound
+    }
+    return wallet, err
+}
+
+// Метод для получения кошельков владельца в порядке создания
+func (r *PostgresWalletRepository) ListWalletsByOwner(ctx context.Context, ownerID uuid.UUID) ([]domain.Wallet, error) {
+    rows, err := r.conn(ctx).QueryContext(ctx,
+        "SELECT "+walletColumns+" FROM wallets WHERE owner_id = $1 ORDER BY created_at, id", ownerID)
+    if err != nil {
+        return nil, err
+    }
+    wallets := []domain.Wallet{}
+    err = scanRows(rows, func() error {
+        wallet, err := scanWallet(rows)
+        if err != nil {
+            return err
+        }
+        wallets = append(wallets, wallet)
+        return nil
+    })
+    return wallets, err
+}
+
+// Метод для получения кошельков, метаданные которых содержат все метки фильтра, в порядке создания
+func (r *PostgresWalletRepository) ListWallets(ctx context.Context, filter domain.WalletFilter) ([]domain.Wallet, error) {
+    labels, err := encodeMetadata(filter.Labels)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := r.conn(ctx).QueryContext(ctx,
+        "SELECT "+walletColumns+" FROM wallets WHERE metadata @> $1 ORDER BY created_at, id LIMIT $2", labels, filter.Limit)
+    if err != nil {
+        return nil, err
+    }
+    wallets := []domain.Wallet{}
+    err = scanRows(rows, func() error {
+        wallet, err := scanWallet(rows)
+        if err != nil {
+            return err
+        }
+        wallets = append(wallets, wallet)
+        return nil
+    })
+    return wallets, err
+}
+
+// Метод для замены отображаемого имени и метаданных кошелька
+func (r *PostgresWalletRepository) UpdateWalletDetails(ctx context.Context, walletID uuid.UUID, displayName string, metadata map[string]string) error {
+    encoded, err := encodeMetadata(metadata)
+    if err != nil {
+        return err
+    }
+    res, err := r.conn(ctx).ExecContext(ctx,
+        "UPDATE wallets SET display_name = $1, metadata = $2 WHERE id = $3", displayName, encoded, walletID)
+    if err != nil {
+        return err
+    }
+    rows, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return domain.ErrWalletNotFound
+    }
+    return nil
+}
+
+// Метод для установки кредитного лимита; лимит ниже уже использованного кредита отклоняется ограничениями таблицы
+func (r *PostgresWalletRepository) SetOverdraftLimit(ctx context.Context, walletID uuid.UUID, limit int64) error {
+    res, err := r.conn(ctx).ExecContext(ctx,
+        "UPDATE wallets SET overdraft_limit = $1 WHERE id = $2", limit, walletID)
+    if err != nil {
+        return mapError(err)
+    }
+    rows, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return domain.ErrWalletNotFound
+    }
+    return nil
+}
+
+// Метод для назначения кошельку тарифа комиссий
+func (r *PostgresWalletRepository) SetWalletTier(ctx context.Context, walletID uuid.UUID, tier string) error {
+    res, err := r.conn(ctx).ExecContext(ctx, "UPDATE wallets SET tier = $1 WHERE id = $2", tier, walletID)
+    if err != nil {
+        return err
+    }
+    rows, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if rows == 0 {
+        return domain.ErrWalletNotFound
+    }
+    return nil
+}
+
+// Метод для смены статуса кошелька с записью в историю; обе записи выполняются в одной транзакции
+func (r *PostgresWalletRepository) ChangeWalletStatus(ctx context.Context, change *domain.WalletStatusChange) error {
+    if change.ID == uuid.Nil {
+        change.ID = uuid.New()
+    }
+    return r.WithinTransaction(ctx, func(ctx context.Context) error {
+        res, err := r.conn(ctx).ExecContext(ctx,
+            "UPDATE wallets SET status = $1 WHERE id = $2", change.ToStatus, change.WalletID)
+        if err != nil {
+            return err
+        }
+        rows, err := res.RowsAffected()
+        if err != nil {
+            return err
+        }
+        if rows == 0 {
+            return domain.ErrWalletNotFound
+        }
+        return r.conn(ctx).QueryRowContext(ctx,
+            `INSERT INTO wallet_status_changes (id, wallet_id, from_status, to_status, reason, actor)
+             VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`,
+            change.ID, change.WalletID, change.FromStatus, change.ToStatus, change.Reason, change.Actor,
+        ).Scan(&change.CreatedAt)
+    })
+}
+
+// Метод для получения истории смены статусов кошелька
+func (r *PostgresWalletRepository) ListWalletStatusChanges(ctx context.Context, walletID uuid.UUID) ([]domain.WalletStatusChange, error) {
+    rows, err := r.conn(ctx).QueryContext(ctx,
+        `SELECT id, from_status, to_status, reason, actor, created_at FROM wallet_status_changes
+         WHERE wallet_id = $1 ORDER BY created_at, id`, walletID)
+    if err != nil {
+        return nil, err
+    }
+    changes := []domain.WalletStatusChange{}
+    err = scanRows(rows, func() error {
+        change := domain.WalletStatusChange{WalletID: walletID}
+        if err := rows.Scan(&change.ID, &change.FromStatus, &change.ToStatus, &change.Reason,
+            &change.Actor, &change.CreatedAt); err != nil {
+            return err
+        }
+        changes = append(changes, change)
+        return nil
+    })
+    return changes, err
+}
+
+// Метод для записи операции в таблицу transactions и соответствующей проводки в главную книгу
+func (r *PostgresWalletRepository) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+    if tx.ID == uuid.Nil {
+        tx.ID = uuid.New()
+    }
+    return r.WithinTransaction(ctx, func(ctx context.Context) error {
+        err := r.conn(ctx).QueryRowContext(ctx,
+            `INSERT INTO transactions (id, wallet_id, amount, operation_type, counterparty_wallet_id, related_transaction_id,
+                 exchange_rate, exchange_rate_id, hold_id, reversed_transaction_id)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING created_at`,
+            tx.ID, tx.WalletID, tx.Amount, tx.OperationType, tx.CounterpartyWalletID, tx.RelatedTransactionID,
+            tx.ExchangeRate, tx.ExchangeRateID, tx.HoldID, tx.ReversedTransactionID,
+        ).Scan(&tx.CreatedAt)
+        if err != nil {
+            return mapError(err)
+        }
+        // Проводка изменяет баланс кошелька через триггер; ограничения таблицы wallets
+        // отклоняют списание сверх доступного остатка и переполнение
+        return mapError(r.postJournalEntry(ctx, tx))
+    })
+}
+
+// Метод для занятия ключа однократной операции; параллельная транзакция с тем же ключом
+// ждёт на первичном ключе, пока первая не завершится
+func (r *PostgresWalletRepository) ClaimOperationKey(ctx context.Context, key string) error {
+    res, err := r.conn(ctx).ExecContext(ctx,
+        "INSERT INTO operation_keys (key) VALUES ($1) ON CONFLICT (key) DO NOTHING", key)
+    if err != nil {
+        return err
+    }
+    inserted, err := res.RowsAffected()
+    if err != nil {
+        return err
+    }
+    if inserted == 0 {
+        return domain.ErrOperationApplied
+    }
+    return nil
+}
+
+// Метод для получения истории операций кошелька с keyset-пагинацией по (created_at, id)
+func (r *PostgresWalletRepository) ListTransactions(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+    conditions := []string{"wallet_id = $1"}
+    args := []interface{}{filter.WalletID}
+    addArg := func(value interface{}) string {
+        args = append(args, value)
+        return fmt.Sprintf("$%d", len(args))
+    }
+
+    if filter.OperationType != "" {
+        conditions = append(conditions, "operation_type = "+addArg(filter.OperationType))
+    }
+    if filter.From != nil {
+        conditions = append(conditions, "created_at >= "+addArg(*filter.From))
+    }
+    if filter.To != nil {
+        conditions = append(conditions, "created_at < "+addArg(*filter.To))
+    }
+    if filter.After != nil {
+        createdAt := addArg(filter.After.CreatedAt)
+        id := addArg(filter.After.ID)
+        conditions = append(conditions, fmt.Sprintf("(created_at, id) < (%s, %s)", createdAt, id))
+    }
+
+    query := "SELECT " + transactionColumns + " FROM transactions WHERE " +
+        strings.Join(conditions, " AND ") +
+        " ORDER BY created_at DESC, id DESC LIMIT " + addArg(filter.Limit)
+
+    rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var transactions []domain.Transaction
+    for rows.Next() {
+        tx, err := scanTransaction(rows)
+        if err != nil {
+            return nil, err
+        }
+        transactions = append(transactions, tx)
+    }
+    return transactions, rows.Err()
+}
+
+// Метод для чтения записей счёта кошелька за период; строки читаются по одной. Порядок - по id записи
+// (postings.id растёт с каждой вставкой): у операций одной транзакции БД, например операции и её
+// комиссии, created_at совпадает. Входящий остаток без записи в transactions идёт операцией OPENING_BALANCE
+func (r *PostgresWalletRepository) StreamTransactions(ctx context.Context, walletID uuid.UUID, from, to time.Time, fn func(domain.Transaction) error) error {
+    rows, err := r.conn(ctx).QueryContext(ctx,
+        `SELECT COALESCE(t.id, e.id), a.wallet_id, p.amount, COALESCE(t.operation_type, e.description), e.created_at,
+             t.counterparty_wallet_id, t.related_transaction_id, t.exchange_rate, t.exchange_rate_id, t.hold_id,
+             t.reversed_transaction_id
+         FROM postings p
+         JOIN ledger_accounts a ON a.id = p.account_id
+         JOIN journal_entries e ON e.id = p.entry_id
+         LEFT JOIN transactions t ON t.id = e.transaction_id
+         WHERE a.wallet_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+         ORDER BY p.id`, walletID, from, to)
+    if err != nil {
+        return err
+    }
+    return scanRows(rows, func() error {
+        tx, err := scanTransaction(rows)
+        if err != nil {
+            return err
+        }
+        return fn(tx)
+    })
+}
+
+const transactionColumns = "id, wallet_id, amount, operation_type, created_at, counterparty_wallet_id, related_transaction_id, " +
+    "exchange_rate, exchange_rate_id, hold_id, reversed_transaction_id"
+
+// Функция читает запись об операции из строки результата с колонками transactionColumns
+func scanTransaction(row interface{ Scan(dest ...interface{}) error }) (domain.Transaction, error) {
+    var tx domain.Transaction
+    err := row.Scan(&tx.ID, &tx.WalletID, &tx.Amount, &tx.OperationType, &tx.CreatedAt,
+        &tx.CounterpartyWalletID, &tx.RelatedTransactionID, &tx.ExchangeRate, &tx.ExchangeRateID, &tx.HoldID,
+        &tx.ReversedTransactionID)
+    return tx, err
+}
+
+// Метод для блокировки записи об операции до конца транзакции; вызывается внутри WithinTransaction
+func (r *PostgresWalletRepository) LockTransaction(ctx context.Context, transactionID uuid.UUID) (domain.Transaction, error) {
+    tx, err := scanTransaction(r.conn(ctx).QueryRowContext(ctx,
+        "SELECT "+transactionColumns+" FROM transactions WHERE id = $1 FOR UPDATE", transactionID))
+    if err == sql.ErrNoRows {
+        return domain.Transaction{}, domain.ErrTransactionNotFound
+    }
+    return tx, err
+}
+
+// Метод для блокировки записи зачисления перевода, связанной со списанием debitID
+func (r *PostgresWalletRepository) LockTransferCredit(ctx context.Context, debitID uuid.UUID) (domain.Transaction, error) {
+    tx, err := scanTransaction(r.conn(ctx).QueryRowContext(ctx,
+        "SELECT "+transactionColumns+" FROM transactions WHERE related_transaction_id = $1 AND operation_type = 'TRANSFER' FOR UPDATE",
+        debitID))
+    if err == sql.ErrNoRows {
+        return domain.Transaction{}, domain.ErrTransactionNotFound
+    }
+    return tx, err
+}
+
+// Метод для подсчёта суммы, уже возвращённой по операции
+func (r *PostgresWalletRepository) GetReversedAmount(ctx context.Context, transactionID uuid.UUID) (int64, error) {
+    var amount int64
+    err := r.conn(ctx).QueryRowContext(ctx,
+        "SELECT COALESCE(SUM(ABS(amount)), 0) FROM transactions WHERE reversed_transaction_id = $1", transactionID,
+    ).Scan(&amount)
+    return amount, err
 }
\ No newline at end of file
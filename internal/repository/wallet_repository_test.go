@@ -3,10 +3,22 @@ package repository_test
 import (
     "context"
     "database/sql"
+    "errors"
+    "math"
+    "sync"
+    "sync/atomic"
     "testing"
+    "time"
 
+    "github.com/golang-migrate/migrate/v4"
+    "github.com/golang-migrate/migrate/v4/database/postgres"
+    _ "github.com/golang-migrate/migrate/v4/source/file"
+    "github.com/google/uuid"
     _ "github.com/lib/pq"
+
+    "WalletApp/internal/domain"
     "WalletApp/internal/repository"
+    "WalletApp/internal/usecase"
 )
 
 func setupTestDB() (*sql.DB, error) {
@@ -15,9 +27,19 @@ func setupTestDB() (*sql.DB, error) {
 	    return nil, err
 	}
 
-	// Создание таблицы для тестов
-	_, err = db.Exec("CREATE TABLE IF NOT EXISTS wallets (id UUID PRIMARY KEY,balance BIGINT NOT NULL DEFAULT 0)")
-	return db, err
+	// Применяем миграции приложения, чтобы схема совпадала с боевой
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+	    return nil, err
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../../db/migrations", "postgres", driver)
+	if err != nil {
+	    return nil, err
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+	    return nil, err
+	}
+	return db, nil
 }
 
 func TestPostgresWalletRepository(t *testing.T) {
@@ -30,7 +52,7 @@ func TestPostgresWalletRepository(t *testing.T) {
 	repo := repository.NewPostgresWalletRepository(db)
 
 	// Создаём кошелёк
-	walletID, err := repo.CreateWallet(context.Background())
+	walletID, err := repo.CreateWallet(context.Background(), domain.Wallet{Currency: "RUB"})
 	if err != nil {
 		t.Fatalf("could not create wallet: %v", err)
 	}
@@ -40,13 +62,522 @@ func TestPostgresWalletRepository(t *testing.T) {
 	    t.Fatalf("expected balance to be 0 but got %d; error:%v", balance, err)
     }
 
-	err = repo.UpdateBalance(context.Background(), walletID ,1000)
+	err = repo.CreateTransaction(context.Background(), &domain.Transaction{WalletID: walletID, Amount: 1000, OperationType: "DEPOSIT"})
 	if err != nil {
-	    t.Fatalf("could not update balance :%v", err)
+	    t.Fatalf("could not create transaction :%v", err)
     }
 
 	balance, err = repo.GetBalance(context.Background(), walletID)
 	if err != nil || balance != 1000 {
 	    t.Fatalf("expected balance to be 1000 but got %d; error:%v", balance, err)
     }
-}
\ No newline at end of file
+}
+
+func TestPostgresWalletRepository_WithinTransaction(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+
+	walletID, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if err != nil {
+	    t.Fatalf("could not create wallet: %v", err)
+	}
+
+	// Ошибка внутри транзакции откатывает и баланс, и запись операции
+	errAbort := errors.New("abort")
+	err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+	    if err := repo.CreateTransaction(ctx, &domain.Transaction{WalletID: walletID, Amount: 500, OperationType: "DEPOSIT"}); err != nil {
+	        return err
+	    }
+	    return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+	    t.Fatalf("expected abort error, got %v", err)
+	}
+
+	balance, err := repo.GetBalance(ctx, walletID)
+	if err != nil || balance != 0 {
+	    t.Fatalf("expected balance to be 0 after rollback but got %d; error:%v", balance, err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM transactions WHERE wallet_id = $1", walletID).Scan(&count); err != nil || count != 0 {
+	    t.Fatalf("expected no transactions after rollback but got %d; error:%v", count, err)
+	}
+
+	// Успешная транзакция фиксирует обе записи
+	tx := &domain.Transaction{WalletID: walletID, Amount: 700, OperationType: "DEPOSIT"}
+	err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+	    return repo.CreateTransaction(ctx, tx)
+	})
+	if err != nil {
+	    t.Fatalf("could not commit transaction: %v", err)
+	}
+	if tx.CreatedAt.IsZero() {
+	    t.Errorf("expected created_at to be filled")
+	}
+
+	balance, err = repo.GetBalance(ctx, walletID)
+	if err != nil || balance != 700 {
+	    t.Fatalf("expected balance to be 700 but got %d; error:%v", balance, err)
+	}
+}
+
+func TestPostgresWalletRepository_ClaimOperationKey(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	key := "test:" + uuid.New().String()
+
+	// Откат транзакции освобождает ключ
+	errAbort := errors.New("abort")
+	err = repo.WithinTransaction(ctx, func(ctx context.Context) error {
+	    if err := repo.ClaimOperationKey(ctx, key); err != nil {
+	        return err
+	    }
+	    return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+	    t.Fatalf("expected abort error, got %v", err)
+	}
+
+	if err := repo.ClaimOperationKey(ctx, key); err != nil {
+	    t.Fatalf("could not claim key: %v", err)
+	}
+	if err := repo.ClaimOperationKey(ctx, key); !errors.Is(err, domain.ErrOperationApplied) {
+	    t.Errorf("expected ErrOperationApplied, got %v", err)
+	}
+}
+
+func TestPostgresWalletRepository_ListTransactions(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+
+	walletID, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if err != nil {
+	    t.Fatalf("could not create wallet: %v", err)
+	}
+	for _, amount := range []int64{100, -40, 200, -10, 300} {
+	    opType := "DEPOSIT"
+	    if amount < 0 {
+	        opType = "WITHDRAW"
+	    }
+	    if err := repo.CreateTransaction(ctx, &domain.Transaction{WalletID: walletID, Amount: amount, OperationType: opType}); err != nil {
+	        t.Fatalf("could not create transaction: %v", err)
+	    }
+	}
+
+	// Обходим историю страницами по 2 записи
+	var all []domain.Transaction
+	filter := domain.TransactionFilter{WalletID: walletID, Limit: 2}
+	for {
+	    page, err := repo.ListTransactions(ctx, filter)
+	    if err != nil {
+	        t.Fatalf("could not list transactions: %v", err)
+	    }
+	    if len(page) == 0 {
+	        break
+	    }
+	    all = append(all, page...)
+	    last := page[len(page)-1]
+	    filter.After = &domain.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	if len(all) != 5 {
+	    t.Fatalf("expected 5 transactions, got %d", len(all))
+	}
+	for i := 1; i < len(all); i++ {
+	    if all[i].CreatedAt.After(all[i-1].CreatedAt) {
+	        t.Fatalf("transactions are not ordered newest-first")
+	    }
+	}
+
+	withdrawals, err := repo.ListTransactions(ctx, domain.TransactionFilter{WalletID: walletID, OperationType: "WITHDRAW", Limit: 10})
+	if err != nil || len(withdrawals) != 2 {
+	    t.Fatalf("expected 2 withdrawals but got %d; error:%v", len(withdrawals), err)
+	}
+}
+
+// Функция записывает операцию кошелька; баланс меняется её проводкой
+func record(ctx context.Context, repo *repository.PostgresWalletRepository, walletID uuid.UUID, operationType string, amount int64) error {
+	return repo.CreateTransaction(ctx, &domain.Transaction{WalletID: walletID, Amount: amount, OperationType: operationType})
+}
+
+func TestPostgresWalletRepository_Withdraw(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+
+	walletID, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if err != nil {
+	    t.Fatalf("could not create wallet: %v", err)
+	}
+	if err := record(ctx, repo, walletID, "DEPOSIT", 100); err != nil {
+	    t.Fatalf("could not deposit :%v", err)
+	}
+
+	// Списание сверх остатка отклоняется вместе с записью операции
+	if err := record(ctx, repo, walletID, "WITHDRAW", -150); !errors.Is(err, domain.ErrInsufficientFunds) {
+	    t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if err := record(ctx, repo, walletID, "WITHDRAW", -100); err != nil {
+	    t.Fatalf("could not withdraw: %v", err)
+	}
+
+	balance, err := repo.GetBalance(ctx, walletID)
+	if err != nil || balance != 0 {
+	    t.Fatalf("expected balance to be 0 but got %d; error:%v", balance, err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM transactions WHERE wallet_id = $1", walletID).Scan(&count); err != nil || count != 2 {
+	    t.Fatalf("expected 2 transactions but got %d; error:%v", count, err)
+	}
+}
+
+// wallets.balance - проекция счёта в главной книге: её меняют только проводки
+func TestPostgresWalletRepository_BalanceProjection(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	for _, amount := range []int64{500, -120, 30} {
+	    opType := "DEPOSIT"
+	    if amount < 0 {
+	        opType = "WITHDRAW"
+	    }
+	    if err := record(ctx, repo, walletID, opType, amount); err != nil {
+	        t.Fatalf("could not record %s: %v", opType, err)
+	    }
+	}
+
+	balance, _ := repo.GetBalance(ctx, walletID)
+	ledger, err := repo.GetBalanceAt(ctx, walletID, time.Now())
+	if err != nil || balance != 410 || ledger != balance {
+	    t.Fatalf("expected balance and ledger balance 410, got %d and %d; error:%v", balance, ledger, err)
+	}
+
+	// Прямое изменение баланса и записей книги отклоняется
+	if _, err := db.Exec("UPDATE wallets SET balance = balance + 1 WHERE id = $1", walletID); err == nil {
+	    t.Error("expected direct balance update to be rejected")
+	}
+	if _, err := db.Exec(`UPDATE postings SET amount = amount * 2 WHERE account_id =
+	    (SELECT id FROM ledger_accounts WHERE wallet_id = $1)`, walletID); err == nil {
+	    t.Error("expected posting update to be rejected")
+	}
+	// Замена баланса остатком книги (исправление сверкой) допускается
+	if err := repo.SetBalance(ctx, walletID, 410); err != nil {
+	    t.Errorf("expected balance equal to the ledger to be accepted, got %v", err)
+	}
+	if err := repo.SetBalance(ctx, walletID, 400); err == nil {
+	    t.Error("expected balance different from the ledger to be rejected")
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 410 {
+	    t.Errorf("expected balance 410, got %d", balance)
+	}
+}
+
+// Сотни параллельных списаний с одного кошелька не должны увести баланс в минус
+func TestConcurrentWithdrawals(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(20)
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	const (
+	    initialBalance = 100
+	    workers        = 300
+	)
+
+	walletID, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if err != nil {
+	    t.Fatalf("could not create wallet: %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: initialBalance}); err != nil {
+	    t.Fatalf("could not deposit: %v", err)
+	}
+
+	var (
+	    wg        sync.WaitGroup
+	    succeeded int64
+	    rejected  int64
+	)
+	for i := 0; i < workers; i++ {
+	    wg.Add(1)
+	    go func() {
+	        defer wg.Done()
+	        _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 1})
+	        switch {
+	        case err == nil:
+	            atomic.AddInt64(&succeeded, 1)
+	        case errors.Is(err, domain.ErrInsufficientFunds):
+	            atomic.AddInt64(&rejected, 1)
+	        default:
+	            t.Errorf("unexpected error: %v", err)
+	        }
+	    }()
+	}
+	wg.Wait()
+
+	if succeeded != initialBalance || rejected != workers-initialBalance {
+	    t.Errorf("expected %d successful and %d rejected withdrawals, got %d and %d",
+	        initialBalance, workers-initialBalance, succeeded, rejected)
+	}
+
+	balance, err := repo.GetBalance(ctx, walletID)
+	if err != nil || balance != 0 {
+	    t.Fatalf("expected balance to be 0 but got %d; error:%v", balance, err)
+	}
+
+	// Каждому успешному списанию соответствует ровно одна запись в журнале
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM transactions WHERE wallet_id = $1 AND operation_type = 'WITHDRAW'", walletID).Scan(&count); err != nil {
+	    t.Fatalf("could not count transactions: %v", err)
+	}
+	if count != initialBalance {
+	    t.Errorf("expected %d withdrawal records, got %d", initialBalance, count)
+	}
+}
+
+// Встречные переводы между двумя кошельками не должны взаимоблокироваться и терять деньги
+func TestConcurrentOppositeTransfers(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(20)
+
+	repo := repository.NewPostgresWalletRepository(db)
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	a, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	b, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	record(ctx, repo, a, "DEPOSIT", 1000)
+	record(ctx, repo, b, "DEPOSIT", 1000)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+	    wg.Add(2)
+	    go func() {
+	        defer wg.Done()
+	        if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.TRANSFER, Amount: 1, DestinationWalletID: b}); err != nil {
+	            t.Errorf("transfer a->b failed: %v", err)
+	        }
+	    }()
+	    go func() {
+	        defer wg.Done()
+	        if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: b, OperationType: usecase.TRANSFER, Amount: 1, DestinationWalletID: a}); err != nil {
+	            t.Errorf("transfer b->a failed: %v", err)
+	        }
+	    }()
+	}
+	wg.Wait()
+
+	balanceA, _ := repo.GetBalance(ctx, a)
+	balanceB, _ := repo.GetBalance(ctx, b)
+	if balanceA != 1000 || balanceB != 1000 {
+	    t.Errorf("expected both balances to be 1000, got %d and %d", balanceA, balanceB)
+	}
+}
+
+func TestPostgresWalletRepository_UnknownWallet(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	unknownID := uuid.New()
+
+	if _, err := repo.GetBalance(ctx, unknownID); !errors.Is(err, domain.ErrWalletNotFound) {
+	    t.Errorf("GetBalance: expected ErrWalletNotFound, got %v", err)
+	}
+	if err := record(ctx, repo, unknownID, "DEPOSIT", 100); !errors.Is(err, domain.ErrWalletNotFound) {
+	    t.Errorf("CreateTransaction: expected ErrWalletNotFound, got %v", err)
+	}
+	if err := repo.ReserveBalance(ctx, unknownID, 100); !errors.Is(err, domain.ErrWalletNotFound) {
+	    t.Errorf("ReserveBalance: expected ErrWalletNotFound, got %v", err)
+	}
+}
+
+func TestPostgresWalletRepository_BalanceOverflow(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+
+	walletID, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if err != nil {
+	    t.Fatalf("could not create wallet: %v", err)
+	}
+	if err := record(ctx, repo, walletID, "DEPOSIT", math.MaxInt64); err != nil {
+	    t.Fatalf("could not deposit :%v", err)
+	}
+	if err := record(ctx, repo, walletID, "DEPOSIT", 1); !errors.Is(err, domain.ErrBalanceOverflow) {
+	    t.Errorf("expected ErrBalanceOverflow, got %v", err)
+	}
+
+	// CHECK-ограничение не даёт увести баланс в минус и записью любой операции
+	other, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if err := record(ctx, repo, other, "TRANSFER", -1); !errors.Is(err, domain.ErrInsufficientFunds) {
+	    t.Errorf("expected ErrInsufficientFunds from balance constraint, got %v", err)
+	}
+}
+
+// Смена статуса кошелька сохраняется вместе с записью в истории
+func TestPostgresWalletRepository_ChangeWalletStatus(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	if wallet, _ := repo.GetWallet(ctx, walletID); wallet.Status != domain.WalletActive {
+	    t.Errorf("expected new wallet to be ACTIVE, got %q", wallet.Status)
+	}
+
+	change := domain.WalletStatusChange{
+	    WalletID:   walletID,
+	    FromStatus: domain.WalletActive,
+	    ToStatus:   domain.WalletFrozen,
+	    Reason:     "fraud check",
+	    Actor:      "ops",
+	}
+	if err := repo.ChangeWalletStatus(ctx, &change); err != nil {
+	    t.Fatalf("could not change wallet status: %v", err)
+	}
+	if wallet, _ := repo.GetWallet(ctx, walletID); wallet.Status != domain.WalletFrozen {
+	    t.Errorf("expected wallet to be FROZEN, got %q", wallet.Status)
+	}
+
+	changes, err := repo.ListWalletStatusChanges(ctx, walletID)
+	if err != nil || len(changes) != 1 {
+	    t.Fatalf("expected 1 status change, got %d; error:%v", len(changes), err)
+	}
+	if changes[0].ID != change.ID || changes[0].Reason != "fraud check" || changes[0].CreatedAt.IsZero() {
+	    t.Errorf("unexpected status change: %+v", changes[0])
+	}
+
+	missing := domain.WalletStatusChange{WalletID: uuid.New(), ToStatus: domain.WalletFrozen, Reason: "x", Actor: "ops"}
+	if err := repo.ChangeWalletStatus(ctx, &missing); !errors.Is(err, domain.ErrWalletNotFound) {
+	    t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+}
+
+// Списание и резервирование разрешены до баланса -overdraft_limit
+func TestPostgresWalletRepository_Overdraft(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	if err := record(ctx, repo, walletID, "WITHDRAW", -100); !errors.Is(err, domain.ErrInsufficientFunds) {
+	    t.Errorf("expected ErrInsufficientFunds without overdraft, got %v", err)
+	}
+	if err := repo.SetOverdraftLimit(ctx, walletID, 500); err != nil {
+	    t.Fatalf("could not set overdraft limit: %v", err)
+	}
+	if err := record(ctx, repo, walletID, "WITHDRAW", -300); err != nil {
+	    t.Fatalf("expected debit within overdraft to succeed, got %v", err)
+	}
+	if err := repo.ReserveBalance(ctx, walletID, 200); err != nil {
+	    t.Fatalf("expected reserve within overdraft to succeed, got %v", err)
+	}
+	if err := record(ctx, repo, walletID, "WITHDRAW", -1); !errors.Is(err, domain.ErrInsufficientFunds) {
+	    t.Errorf("expected ErrInsufficientFunds beyond overdraft, got %v", err)
+	}
+	wallet, _ := repo.GetWallet(ctx, walletID)
+	if wallet.Balance != -300 || wallet.OverdraftLimit != 500 || wallet.RemainingCredit() != 0 {
+	    t.Errorf("unexpected wallet: %+v", wallet)
+	}
+
+	// Ограничения таблицы не дают уменьшить лимит ниже использованного кредита
+	if err := repo.SetOverdraftLimit(ctx, walletID, 100); !errors.Is(err, domain.ErrInsufficientFunds) {
+	    t.Errorf("expected ErrInsufficientFunds for limit below used credit, got %v", err)
+	}
+}
+
+func TestPostgresWalletRepository_Metadata(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+	    t.Fatalf("could not connect to database :%v", err)
+	}
+	defer db.Close()
+
+	repo := repository.NewPostgresWalletRepository(db)
+	ctx := context.Background()
+	batch := uuid.NewString() // метка, уникальная для этого запуска теста
+
+	gold, err := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB", DisplayName: "Payroll",
+	    Metadata: map[string]string{"batch": batch, "tier": "gold"}})
+	if err != nil {
+	    t.Fatalf("could not create wallet: %v", err)
+	}
+	silver, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB", Metadata: map[string]string{"batch": batch, "tier": "silver"}})
+	plain, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	if wallet, _ := repo.GetWallet(ctx, plain); wallet.DisplayName != "" || wallet.Metadata != nil {
+	    t.Errorf("expected wallet without details, got %+v", wallet)
+	}
+	wallets, err := repo.ListWallets(ctx, domain.WalletFilter{Labels: map[string]string{"batch": batch, "tier": "gold"}, Limit: 10})
+	if err != nil || len(wallets) != 1 || wallets[0].ID != gold || wallets[0].DisplayName != "Payroll" {
+	    t.Fatalf("unexpected wallets by label: %+v; error:%v", wallets, err)
+	}
+
+	if err := repo.UpdateWalletDetails(ctx, silver, "Savings", map[string]string{"batch": batch, "tier": "gold"}); err != nil {
+	    t.Fatalf("could not update wallet details: %v", err)
+	}
+	wallets, _ = repo.ListWallets(ctx, domain.WalletFilter{Labels: map[string]string{"batch": batch, "tier": "gold"}, Limit: 10})
+	if len(wallets) != 2 || wallets[0].ID != gold || wallets[1].ID != silver || wallets[1].DisplayName != "Savings" {
+	    t.Errorf("unexpected wallets after update: %+v", wallets)
+	}
+	if err := repo.UpdateWalletDetails(ctx, uuid.New(), "", nil); !errors.Is(err, domain.ErrWalletNotFound) {
+	    t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+const maxBatchOperations = 1000 // операций в одном пакете
+
+// Метод для выполнения пакета операций DEPOSIT, WITHDRAW и TRANSFER в одной транзакции БД:
+// либо выполняются все операции, либо ни одной. Кошельки пакета (и кошельки доходов комиссий)
+// блокируются заранее в порядке id, поэтому встречные пакеты не приводят к взаимоблокировке.
+// Ошибка операции возвращается как *domain.BatchError с её номером
+func (s *walletService) PerformBatch(ctx context.Context, ops []domain.Operation) ([]domain.OperationResult, error) {
+	if len(ops) == 0 || len(ops) > maxBatchOperations {
+		return nil, fmt.Errorf("%w: %d operations, allowed 1-%d", domain.ErrInvalidBatch, len(ops), maxBatchOperations)
+	}
+
+	results := make([]domain.OperationResult, len(ops))
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Неизвестный кошелёк не блокируется; ошибка достанется операции, которая к нему обратится
+		if err := s.repo.LockWallets(ctx, s.batchWallets(ops)...); err != nil && !errors.Is(err, domain.ErrWalletNotFound) {
+			return err
+		}
+		for i, op := range ops {
+			result, err := s.PerformOperation(ctx, op)
+			if err != nil {
+				return &domain.BatchError{Index: i, Err: err}
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Метод возвращает кошельки, которые затронет пакет: участников операций и кошельки доходов комиссий
+func (s *walletService) batchWallets(ops []domain.Operation) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var walletIDs []uuid.UUID
+	add := func(walletID uuid.UUID) {
+		if walletID != uuid.Nil && !seen[walletID] {
+			seen[walletID] = true
+			walletIDs = append(walletIDs, walletID)
+		}
+	}
+	for _, op := range ops {
+		add(op.WalletID)
+		add(op.DestinationWalletID)
+	}
+	for _, walletID := range s.fees.RevenueWallets {
+		add(walletID)
+	}
+	return walletIDs
+}
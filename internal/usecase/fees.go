@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+// Метод для списания комиссии за операцию operationID: сумма по первому подходящему правилу сетки
+// списывается с кошелька payer и зачисляется на кошелёк доходов его валюты парой связанных записей FEE.
+// Вызывается внутри транзакции операции, поэтому операция без комиссии не фиксируется; кошелёк доходов
+// к этому моменту заблокирован вместе с участниками операции (lockOperationWallets).
+// С кошелька, списание с которого запрещено (замороженного), комиссия не берётся
+func (s *walletService) chargeFees(ctx context.Context, payer domain.Wallet, operationType string, amount int64, operationID uuid.UUID) ([]domain.Fee, error) {
+	fees := []domain.Fee{}
+	revenueID, ok := s.fees.RevenueWallets[payer.Currency]
+	if !ok || revenueID == payer.ID { // кошелёк доходов не платит комиссию сам себе
+		return fees, nil
+	}
+	if payer.CanDebit() != nil { // зачисление на замороженный кошелёк не должно списывать с него средства
+		return fees, nil
+	}
+	tier := payer.Tier
+	if tier == "" {
+		tier = domain.DefaultWalletTier
+	}
+	rule, ok := s.fees.Match(operationType, payer.Currency, tier, amount)
+	if !ok {
+		return fees, nil
+	}
+	flat, percentage, total, err := rule.Calculate(amount)
+	if err != nil {
+		return nil, err
+	}
+	if total <= 0 {
+		return fees, nil
+	}
+
+	// Ошибки кошелька доходов - ошибки конфигурации, а не клиента,
+	// поэтому они не оборачиваются в ошибки предметной области
+	revenue, err := s.repo.GetWallet(ctx, revenueID)
+	if err != nil {
+		return nil, fmt.Errorf("revenue wallet %s: %v", revenueID, err)
+	}
+	if revenue.Currency != payer.Currency {
+		return nil, fmt.Errorf("revenue wallet %s is in %s, fee is in %s", revenueID, revenue.Currency, payer.Currency)
+	}
+	if err := revenue.CanCredit(); err != nil {
+		return nil, fmt.Errorf("revenue wallet %s: %v", revenueID, err)
+	}
+
+	// Запись списания проверяет доступный остаток; при нехватке на комиссию ErrInsufficientFunds
+	debit := &domain.Transaction{
+		WalletID:             payer.ID,
+		Amount:               -total,
+		OperationType:        FEE,
+		CounterpartyWalletID: &revenueID,
+		RelatedTransactionID: &operationID,
+	}
+	if err := s.repo.CreateTransaction(ctx, debit); err != nil {
+		return nil, err
+	}
+	payerID := payer.ID
+	err = s.repo.CreateTransaction(ctx, &domain.Transaction{
+		WalletID:             revenueID,
+		Amount:               total,
+		OperationType:        FEE,
+		CounterpartyWalletID: &payerID,
+		RelatedTransactionID: &debit.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(fees, domain.Fee{
+		Rule:            rule.Name,
+		Amount:          total,
+		Currency:        payer.Currency,
+		Flat:            flat,
+		Percentage:      percentage,
+		WalletID:        payer.ID,
+		RevenueWalletID: revenueID,
+		TransactionID:   debit.ID,
+	}), nil
+}
+
+// Метод блокирует кошельки операции walletIDs вместе с кошельком доходов валюты плательщика payerID
+// одним вызовом LockWallets, то есть в порядке id, как и пакет операций: если бы кошелёк доходов
+// блокировался после участников, операция с ним самим в роли участника могла бы взаимоблокироваться
+// с операцией, ожидающей его для комиссии. Валюта кошелька не меняется, поэтому её можно прочитать
+// до блокировки. Если кошелька доходов нет, блокируются только участники, а ошибку конфигурации
+// вернёт chargeFees, когда комиссия понадобится
+func (s *walletService) lockOperationWallets(ctx context.Context, payerID uuid.UUID, walletIDs ...uuid.UUID) error {
+	if len(s.fees.Rules) == 0 {
+		return s.repo.LockWallets(ctx, walletIDs...)
+	}
+	payer, err := s.repo.GetWallet(ctx, payerID)
+	if err != nil {
+		return err
+	}
+	revenueID, ok := s.fees.RevenueWallets[payer.Currency]
+	if !ok {
+		return s.repo.LockWallets(ctx, walletIDs...)
+	}
+	err = s.repo.LockWallets(ctx, append([]uuid.UUID{revenueID}, walletIDs...)...)
+	if errors.Is(err, domain.ErrWalletNotFound) {
+		return s.repo.LockWallets(ctx, walletIDs...)
+	}
+	return err
+}
+
+// Метод для назначения кошельку тарифа комиссий
+func (s *walletService) SetWalletTier(ctx context.Context, walletID uuid.UUID, tier string) (domain.Wallet, error) {
+	tier, err := domain.ValidateTier(tier)
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	var wallet domain.Wallet
+	err = s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if wallet, err = s.lockWallet(ctx, walletID); err != nil {
+			return err
+		}
+		if wallet.Status == domain.WalletClosed {
+			return domain.ErrWalletClosed
+		}
+		if err := s.repo.SetWalletTier(ctx, walletID, tier); err != nil {
+			return err
+		}
+		wallet.Tier = tier
+		return nil
+	})
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	return wallet, nil
+}
+
+// Метод для получения тарифной сетки комиссий
+func (s *walletService) GetFeeSchedule(ctx context.Context) domain.FeeSchedule {
+	schedule := s.fees
+	if schedule.Rules == nil {
+		schedule.Rules = []domain.FeeRule{}
+	}
+	if schedule.RevenueWallets == nil {
+		schedule.RevenueWallets = map[string]uuid.UUID{}
+	}
+	return schedule
+}
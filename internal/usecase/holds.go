@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+const expireHoldsBatch = 100 // число блокировок, освобождаемых в одной транзакции БД
+
+var errHoldsDisabled = errors.New("hold store is not configured")
+
+// Метод для создания блокировки: сумма резервируется на кошельке, баланс по главной книге не меняется
+func (s *walletService) CreateHold(ctx context.Context, walletID uuid.UUID, amount int64, expiresAt time.Time) (domain.Hold, error) {
+	if s.holds == nil {
+		return domain.Hold{}, errHoldsDisabled
+	}
+	if err := s.validateAmount(CAPTURE, amount); err != nil {
+		return domain.Hold{}, err
+	}
+	now := time.Now()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(s.holdTTL)
+	}
+	if !expiresAt.After(now) {
+		return domain.Hold{}, fmt.Errorf("%w: hold expiry is in the past", domain.ErrInvalidOperation)
+	}
+
+	hold := domain.Hold{WalletID: walletID, Amount: amount, Status: domain.HoldActive, ExpiresAt: expiresAt}
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		wallet, err := s.lockWallet(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		if err := wallet.CanDebit(); err != nil {
+			return err
+		}
+		// Резерв - будущее списание, поэтому он проверяется по лимитам расходов уже сейчас
+		if err := s.checkSpendingLimits(ctx, walletID, amount, nil); err != nil {
+			return err
+		}
+		// Проверка доступного остатка и резервирование атомарны; при нехватке средств ErrInsufficientFunds
+		if err := s.repo.ReserveBalance(ctx, walletID, amount); err != nil {
+			return err
+		}
+		return s.holds.CreateHold(ctx, &hold)
+	})
+	if err != nil {
+		return domain.Hold{}, err
+	}
+	return hold, nil
+}
+
+// Метод для списания по блокировке: списывается amount (0 - вся сумма), остаток резерва освобождается.
+// Резерв, списание и запись CAPTURE в transactions выполняются в одной транзакции БД
+func (s *walletService) CaptureHold(ctx context.Context, holdID uuid.UUID, amount int64) (domain.Hold, error) {
+	if s.holds == nil {
+		return domain.Hold{}, errHoldsDisabled
+	}
+	if amount < 0 {
+		return domain.Hold{}, domain.ErrInvalidAmount
+	}
+
+	var hold domain.Hold
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if hold, err = s.lockActiveHold(ctx, holdID); err != nil {
+			return err
+		}
+		if amount == 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return fmt.Errorf("%w: capture exceeds held amount %d", domain.ErrInvalidAmount, hold.Amount)
+		}
+		wallet, err := s.lockWallet(ctx, hold.WalletID)
+		if err != nil {
+			return err
+		}
+		if err := wallet.CanDebit(); err != nil {
+			return err
+		}
+		// Повторная проверка ловит лимиты, снижённые после создания блокировки;
+		// сама блокировка заменяется списанием и не учитывается
+		if err := s.checkSpendingLimits(ctx, wallet.ID, amount, &hold); err != nil {
+			return err
+		}
+
+		if err := s.repo.ReleaseReserve(ctx, hold.WalletID, hold.Amount); err != nil {
+			return err
+		}
+		if err := s.repo.CreateTransaction(ctx, &domain.Transaction{
+			WalletID:      hold.WalletID,
+			Amount:        -amount,
+			OperationType: CAPTURE,
+			HoldID:        &hold.ID,
+		}); err != nil {
+			return err
+		}
+
+		hold.Status, hold.CapturedAmount = domain.HoldCaptured, amount
+		return s.holds.UpdateHold(ctx, &hold)
+	})
+	if err != nil {
+		return domain.Hold{}, err
+	}
+	return hold, nil
+}
+
+// Метод для отмены блокировки: вся сумма резерва освобождается
+func (s *walletService) VoidHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	if s.holds == nil {
+		return domain.Hold{}, errHoldsDisabled
+	}
+
+	var hold domain.Hold
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if hold, err = s.holds.LockHold(ctx, holdID); err != nil {
+			return err
+		}
+		if hold.Status != domain.HoldActive {
+			return domain.ErrHoldNotActive
+		}
+		return s.releaseHold(ctx, &hold, domain.HoldVoided)
+	})
+	if err != nil {
+		return domain.Hold{}, err
+	}
+	return hold, nil
+}
+
+// Метод для получения блокировки по id
+func (s *walletService) GetHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	if s.holds == nil {
+		return domain.Hold{}, errHoldsDisabled
+	}
+	return s.holds.GetHold(ctx, holdID)
+}
+
+// Метод для освобождения истёкших блокировок; обрабатывает их пачками, каждая в своей транзакции БД
+func (s *walletService) ExpireHolds(ctx context.Context) (int, error) {
+	if s.holds == nil {
+		return 0, nil
+	}
+
+	expired := 0
+	for {
+		var batch int
+		err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+			holds, err := s.holds.LockExpiredHolds(ctx, time.Now(), expireHoldsBatch)
+			if err != nil {
+				return err
+			}
+			for i := range holds {
+				if err := s.releaseHold(ctx, &holds[i], domain.HoldExpired); err != nil {
+					return err
+				}
+			}
+			batch = len(holds)
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+		expired += batch
+		if batch < expireHoldsBatch {
+			return expired, nil
+		}
+	}
+}
+
+// Метод блокирует строку блокировки и проверяет, что по ней ещё можно списать средства
+func (s *walletService) lockActiveHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	hold, err := s.holds.LockHold(ctx, holdID)
+	if err != nil {
+		return domain.Hold{}, err
+	}
+	if hold.Status != domain.HoldActive {
+		return domain.Hold{}, domain.ErrHoldNotActive
+	}
+	// Истёкшую блокировку освободит фоновая очистка
+	if !hold.ExpiresAt.After(time.Now()) {
+		return domain.Hold{}, fmt.Errorf("%w: hold expired at %s", domain.ErrHoldNotActive, hold.ExpiresAt.Format(time.RFC3339))
+	}
+	return hold, nil
+}
+
+// Метод освобождает резерв блокировки и переводит её в статус status
+func (s *walletService) releaseHold(ctx context.Context, hold *domain.Hold, status string) error {
+	if err := s.repo.ReleaseReserve(ctx, hold.WalletID, hold.Amount); err != nil {
+		return err
+	}
+	hold.Status = status
+	return s.holds.UpdateHold(ctx, hold)
+}
@@ -0,0 +1,551 @@
+package usecase
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+const (
+	DefaultImportParallelism = 4  // операций загрузки, выполняемых одновременно
+	MaxImportParallelism     = 32 // наибольшая допустимая параллельность загрузки
+)
+
+var (
+	errDryRun        = errors.New("dry run")        // ошибка, которой откатывается транзакция проверки операции
+	errImportStopped = errors.New("import stopped") // загрузка остановлена ошибкой другой строки
+)
+
+// ошибки операции, из-за которых отклоняется только её строка; остальные ошибки
+// (недоступность БД и т.п.) прерывают загрузку, не отмечая строку обработанной
+var rowErrors = []error{
+	domain.ErrInvalidImport,
+	domain.ErrInsufficientFunds,
+	domain.ErrWalletNotFound,
+	domain.ErrInvalidOperation,
+	domain.ErrInvalidAmount,
+	domain.ErrAmountOutOfRange,
+	domain.ErrBalanceOverflow,
+	domain.ErrInvalidDestination,
+	domain.ErrUnsupportedCurrency,
+	domain.ErrCurrencyMismatch,
+	domain.ErrRateNotFound,
+	domain.ErrQuoteNotFound,
+	domain.ErrQuoteExpired,
+	domain.ErrQuoteMismatch,
+	domain.ErrWalletFrozen,
+	domain.ErrWalletClosed,
+	domain.ErrSpendingLimitExceeded,
+}
+
+// параметры загрузки операций
+type ImportOptions struct {
+	Parallelism int                     // операций одновременно; 0 - DefaultImportParallelism
+	DryRun      bool                    // только проверить строки, ничего не фиксируя
+	From        domain.ImportCheckpoint // контрольная точка прежнего запуска: обработанные строки пропускаются
+
+	// Key - идентификатор загрузки файла, одинаковый при всех её запусках: строка выполняется
+	// с ключом из Key и номера строки, поэтому выполненная прежним запуском строка не выполняется снова
+	Key string
+
+	// CheckpointEvery - через сколько обработанных строк вызывается Checkpoint (по умолчанию после каждой);
+	// Checkpoint вызывается и в конце загрузки, при проверке не вызывается
+	CheckpointEvery int
+	Checkpoint      func(domain.ImportCheckpoint) error
+	// Reject получает отклонённые строки; вызывается до контрольной точки, которая их учитывает
+	Reject func(domain.RejectedRow) error
+}
+
+// ключ контекста с изменениями кошельков от уже проверенных операций
+type dryRunKey struct{}
+
+// изменения кошельков от операций, проверенных внутри DryRun
+type dryRunState struct {
+	mu      sync.Mutex
+	credits map[uuid.UUID]int64 // сумма зачислений
+	debits  map[uuid.UUID]int64 // сумма списаний
+}
+
+// Метод для учёта записей проверенной операции
+func (d *dryRunState) add(result domain.OperationResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, tx := range result.Transactions {
+		d.record(tx.WalletID, tx.Amount)
+	}
+	for _, fee := range result.Fees {
+		d.record(fee.WalletID, -fee.Amount)
+		d.record(fee.RevenueWalletID, fee.Amount)
+	}
+}
+
+func (d *dryRunState) record(walletID uuid.UUID, amount int64) {
+	if amount > 0 {
+		d.credits[walletID] += amount
+	} else {
+		d.debits[walletID] -= amount
+	}
+}
+
+// Метод возвращает суммы зачислений и списаний кошелька от уже проверенных операций
+func (d *dryRunState) changes(walletID uuid.UUID) (credit, debit int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.credits[walletID], d.debits[walletID]
+}
+
+// Метод для выполнения fn так, что операции DryRunOperation с переданным в fn контекстом
+// учитывают изменения кошельков от уже проверенных. Транзакция БД между операциями не держится:
+// каждая проверяется в своей короткой откатываемой транзакции
+func (s *walletService) DryRun(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(dryRunKey{}) != nil {
+		return fn(ctx)
+	}
+	return fn(context.WithValue(ctx, dryRunKey{}, &dryRunState{
+		credits: make(map[uuid.UUID]int64),
+		debits:  make(map[uuid.UUID]int64),
+	}))
+}
+
+// Метод для проверки операции: операция выполняется в транзакции, которая затем откатывается.
+// Внутри DryRun до операции в той же транзакции на её кошельки переносятся изменения от уже
+// проверенных операций: зачисления - пополнением, списания - выводом, поэтому они учитываются
+// и в остатке, и в лимитах расходов
+func (s *walletService) DryRunOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	state, _ := ctx.Value(dryRunKey{}).(*dryRunState)
+	var result domain.OperationResult
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if state != nil {
+			if err := s.applyDryRunChanges(ctx, state, op); err != nil {
+				return err
+			}
+		}
+		var err error
+		if result, err = s.PerformOperation(ctx, op); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		return domain.OperationResult{}, err
+	}
+	if state != nil {
+		state.add(result)
+	}
+	return result, nil
+}
+
+// Метод для переноса изменений от уже проверенных операций на кошельки операции op. Кошельки
+// блокируются заранее в том же порядке, что и при выполнении операции, вместе с кошельком доходов
+func (s *walletService) applyDryRunChanges(ctx context.Context, state *dryRunState, op domain.Operation) error {
+	walletIDs := []uuid.UUID{op.WalletID}
+	if op.DestinationWalletID != uuid.Nil && op.DestinationWalletID != op.WalletID {
+		walletIDs = append(walletIDs, op.DestinationWalletID)
+	}
+	if err := s.lockOperationWallets(ctx, op.WalletID, walletIDs...); err != nil {
+		// Отсутствующий кошелёк отклонит сама операция
+		if errors.Is(err, domain.ErrWalletNotFound) {
+			return nil
+		}
+		return err
+	}
+	for _, walletID := range walletIDs {
+		credit, debit := state.changes(walletID)
+		for _, change := range []struct {
+			operationType string
+			amount        int64
+		}{{DEPOSIT, credit}, {WITHDRAW, -debit}} {
+			if change.amount == 0 {
+				continue
+			}
+			err := s.repo.CreateTransaction(ctx, &domain.Transaction{
+				WalletID:      walletID,
+				Amount:        change.amount,
+				OperationType: change.operationType,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Метод для однократного выполнения операции: ключ занимается в транзакции операции
+// и освобождается вместе с её откатом
+func (s *walletService) PerformOperationOnce(ctx context.Context, key string, op domain.Operation) (domain.OperationResult, error) {
+	var result domain.OperationResult
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.ClaimOperationKey(ctx, key); err != nil {
+			return err
+		}
+		var err error
+		result, err = s.PerformOperation(ctx, op)
+		return err
+	})
+	if err != nil {
+		return domain.OperationResult{}, err
+	}
+	return result, nil
+}
+
+// ImportOperations выполняет операции из rows через service, по opts.Parallelism одновременно.
+// Строки одного кошелька (walletId или destinationWalletId) выполняются в порядке файла.
+// Строка, которую не удалось разобрать или операция которой отклонена по правилам кошелька,
+// передаётся в opts.Reject, загрузка продолжается. Другая ошибка или отмена ctx прекращают
+// чтение: начатые операции завершаются, последняя контрольная точка учитывает все обработанные
+// строки, и повторный запуск с неё не выполняет их повторно. После аварийного завершения процесса
+// строки, обработанные после последнего вызова opts.Checkpoint, обрабатываются снова: с opts.Key
+// уже выполненные из них пропускаются (считаются выполненными), без него выполняются второй раз;
+// отклонённые строки проверяются заново.
+// При opts.DryRun каждая строка проверяется в своей откатываемой транзакции с учётом предыдущих строк
+func ImportOperations(ctx context.Context, service WalletService, rows *domain.OperationReader, opts ImportOptions) (domain.ImportReport, error) {
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = DefaultImportParallelism
+	}
+	if opts.Parallelism > MaxImportParallelism {
+		opts.Parallelism = MaxImportParallelism
+	}
+	if opts.CheckpointEvery <= 0 {
+		opts.CheckpointEvery = 1
+	}
+	im := &importer{
+		opts: opts,
+		perform: func(ctx context.Context, row domain.ImportRow) error {
+			_, err := service.PerformOperation(ctx, row.Operation)
+			return err
+		},
+		skip:     make(map[int]bool),
+		done:     make(map[int]bool),
+		wallets:  make(map[uuid.UUID]*walletQueue),
+		released: make(chan struct{}, 1),
+		failed:   make(chan struct{}),
+		report: domain.ImportReport{
+			DryRun:   opts.DryRun,
+			Applied:  opts.From.Applied,
+			Rejected: opts.From.Rejected,
+			Line:     opts.From.Line,
+		},
+	}
+	switch {
+	case opts.DryRun:
+		im.perform = func(ctx context.Context, row domain.ImportRow) error {
+			_, err := service.DryRunOperation(ctx, row.Operation)
+			return err
+		}
+	case opts.Key != "":
+		im.perform = func(ctx context.Context, row domain.ImportRow) error {
+			_, err := service.PerformOperationOnce(ctx, importRowKey(opts.Key, row.Line), row.Operation)
+			if errors.Is(err, domain.ErrOperationApplied) { // выполнена прежним запуском после его контрольной точки
+				return nil
+			}
+			return err
+		}
+	}
+	for _, line := range opts.From.Done {
+		im.skip[line] = true
+	}
+	if !opts.DryRun {
+		return im.run(ctx, rows)
+	}
+
+	// Строка проверяется с учётом уже проверенных строк: очереди сохраняют порядок строк кошелька
+	var report domain.ImportReport
+	var runErr error
+	err := service.DryRun(ctx, func(ctx context.Context) error {
+		report, runErr = im.run(ctx, rows)
+		return nil
+	})
+	if err != nil {
+		return im.report, err
+	}
+	return report, runErr
+}
+
+// Метод для выполнения загрузки: строки читаются и распределяются по opts.Parallelism очередям
+func (im *importer) run(ctx context.Context, rows *domain.OperationReader) (domain.ImportReport, error) {
+	opts := im.opts
+	// Начатые операции доводятся до конца и после отмены ctx
+	workCtx := context.WithoutCancel(ctx)
+	queues := make([]chan domain.ImportRow, opts.Parallelism)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan domain.ImportRow, 1)
+		wg.Add(1)
+		go func(queue <-chan domain.ImportRow) {
+			defer wg.Done()
+			for row := range queue {
+				if !im.stopped() { // иначе строка остаётся необработанной до повторного запуска
+					im.complete(row, im.perform(workCtx, row))
+				}
+				im.release(row)
+			}
+		}(queues[i])
+	}
+
+	readErr := im.dispatch(ctx, rows, queues)
+	for _, queue := range queues {
+		close(queue)
+	}
+	wg.Wait()
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if im.err == nil {
+		im.err = readErr
+	}
+	if !opts.DryRun && opts.Checkpoint != nil {
+		if err := opts.Checkpoint(im.checkpoint()); err != nil && im.err == nil {
+			im.err = err
+		}
+	}
+	return im.report, im.err
+}
+
+// состояние загрузки операций
+type importer struct {
+	opts    ImportOptions
+	perform func(context.Context, domain.ImportRow) error
+	skip    map[int]bool // строки после From.Line, обработанные прежним запуском
+
+	mu         sync.Mutex
+	report     domain.ImportReport
+	pending    []int                      // отправленные на обработку строки после report.Line по возрастанию
+	done       map[int]bool               // обработанные строки из pending
+	processed  int                        // строк обработано после последней контрольной точки
+	wallets    map[uuid.UUID]*walletQueue // кошельки строк, которые ещё выполняются
+	released   chan struct{}              // сигнал о завершении строки для ожидающего распределения
+	err        error                      // ошибка, прервавшая загрузку
+	failed     chan struct{}
+	failedOnce sync.Once
+}
+
+// очередь, в которой выполняются строки кошелька, и число его строк в ней
+type walletQueue struct {
+	queue int
+	rows  int
+}
+
+// Метод для чтения строк и распределения их по очередям: пока у кошелька есть невыполненные строки,
+// его следующие строки попадают в ту же очередь. Возвращает ошибку чтения или отмены ctx
+func (im *importer) dispatch(ctx context.Context, rows *domain.OperationReader, queues []chan domain.ImportRow) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if im.stopped() {
+			return nil
+		}
+		row, err := rows.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if row.Line <= im.opts.From.Line || im.skip[row.Line] {
+			im.mu.Lock()
+			im.report.Skipped++
+			if row.Line > im.opts.From.Line {
+				im.pending = append(im.pending, row.Line)
+				im.finish(row.Line)
+			}
+			im.mu.Unlock()
+			continue
+		}
+
+		im.mu.Lock()
+		im.pending = append(im.pending, row.Line)
+		im.mu.Unlock()
+		if row.Err != nil {
+			im.complete(row, row.Err)
+			continue
+		}
+		queue, err := im.route(ctx, row, len(queues))
+		if err == nil {
+			select {
+			case queues[queue] <- row:
+				continue
+			case <-ctx.Done():
+				im.release(row)
+				err = ctx.Err()
+			}
+		}
+		im.mu.Lock()
+		im.pending = im.pending[:len(im.pending)-1] // строка не начата
+		im.mu.Unlock()
+		if im.stopped() {
+			return nil
+		}
+		return err
+	}
+}
+
+// Метод выбирает очередь строки. Строка перевода затрагивает два кошелька: если их строки
+// выполняются в разных очередях, распределение ждёт, пока одна из очередей закончит строки
+// своего кошелька, иначе перевод мог бы обогнать предыдущие строки получателя или отстать от следующих
+func (im *importer) route(ctx context.Context, row domain.ImportRow, queues int) (int, error) {
+	walletIDs := rowWallets(row.Operation)
+	for {
+		im.mu.Lock()
+		queue, conflict := -1, false
+		for _, walletID := range walletIDs {
+			if w, ok := im.wallets[walletID]; ok {
+				conflict = conflict || (queue >= 0 && queue != w.queue)
+				queue = w.queue
+			}
+		}
+		if !conflict {
+			if queue < 0 {
+				queue = int(binary.BigEndian.Uint64(walletIDs[0][8:]) % uint64(queues))
+			}
+			for _, walletID := range walletIDs {
+				if im.wallets[walletID] == nil {
+					im.wallets[walletID] = &walletQueue{queue: queue}
+				}
+				im.wallets[walletID].rows++
+			}
+			im.mu.Unlock()
+			return queue, nil
+		}
+		im.mu.Unlock()
+
+		select {
+		case <-im.released:
+		case <-im.failed:
+			return 0, errImportStopped
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// Метод для учёта завершения строки в очереди её кошельков
+func (im *importer) release(row domain.ImportRow) {
+	im.mu.Lock()
+	for _, walletID := range rowWallets(row.Operation) {
+		if w := im.wallets[walletID]; w != nil {
+			if w.rows--; w.rows == 0 {
+				delete(im.wallets, walletID)
+			}
+		}
+	}
+	im.mu.Unlock()
+	select {
+	case im.released <- struct{}{}:
+	default: // сигнал уже ждёт распределения
+	}
+}
+
+// Функция возвращает кошельки, которые затрагивает операция
+func rowWallets(op domain.Operation) []uuid.UUID {
+	if op.DestinationWalletID != uuid.Nil && op.DestinationWalletID != op.WalletID {
+		return []uuid.UUID{op.WalletID, op.DestinationWalletID}
+	}
+	return []uuid.UUID{op.WalletID}
+}
+
+// Метод для учёта результата строки: отклонённая строка передаётся в Reject,
+// граница обработанных строк сдвигается, при необходимости сохраняется контрольная точка
+func (im *importer) complete(row domain.ImportRow, err error) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if err != nil && !isRowError(err) {
+		im.fail(err)
+		return
+	}
+	if err != nil {
+		if im.opts.Reject != nil {
+			if err := im.opts.Reject(domain.RejectedRow{Line: row.Line, Row: row.Raw, Error: err.Error()}); err != nil {
+				im.fail(err)
+				return
+			}
+		}
+		im.report.Rejected++
+	} else {
+		im.report.Applied++
+	}
+
+	im.finish(row.Line)
+
+	im.processed++
+	if im.processed >= im.opts.CheckpointEvery && !im.opts.DryRun && im.opts.Checkpoint != nil {
+		if err := im.opts.Checkpoint(im.checkpoint()); err != nil {
+			im.fail(err)
+			return
+		}
+		im.processed = 0
+	}
+}
+
+// Метод для отметки строки обработанной и сдвига границы, до которой обработаны все строки;
+// вызывается под im.mu
+func (im *importer) finish(line int) {
+	im.done[line] = true
+	for len(im.pending) > 0 && im.done[im.pending[0]] {
+		delete(im.done, im.pending[0])
+		im.report.Line = im.pending[0]
+		im.pending = im.pending[1:]
+	}
+}
+
+// Метод возвращает контрольную точку; вызывается под im.mu
+func (im *importer) checkpoint() domain.ImportCheckpoint {
+	checkpoint := domain.ImportCheckpoint{
+		Key:      im.opts.Key,
+		Line:     im.report.Line,
+		Applied:  im.report.Applied,
+		Rejected: im.report.Rejected,
+	}
+	for line := range im.done {
+		checkpoint.Done = append(checkpoint.Done, line)
+	}
+	// Строки прежнего запуска, до которых чтение не дошло, остаются обработанными
+	for line := range im.skip {
+		if line > checkpoint.Line && !im.done[line] {
+			checkpoint.Done = append(checkpoint.Done, line)
+		}
+	}
+	sort.Ints(checkpoint.Done)
+	return checkpoint
+}
+
+// Метод для остановки загрузки из-за ошибки err; вызывается под im.mu
+func (im *importer) fail(err error) {
+	if im.err == nil {
+		im.err = err
+	}
+	im.failedOnce.Do(func() { close(im.failed) })
+}
+
+// Метод сообщает, остановлена ли загрузка из-за ошибки
+func (im *importer) stopped() bool {
+	select {
+	case <-im.failed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Функция возвращает ключ однократной операции строки загрузки
+func importRowKey(key string, line int) string {
+	return "import:" + key + ":" + strconv.Itoa(line)
+}
+
+// Функция сообщает, отклоняет ли ошибка только строку загрузки
+func isRowError(err error) bool {
+	for _, e := range rowErrors {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,345 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+	"WalletApp/internal/usecase"
+)
+
+// сервис, выполняющий операции по одной: мок хранилища не рассчитан на параллельные вызовы
+type serialService struct {
+	usecase.WalletService
+	mu sync.Mutex
+}
+
+func (s *serialService) PerformOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.WalletService.PerformOperation(ctx, op)
+}
+
+func (s *serialService) DryRunOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.WalletService.DryRunOperation(ctx, op)
+}
+
+// Функция собирает файл JSONL из строк операций
+func importFile(lines ...string) *strings.Reader {
+	return strings.NewReader(strings.Join(lines, "\n"))
+}
+
+func operationLine(walletID uuid.UUID, operationType string, amount int64) string {
+	return fmt.Sprintf(`{"walletId":"%s","operationType":"%s","amount":%d}`, walletID, operationType, amount)
+}
+
+func TestDryRunOperation(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 100)
+
+	result, err := svc.DryRunOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 40})
+	if err != nil || len(result.Transactions) != 1 || result.Transactions[0].Amount != -40 {
+		t.Fatalf("unexpected dry run result %+v; error:%v", result, err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 100 || len(repo.transactions) != 0 {
+		t.Errorf("expected dry run to change nothing, got balance %d and %d records", balance, len(repo.transactions))
+	}
+	if _, err := svc.DryRunOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 400}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	// Внутри DryRun операции видят результаты предыдущих, транзакция между ними не держится
+	err = svc.DryRun(ctx, func(ctx context.Context) error {
+		if _, err := svc.DryRunOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 300}); err != nil {
+			return err
+		}
+		if repo.openTx != 0 {
+			t.Error("expected no transaction to stay open between dry run operations")
+		}
+		if _, err := svc.DryRunOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 400}); err != nil {
+			return err
+		}
+		_, err := svc.DryRunOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 1})
+		if !errors.Is(err, domain.ErrInsufficientFunds) {
+			t.Errorf("expected ErrInsufficientFunds, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 100 || len(repo.transactions) != 0 {
+		t.Errorf("expected dry run to change nothing, got balance %d and %d records", balance, len(repo.transactions))
+	}
+}
+
+func TestImportOperations(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := &serialService{WalletService: usecase.NewWalletService(repo)}
+	ctx := context.Background()
+	wallets := make([]uuid.UUID, 8)
+	for i := range wallets {
+		wallets[i], _ = repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	}
+
+	// Пополнение и следующее за ним списание каждого кошелька: строки кошелька выполняются по порядку
+	var lines []string
+	for _, walletID := range wallets {
+		lines = append(lines, operationLine(walletID, usecase.DEPOSIT, 100))
+	}
+	for _, walletID := range wallets {
+		lines = append(lines, operationLine(walletID, usecase.WITHDRAW, 60))
+	}
+	lines = append(lines, operationLine(wallets[0], usecase.WITHDRAW, 1000), "not json", operationLine(uuid.New(), usecase.DEPOSIT, 1))
+
+	// Проверка ничего не меняет, но учитывает предыдущие строки: итог как у загрузки
+	rows, _ := domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err := usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{
+		Parallelism: 4,
+		DryRun:      true,
+		Checkpoint:  func(domain.ImportCheckpoint) error { t.Error("checkpoint saved in dry run"); return nil },
+	})
+	if err != nil || !report.DryRun || report.Applied != 16 || report.Rejected != 3 || report.Line != 19 {
+		t.Errorf("unexpected dry run report %+v; error:%v", report, err)
+	}
+	if len(repo.transactions) != 0 {
+		t.Fatalf("expected dry run to change nothing, got %d records", len(repo.transactions))
+	}
+
+	var rejected []domain.RejectedRow
+	var checkpoints []domain.ImportCheckpoint
+	rows, _ = domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err = usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{
+		Parallelism: 4,
+		Checkpoint:  func(c domain.ImportCheckpoint) error { checkpoints = append(checkpoints, c); return nil },
+		Reject:      func(row domain.RejectedRow) error { rejected = append(rejected, row); return nil },
+	})
+	if err != nil || report.Applied != 16 || report.Rejected != 3 || report.Line != 19 {
+		t.Fatalf("unexpected report %+v; error:%v", report, err)
+	}
+	for _, walletID := range wallets {
+		if balance, _ := repo.GetBalance(ctx, walletID); balance != 40 {
+			t.Errorf("expected balance 40 for %s, got %d", walletID, balance)
+		}
+	}
+	sort.Slice(rejected, func(i, j int) bool { return rejected[i].Line < rejected[j].Line })
+	if len(rejected) != 3 || rejected[0].Line != 17 || !strings.Contains(rejected[0].Error, "insufficient funds") || rejected[1].Row != "not json" {
+		t.Errorf("unexpected rejected rows: %+v", rejected)
+	}
+	if last := checkpoints[len(checkpoints)-1]; len(checkpoints) != 20 || last.Line != 19 || last.Applied != 16 || last.Rejected != 3 || len(last.Done) != 0 {
+		t.Errorf("unexpected checkpoints: %d, last %+v", len(checkpoints), last)
+	}
+
+	// Повторный запуск с контрольной точки ничего не выполняет
+	n := len(repo.transactions)
+	rows, _ = domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err = usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{From: checkpoints[len(checkpoints)-1]})
+	if err != nil || report.Skipped != 19 || report.Applied != 16 || len(repo.transactions) != n {
+		t.Errorf("expected resumed import to skip all rows, got %+v; error:%v", report, err)
+	}
+}
+
+// сервис, который выполняет переводы с задержкой, давая следующим строкам шанс их обогнать
+type slowTransfers struct {
+	usecase.WalletService
+}
+
+func (s slowTransfers) PerformOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	if op.OperationType == usecase.TRANSFER {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return s.WalletService.PerformOperation(ctx, op)
+}
+
+func TestImportOperations_TransferOrder(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := slowTransfers{&serialService{WalletService: usecase.NewWalletService(repo)}}
+	ctx := context.Background()
+
+	// Списание с получателя идёт после перевода, который его пополняет, в какой бы очереди ни был получатель
+	var lines []string
+	var receivers []uuid.UUID
+	for i := 0; i < 16; i++ {
+		sender, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+		receiver, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+		repo.UpdateBalance(ctx, sender, 100)
+		receivers = append(receivers, receiver)
+		lines = append(lines,
+			fmt.Sprintf(`{"walletId":"%s","operationType":"TRANSFER","amount":100,"destinationWalletId":"%s"}`, sender, receiver),
+			operationLine(receiver, usecase.WITHDRAW, 70))
+	}
+
+	rows, _ := domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err := usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{
+		Parallelism: 4,
+		Reject:      func(row domain.RejectedRow) error { t.Errorf("unexpected rejected row: %+v", row); return nil },
+	})
+	if err != nil || report.Applied != 32 || report.Rejected != 0 {
+		t.Fatalf("unexpected report %+v; error:%v", report, err)
+	}
+	for _, receiver := range receivers {
+		if balance, _ := repo.GetBalance(ctx, receiver); balance != 30 {
+			t.Errorf("expected balance 30, got %d", balance)
+		}
+	}
+}
+
+func TestImportOperations_Resume(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+	first, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	second, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	lines := []string{
+		operationLine(first, usecase.DEPOSIT, 1),
+		operationLine(second, usecase.DEPOSIT, 10),
+		operationLine(first, usecase.DEPOSIT, 100),
+		operationLine(second, usecase.DEPOSIT, 1000),
+	}
+
+	// Прежний запуск обработал строки 1 и 3; строка 2 ещё не выполнена
+	from := domain.ImportCheckpoint{Line: 1, Done: []int{3}, Applied: 2}
+	rows, _ := domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	var last domain.ImportCheckpoint
+	report, err := usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{
+		Parallelism: 1,
+		From:        from,
+		Checkpoint:  func(c domain.ImportCheckpoint) error { last = c; return nil },
+	})
+	if err != nil || report.Skipped != 2 || report.Applied != 4 || report.Line != 4 {
+		t.Fatalf("unexpected report %+v; error:%v", report, err)
+	}
+	if a, _ := repo.GetBalance(ctx, first); a != 0 {
+		t.Errorf("expected rows of the first wallet to be skipped, got balance %d", a)
+	}
+	if b, _ := repo.GetBalance(ctx, second); b != 1010 {
+		t.Errorf("expected balance 1010, got %d", b)
+	}
+	if last.Line != 4 || len(last.Done) != 0 {
+		t.Errorf("unexpected checkpoint %+v", last)
+	}
+}
+
+func TestImportOperations_Key(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	lines := []string{
+		operationLine(walletID, usecase.DEPOSIT, 100),
+		operationLine(walletID, usecase.WITHDRAW, 500),
+		operationLine(walletID, usecase.WITHDRAW, 30),
+	}
+
+	var last domain.ImportCheckpoint
+	rows, _ := domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err := usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{
+		Key:        "file-1",
+		Checkpoint: func(c domain.ImportCheckpoint) error { last = c; return nil },
+	})
+	if err != nil || report.Applied != 2 || report.Rejected != 1 || last.Key != "file-1" {
+		t.Fatalf("unexpected report %+v, checkpoint %+v; error:%v", report, last, err)
+	}
+
+	// Процесс упал до сохранения контрольной точки: строки обрабатываются снова,
+	// выполненные считаются выполненными без повтора, отклонённая проверяется заново
+	rows, _ = domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err = usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{Key: "file-1"})
+	if err != nil || report.Applied != 2 || report.Rejected != 1 || report.Line != 3 {
+		t.Fatalf("unexpected resumed report %+v; error:%v", report, err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 70 || len(repo.transactions) != 2 {
+		t.Errorf("expected rows to be applied once, got balance %d and %d records", balance, len(repo.transactions))
+	}
+
+	// Другая загрузка того же файла выполняет строки снова
+	rows, _ = domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	if _, err := usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{Key: "file-2"}); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 140 {
+		t.Errorf("expected balance 140, got %d", balance)
+	}
+
+	// Отклонённая операция не занимает ключ
+	if _, err := svc.PerformOperationOnce(ctx, "op", domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 1000}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if _, err := svc.PerformOperationOnce(ctx, "op", domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1}); err != nil {
+		t.Fatalf("perform once: %v", err)
+	}
+	if _, err := svc.PerformOperationOnce(ctx, "op", domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1}); !errors.Is(err, domain.ErrOperationApplied) {
+		t.Errorf("expected ErrOperationApplied, got %v", err)
+	}
+}
+
+func TestImportOperations_Stop(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	lines := []string{
+		operationLine(walletID, usecase.DEPOSIT, 1),
+		operationLine(walletID, usecase.DEPOSIT, 10),
+		operationLine(walletID, usecase.DEPOSIT, 100),
+	}
+
+	// Ошибка записи операции прерывает загрузку; строка не считается обработанной
+	var last domain.ImportCheckpoint
+	var rejected int
+	performed := 0
+	rows, _ := domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err := usecase.ImportOperations(ctx, &failingService{WalletService: svc, after: 1, performed: &performed, repo: repo}, rows, usecase.ImportOptions{
+		Parallelism: 1,
+		Checkpoint:  func(c domain.ImportCheckpoint) error { last = c; return nil },
+		Reject:      func(domain.RejectedRow) error { rejected++; return nil },
+	})
+	if err == nil || report.Applied != 1 || report.Line != 1 || last.Line != 1 || rejected != 0 {
+		t.Fatalf("expected import to stop after line 1, got %+v, checkpoint %+v; error:%v", report, last, err)
+	}
+
+	// Продолжение с контрольной точки выполняет оставшиеся строки
+	repo.failCreateTransaction = false
+	rows, _ = domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	report, err = usecase.ImportOperations(ctx, svc, rows, usecase.ImportOptions{From: last})
+	if err != nil || report.Applied != 3 || report.Skipped != 1 {
+		t.Errorf("unexpected resumed report %+v; error:%v", report, err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 111 {
+		t.Errorf("expected balance 111, got %d", balance)
+	}
+
+	// Отмена контекста прекращает чтение
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	rows, _ = domain.NewOperationReader(importFile(lines...), domain.ImportJSONL)
+	if _, err := usecase.ImportOperations(cancelled, svc, rows, usecase.ImportOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// сервис, у которого после after операций перестаёт работать запись операций
+type failingService struct {
+	usecase.WalletService
+	after     int
+	performed *int
+	repo      *mockWalletRepository
+}
+
+func (s *failingService) PerformOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	*s.performed++
+	s.repo.failCreateTransaction = *s.performed > s.after
+	return s.WalletService.PerformOperation(ctx, op)
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+var errInterestDisabled = errors.New("interest store is not configured")
+
+// Метод для начисления процентов за день date. Остатки на конец дня читаются по главной книге,
+// начисления сохраняются с точностью domain.InterestScale знаков и не перезаписываются при повторном
+// запуске. В день выплаты каждому кошельку выплачивается целая часть всех его начислений за вычетом
+// уже выплаченного - сумма считается под блокировкой кошелька, поэтому перезапуск после сбоя
+// не выплачивает проценты повторно, а дробный остаток переходит в следующую выплату
+func (s *walletService) AccrueInterest(ctx context.Context, date time.Time) (domain.InterestRun, error) {
+	if s.interest == nil {
+		return domain.InterestRun{}, errInterestDisabled
+	}
+	date = domain.AccrualDate(date)
+	if !date.Before(domain.AccrualDate(time.Now())) {
+		return domain.InterestRun{}, domain.ErrInvalidAccrualDate
+	}
+
+	var accruals []domain.InterestAccrual
+	err := s.interest.StreamEndOfDayBalances(ctx, date.AddDate(0, 0, 1), func(balance domain.EndOfDayBalance) error {
+		if balance.Status == domain.WalletClosed {
+			return nil
+		}
+		rate, ok := s.interestRates.Match(balance.Tier, balance.Currency)
+		if !ok {
+			return nil
+		}
+		amount, ok := s.interestRates.DailyInterest(balance.Balance, rate.AnnualRate)
+		if !ok {
+			return nil
+		}
+		accruals = append(accruals, domain.InterestAccrual{
+			WalletID:   balance.WalletID,
+			Date:       date,
+			Balance:    balance.Balance,
+			AnnualRate: rate.AnnualRate,
+			Amount:     amount,
+		})
+		return nil
+	})
+	if err != nil {
+		return domain.InterestRun{}, err
+	}
+	accrued, err := s.interest.CreateInterestAccruals(ctx, accruals)
+	if err != nil {
+		return domain.InterestRun{}, err
+	}
+	run := domain.InterestRun{
+		Date:           date,
+		Accrued:        accrued,
+		AlreadyAccrued: int64(len(accruals)) - accrued,
+		Payouts:        []domain.InterestPayout{},
+	}
+
+	if s.interestRates.PayoutDue(date) {
+		payees, err := s.interest.ListInterestPayees(ctx, date)
+		if err != nil {
+			return domain.InterestRun{}, err
+		}
+		for _, walletID := range payees {
+			payout, err := s.payoutInterest(ctx, walletID, date)
+			if err != nil {
+				return domain.InterestRun{}, err
+			}
+			if payout.Amount > 0 {
+				run.Payouts = append(run.Payouts, payout)
+			}
+		}
+	}
+
+	run.CompletedAt = time.Now()
+	if err := s.interest.SaveInterestRun(ctx, run); err != nil {
+		return domain.InterestRun{}, err
+	}
+	return run, nil
+}
+
+// Метод для выплаты кошельку процентов, начисленных по день through: зачисление, запись INTEREST
+// и отметка о выплате начислений выполняются в одной транзакции. Закрытому кошельку проценты
+// не выплачиваются, начисления остаются невыплаченными
+func (s *walletService) payoutInterest(ctx context.Context, walletID uuid.UUID, through time.Time) (domain.InterestPayout, error) {
+	var payout domain.InterestPayout
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		wallet, err := s.lockWallet(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		if wallet.CanCredit() != nil {
+			return nil
+		}
+		accrued, err := s.interest.GetAccruedInterest(ctx, walletID, through)
+		if err != nil {
+			return err
+		}
+		due, err := accrued.Due()
+		if err != nil || due == 0 {
+			return err
+		}
+
+		tx := &domain.Transaction{WalletID: walletID, Amount: due, OperationType: INTEREST}
+		if err := s.repo.CreateTransaction(ctx, tx); err != nil {
+			return err
+		}
+		if err := s.interest.MarkInterestPaid(ctx, walletID, through, tx.ID); err != nil {
+			return err
+		}
+		payout = domain.InterestPayout{WalletID: walletID, Currency: wallet.Currency, Amount: due, TransactionID: tx.ID}
+		return nil
+	})
+	if err != nil {
+		return domain.InterestPayout{}, err
+	}
+	return payout, nil
+}
+
+// Метод для начисления процентов за пропущенные дни: от дня после последнего начисления
+// (при первом запуске - от вчерашнего дня) до последнего завершённого к моменту now дня
+func (s *walletService) RunInterestAccrual(ctx context.Context, now time.Time) ([]domain.InterestRun, error) {
+	if s.interest == nil {
+		return nil, errInterestDisabled
+	}
+	today := domain.AccrualDate(now)
+	last, err := s.interest.GetLastInterestRun(ctx)
+	if err != nil {
+		return nil, err
+	}
+	date := today.AddDate(0, 0, -1)
+	if !last.IsZero() {
+		date = domain.AccrualDate(last).AddDate(0, 0, 1)
+	}
+
+	runs := []domain.InterestRun{}
+	for ; date.Before(today); date = date.AddDate(0, 0, 1) {
+		run, err := s.AccrueInterest(ctx, date)
+		if err != nil {
+			return runs, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"time"
+
+	"WalletApp/internal/domain"
+)
+
+// Option настраивает walletService при создании
+type Option func(*walletService)
+
+// WithAmountLimits задаёт границы сумм по типам операций (DEPOSIT, WITHDRAW, TRANSFER)
+func WithAmountLimits(limits map[string]AmountLimits) Option {
+	return func(s *walletService) {
+		s.amountLimits = limits
+	}
+}
+
+// WithSpendingLimits задаёт политику лимитов расходов по умолчанию; лимиты кошелька её переопределяют
+func WithSpendingLimits(limits []domain.SpendingLimit) Option {
+	return func(s *walletService) {
+		s.spendingLimits = limits
+	}
+}
+
+// WithDefaultCurrency задаёт валюту кошельков, созданных без явного указания валюты
+func WithDefaultCurrency(currency string) Option {
+	return func(s *walletService) {
+		s.defaultCurrency = currency
+	}
+}
+
+// WithExchangeRates подключает хранилище курсов, разрешая переводы между кошельками разных валют
+func WithExchangeRates(rates domain.ExchangeRateRepository) Option {
+	return func(s *walletService) {
+		s.rates = rates
+	}
+}
+
+// WithRoundingMode задаёт режим округления сумм при конвертации
+func WithRoundingMode(mode domain.RoundingMode) Option {
+	return func(s *walletService) {
+		s.roundingMode = mode
+	}
+}
+
+// WithQuoteTTL задаёт время, на которое котировка фиксирует курс
+func WithQuoteTTL(ttl time.Duration) Option {
+	return func(s *walletService) {
+		s.quoteTTL = ttl
+	}
+}
+
+// WithHolds подключает хранилище блокировок средств
+func WithHolds(holds domain.HoldRepository) Option {
+	return func(s *walletService) {
+		s.holds = holds
+	}
+}
+
+// WithOwners подключает хранилище владельцев кошельков
+func WithOwners(owners domain.OwnerRepository) Option {
+	return func(s *walletService) {
+		s.owners = owners
+	}
+}
+
+// WithHoldTTL задаёт срок блокировки, если он не указан при создании
+func WithHoldTTL(ttl time.Duration) Option {
+	return func(s *walletService) {
+		s.holdTTL = ttl
+	}
+}
+
+// WithFees задаёт тарифную сетку комиссий; без неё комиссии не берутся
+func WithFees(schedule domain.FeeSchedule) Option {
+	return func(s *walletService) {
+		s.fees = schedule
+	}
+}
+
+// WithInterest подключает хранилище начислений процентов и задаёт ставки по тарифам кошельков
+func WithInterest(interest domain.InterestRepository, schedule domain.InterestSchedule) Option {
+	return func(s *walletService) {
+		s.interest = interest
+		s.interestRates = schedule
+	}
+}
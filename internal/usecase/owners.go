@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+var errOwnersDisabled = errors.New("owner store is not configured")
+
+// Метод для регистрации владельца кошельков; имя обязательно
+func (s *walletService) CreateOwner(ctx context.Context, owner domain.Owner) (domain.Owner, error) {
+	if s.owners == nil {
+		return domain.Owner{}, errOwnersDisabled
+	}
+	owner.ID = uuid.Nil
+	owner.Name, owner.Email = strings.TrimSpace(owner.Name), strings.TrimSpace(owner.Email)
+	if owner.Name == "" {
+		return domain.Owner{}, domain.ErrInvalidOwner
+	}
+	if err := s.owners.CreateOwner(ctx, &owner); err != nil {
+		return domain.Owner{}, err
+	}
+	return owner, nil
+}
+
+// Метод для получения владельца по id
+func (s *walletService) GetOwner(ctx context.Context, ownerID uuid.UUID) (domain.Owner, error) {
+	if s.owners == nil {
+		return domain.Owner{}, errOwnersDisabled
+	}
+	return s.owners.GetOwner(ctx, ownerID)
+}
+
+// Метод для получения всех кошельков владельца с балансами; для неизвестного владельца ErrOwnerNotFound
+func (s *walletService) ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]domain.Wallet, error) {
+	if _, err := s.GetOwner(ctx, ownerID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListWalletsByOwner(ctx, ownerID)
+}
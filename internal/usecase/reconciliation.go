@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"WalletApp/internal/domain"
+)
+
+// Метод для сверки балансов всех кошельков с их операциями. Сначала кошельки проверяются одним
+// проходом без блокировок; при repair баланс каждого кошелька с расхождением пересчитывается
+// под блокировкой строки и заменяется ожидаемым. Расхождение главной книги только отражается в отчёте:
+// его причину нужно разбирать вручную
+func (s *walletService) ReconcileBalances(ctx context.Context, repair bool) (domain.ReconciliationReport, error) {
+	report := domain.ReconciliationReport{StartedAt: time.Now(), Repair: repair, Wallets: []domain.BalanceCheck{}}
+	err := s.repo.StreamBalanceChecks(ctx, func(check domain.BalanceCheck) error {
+		report.Checked++
+		if !check.Consistent() {
+			report.Wallets = append(report.Wallets, check)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.ReconciliationReport{}, err
+	}
+
+	report.Drifted = int64(len(report.Wallets))
+	report.Consistent = true
+	for i := range report.Wallets {
+		check := &report.Wallets[i]
+		if repair && check.Drift != 0 {
+			if err := s.repairBalance(ctx, check); err != nil {
+				check.RepairError = err.Error()
+			}
+		}
+		if check.Repaired {
+			report.Repaired++
+		}
+		// Исправленный баланс больше не расходится, расхождение книги остаётся
+		if check.LedgerDrift != 0 || (check.Drift != 0 && !check.Repaired) {
+			report.Consistent = false
+		}
+	}
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Метод пересчитывает сверку кошелька под блокировкой и заменяет баланс ожидаемым;
+// в check записываются данные на момент исправления, включая исправленное расхождение
+func (s *walletService) repairBalance(ctx context.Context, check *domain.BalanceCheck) error {
+	return s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.LockWallets(ctx, check.WalletID); err != nil {
+			return err
+		}
+		current, err := s.repo.GetBalanceCheck(ctx, check.WalletID)
+		if err != nil {
+			return err
+		}
+		if current.Drift != 0 {
+			// Баланс - проекция счёта в книге; если с операциями расходится и книга, исправлять не на что
+			if current.LedgerDrift != 0 {
+				return fmt.Errorf("ledger balance %d differs from transactions by %d", current.LedgerBalance, current.LedgerDrift)
+			}
+			if err := s.repo.SetBalance(ctx, check.WalletID, current.ExpectedBalance); err != nil {
+				return err
+			}
+			current.Repaired = true
+		}
+		*check = current
+		return nil
+	})
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+// Метод для отмены (возврата) операции: создаются компенсирующие записи REVERSAL, ссылающиеся
+// на исходную, сама она не меняется. amount = 0 возвращает весь невозвращённый остаток.
+// Запись исходной операции блокируется, поэтому параллельные возвраты не превышают её сумму
+func (s *walletService) ReverseTransaction(ctx context.Context, transactionID uuid.UUID, amount int64) (domain.Reversal, error) {
+	if amount < 0 {
+		return domain.Reversal{}, domain.ErrInvalidAmount
+	}
+
+	var reversal domain.Reversal
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		original, err := s.repo.LockTransaction(ctx, transactionID)
+		if err != nil {
+			return err
+		}
+		// Перевод отменяется по записи списания, даже если передан id зачисления
+		if original.OperationType == TRANSFER && original.Amount > 0 {
+			if original.RelatedTransactionID == nil {
+				return domain.ErrNotReversible
+			}
+			if original, err = s.repo.LockTransaction(ctx, *original.RelatedTransactionID); err != nil {
+				return err
+			}
+		}
+
+		total := original.Amount
+		if total < 0 {
+			total = -total
+		}
+		reversed, err := s.repo.GetReversedAmount(ctx, original.ID)
+		if err != nil {
+			return err
+		}
+		remaining := total - reversed
+		if remaining <= 0 {
+			return domain.ErrAlreadyReversed
+		}
+		if amount == 0 {
+			amount = remaining
+		}
+		if amount > remaining {
+			return fmt.Errorf("%w: %d of %d can still be refunded", domain.ErrRefundExceedsAmount, remaining, total)
+		}
+
+		var transactions []domain.Transaction
+		switch original.OperationType {
+		case DEPOSIT, WITHDRAW, CAPTURE:
+			transactions, err = s.reverseSingle(ctx, original, amount)
+		case TRANSFER:
+			transactions, err = s.reverseTransfer(ctx, original, amount, amount == remaining)
+		default:
+			return domain.ErrNotReversible
+		}
+		if err != nil {
+			return err
+		}
+
+		reversal = domain.Reversal{
+			TransactionID: original.ID,
+			Amount:        amount,
+			Remaining:     remaining - amount,
+			Transactions:  transactions,
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.Reversal{}, err
+	}
+	return reversal, nil
+}
+
+// Метод отменяет операцию с одним кошельком: депозит списывается обратно, снятие и списание
+// по блокировке зачисляются. Замороженный кошелёк не мешает исправлению, закрытый - мешает
+func (s *walletService) reverseSingle(ctx context.Context, original domain.Transaction, amount int64) ([]domain.Transaction, error) {
+	wallet, err := s.lockWallet(ctx, original.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := wallet.CanCredit(); err != nil {
+		return nil, err
+	}
+
+	// Депозит нельзя вернуть, если средства уже потрачены (с учётом кредитной линии):
+	// запись списания вернёт ErrInsufficientFunds
+	delta := amount
+	if original.Amount > 0 {
+		delta = -amount
+	}
+
+	tx := domain.Transaction{
+		WalletID:              wallet.ID,
+		Amount:                delta,
+		OperationType:         REVERSAL,
+		HoldID:                original.HoldID,
+		ReversedTransactionID: &original.ID,
+	}
+	if err := s.repo.CreateTransaction(ctx, &tx); err != nil {
+		return nil, err
+	}
+	return []domain.Transaction{tx}, nil
+}
+
+// Метод отменяет перевод: с получателя списывается, отправителю зачисляется. Для перевода между
+// валютами сумма получателя берётся пропорционально по исходному курсу; последний возврат
+// забирает точный остаток зачисления, чтобы округления не накапливались
+func (s *walletService) reverseTransfer(ctx context.Context, debit domain.Transaction, amount int64, final bool) ([]domain.Transaction, error) {
+	if debit.CounterpartyWalletID == nil {
+		return nil, domain.ErrNotReversible
+	}
+	credit, err := s.repo.LockTransferCredit(ctx, debit.ID)
+	if err != nil {
+		return nil, err
+	}
+	creditReversed, err := s.repo.GetReversedAmount(ctx, credit.ID)
+	if err != nil {
+		return nil, err
+	}
+	creditRemaining := credit.Amount - creditReversed
+
+	creditAmount := creditRemaining
+	if !final {
+		creditAmount = domain.Prorate(credit.Amount, amount, -debit.Amount, s.roundingMode)
+		if creditAmount > creditRemaining {
+			creditAmount = creditRemaining
+		}
+	}
+	if creditAmount <= 0 {
+		return nil, fmt.Errorf("%w: amount is too small to refund", domain.ErrInvalidAmount)
+	}
+
+	fromWalletID, toWalletID := debit.WalletID, credit.WalletID
+	if err := s.repo.LockWallets(ctx, fromWalletID, toWalletID); err != nil {
+		return nil, err
+	}
+	for _, walletID := range []uuid.UUID{fromWalletID, toWalletID} {
+		wallet, err := s.repo.GetWallet(ctx, walletID)
+		if err != nil {
+			return nil, err
+		}
+		if err := wallet.CanCredit(); err != nil {
+			return nil, err
+		}
+	}
+
+	refundDebit := domain.Transaction{
+		WalletID:              toWalletID,
+		Amount:                -creditAmount,
+		OperationType:         REVERSAL,
+		CounterpartyWalletID:  &fromWalletID,
+		ExchangeRate:          credit.ExchangeRate,
+		ExchangeRateID:        credit.ExchangeRateID,
+		ReversedTransactionID: &credit.ID,
+	}
+	if err := s.repo.CreateTransaction(ctx, &refundDebit); err != nil {
+		return nil, err
+	}
+	refundCredit := domain.Transaction{
+		WalletID:              fromWalletID,
+		Amount:                amount,
+		OperationType:         REVERSAL,
+		CounterpartyWalletID:  &toWalletID,
+		RelatedTransactionID:  &refundDebit.ID,
+		ExchangeRate:          debit.ExchangeRate,
+		ExchangeRateID:        debit.ExchangeRateID,
+		ReversedTransactionID: &debit.ID,
+	}
+	if err := s.repo.CreateTransaction(ctx, &refundCredit); err != nil {
+		return nil, err
+	}
+	return []domain.Transaction{refundDebit, refundCredit}, nil
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+// Метод для получения действующих лимитов расходов кошелька и их использования в текущих окнах
+func (s *walletService) GetSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]domain.SpendingLimitStatus, error) {
+	if _, err := s.repo.GetWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.spendingLimitStatuses(ctx, walletID, time.Now())
+}
+
+// Метод для установки лимитов кошелька: каждый лимит заменяет политику по умолчанию для своего периода
+func (s *walletService) SetSpendingLimits(ctx context.Context, walletID uuid.UUID, limits []domain.SpendingLimit) ([]domain.SpendingLimitStatus, error) {
+	for i := range limits {
+		if err := limits[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.LockWallets(ctx, walletID); err != nil {
+			return err
+		}
+		for _, limit := range limits {
+			if err := s.repo.SetSpendingLimit(ctx, walletID, limit); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.spendingLimitStatuses(ctx, walletID, time.Now())
+}
+
+// Метод для сброса лимита кошелька на период к политике по умолчанию
+func (s *walletService) ResetSpendingLimit(ctx context.Context, walletID uuid.UUID, period string) error {
+	limit := domain.SpendingLimit{Period: period}
+	if err := limit.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetWallet(ctx, walletID); err != nil {
+		return err
+	}
+	return s.repo.DeleteSpendingLimit(ctx, walletID, limit.Period)
+}
+
+// Метод проверяет, что списание amount укладывается в лимиты расходов кошелька.
+// Вызывается внутри транзакции после блокировки строки кошелька: параллельные списания
+// с того же кошелька выполняются по очереди и видят использование лимитов друг друга.
+// Активные блокировки, созданные в окне лимита, считаются списаниями этого окна: они будут списаны
+// при захвате. Захватываемая блокировка capture заменяется своим списанием и не учитывается
+func (s *walletService) checkSpendingLimits(ctx context.Context, walletID uuid.UUID, amount int64, capture *domain.Hold) error {
+	limits, err := s.effectiveSpendingLimits(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, limit := range limits {
+		if limit.Unlimited() {
+			continue
+		}
+		since, err := domain.PeriodStart(limit.Period, now)
+		if err != nil {
+			return err
+		}
+		usage, err := s.repo.GetSpendingUsage(ctx, walletID, since)
+		if err != nil {
+			return err
+		}
+		if s.holds != nil {
+			held, err := s.holds.GetActiveHoldUsage(ctx, walletID, since)
+			if err != nil {
+				return err
+			}
+			if capture != nil && !capture.CreatedAt.Before(since) {
+				held.Amount -= capture.Amount
+				held.Count--
+			}
+			usage.Amount += held.Amount
+			usage.Count += held.Count
+		}
+		if err := limit.Check(usage, amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Метод возвращает лимиты кошелька с их использованием на момент now
+func (s *walletService) spendingLimitStatuses(ctx context.Context, walletID uuid.UUID, now time.Time) ([]domain.SpendingLimitStatus, error) {
+	limits, err := s.effectiveSpendingLimits(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]domain.SpendingLimitStatus, 0, len(limits))
+	for _, limit := range limits {
+		since, err := domain.PeriodStart(limit.Period, now)
+		if err != nil {
+			return nil, err
+		}
+		usage, err := s.repo.GetSpendingUsage(ctx, walletID, since)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, domain.SpendingLimitStatus{
+			SpendingLimit: limit.SpendingLimit,
+			Override:      limit.Override,
+			WindowStart:   since,
+			Used:          usage,
+		})
+	}
+	return statuses, nil
+}
+
+// лимит вместе с признаком того, что он задан для кошелька
+type effectiveLimit struct {
+	domain.SpendingLimit
+	Override bool
+}
+
+// Метод объединяет политику по умолчанию с лимитами кошелька; результат упорядочен по периодам
+func (s *walletService) effectiveSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]effectiveLimit, error) {
+	overrides, err := s.repo.GetSpendingLimits(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	byPeriod := make(map[string]effectiveLimit, len(domain.SpendingPeriods))
+	for _, limit := range s.spendingLimits {
+		byPeriod[limit.Period] = effectiveLimit{SpendingLimit: limit}
+	}
+	for _, limit := range overrides {
+		byPeriod[limit.Period] = effectiveLimit{SpendingLimit: limit, Override: true}
+	}
+
+	limits := make([]effectiveLimit, 0, len(byPeriod))
+	for _, period := range domain.SpendingPeriods {
+		if limit, ok := byPeriod[period]; ok {
+			limits = append(limits, limit)
+		}
+	}
+	return limits, nil
+}
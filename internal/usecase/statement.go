@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"WalletApp/internal/domain"
+)
+
+// Метод для формирования выписки по кошельку за период [from, to): входящий остаток по главной книге,
+// записи счёта кошелька (операции и перенесённые при создании книги остатки) с балансом после каждой
+// и исходящий остаток. Строки передаются в out по мере чтения из БД;
+// ошибки проверки периода и поиска кошелька возвращаются до вызова out.Begin.
+// Все чтения идут из одного снимка БД, поэтому операции, зафиксированные во время выгрузки,
+// не нарушают сходимость входящего остатка, строк и исходящего остатка
+func (s *walletService) WriteStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, out domain.StatementWriter) error {
+	if from.IsZero() || to.IsZero() || !from.Before(to) {
+		return domain.ErrInvalidPeriod
+	}
+	return s.repo.WithinSnapshot(ctx, func(ctx context.Context) error {
+		return s.writeStatement(ctx, walletID, from, to, out)
+	})
+}
+
+// Метод для выгрузки выписки внутри снимка БД
+func (s *walletService) writeStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, out domain.StatementWriter) error {
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+	// Время в Postgres хранится с точностью до микросекунды: баланс на from-1мкс - остаток до начала периода
+	opening, err := s.repo.GetBalanceAt(ctx, walletID, from.Add(-time.Microsecond))
+	if err != nil {
+		return err
+	}
+
+	header := domain.StatementHeader{
+		WalletID:       walletID,
+		Currency:       wallet.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+	}
+	if err := out.Begin(header); err != nil {
+		return err
+	}
+
+	summary := domain.StatementSummary{ClosingBalance: opening}
+	err = s.repo.StreamTransactions(ctx, walletID, from, to, func(tx domain.Transaction) error {
+		summary.ClosingBalance += tx.Amount
+		summary.Count++
+		if tx.Amount > 0 {
+			summary.TotalCredits += tx.Amount
+		} else {
+			summary.TotalDebits -= tx.Amount
+		}
+		return out.Line(domain.StatementLine{Transaction: tx, Balance: summary.ClosingBalance})
+	})
+	if err != nil {
+		return err
+	}
+	return out.End(summary)
+}
@@ -0,0 +1,32 @@
+package usecase
+
+import (
+	"fmt"
+
+	"WalletApp/internal/domain"
+)
+
+// границы суммы одной операции; 0 - граница не задана
+type AmountLimits struct {
+	Min int64
+	Max int64
+}
+
+// Метод для проверки суммы операции: сумма положительна и укладывается в границы для её типа
+func (s *walletService) validateAmount(operationType string, amount int64) error {
+	if amount <= 0 {
+		return domain.ErrInvalidAmount
+	}
+
+	limits, ok := s.amountLimits[operationType]
+	if !ok {
+		return nil
+	}
+	if limits.Min > 0 && amount < limits.Min {
+		return fmt.Errorf("%w: minimum %s amount is %d", domain.ErrAmountOutOfRange, operationType, limits.Min)
+	}
+	if limits.Max > 0 && amount > limits.Max {
+		return fmt.Errorf("%w: maximum %s amount is %d", domain.ErrAmountOutOfRange, operationType, limits.Max)
+	}
+	return nil
+}
@@ -3,6 +3,9 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"WalletApp/internal/domain"
@@ -11,50 +14,622 @@ import (
 const (
 	DEPOSIT  = "DEPOSIT" // депозит
 	WITHDRAW = "WITHDRAW" // снятие
+	TRANSFER = "TRANSFER" // перевод на другой кошелёк
+	CAPTURE  = "CAPTURE"  // списание по блокировке
+	REVERSAL = "REVERSAL" // отмена (возврат) ранее выполненной операции
+	FEE      = "FEE"      // комиссия за операцию
+	INTEREST = "INTEREST" // выплата процентов на остаток
 )
 
+const (
+	DefaultTransactionsLimit = 20  // размер страницы истории по умолчанию
+	MaxTransactionsLimit     = 100 // максимальный размер страницы истории
+	DefaultWalletsLimit      = 50  // размер списка кошельков по умолчанию
+	MaxWalletsLimit          = 500 // максимальный размер списка кошельков
+)
+
+const DefaultCurrency = "RUB" // валюта кошелька, если она не указана при создании
+
+const (
+	DefaultRoundingMode = domain.RoundHalfEven // округление сумм при конвертации по умолчанию
+	DefaultQuoteTTL     = 30 * time.Second     // время жизни котировки по умолчанию
+	DefaultHoldTTL      = 7 * 24 * time.Hour   // срок блокировки средств по умолчанию
+)
+
+var errExchangeRatesDisabled = errors.New("exchange rate store is not configured")
+
 // WalletService бизнес-логика кошельков
 type WalletService interface {
 	GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error)
-	CreateWallet(ctx context.Context) (uuid.UUID, error)
-	PerformOperation(ctx context.Context, walletID uuid.UUID, operationType string, amount int64) error
+	GetWallet(ctx context.Context, walletID uuid.UUID) (domain.Wallet, error)
+	// GetBalanceAt возвращает баланс кошелька по главной книге на момент asOf
+	GetBalanceAt(ctx context.Context, walletID uuid.UUID, asOf time.Time) (domain.BalanceSnapshot, error)
+	// CreateWallet создаёт кошелёк в валюте wallet.Currency (пустая - валюта по умолчанию) для владельца wallet.OwnerID
+	// с именем wallet.DisplayName и метаданными wallet.Metadata
+	CreateWallet(ctx context.Context, wallet domain.Wallet) (uuid.UUID, error)
+	// UpdateWallet меняет отображаемое имя и метаданные кошелька и возвращает обновлённый кошелёк
+	UpdateWallet(ctx context.Context, walletID uuid.UUID, update domain.WalletUpdate) (domain.Wallet, error)
+	// ListWallets возвращает кошельки с метками filter.Labels в порядке создания
+	ListWallets(ctx context.Context, filter domain.WalletFilter) ([]domain.Wallet, error)
+	// PerformOperation выполняет операцию и возвращает её записи с расшифровкой списанных комиссий
+	PerformOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error)
+	// PerformBatch выполняет операции пакета по порядку в одной транзакции: при ошибке любой операции
+	// не выполняется ни одна, ошибка возвращается как *domain.BatchError с номером операции
+	PerformBatch(ctx context.Context, ops []domain.Operation) ([]domain.OperationResult, error)
+	// PerformOperationOnce выполняет операцию в одной транзакции с занятием ключа key: если операция
+	// с этим ключом уже выполнена, возвращает domain.ErrOperationApplied и ничего не меняет
+	PerformOperationOnce(ctx context.Context, key string, op domain.Operation) (domain.OperationResult, error)
+	// DryRun выполняет fn так, что операции DryRunOperation с переданным в fn контекстом видят
+	// результаты предыдущих; транзакция БД между операциями не держится, ничего не фиксируется
+	DryRun(ctx context.Context, fn func(ctx context.Context) error) error
+	// DryRunOperation проверяет операцию по текущему состоянию кошельков (внутри DryRun - с учётом
+	// уже проверенных операций) и возвращает результат, который получила бы операция
+	DryRunOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error)
+	ListTransactions(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error)
+	// WriteStatement передаёт в out выписку по кошельку за период [from, to) с балансом после каждой операции
+	WriteStatement(ctx context.Context, walletID uuid.UUID, from, to time.Time, out domain.StatementWriter) error
+	// ReverseTransaction возвращает amount (0 - весь невозвращённый остаток) по операции transactionID
+	// компенсирующими записями REVERSAL
+	ReverseTransaction(ctx context.Context, transactionID uuid.UUID, amount int64) (domain.Reversal, error)
+	GetLedgerSummary(ctx context.Context) ([]domain.LedgerSummary, error)
+	// ReconcileBalances сверяет баланс каждого кошелька с его операциями; при repair
+	// исправляет wallets.balance по операциям
+	ReconcileBalances(ctx context.Context, repair bool) (domain.ReconciliationReport, error)
+	// AccrueInterest начисляет проценты на остатки кошельков на конец дня date и, если день выплаты,
+	// выплачивает накопленное; повторный вызов за ту же дату ничего не начисляет и не выплачивает повторно
+	AccrueInterest(ctx context.Context, date time.Time) (domain.InterestRun, error)
+	// RunInterestAccrual начисляет проценты за все завершённые к моменту now дни после последнего начисления
+	RunInterestAccrual(ctx context.Context, now time.Time) ([]domain.InterestRun, error)
+
+	AddExchangeRates(ctx context.Context, rates []domain.ExchangeRate) ([]domain.ExchangeRate, error)
+	ListExchangeRates(ctx context.Context) ([]domain.ExchangeRate, error)
+	// QuoteExchange фиксирует текущий курс для суммы amount на время жизни котировки
+	QuoteExchange(ctx context.Context, fromCurrency, toCurrency string, amount int64) (domain.FXQuote, error)
+
+	// CreateHold резервирует amount до expiresAt (нулевое значение - срок по умолчанию)
+	CreateHold(ctx context.Context, walletID uuid.UUID, amount int64, expiresAt time.Time) (domain.Hold, error)
+	// CaptureHold списывает amount (0 - всю сумму) по блокировке и освобождает остаток
+	CaptureHold(ctx context.Context, holdID uuid.UUID, amount int64) (domain.Hold, error)
+	VoidHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error)
+	GetHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error)
+	// ExpireHolds освобождает средства блокировок с истёкшим сроком и возвращает их число
+	ExpireHolds(ctx context.Context) (int, error)
+
+	// ChangeWalletStatus переводит кошелёк в статус status, записывая причину и автора изменения
+	ChangeWalletStatus(ctx context.Context, walletID uuid.UUID, status, reason, actor string) (domain.WalletStatusChange, error)
+	ListWalletStatusChanges(ctx context.Context, walletID uuid.UUID) ([]domain.WalletStatusChange, error)
+	// SetOverdraftLimit устанавливает кредитную линию кошелька и возвращает обновлённый кошелёк
+	SetOverdraftLimit(ctx context.Context, walletID uuid.UUID, limit int64) (domain.Wallet, error)
+	// SetWalletTier назначает кошельку тариф комиссий и возвращает обновлённый кошелёк
+	SetWalletTier(ctx context.Context, walletID uuid.UUID, tier string) (domain.Wallet, error)
+	// GetFeeSchedule возвращает тарифную сетку комиссий
+	GetFeeSchedule(ctx context.Context) domain.FeeSchedule
+
+	// GetSpendingLimits возвращает действующие лимиты расходов кошелька с их использованием
+	GetSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]domain.SpendingLimitStatus, error)
+	// SetSpendingLimits задаёт кошельку лимиты, переопределяющие политику по умолчанию
+	SetSpendingLimits(ctx context.Context, walletID uuid.UUID, limits []domain.SpendingLimit) ([]domain.SpendingLimitStatus, error)
+	// ResetSpendingLimit возвращает лимит кошелька на период к политике по умолчанию
+	ResetSpendingLimit(ctx context.Context, walletID uuid.UUID, period string) error
+
+	CreateOwner(ctx context.Context, owner domain.Owner) (domain.Owner, error)
+	GetOwner(ctx context.Context, ownerID uuid.UUID) (domain.Owner, error)
+	// ListOwnerWallets возвращает кошельки владельца с балансами в порядке создания
+	ListOwnerWallets(ctx context.Context, ownerID uuid.UUID) ([]domain.Wallet, error)
 }
 
 // Структура walletService реализует интерфейс WalletService
 type walletService struct {
 	repo domain.WalletRepository
+
+	amountLimits    map[string]AmountLimits // границы сумм по типам операций
+	spendingLimits  []domain.SpendingLimit  // политика лимитов расходов по умолчанию
+	defaultCurrency string                  // валюта новых кошельков по умолчанию
+
+	rates        domain.ExchangeRateRepository // курсы обмена; nil - переводы между валютами запрещены
+	roundingMode domain.RoundingMode           // округление сумм при конвертации
+	quoteTTL     time.Duration                 // время жизни котировки
+
+	holds   domain.HoldRepository // блокировки средств; nil - блокировки недоступны
+	holdTTL time.Duration         // срок блокировки по умолчанию
+
+	owners domain.OwnerRepository // владельцы кошельков; nil - владельцы недоступны
+
+	fees domain.FeeSchedule // тарифная сетка комиссий; без правил комиссии не берутся
+
+	interest      domain.InterestRepository // начисления процентов; nil - проценты не начисляются
+	interestRates domain.InterestSchedule   // ставки процентов по тарифам кошельков
 }
 
 // экземпляр
-func NewWalletService(repo domain.WalletRepository) WalletService {
-	return &walletService{repo: repo}
+func NewWalletService(repo domain.WalletRepository, opts ...Option) WalletService {
+	s := &walletService{
+		repo:            repo,
+		defaultCurrency: DefaultCurrency,
+		roundingMode:    DefaultRoundingMode,
+		quoteTTL:        DefaultQuoteTTL,
+		holdTTL:         DefaultHoldTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Метод для получения текущего баланса кошелька по id
+// Метод для получения доступного баланса кошелька по id (без заблокированных средств)
 func (s *walletService) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
-	return s.repo.GetBalance(ctx, walletID)
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return 0, err
+	}
+	return wallet.Available(), nil
+}
+
+// Метод для получения кошелька с балансом и валютой
+func (s *walletService) GetWallet(ctx context.Context, walletID uuid.UUID) (domain.Wallet, error) {
+	return s.repo.GetWallet(ctx, walletID)
+}
+
+// Метод для получения баланса кошелька на момент asOf по записанным операциям
+func (s *walletService) GetBalanceAt(ctx context.Context, walletID uuid.UUID, asOf time.Time) (domain.BalanceSnapshot, error) {
+	wallet, err := s.repo.GetWallet(ctx, walletID)
+	if err != nil {
+		return domain.BalanceSnapshot{}, err
+	}
+	balance, err := s.repo.GetBalanceAt(ctx, walletID, asOf)
+	if err != nil {
+		return domain.BalanceSnapshot{}, err
+	}
+	return domain.BalanceSnapshot{WalletID: walletID, Currency: wallet.Currency, AsOf: asOf, Balance: balance}, nil
+}
+
+// Метод для создания нового кошелька в валюте wallet.Currency (пустая строка - валюта по умолчанию);
+// если указан владелец, кошелёк привязывается к нему. Имя и метаданные сохраняются вместе с кошельком
+func (s *walletService) CreateWallet(ctx context.Context, wallet domain.Wallet) (uuid.UUID, error) {
+	if wallet.Currency == "" {
+		wallet.Currency = s.defaultCurrency
+	}
+	c, err := domain.LookupCurrency(wallet.Currency)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	wallet.DisplayName = strings.TrimSpace(wallet.DisplayName)
+	if err := wallet.ValidateDetails(); err != nil {
+		return uuid.Nil, err
+	}
+	return s.repo.CreateWallet(ctx, domain.Wallet{
+		Currency:    c.Code,
+		OwnerID:     wallet.OwnerID,
+		DisplayName: wallet.DisplayName,
+		Metadata:    wallet.Metadata,
+	})
+}
+
+// Метод для изменения отображаемого имени и метаданных кошелька; строка кошелька блокируется,
+// чтобы параллельные изменения разных ключей не затирали друг друга
+func (s *walletService) UpdateWallet(ctx context.Context, walletID uuid.UUID, update domain.WalletUpdate) (domain.Wallet, error) {
+	var wallet domain.Wallet
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		current, err := s.lockWallet(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		wallet = update.Apply(current)
+		if err := wallet.ValidateDetails(); err != nil {
+			return err
+		}
+		return s.repo.UpdateWalletDetails(ctx, walletID, wallet.DisplayName, wallet.Metadata)
+	})
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	return wallet, nil
+}
+
+// Метод для получения кошельков по меткам метаданных
+func (s *walletService) ListWallets(ctx context.Context, filter domain.WalletFilter) ([]domain.Wallet, error) {
+	// Метки проверяются по тем же правилам, что и метаданные
+	if err := (domain.Wallet{Metadata: filter.Labels}).ValidateDetails(); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultWalletsLimit
+	}
+	if filter.Limit > MaxWalletsLimit {
+		filter.Limit = MaxWalletsLimit
+	}
+	return s.repo.ListWallets(ctx, filter)
 }
 
-// Метод для создания нового кошелька и возврата его id
-func (s *walletService) CreateWallet(ctx context.Context) (uuid.UUID, error) {
-	return s.repo.CreateWallet(ctx)
+// Метод для выполнения операций (депозит/снятие/перевод) с кошельком.
+// Изменение баланса, запись в transactions и комиссия выполняются в одной транзакции БД
+func (s *walletService) PerformOperation(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	if err := s.validateAmount(op.OperationType, op.Amount); err != nil {
+		return domain.OperationResult{}, err
+	}
+	op.Currency = strings.ToUpper(op.Currency)
+	if op.OperationType == TRANSFER {
+		return s.transfer(ctx, op)
+	}
+
+	walletID, operationType, amount := op.WalletID, op.OperationType, op.Amount
+	var result domain.OperationResult
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.lockOperationWallets(ctx, walletID, walletID); err != nil {
+			return err
+		}
+		wallet, err := s.repo.GetWallet(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		if op.Currency != "" && op.Currency != wallet.Currency {
+			return domain.ErrCurrencyMismatch
+		}
+
+		var delta int64
+		switch operationType {
+		case DEPOSIT:
+			if err := wallet.CanCredit(); err != nil { // На замороженный кошелёк зачислять можно, на закрытый нет
+				return err
+			}
+			delta = amount
+		case WITHDRAW:
+			if err := wallet.CanDebit(); err != nil {
+				return err
+			}
+			if err := s.checkSpendingLimits(ctx, walletID, amount, nil); err != nil {
+				return err
+			}
+			delta = -amount
+		default:
+			return domain.ErrInvalidOperation // Ошибка при неверном типе операции
+		}
+
+		// Запись операции изменяет баланс; проверка остатка с учётом кредитной линии и списание
+		// атомарны на уровне БД, при нехватке средств ErrInsufficientFunds
+		tx := &domain.Transaction{
+			WalletID:      walletID,
+			Amount:        delta,
+			OperationType: operationType,
+		}
+		if err := s.repo.CreateTransaction(ctx, tx); err != nil {
+			return err
+		}
+		fees, err := s.chargeFees(ctx, wallet, operationType, amount, tx.ID)
+		if err != nil {
+			return err
+		}
+		result = domain.OperationResult{Transactions: []domain.Transaction{*tx}, Fees: fees}
+		return nil
+	})
+	if err != nil {
+		return domain.OperationResult{}, err
+	}
+	return result, nil
 }
 
-// Метод для выполнения операций (депозит/снятие) с кошельком
-func (s *walletService) PerformOperation(ctx context.Context, walletID uuid.UUID, operationType string, amount int64) error {
-	switch operationType {
-	case DEPOSIT:
-		return s.repo.UpdateBalance(ctx, walletID, amount) // Увеличиваем баланс
-	case WITHDRAW:
-		balance, err := s.repo.GetBalance(ctx, walletID)
+// Метод для перевода между кошельками: списание, зачисление и пара связанных записей
+// в transactions выполняются в одной транзакции БД. Если валюты кошельков различаются,
+// сумма зачисления пересчитывается по котировке op.QuoteID или по текущему курсу.
+// Комиссия списывается с отправителя в его валюте
+func (s *walletService) transfer(ctx context.Context, op domain.Operation) (domain.OperationResult, error) {
+	fromWalletID, toWalletID, amount := op.WalletID, op.DestinationWalletID, op.Amount
+	if toWalletID == uuid.Nil || toWalletID == fromWalletID {
+		return domain.OperationResult{}, domain.ErrInvalidDestination
+	}
+
+	var result domain.OperationResult
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Блокируем оба кошелька (и кошелёк доходов) заранее в едином порядке, чтобы встречные переводы не взаимоблокировались
+		if err := s.lockOperationWallets(ctx, fromWalletID, fromWalletID, toWalletID); err != nil {
+			return err
+		}
+		from, err := s.repo.GetWallet(ctx, fromWalletID)
+		if err != nil {
+			return err
+		}
+		to, err := s.repo.GetWallet(ctx, toWalletID)
+		if err != nil {
+			return err
+		}
+		if op.Currency != "" && op.Currency != from.Currency {
+			return domain.ErrCurrencyMismatch
+		}
+		if err := from.CanDebit(); err != nil {
+			return err
+		}
+		if err := s.checkSpendingLimits(ctx, from.ID, amount, nil); err != nil {
+			return err
+		}
+		if err := to.CanCredit(); err != nil {
+			return err
+		}
+
+		credit := amount
+		var exchangeRate *string
+		var exchangeRateID *uuid.UUID
+		if from.Currency != to.Currency {
+			quote, err := s.conversion(ctx, op, from.Currency, to.Currency)
+			if err != nil {
+				return err
+			}
+			credit, exchangeRate, exchangeRateID = quote.ConvertedAmount, &quote.Rate, &quote.RateID
+		} else if op.QuoteID != nil {
+			return domain.ErrQuoteMismatch
+		}
+
+		debit := &domain.Transaction{
+			WalletID:             fromWalletID,
+			Amount:               -amount,
+			OperationType:        TRANSFER,
+			CounterpartyWalletID: &toWalletID,
+			ExchangeRate:         exchangeRate,
+			ExchangeRateID:       exchangeRateID,
+		}
+		if err := s.repo.CreateTransaction(ctx, debit); err != nil {
+			return err
+		}
+		creditTx := &domain.Transaction{
+			WalletID:             toWalletID,
+			Amount:               credit,
+			OperationType:        TRANSFER,
+			CounterpartyWalletID: &fromWalletID,
+			RelatedTransactionID: &debit.ID,
+			ExchangeRate:         exchangeRate,
+			ExchangeRateID:       exchangeRateID,
+		}
+		if err := s.repo.CreateTransaction(ctx, creditTx); err != nil {
+			return err
+		}
+		fees, err := s.chargeFees(ctx, from, TRANSFER, amount, debit.ID)
 		if err != nil {
 			return err
 		}
-		if balance < amount {
-			return errors.New("insufficient funds") // Ошибка при недостатке средств
+		result = domain.OperationResult{Transactions: []domain.Transaction{*debit, *creditTx}, Fees: fees}
+		return nil
+	})
+	if err != nil {
+		return domain.OperationResult{}, err
+	}
+	return result, nil
+}
+
+// Метод определяет курс и сумму зачисления перевода между валютами. Котировка из op.QuoteID
+// используется однократно и должна быть выдана на ту же пару валют и сумму
+func (s *walletService) conversion(ctx context.Context, op domain.Operation, fromCurrency, toCurrency string) (domain.FXQuote, error) {
+	if s.rates == nil {
+		return domain.FXQuote{}, domain.ErrCurrencyMismatch
+	}
+	if op.QuoteID == nil {
+		return s.quote(ctx, fromCurrency, toCurrency, op.Amount)
+	}
+
+	quote, err := s.rates.UseQuote(ctx, *op.QuoteID, time.Now())
+	if err != nil {
+		return domain.FXQuote{}, err
+	}
+	if quote.FromCurrency != fromCurrency || quote.ToCurrency != toCurrency || quote.Amount != op.Amount {
+		return domain.FXQuote{}, domain.ErrQuoteMismatch
+	}
+	return quote, nil
+}
+
+// Метод для расчёта суммы по текущему курсу пары; котировка не сохраняется
+func (s *walletService) quote(ctx context.Context, fromCurrency, toCurrency string, amount int64) (domain.FXQuote, error) {
+	from, err := domain.LookupCurrency(fromCurrency)
+	if err != nil {
+		return domain.FXQuote{}, err
+	}
+	to, err := domain.LookupCurrency(toCurrency)
+	if err != nil {
+		return domain.FXQuote{}, err
+	}
+	if from.Code == to.Code {
+		return domain.FXQuote{}, fmt.Errorf("%w: currencies of the exchange are the same", domain.ErrCurrencyMismatch)
+	}
+
+	rate, err := s.rates.GetRate(ctx, from.Code, to.Code, time.Now())
+	if err != nil {
+		return domain.FXQuote{}, err
+	}
+	converted, err := domain.Convert(amount, from, to, rate.Rate, s.roundingMode)
+	if err != nil {
+		return domain.FXQuote{}, err
+	}
+	if converted <= 0 {
+		return domain.FXQuote{}, fmt.Errorf("%w: amount is too small to convert", domain.ErrInvalidAmount)
+	}
+	return domain.FXQuote{
+		FromCurrency:    from.Code,
+		ToCurrency:      to.Code,
+		Rate:            rate.Rate,
+		RateID:          rate.ID,
+		Amount:          amount,
+		ConvertedAmount: converted,
+	}, nil
+}
+
+// Метод для получения котировки: текущий курс фиксируется для суммы amount на время quoteTTL
+func (s *walletService) QuoteExchange(ctx context.Context, fromCurrency, toCurrency string, amount int64) (domain.FXQuote, error) {
+	if s.rates == nil {
+		return domain.FXQuote{}, domain.ErrRateNotFound
+	}
+	if err := s.validateAmount(TRANSFER, amount); err != nil {
+		return domain.FXQuote{}, err
+	}
+	quote, err := s.quote(ctx, fromCurrency, toCurrency, amount)
+	if err != nil {
+		return domain.FXQuote{}, err
+	}
+	quote.ExpiresAt = time.Now().Add(s.quoteTTL)
+	if err := s.rates.CreateQuote(ctx, &quote); err != nil {
+		return domain.FXQuote{}, err
+	}
+	return quote, nil
+}
+
+// Метод для загрузки новых версий курсов; курс без effectiveAt действует с момента загрузки
+func (s *walletService) AddExchangeRates(ctx context.Context, rates []domain.ExchangeRate) ([]domain.ExchangeRate, error) {
+	if s.rates == nil {
+		return nil, errExchangeRatesDisabled
+	}
+	now := time.Now()
+	for i := range rates {
+		rate := &rates[i]
+		base, err := domain.LookupCurrency(rate.BaseCurrency)
+		if err != nil {
+			return nil, err
+		}
+		quote, err := domain.LookupCurrency(rate.QuoteCurrency)
+		if err != nil {
+			return nil, err
+		}
+		if base.Code == quote.Code {
+			return nil, fmt.Errorf("%w: base and quote currency are the same", domain.ErrInvalidRate)
 		}
-		return s.repo.UpdateBalance(ctx, walletID, -amount) // Уменьшаем баланс
+		if _, err := domain.ParseRate(rate.Rate); err != nil {
+			return nil, fmt.Errorf("%w: %s/%s %q", err, base.Code, quote.Code, rate.Rate)
+		}
+		rate.BaseCurrency, rate.QuoteCurrency, rate.Rate = base.Code, quote.Code, strings.TrimSpace(rate.Rate)
+		if rate.EffectiveAt.IsZero() {
+			rate.EffectiveAt = now
+		}
+	}
+	if len(rates) == 0 {
+		return []domain.ExchangeRate{}, nil
+	}
+	if err := s.rates.AddRates(ctx, rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// Метод для получения действующих курсов всех пар
+func (s *walletService) ListExchangeRates(ctx context.Context) ([]domain.ExchangeRate, error) {
+	if s.rates == nil {
+		return []domain.ExchangeRate{}, nil
+	}
+	return s.rates.ListRates(ctx, time.Now())
+}
+
+// Метод для получения страницы истории операций кошелька (от новых к старым)
+func (s *walletService) ListTransactions(ctx context.Context, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	switch filter.OperationType {
+	case "", DEPOSIT, WITHDRAW, TRANSFER, CAPTURE, REVERSAL, FEE, INTEREST:
 	default:
-		return errors.New("invalid operation type") // Ошибка при неверном типе операции
+		return domain.TransactionPage{}, domain.ErrInvalidOperation
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultTransactionsLimit
+	}
+	if filter.Limit > MaxTransactionsLimit {
+		filter.Limit = MaxTransactionsLimit
+	}
+	// Пустая история неизвестного кошелька не отличалась бы от истории нового
+	if _, err := s.repo.GetWallet(ctx, filter.WalletID); err != nil {
+		return domain.TransactionPage{}, err
+	}
+
+	limit := filter.Limit
+	filter.Limit++ // лишняя запись показывает, есть ли следующая страница
+	transactions, err := s.repo.ListTransactions(ctx, filter)
+	if err != nil {
+		return domain.TransactionPage{}, err
+	}
+
+	page := domain.TransactionPage{Transactions: transactions}
+	if len(transactions) > limit {
+		page.Transactions = transactions[:limit]
+		last := page.Transactions[limit-1]
+		page.NextCursor = domain.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	if page.Transactions == nil {
+		page.Transactions = []domain.Transaction{}
+	}
+	return page, nil
+}
+
+// Метод блокирует строку кошелька до конца транзакции и возвращает кошелёк:
+// статус не сменится, пока операция не завершится. Должен вызываться внутри WithinTransaction
+func (s *walletService) lockWallet(ctx context.Context, walletID uuid.UUID) (domain.Wallet, error) {
+	if err := s.repo.LockWallets(ctx, walletID); err != nil {
+		return domain.Wallet{}, err
+	}
+	return s.repo.GetWallet(ctx, walletID)
+}
+
+// Метод для смены статуса кошелька: переход проверяется по таблице допустимых переходов,
+// новый статус и запись в истории сохраняются в одной транзакции БД
+func (s *walletService) ChangeWalletStatus(ctx context.Context, walletID uuid.UUID, status, reason, actor string) (domain.WalletStatusChange, error) {
+	change := domain.WalletStatusChange{
+		WalletID: walletID,
+		ToStatus: strings.ToUpper(status),
+		Reason:   reason,
+		Actor:    actor,
+	}
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		wallet, err := s.lockWallet(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		if err := wallet.CheckTransition(change.ToStatus); err != nil {
+			return err
+		}
+		change.FromStatus = wallet.Status
+		return s.repo.ChangeWalletStatus(ctx, &change)
+	})
+	if err != nil {
+		return domain.WalletStatusChange{}, err
+	}
+	return change, nil
+}
+
+// Метод для установки кредитной линии: списания разрешены до баланса -limit.
+// Лимит нельзя уменьшить ниже уже использованного кредита
+func (s *walletService) SetOverdraftLimit(ctx context.Context, walletID uuid.UUID, limit int64) (domain.Wallet, error) {
+	if limit < 0 {
+		return domain.Wallet{}, fmt.Errorf("%w: overdraft limit must not be negative", domain.ErrInvalidAmount)
+	}
+	var wallet domain.Wallet
+	err := s.repo.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if wallet, err = s.lockWallet(ctx, walletID); err != nil {
+			return err
+		}
+		if wallet.Status == domain.WalletClosed {
+			return domain.ErrWalletClosed
+		}
+		if wallet.Available() < -limit {
+			return fmt.Errorf("%w: %d of credit is used", domain.ErrOverdraftInUse, -wallet.Available())
+		}
+		if err := s.repo.SetOverdraftLimit(ctx, walletID, limit); err != nil {
+			return err
+		}
+		wallet.OverdraftLimit = limit
+		return nil
+	})
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+	return wallet, nil
+}
+
+// Метод для получения истории смены статусов кошелька
+func (s *walletService) ListWalletStatusChanges(ctx context.Context, walletID uuid.UUID) ([]domain.WalletStatusChange, error) {
+	if _, err := s.repo.GetWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListWalletStatusChanges(ctx, walletID)
+}
+
+// Метод для получения сводки по главной книге с результатом сверки по каждой валюте
+func (s *walletService) GetLedgerSummary(ctx context.Context) ([]domain.LedgerSummary, error) {
+	summaries, err := s.repo.GetLedgerSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range summaries {
+		summaries[i].Reconcile()
 	}
+	return summaries, nil
 }
\ No newline at end of file
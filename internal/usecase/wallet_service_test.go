@@ -3,44 +3,514 @@ package usecase_test
 import (
 	"context"
 	"errors"
+	"math"
+	"math/big"
+	"sort"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"WalletApp/internal/domain"
 	"WalletApp/internal/usecase"
 )
 
 type mockWalletRepository struct {
-	wallets map[uuid.UUID]int64 // Хранилище для кошельков
+	wallets       map[uuid.UUID]int64                           // Хранилище для кошельков
+	reserved      map[uuid.UUID]int64                           // Зарезервированные блокировками суммы
+	currencies    map[uuid.UUID]string                          // Валюты кошельков
+	statuses      map[uuid.UUID]string                          // Статусы кошельков; отсутствие записи - ACTIVE
+	overdrafts    map[uuid.UUID]int64                           // Кредитные лимиты кошельков
+	transactions  []domain.Transaction                          // Записанные операции
+	statusChanges []domain.WalletStatusChange                   // История смены статусов
+	limits        map[uuid.UUID]map[string]domain.SpendingLimit // Лимиты расходов кошельков по периодам
+	epoch         time.Time                                     // Время первой записанной операции
+	owners        map[uuid.UUID]domain.Owner                    // Владельцы; мок заодно реализует domain.OwnerRepository
+	ownerOf       map[uuid.UUID]uuid.UUID                       // Владельцы кошельков
+	walletIDs     []uuid.UUID                                   // Кошельки в порядке создания
+	details       map[uuid.UUID]domain.Wallet                   // Имена и метаданные кошельков
+	lastFilter    domain.WalletFilter                           // Фильтр последнего поиска кошельков
+	ledgerDrift   map[uuid.UUID]int64                           // Расхождение главной книги с операциями
+	tiers         map[uuid.UUID]string                          // Тарифы кошельков; отсутствие записи - STANDARD
+	locks         [][]uuid.UUID                                 // Кошельки каждого вызова LockWallets
+	operationKeys []string                                      // Занятые ключи однократных операций
+	snapshot      []domain.Transaction                          // Операции, видимые внутри WithinSnapshot
+	openTx        int                                           // Число открытых вызовов WithinTransaction
+
+	failCreateTransaction bool // имитация ошибки записи операции
 }
 
 func newMockWalletRepository() *mockWalletRepository {
 	return &mockWalletRepository{
-		wallets: make(map[uuid.UUID]int64),
+		wallets:    make(map[uuid.UUID]int64),
+		reserved:   make(map[uuid.UUID]int64),
+		currencies: make(map[uuid.UUID]string),
+		statuses:   make(map[uuid.UUID]string),
+		overdrafts: make(map[uuid.UUID]int64),
+		limits:     make(map[uuid.UUID]map[string]domain.SpendingLimit),
+		epoch:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		owners:     make(map[uuid.UUID]domain.Owner),
+		ownerOf:    make(map[uuid.UUID]uuid.UUID),
+		details:    make(map[uuid.UUID]domain.Wallet),
+	}
+}
+
+// CreateTransaction, как триггер проводок, изменяет баланс кошелька на сумму записи
+// и проверяет ограничения кредитной линии и резерва
+func (m *mockWalletRepository) CreateTransaction(ctx context.Context, tx *domain.Transaction) error {
+	if m.failCreateTransaction {
+		return errors.New("insert failed")
+	}
+	balance, exists := m.wallets[tx.WalletID]
+	if !exists {
+		return domain.ErrWalletNotFound
+	}
+	if tx.Amount > 0 && balance > math.MaxInt64-tx.Amount { // Postgres отвечает "bigint out of range"
+		return domain.ErrBalanceOverflow
+	}
+	if tx.Amount < 0 && balance-m.reserved[tx.WalletID] < -tx.Amount-m.overdrafts[tx.WalletID] {
+		return domain.ErrInsufficientFunds
+	}
+	m.wallets[tx.WalletID] += tx.Amount
+	tx.ID = uuid.New()
+	tx.CreatedAt = m.epoch.Add(time.Duration(len(m.transactions)) * time.Second) // время растёт с каждой записью
+	m.transactions = append(m.transactions, *tx)
+	return nil
+}
+
+func (m *mockWalletRepository) ClaimOperationKey(ctx context.Context, key string) error {
+	for _, claimed := range m.operationKeys {
+		if claimed == key {
+			return domain.ErrOperationApplied
+		}
+	}
+	m.operationKeys = append(m.operationKeys, key)
+	return nil
+}
+
+func (m *mockWalletRepository) LockTransaction(ctx context.Context, transactionID uuid.UUID) (domain.Transaction, error) {
+	for _, tx := range m.transactions {
+		if tx.ID == transactionID {
+			return tx, nil
+		}
+	}
+	return domain.Transaction{}, domain.ErrTransactionNotFound
+}
+
+func (m *mockWalletRepository) LockTransferCredit(ctx context.Context, debitID uuid.UUID) (domain.Transaction, error) {
+	for _, tx := range m.transactions {
+		if tx.OperationType == usecase.TRANSFER && tx.RelatedTransactionID != nil && *tx.RelatedTransactionID == debitID {
+			return tx, nil
+		}
+	}
+	return domain.Transaction{}, domain.ErrTransactionNotFound
+}
+
+func (m *mockWalletRepository) GetReversedAmount(ctx context.Context, transactionID uuid.UUID) (int64, error) {
+	var amount int64
+	for _, tx := range m.transactions {
+		if tx.ReversedTransactionID != nil && *tx.ReversedTransactionID == transactionID {
+			if tx.Amount < 0 {
+				amount -= tx.Amount
+			} else {
+				amount += tx.Amount
+			}
+		}
+	}
+	return amount, nil
+}
+
+func (m *mockWalletRepository) StreamTransactions(ctx context.Context, walletID uuid.UUID, from, to time.Time, fn func(domain.Transaction) error) error {
+	for _, tx := range m.visibleTransactions() { // записи уже упорядочены по времени
+		if tx.WalletID == walletID && !tx.CreatedAt.Before(from) && tx.CreatedAt.Before(to) {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mockWalletRepository) ListTransactions(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+	var result []domain.Transaction
+	for i := len(m.transactions) - 1; i >= 0 && len(result) < filter.Limit; i-- { // от новых к старым
+		tx := m.transactions[i]
+		if tx.WalletID != filter.WalletID {
+			continue
+		}
+		if filter.OperationType != "" && tx.OperationType != filter.OperationType {
+			continue
+		}
+		if filter.After != nil && !tx.CreatedAt.Before(filter.After.CreatedAt) {
+			continue
+		}
+		result = append(result, tx)
+	}
+	return result, nil
+}
+
+// WithinTransaction восстанавливает состояние мока, если fn вернула ошибку
+func (m *mockWalletRepository) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	wallets, reserved, overdrafts := copyAmounts(m.wallets), copyAmounts(m.reserved), copyAmounts(m.overdrafts)
+	transactions, statusChanges, operationKeys := m.transactions, m.statusChanges, m.operationKeys
+	statuses := make(map[uuid.UUID]string, len(m.statuses))
+	for id, status := range m.statuses {
+		statuses[id] = status
 	}
+
+	m.openTx++
+	defer func() { m.openTx-- }()
+	if err := fn(ctx); err != nil {
+		m.wallets, m.reserved, m.transactions = wallets, reserved, transactions
+		m.statuses, m.statusChanges, m.overdrafts = statuses, statusChanges, overdrafts
+		m.operationKeys = operationKeys
+		return err
+	}
+	return nil
+}
+
+// WithinSnapshot выполняет fn так, что GetBalanceAt и StreamTransactions видят операции на момент вызова
+func (m *mockWalletRepository) WithinSnapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.snapshot = m.transactions[:len(m.transactions):len(m.transactions)]
+	defer func() { m.snapshot = nil }()
+	return fn(ctx)
+}
+
+// Метод возвращает операции, видимые чтению: внутри WithinSnapshot - снимок
+func (m *mockWalletRepository) visibleTransactions() []domain.Transaction {
+	if m.snapshot != nil {
+		return m.snapshot
+	}
+	return m.transactions
+}
+
+func copyAmounts(amounts map[uuid.UUID]int64) map[uuid.UUID]int64 {
+	result := make(map[uuid.UUID]int64, len(amounts))
+	for id, amount := range amounts {
+		result[id] = amount
+	}
+	return result
 }
 
 func (m *mockWalletRepository) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
 	balance, exists := m.wallets[walletID]
 	if !exists {
-		return 0, errors.New("wallet not found")
+		return 0, domain.ErrWalletNotFound
+	}
+	return balance, nil
+}
+
+// GetBalanceAt суммирует записанные операции кошелька, как главная книга
+func (m *mockWalletRepository) GetBalanceAt(ctx context.Context, walletID uuid.UUID, at time.Time) (int64, error) {
+	var balance int64
+	for _, tx := range m.visibleTransactions() {
+		if tx.WalletID == walletID && !tx.CreatedAt.After(at) {
+			balance += tx.Amount
+		}
 	}
 	return balance, nil
 }
 
+// UpdateBalance задаёт тестам начальный баланс в обход операций; в интерфейс хранилища не входит
 func (m *mockWalletRepository) UpdateBalance(ctx context.Context, walletID uuid.UUID, amount int64) error {
 	if _, exists := m.wallets[walletID]; !exists {
-		return errors.New("wallet not found")
+		return domain.ErrWalletNotFound
+	}
+	m.wallets[walletID] += amount
+	return nil
+}
+
+func (m *mockWalletRepository) ReserveBalance(ctx context.Context, walletID uuid.UUID, amount int64) error {
+	balance, exists := m.wallets[walletID]
+	if !exists {
+		return domain.ErrWalletNotFound
+	}
+	if balance-m.reserved[walletID] < amount-m.overdrafts[walletID] {
+		return domain.ErrInsufficientFunds
+	}
+	m.reserved[walletID] += amount
+	return nil
+}
+
+func (m *mockWalletRepository) ReleaseReserve(ctx context.Context, walletID uuid.UUID, amount int64) error {
+	if _, exists := m.wallets[walletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	m.reserved[walletID] -= amount
+	return nil
+}
+
+// GetLedgerSummary считает итоги так же, как главная книга: депозиты корреспондируют
+// с funding, снятия с payout, переводы с транзитным счётом
+func (m *mockWalletRepository) StreamBalanceChecks(ctx context.Context, fn func(domain.BalanceCheck) error) error {
+	ids := append([]uuid.UUID(nil), m.walletIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	for _, id := range ids {
+		check, _ := m.GetBalanceCheck(ctx, id)
+		if err := fn(check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockWalletRepository) GetBalanceCheck(ctx context.Context, walletID uuid.UUID) (domain.BalanceCheck, error) {
+	balance, exists := m.wallets[walletID]
+	if !exists {
+		return domain.BalanceCheck{}, domain.ErrWalletNotFound
+	}
+	check := domain.BalanceCheck{WalletID: walletID, Currency: m.currencies[walletID], StoredBalance: balance}
+	for _, tx := range m.transactions {
+		if tx.WalletID == walletID {
+			check.TransactionsSum += tx.Amount
+		}
+	}
+	check.LedgerBalance = check.TransactionsSum + m.ledgerDrift[walletID]
+	check.Reconcile()
+	return check, nil
+}
+
+func (m *mockWalletRepository) SetBalance(ctx context.Context, walletID uuid.UUID, balance int64) error {
+	if _, exists := m.wallets[walletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	if balance < -m.overdrafts[walletID] { // ограничение wallets_balance_within_overdraft
+		return domain.ErrInsufficientFunds
+	}
+	if check, _ := m.GetBalanceCheck(ctx, walletID); balance != check.LedgerBalance { // триггер wallets_balance_projection
+		return errors.New("balance is maintained from ledger postings")
+	}
+	m.wallets[walletID] = balance
+	return nil
+}
+
+func (m *mockWalletRepository) GetLedgerSummary(ctx context.Context) ([]domain.LedgerSummary, error) {
+	summaries := make(map[string]*domain.LedgerSummary)
+	get := func(walletID uuid.UUID) *domain.LedgerSummary {
+		currency := m.currencies[walletID]
+		if summaries[currency] == nil {
+			summaries[currency] = &domain.LedgerSummary{Currency: currency}
+		}
+		return summaries[currency]
+	}
+
+	for _, tx := range m.transactions {
+		summary := get(tx.WalletID)
+		summary.WalletLiabilities += tx.Amount
+		operationType := tx.OperationType
+		if operationType == usecase.REVERSAL { // отмена проводится по счетам исходной операции
+			original, _ := m.LockTransaction(ctx, *tx.ReversedTransactionID)
+			operationType = original.OperationType
+		}
+		switch operationType {
+		case usecase.DEPOSIT:
+			summary.Funded += tx.Amount
+		case usecase.WITHDRAW, usecase.CAPTURE:
+			summary.PaidOut -= tx.Amount
+		case usecase.TRANSFER:
+			summary.InTransit -= tx.Amount
+		case usecase.INTEREST:
+			summary.InterestPaid += tx.Amount
+		}
+	}
+	for id, balance := range m.wallets {
+		get(id).WalletBalances += balance
+	}
+
+	var result []domain.LedgerSummary
+	for _, summary := range summaries {
+		result = append(result, *summary)
+	}
+	return result, nil
+}
+
+func (m *mockWalletRepository) LockWallets(ctx context.Context, walletIDs ...uuid.UUID) error {
+	m.locks = append(m.locks, walletIDs)
+	for _, id := range walletIDs {
+		if _, exists := m.wallets[id]; !exists {
+			return domain.ErrWalletNotFound
+		}
 	}
-	m.wallets[walletID] += amount // Обновляем баланс
 	return nil
 }
 
-func (m *mockWalletRepository) CreateWallet(ctx context.Context) (uuid.UUID, error) {
+func (m *mockWalletRepository) CreateWallet(ctx context.Context, wallet domain.Wallet) (uuid.UUID, error) {
+	if wallet.OwnerID != nil {
+		if _, exists := m.owners[*wallet.OwnerID]; !exists { // внешний ключ на owners
+			return uuid.Nil, domain.ErrOwnerNotFound
+		}
+	}
 	walletID := uuid.New()
 	m.wallets[walletID] = 0 // Создаём кошелёк с балансом 0
+	m.currencies[walletID] = wallet.Currency
+	if wallet.OwnerID != nil {
+		m.ownerOf[walletID] = *wallet.OwnerID
+	}
+	m.walletIDs = append(m.walletIDs, walletID)
+	m.details[walletID] = domain.Wallet{DisplayName: wallet.DisplayName, Metadata: wallet.Metadata}
 	return walletID, nil
 }
 
+func (m *mockWalletRepository) UpdateWalletDetails(ctx context.Context, walletID uuid.UUID, displayName string, metadata map[string]string) error {
+	if _, exists := m.wallets[walletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	m.details[walletID] = domain.Wallet{DisplayName: displayName, Metadata: metadata}
+	return nil
+}
+
+func (m *mockWalletRepository) ListWallets(ctx context.Context, filter domain.WalletFilter) ([]domain.Wallet, error) {
+	m.lastFilter = filter
+	var result []domain.Wallet
+	for _, walletID := range m.walletIDs {
+		wallet, _ := m.GetWallet(ctx, walletID)
+		matches := true
+		for key, value := range filter.Labels {
+			if stored, ok := wallet.Metadata[key]; !ok || stored != value {
+				matches = false
+			}
+		}
+		if matches && len(result) < filter.Limit {
+			result = append(result, wallet)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockWalletRepository) ListWalletsByOwner(ctx context.Context, ownerID uuid.UUID) ([]domain.Wallet, error) {
+	var result []domain.Wallet
+	for _, walletID := range m.walletIDs {
+		if owner, ok := m.ownerOf[walletID]; ok && owner == ownerID {
+			wallet, _ := m.GetWallet(ctx, walletID)
+			result = append(result, wallet)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockWalletRepository) CreateOwner(ctx context.Context, owner *domain.Owner) error {
+	owner.ID = uuid.New()
+	owner.CreatedAt = m.epoch
+	m.owners[owner.ID] = *owner
+	return nil
+}
+
+func (m *mockWalletRepository) GetOwner(ctx context.Context, ownerID uuid.UUID) (domain.Owner, error) {
+	owner, exists := m.owners[ownerID]
+	if !exists {
+		return domain.Owner{}, domain.ErrOwnerNotFound
+	}
+	return owner, nil
+}
+
+func (m *mockWalletRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (domain.Wallet, error) {
+	balance, exists := m.wallets[walletID]
+	if !exists {
+		return domain.Wallet{}, domain.ErrWalletNotFound
+	}
+	status := m.statuses[walletID]
+	if status == "" {
+		status = domain.WalletActive
+	}
+	return domain.Wallet{
+		ID:             walletID,
+		Balance:        balance,
+		Reserved:       m.reserved[walletID],
+		Currency:       m.currencies[walletID],
+		Status:         status,
+		OverdraftLimit: m.overdrafts[walletID],
+		Tier:           m.tier(walletID),
+		OwnerID:        m.ownerPtr(walletID),
+		DisplayName:    m.details[walletID].DisplayName,
+		Metadata:       m.details[walletID].Metadata,
+	}, nil
+}
+
+func (m *mockWalletRepository) tier(walletID uuid.UUID) string {
+	if tier, ok := m.tiers[walletID]; ok {
+		return tier
+	}
+	return domain.DefaultWalletTier
+}
+
+func (m *mockWalletRepository) SetWalletTier(ctx context.Context, walletID uuid.UUID, tier string) error {
+	if _, exists := m.wallets[walletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	if m.tiers == nil {
+		m.tiers = make(map[uuid.UUID]string)
+	}
+	m.tiers[walletID] = tier
+	return nil
+}
+
+func (m *mockWalletRepository) ownerPtr(walletID uuid.UUID) *uuid.UUID {
+	if ownerID, ok := m.ownerOf[walletID]; ok {
+		return &ownerID
+	}
+	return nil
+}
+
+func (m *mockWalletRepository) GetSpendingLimits(ctx context.Context, walletID uuid.UUID) ([]domain.SpendingLimit, error) {
+	var result []domain.SpendingLimit
+	for _, limit := range m.limits[walletID] {
+		result = append(result, limit)
+	}
+	return result, nil
+}
+
+func (m *mockWalletRepository) SetSpendingLimit(ctx context.Context, walletID uuid.UUID, limit domain.SpendingLimit) error {
+	if m.limits[walletID] == nil {
+		m.limits[walletID] = make(map[string]domain.SpendingLimit)
+	}
+	m.limits[walletID][limit.Period] = limit
+	return nil
+}
+
+func (m *mockWalletRepository) DeleteSpendingLimit(ctx context.Context, walletID uuid.UUID, period string) error {
+	delete(m.limits[walletID], period)
+	return nil
+}
+
+func (m *mockWalletRepository) GetSpendingUsage(ctx context.Context, walletID uuid.UUID, since time.Time) (domain.SpendingUsage, error) {
+	var usage domain.SpendingUsage
+	for _, tx := range m.transactions {
+		if tx.WalletID == walletID && tx.Amount < 0 && tx.OperationType != usecase.REVERSAL && tx.OperationType != usecase.FEE && !tx.CreatedAt.Before(since) {
+			usage.Amount -= tx.Amount
+			usage.Count++
+		}
+	}
+	return usage, nil
+}
+
+func (m *mockWalletRepository) SetOverdraftLimit(ctx context.Context, walletID uuid.UUID, limit int64) error {
+	if _, exists := m.wallets[walletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	m.overdrafts[walletID] = limit
+	return nil
+}
+
+func (m *mockWalletRepository) ChangeWalletStatus(ctx context.Context, change *domain.WalletStatusChange) error {
+	if _, exists := m.wallets[change.WalletID]; !exists {
+		return domain.ErrWalletNotFound
+	}
+	change.ID = uuid.New()
+	change.CreatedAt = time.Date(2026, 1, 1, 0, 0, len(m.statusChanges), 0, time.UTC)
+	m.statuses[change.WalletID] = change.ToStatus
+	m.statusChanges = append(m.statusChanges, *change)
+	return nil
+}
+
+func (m *mockWalletRepository) ListWalletStatusChanges(ctx context.Context, walletID uuid.UUID) ([]domain.WalletStatusChange, error) {
+	var result []domain.WalletStatusChange
+	for _, change := range m.statusChanges {
+		if change.WalletID == walletID {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
 func TestPerformOperation(t *testing.T) {
 	repo := newMockWalletRepository()
 	svc := usecase.NewWalletService(repo) // Передаём мок-репозиторий
@@ -60,7 +530,7 @@ func TestPerformOperation(t *testing.T) {
 
 	for _, tt := range tests {
 	    t.Run(tt.name, func(t *testing.T) {
-	        walletID, _ := repo.CreateWallet(context.Background()) // Создаём новый кошелёк
+	        walletID, _ := repo.CreateWallet(context.Background(), domain.Wallet{Currency: "RUB"}) // Создаём новый кошелёк
 	        repo.UpdateBalance(context.Background(), walletID, 0) // Устанавливаем начальный баланс в 0
 
 	        if tt.operationType == usecase.DEPOSIT {
@@ -70,7 +540,7 @@ func TestPerformOperation(t *testing.T) {
 	            repo.UpdateBalance(context.Background(), walletID, 500); // Устанавливаем начальный баланс в 500 для тестов вывода
 	        }
 
-	        err := svc.PerformOperation(context.Background(), walletID ,tt.operationType ,tt.amount)
+	        _, err := svc.PerformOperation(context.Background(), domain.Operation{WalletID: walletID, OperationType: tt.operationType, Amount: tt.amount})
 
 	        if (err != nil) != tt.expectError {
 	            t.Errorf("expected error status %v but got %v", tt.expectError, err)
@@ -85,4 +555,1940 @@ func TestPerformOperation(t *testing.T) {
 	        }
 	    })
     }
-}
\ No newline at end of file
+}
+
+func TestPerformOperation_RecordsTransaction(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 300}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 100}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(repo.transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(repo.transactions))
+	}
+	// Списание хранится с отрицательной суммой
+	if repo.transactions[0].Amount != 300 || repo.transactions[1].Amount != -100 {
+		t.Errorf("unexpected transaction amounts: %d, %d", repo.transactions[0].Amount, repo.transactions[1].Amount)
+	}
+	if repo.transactions[1].OperationType != usecase.WITHDRAW || repo.transactions[1].WalletID != walletID {
+		t.Errorf("unexpected transaction: %+v", repo.transactions[1])
+	}
+}
+
+func TestPerformOperation_RollbackOnTransactionFailure(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.failCreateTransaction = true
+
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 500}); err == nil {
+		t.Fatal("expected error when transaction record cannot be written")
+	}
+
+	// Баланс не должен измениться без записи в журнал
+	balance, _ := repo.GetBalance(ctx, walletID)
+	if balance != 0 {
+		t.Errorf("expected balance to stay 0 after rollback, got %d", balance)
+	}
+}
+
+func TestListTransactions_Pagination(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	for i := 0; i < 5; i++ {
+		svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: int64(100+i)})
+	}
+
+	page, err := svc.ListTransactions(ctx, domain.TransactionFilter{WalletID: walletID, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Transactions) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected 2 transactions and a cursor, got %d and %q", len(page.Transactions), page.NextCursor)
+	}
+	if page.Transactions[0].Amount != 104 { // новые операции первыми
+		t.Errorf("expected newest transaction first, got amount %d", page.Transactions[0].Amount)
+	}
+
+	// Проходим по курсорам до конца истории
+	seen := len(page.Transactions)
+	for page.NextCursor != "" {
+		cursor, err := domain.DecodeTransactionCursor(page.NextCursor)
+		if err != nil {
+			t.Fatalf("invalid cursor: %v", err)
+		}
+		page, err = svc.ListTransactions(ctx, domain.TransactionFilter{WalletID: walletID, Limit: 2, After: &cursor})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		seen += len(page.Transactions)
+	}
+	if seen != 5 {
+		t.Errorf("expected to see 5 transactions, got %d", seen)
+	}
+}
+
+func TestListTransactions_UnknownWallet(t *testing.T) {
+	svc := usecase.NewWalletService(newMockWalletRepository())
+
+	_, err := svc.ListTransactions(context.Background(), domain.TransactionFilter{WalletID: uuid.New()})
+	if !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+}
+
+func TestListTransactions_InvalidOperationType(t *testing.T) {
+	svc := usecase.NewWalletService(newMockWalletRepository())
+
+	_, err := svc.ListTransactions(context.Background(), domain.TransactionFilter{WalletID: uuid.New(), OperationType: "INVALID"})
+	if err == nil {
+		t.Error("expected error for invalid operation type filter")
+	}
+}
+
+func TestPerformOperation_InsufficientFunds(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 100)
+
+	_, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 150})
+	if !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 100 {
+		t.Errorf("expected balance to stay 100, got %d", balance)
+	}
+	if len(repo.transactions) != 0 {
+		t.Errorf("expected no transaction records, got %d", len(repo.transactions))
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	from, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	to, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, from, 500)
+
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: from, OperationType: usecase.TRANSFER, Amount: 200, DestinationWalletID: to}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fromBalance, _ := repo.GetBalance(ctx, from)
+	toBalance, _ := repo.GetBalance(ctx, to)
+	if fromBalance != 300 || toBalance != 200 {
+		t.Errorf("expected balances 300 and 200, got %d and %d", fromBalance, toBalance)
+	}
+
+	// Пара записей: списание и связанное с ним зачисление
+	if len(repo.transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(repo.transactions))
+	}
+	debit, credit := repo.transactions[0], repo.transactions[1]
+	if debit.WalletID != from || debit.Amount != -200 || *debit.CounterpartyWalletID != to {
+		t.Errorf("unexpected debit record: %+v", debit)
+	}
+	if credit.WalletID != to || credit.Amount != 200 || *credit.CounterpartyWalletID != from {
+		t.Errorf("unexpected credit record: %+v", credit)
+	}
+	if credit.RelatedTransactionID == nil || *credit.RelatedTransactionID != debit.ID {
+		t.Errorf("expected credit to reference debit %s, got %v", debit.ID, credit.RelatedTransactionID)
+	}
+}
+
+func TestTransfer_Errors(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	from, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	to, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, from, 100)
+
+	tests := []struct {
+		name   string
+		to     uuid.UUID
+		amount int64
+	}{
+		{"Insufficient funds", to, 150},
+		{"Same wallet", from, 50},
+		{"Missing destination", uuid.Nil, 50},
+		{"Unknown destination", uuid.New(), 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: from, OperationType: usecase.TRANSFER, Amount: tt.amount, DestinationWalletID: tt.to}); err == nil {
+				t.Fatal("expected error")
+			}
+			// Ни один из кошельков не изменился
+			fromBalance, _ := repo.GetBalance(ctx, from)
+			toBalance, _ := repo.GetBalance(ctx, to)
+			if fromBalance != 100 || toBalance != 0 || len(repo.transactions) != 0 {
+				t.Errorf("expected no changes, got balances %d/%d and %d records", fromBalance, toBalance, len(repo.transactions))
+			}
+		})
+	}
+}
+func TestPerformOperation_TypedErrors(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	tests := []struct {
+		name          string
+		operationType string
+		amount        int64
+		expected      error
+	}{
+		{"Invalid operation type", "INVALID", 100, domain.ErrInvalidOperation},
+		{"Zero amount", usecase.DEPOSIT, 0, domain.ErrInvalidAmount},
+		{"Negative amount", usecase.DEPOSIT, -100, domain.ErrInvalidAmount},
+		{"Insufficient funds", usecase.WITHDRAW, 100, domain.ErrInsufficientFunds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: tt.operationType, Amount: tt.amount})
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestPerformOperation_UnknownWallet(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	unknownID := uuid.New()
+	for _, opType := range []string{usecase.DEPOSIT, usecase.WITHDRAW} {
+		if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: unknownID, OperationType: opType, Amount: 100}); !errors.Is(err, domain.ErrWalletNotFound) {
+			t.Errorf("%s: expected ErrWalletNotFound, got %v", opType, err)
+		}
+	}
+	if _, err := svc.GetBalance(ctx, unknownID); !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound from GetBalance, got %v", err)
+	}
+	if len(repo.transactions) != 0 || len(repo.wallets) != 0 {
+		t.Errorf("expected no phantom wallets or records, got %d wallets and %d records", len(repo.wallets), len(repo.transactions))
+	}
+}
+
+
+func TestPerformOperation_AmountLimits(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithAmountLimits(map[string]usecase.AmountLimits{
+		usecase.DEPOSIT:  {Min: 10, Max: 1000},
+		usecase.TRANSFER: {Max: 50},
+	}))
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	otherID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 5}); !errors.Is(err, domain.ErrAmountOutOfRange) {
+		t.Errorf("expected ErrAmountOutOfRange below minimum, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1001}); !errors.Is(err, domain.ErrAmountOutOfRange) {
+		t.Errorf("expected ErrAmountOutOfRange above maximum, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000}); err != nil {
+		t.Errorf("expected deposit at maximum to succeed, got %v", err)
+	}
+	// Для WITHDRAW границы не заданы
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 1}); err != nil {
+		t.Errorf("expected withdrawal without limits to succeed, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.TRANSFER, Amount: 51, DestinationWalletID: otherID}); !errors.Is(err, domain.ErrAmountOutOfRange) {
+		t.Errorf("expected ErrAmountOutOfRange for transfer, got %v", err)
+	}
+}
+
+func TestPerformOperation_Overflow(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, math.MaxInt64-10)
+
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 11}); !errors.Is(err, domain.ErrBalanceOverflow) {
+		t.Fatalf("expected ErrBalanceOverflow, got %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != math.MaxInt64-10 {
+		t.Errorf("expected balance to stay unchanged, got %d", balance)
+	}
+}
+
+func TestGetLedgerSummary(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	a, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	b, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.DEPOSIT, Amount: 1000})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.WITHDRAW, Amount: 300})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.TRANSFER, Amount: 200, DestinationWalletID: b})
+
+	summaries, err := svc.GetLedgerSummary(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("expected summary for one currency, got %d", len(summaries))
+	}
+	summary := summaries[0]
+	if !summary.Balanced || summary.WalletLiabilities != 700 || summary.Funded != 1000 || summary.PaidOut != 300 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+
+	// Изменение баланса в обход журнала нарушает сверку
+	repo.UpdateBalance(ctx, b, 50)
+	summaries, _ = svc.GetLedgerSummary(ctx)
+	if summaries[0].Balanced {
+		t.Errorf("expected summary to be unbalanced after direct balance change: %+v", summaries[0])
+	}
+}
+
+func TestCreateWallet_Currency(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithDefaultCurrency("KZT"))
+	ctx := context.Background()
+
+	walletID, err := svc.CreateWallet(ctx, domain.Wallet{Currency: "usd"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wallet, _ := svc.GetWallet(ctx, walletID); wallet.Currency != "USD" {
+		t.Errorf("expected USD wallet, got %q", wallet.Currency)
+	}
+
+	// Без валюты используется валюта по умолчанию
+	walletID, _ = svc.CreateWallet(ctx, domain.Wallet{})
+	if wallet, _ := svc.GetWallet(ctx, walletID); wallet.Currency != "KZT" {
+		t.Errorf("expected default KZT wallet, got %q", wallet.Currency)
+	}
+
+	if _, err := svc.CreateWallet(ctx, domain.Wallet{Currency: "XXX"}); !errors.Is(err, domain.ErrUnsupportedCurrency) {
+		t.Errorf("expected ErrUnsupportedCurrency, got %v", err)
+	}
+}
+
+func TestPerformOperation_CurrencyMismatch(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	rub, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	usd, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	repo.UpdateBalance(ctx, rub, 1000)
+
+	_, err := svc.PerformOperation(ctx, domain.Operation{WalletID: rub, OperationType: usecase.DEPOSIT, Amount: 100, Currency: "USD"})
+	if !errors.Is(err, domain.ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch for deposit, got %v", err)
+	}
+	_, err = svc.PerformOperation(ctx, domain.Operation{WalletID: rub, OperationType: usecase.DEPOSIT, Amount: 100, Currency: "rub"})
+	if err != nil {
+		t.Errorf("expected deposit in wallet currency to succeed, got %v", err)
+	}
+
+	// Перевод между кошельками разных валют
+	_, err = svc.PerformOperation(ctx, domain.Operation{WalletID: rub, OperationType: usecase.TRANSFER, Amount: 100, DestinationWalletID: usd})
+	if !errors.Is(err, domain.ErrCurrencyMismatch) {
+		t.Errorf("expected ErrCurrencyMismatch for cross-currency transfer, got %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, rub); balance != 1100 {
+		t.Errorf("expected balance 1100, got %d", balance)
+	}
+}
+// имитация хранилища курсов обмена
+type mockExchangeRateRepository struct {
+	rates  []domain.ExchangeRate
+	quotes map[uuid.UUID]*domain.FXQuote
+	used   map[uuid.UUID]bool
+}
+
+func newMockExchangeRateRepository() *mockExchangeRateRepository {
+	return &mockExchangeRateRepository{
+		quotes: make(map[uuid.UUID]*domain.FXQuote),
+		used:   make(map[uuid.UUID]bool),
+	}
+}
+
+func (m *mockExchangeRateRepository) AddRates(ctx context.Context, rates []domain.ExchangeRate) error {
+	for i := range rates {
+		rates[i].ID = uuid.New()
+		rates[i].Version = 1
+		for _, existing := range m.rates {
+			if existing.BaseCurrency == rates[i].BaseCurrency && existing.QuoteCurrency == rates[i].QuoteCurrency {
+				rates[i].Version = existing.Version + 1
+			}
+		}
+		m.rates = append(m.rates, rates[i])
+	}
+	return nil
+}
+
+func (m *mockExchangeRateRepository) GetRate(ctx context.Context, baseCurrency, quoteCurrency string, at time.Time) (domain.ExchangeRate, error) {
+	for i := len(m.rates) - 1; i >= 0; i-- { // последняя загруженная версия
+		rate := m.rates[i]
+		if rate.BaseCurrency == baseCurrency && rate.QuoteCurrency == quoteCurrency && !rate.EffectiveAt.After(at) {
+			return rate, nil
+		}
+	}
+	return domain.ExchangeRate{}, domain.ErrRateNotFound
+}
+
+func (m *mockExchangeRateRepository) ListRates(ctx context.Context, at time.Time) ([]domain.ExchangeRate, error) {
+	return m.rates, nil
+}
+
+func (m *mockExchangeRateRepository) CreateQuote(ctx context.Context, quote *domain.FXQuote) error {
+	quote.ID = uuid.New()
+	stored := *quote
+	m.quotes[quote.ID] = &stored
+	return nil
+}
+
+func (m *mockExchangeRateRepository) UseQuote(ctx context.Context, quoteID uuid.UUID, at time.Time) (domain.FXQuote, error) {
+	quote, exists := m.quotes[quoteID]
+	if !exists {
+		return domain.FXQuote{}, domain.ErrQuoteNotFound
+	}
+	if m.used[quoteID] || !quote.ExpiresAt.After(at) {
+		return domain.FXQuote{}, domain.ErrQuoteExpired
+	}
+	m.used[quoteID] = true
+	return *quote, nil
+}
+
+func TestTransfer_CrossCurrency(t *testing.T) {
+	repo := newMockWalletRepository()
+	rates := newMockExchangeRateRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithExchangeRates(rates), usecase.WithRoundingMode(domain.RoundDown))
+	ctx := context.Background()
+
+	usd, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	rub, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, usd, 10000)
+
+	// Без курса перевод между валютами невозможен
+	op := domain.Operation{WalletID: usd, OperationType: usecase.TRANSFER, Amount: 1001, DestinationWalletID: rub}
+	if _, err := svc.PerformOperation(ctx, op); !errors.Is(err, domain.ErrRateNotFound) {
+		t.Fatalf("expected ErrRateNotFound, got %v", err)
+	}
+
+	saved, err := svc.AddExchangeRates(ctx, []domain.ExchangeRate{{BaseCurrency: "usd", QuoteCurrency: "RUB", Rate: "90.555"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved[0].BaseCurrency != "USD" || saved[0].EffectiveAt.IsZero() {
+		t.Errorf("expected normalized rate with effective time, got %+v", saved[0])
+	}
+
+	// 10.01 USD * 90.555 = 906.45555 RUB, округление вниз до 906.45
+	if _, err := svc.PerformOperation(ctx, op); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usdBalance, _ := repo.GetBalance(ctx, usd)
+	rubBalance, _ := repo.GetBalance(ctx, rub)
+	if usdBalance != 8999 || rubBalance != 90645 {
+		t.Errorf("expected balances 8999 USD and 90645 RUB, got %d and %d", usdBalance, rubBalance)
+	}
+
+	// Обе записи перевода хранят курс и его версию
+	debit, credit := repo.transactions[0], repo.transactions[1]
+	if debit.Amount != -1001 || credit.Amount != 90645 {
+		t.Errorf("unexpected amounts: %d and %d", debit.Amount, credit.Amount)
+	}
+	for _, tx := range []domain.Transaction{debit, credit} {
+		if tx.ExchangeRate == nil || *tx.ExchangeRate != "90.555" || tx.ExchangeRateID == nil || *tx.ExchangeRateID != saved[0].ID {
+			t.Errorf("expected transaction to record rate 90.555 (%s), got %v %v", saved[0].ID, tx.ExchangeRate, tx.ExchangeRateID)
+		}
+	}
+
+	// Обратного курса нет
+	back := domain.Operation{WalletID: rub, OperationType: usecase.TRANSFER, Amount: 100, DestinationWalletID: usd}
+	if _, err := svc.PerformOperation(ctx, back); !errors.Is(err, domain.ErrRateNotFound) {
+		t.Errorf("expected ErrRateNotFound for missing reverse rate, got %v", err)
+	}
+}
+
+func TestTransfer_WithQuote(t *testing.T) {
+	repo := newMockWalletRepository()
+	rates := newMockExchangeRateRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithExchangeRates(rates), usecase.WithQuoteTTL(time.Minute))
+	ctx := context.Background()
+
+	usd, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	rub, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, usd, 10000)
+	svc.AddExchangeRates(ctx, []domain.ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: "90"}})
+
+	quote, err := svc.QuoteExchange(ctx, "USD", "rub", 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quote.ConvertedAmount != 90000 || quote.Rate != "90" || time.Until(quote.ExpiresAt) <= 0 {
+		t.Errorf("unexpected quote: %+v", quote)
+	}
+
+	// Курс меняется, но перевод по котировке выполняется по зафиксированному курсу
+	svc.AddExchangeRates(ctx, []domain.ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: "95"}})
+
+	// Котировка на другую сумму не подходит
+	other, _ := svc.QuoteExchange(ctx, "USD", "RUB", 2000)
+	op := domain.Operation{WalletID: usd, OperationType: usecase.TRANSFER, Amount: 1000, DestinationWalletID: rub, QuoteID: &other.ID}
+	if _, err := svc.PerformOperation(ctx, op); !errors.Is(err, domain.ErrQuoteMismatch) {
+		t.Errorf("expected ErrQuoteMismatch, got %v", err)
+	}
+
+	op.QuoteID = &quote.ID
+	if _, err := svc.PerformOperation(ctx, op); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, rub); balance != 90000 {
+		t.Errorf("expected 90000 RUB at quoted rate, got %d", balance)
+	}
+
+	// Котировка используется один раз
+	if _, err := svc.PerformOperation(ctx, op); !errors.Is(err, domain.ErrQuoteExpired) {
+		t.Errorf("expected ErrQuoteExpired for reused quote, got %v", err)
+	}
+
+	unknown := uuid.New()
+	op.QuoteID = &unknown
+	if _, err := svc.PerformOperation(ctx, op); !errors.Is(err, domain.ErrQuoteNotFound) {
+		t.Errorf("expected ErrQuoteNotFound, got %v", err)
+	}
+
+	// Просроченная котировка
+	expired := domain.FXQuote{FromCurrency: "USD", ToCurrency: "RUB", Rate: "90", Amount: 1000, ConvertedAmount: 90000, ExpiresAt: time.Now().Add(-time.Second)}
+	rates.CreateQuote(ctx, &expired)
+	op.QuoteID = &expired.ID
+	if _, err := svc.PerformOperation(ctx, op); !errors.Is(err, domain.ErrQuoteExpired) {
+		t.Errorf("expected ErrQuoteExpired for expired quote, got %v", err)
+	}
+}
+
+func TestAddExchangeRates_Validation(t *testing.T) {
+	svc := usecase.NewWalletService(newMockWalletRepository(), usecase.WithExchangeRates(newMockExchangeRateRepository()))
+	ctx := context.Background()
+
+	tests := []struct {
+		rate     domain.ExchangeRate
+		expected error
+	}{
+		{domain.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "XXX", Rate: "1"}, domain.ErrUnsupportedCurrency},
+		{domain.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "USD", Rate: "1"}, domain.ErrInvalidRate},
+		{domain.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: "0"}, domain.ErrInvalidRate},
+		{domain.ExchangeRate{BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: "ninety"}, domain.ErrInvalidRate},
+	}
+	for _, tt := range tests {
+		if _, err := svc.AddExchangeRates(ctx, []domain.ExchangeRate{tt.rate}); !errors.Is(err, tt.expected) {
+			t.Errorf("%+v: expected %v, got %v", tt.rate, tt.expected, err)
+		}
+	}
+
+	// Сумма, которая после конвертации округляется до нуля, отклоняется
+	svc.AddExchangeRates(ctx, []domain.ExchangeRate{{BaseCurrency: "RUB", QuoteCurrency: "USD", Rate: "0.011"}})
+	if _, err := svc.QuoteExchange(ctx, "RUB", "USD", 10); !errors.Is(err, domain.ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount for amount rounded to zero, got %v", err)
+	}
+}
+
+// имитация хранилища блокировок
+type mockHoldRepository struct {
+	holds map[uuid.UUID]domain.Hold
+}
+
+func newMockHoldRepository() *mockHoldRepository {
+	return &mockHoldRepository{holds: make(map[uuid.UUID]domain.Hold)}
+}
+
+func (m *mockHoldRepository) CreateHold(ctx context.Context, hold *domain.Hold) error {
+	hold.ID = uuid.New()
+	hold.CreatedAt, hold.UpdatedAt = time.Now(), time.Now()
+	m.holds[hold.ID] = *hold
+	return nil
+}
+
+func (m *mockHoldRepository) GetHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	hold, exists := m.holds[holdID]
+	if !exists {
+		return domain.Hold{}, domain.ErrHoldNotFound
+	}
+	return hold, nil
+}
+
+func (m *mockHoldRepository) LockHold(ctx context.Context, holdID uuid.UUID) (domain.Hold, error) {
+	return m.GetHold(ctx, holdID)
+}
+
+func (m *mockHoldRepository) UpdateHold(ctx context.Context, hold *domain.Hold) error {
+	hold.UpdatedAt = time.Now()
+	m.holds[hold.ID] = *hold
+	return nil
+}
+
+func (m *mockHoldRepository) GetActiveHoldUsage(ctx context.Context, walletID uuid.UUID, since time.Time) (domain.SpendingUsage, error) {
+	var usage domain.SpendingUsage
+	for _, hold := range m.holds {
+		if hold.WalletID == walletID && hold.Status == domain.HoldActive && !hold.CreatedAt.Before(since) {
+			usage.Amount += hold.Amount
+			usage.Count++
+		}
+	}
+	return usage, nil
+}
+
+func (m *mockHoldRepository) LockExpiredHolds(ctx context.Context, at time.Time, limit int) ([]domain.Hold, error) {
+	var result []domain.Hold
+	for _, hold := range m.holds {
+		if hold.Status == domain.HoldActive && !hold.ExpiresAt.After(at) && len(result) < limit {
+			result = append(result, hold)
+		}
+	}
+	return result, nil
+}
+
+func TestHolds_CaptureAndVoid(t *testing.T) {
+	repo := newMockWalletRepository()
+	holds := newMockHoldRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithHolds(holds))
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 1000)
+
+	hold, err := svc.CreateHold(ctx, walletID, 600, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hold.Status != domain.HoldActive || time.Until(hold.ExpiresAt) < usecase.DefaultHoldTTL-time.Minute {
+		t.Errorf("unexpected hold: %+v", hold)
+	}
+
+	// Доступный баланс уменьшился, баланс по главной книге нет
+	if available, _ := svc.GetBalance(ctx, walletID); available != 400 {
+		t.Errorf("expected available balance 400, got %d", available)
+	}
+	if wallet, _ := svc.GetWallet(ctx, walletID); wallet.Balance != 1000 || wallet.Reserved != 600 {
+		t.Errorf("expected ledger balance 1000 with 600 reserved, got %+v", wallet)
+	}
+
+	// Заблокированные средства нельзя снять или заблокировать повторно
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 500}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds for withdrawal of reserved funds, got %v", err)
+	}
+	if _, err := svc.CreateHold(ctx, walletID, 500, time.Time{}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds for second hold, got %v", err)
+	}
+
+	// Частичное списание освобождает остаток
+	if _, err := svc.CaptureHold(ctx, hold.ID, 700); !errors.Is(err, domain.ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount for capture above hold, got %v", err)
+	}
+	captured, err := svc.CaptureHold(ctx, hold.ID, 250)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if captured.Status != domain.HoldCaptured || captured.CapturedAmount != 250 {
+		t.Errorf("unexpected captured hold: %+v", captured)
+	}
+	if wallet, _ := svc.GetWallet(ctx, walletID); wallet.Balance != 750 || wallet.Reserved != 0 {
+		t.Errorf("expected balance 750 without reserve, got %+v", wallet)
+	}
+	last := repo.transactions[len(repo.transactions)-1]
+	if last.OperationType != usecase.CAPTURE || last.Amount != -250 || last.HoldID == nil || *last.HoldID != hold.ID {
+		t.Errorf("unexpected capture record: %+v", last)
+	}
+
+	if _, err := svc.CaptureHold(ctx, hold.ID, 0); !errors.Is(err, domain.ErrHoldNotActive) {
+		t.Errorf("expected ErrHoldNotActive for second capture, got %v", err)
+	}
+
+	// Отмена освобождает всю сумму
+	hold, _ = svc.CreateHold(ctx, walletID, 300, time.Time{})
+	voided, err := svc.VoidHold(ctx, hold.ID)
+	if err != nil || voided.Status != domain.HoldVoided {
+		t.Fatalf("unexpected void result: %+v; error: %v", voided, err)
+	}
+	if available, _ := svc.GetBalance(ctx, walletID); available != 750 {
+		t.Errorf("expected available balance 750 after void, got %d", available)
+	}
+	if _, err := svc.VoidHold(ctx, hold.ID); !errors.Is(err, domain.ErrHoldNotActive) {
+		t.Errorf("expected ErrHoldNotActive for second void, got %v", err)
+	}
+	if _, err := svc.VoidHold(ctx, uuid.New()); !errors.Is(err, domain.ErrHoldNotFound) {
+		t.Errorf("expected ErrHoldNotFound, got %v", err)
+	}
+}
+
+func TestHolds_Expire(t *testing.T) {
+	repo := newMockWalletRepository()
+	holds := newMockHoldRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithHolds(holds))
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 1000)
+
+	if _, err := svc.CreateHold(ctx, walletID, 100, time.Now().Add(-time.Second)); !errors.Is(err, domain.ErrInvalidOperation) {
+		t.Errorf("expected error for hold expiring in the past, got %v", err)
+	}
+
+	hold, _ := svc.CreateHold(ctx, walletID, 400, time.Now().Add(time.Hour))
+	active, _ := svc.CreateHold(ctx, walletID, 100, time.Now().Add(time.Hour))
+
+	// Срок блокировки истёк, но очистка ещё не прошла: списать по ней нельзя
+	stored := holds.holds[hold.ID]
+	stored.ExpiresAt = time.Now().Add(-time.Minute)
+	holds.holds[hold.ID] = stored
+	if _, err := svc.CaptureHold(ctx, hold.ID, 0); !errors.Is(err, domain.ErrHoldNotActive) {
+		t.Errorf("expected ErrHoldNotActive for expired hold, got %v", err)
+	}
+
+	expired, err := svc.ExpireHolds(ctx)
+	if err != nil || expired != 1 {
+		t.Fatalf("expected 1 expired hold, got %d; error: %v", expired, err)
+	}
+	if got, _ := svc.GetHold(ctx, hold.ID); got.Status != domain.HoldExpired {
+		t.Errorf("expected hold to be expired, got %s", got.Status)
+	}
+	if got, _ := svc.GetHold(ctx, active.ID); got.Status != domain.HoldActive {
+		t.Errorf("expected unexpired hold to stay active, got %s", got.Status)
+	}
+	if available, _ := svc.GetBalance(ctx, walletID); available != 900 {
+		t.Errorf("expected available balance 900, got %d", available)
+	}
+}
+
+func TestWalletStatus_Enforcement(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	frozen, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	other, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, frozen, 1000)
+	repo.UpdateBalance(ctx, other, 1000)
+
+	change, err := svc.ChangeWalletStatus(ctx, frozen, "freeze", "suspicious activity", "ops@example.com")
+	if !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Errorf("expected ErrInvalidStatusTransition for unknown status, got %v", err)
+	}
+	change, err = svc.ChangeWalletStatus(ctx, frozen, domain.WalletFrozen, "suspicious activity", "ops@example.com")
+	if err != nil {
+		t.Fatalf("could not freeze wallet: %v", err)
+	}
+	if change.FromStatus != domain.WalletActive || change.ToStatus != domain.WalletFrozen || change.Actor != "ops@example.com" {
+		t.Errorf("unexpected status change: %+v", change)
+	}
+
+	// Замороженный кошелёк принимает зачисления и переводы, но не отправляет средства
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: frozen, OperationType: usecase.DEPOSIT, Amount: 100}); err != nil {
+		t.Errorf("expected deposit to frozen wallet to succeed, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: other, OperationType: usecase.TRANSFER, Amount: 100, DestinationWalletID: frozen}); err != nil {
+		t.Errorf("expected transfer to frozen wallet to succeed, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: frozen, OperationType: usecase.WITHDRAW, Amount: 100}); !errors.Is(err, domain.ErrWalletFrozen) {
+		t.Errorf("expected ErrWalletFrozen for withdraw, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: frozen, OperationType: usecase.TRANSFER, Amount: 100, DestinationWalletID: other}); !errors.Is(err, domain.ErrWalletFrozen) {
+		t.Errorf("expected ErrWalletFrozen for outgoing transfer, got %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, frozen); balance != 1200 {
+		t.Errorf("expected balance 1200, got %d", balance)
+	}
+
+	// Закрыть можно только пустой кошелёк
+	if _, err := svc.ChangeWalletStatus(ctx, frozen, domain.WalletClosed, "customer request", "ops@example.com"); !errors.Is(err, domain.ErrWalletNotEmpty) {
+		t.Errorf("expected ErrWalletNotEmpty, got %v", err)
+	}
+	if _, err := svc.ChangeWalletStatus(ctx, frozen, domain.WalletActive, "cleared", "ops@example.com"); err != nil {
+		t.Fatalf("could not unfreeze wallet: %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: frozen, OperationType: usecase.WITHDRAW, Amount: 1200}); err != nil {
+		t.Fatalf("expected withdraw from unfrozen wallet to succeed, got %v", err)
+	}
+	if _, err := svc.ChangeWalletStatus(ctx, frozen, domain.WalletClosed, "customer request", "ops@example.com"); err != nil {
+		t.Fatalf("could not close wallet: %v", err)
+	}
+
+	// Закрытый кошелёк отклоняет любые операции, статус окончательный
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: frozen, OperationType: usecase.DEPOSIT, Amount: 100}); !errors.Is(err, domain.ErrWalletClosed) {
+		t.Errorf("expected ErrWalletClosed for deposit, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: other, OperationType: usecase.TRANSFER, Amount: 100, DestinationWalletID: frozen}); !errors.Is(err, domain.ErrWalletClosed) {
+		t.Errorf("expected ErrWalletClosed for incoming transfer, got %v", err)
+	}
+	if _, err := svc.ChangeWalletStatus(ctx, frozen, domain.WalletActive, "reopen", "ops@example.com"); !errors.Is(err, domain.ErrInvalidStatusTransition) {
+		t.Errorf("expected ErrInvalidStatusTransition for closed wallet, got %v", err)
+	}
+
+	history, err := svc.ListWalletStatusChanges(ctx, frozen)
+	if err != nil {
+		t.Fatalf("could not list status changes: %v", err)
+	}
+	expected := []string{domain.WalletFrozen, domain.WalletActive, domain.WalletClosed}
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d status changes, got %d", len(expected), len(history))
+	}
+	for i, status := range expected {
+		if history[i].ToStatus != status || history[i].Reason == "" {
+			t.Errorf("change %d: expected status %s with reason, got %+v", i, status, history[i])
+		}
+	}
+}
+
+func TestWalletStatus_FrozenWalletCannotHold(t *testing.T) {
+	repo := newMockWalletRepository()
+	holds := newMockHoldRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithHolds(holds))
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 1000)
+	hold, _ := svc.CreateHold(ctx, walletID, 300, time.Time{})
+
+	if _, err := svc.ChangeWalletStatus(ctx, walletID, domain.WalletFrozen, "chargeback", "risk-bot"); err != nil {
+		t.Fatalf("could not freeze wallet: %v", err)
+	}
+	if _, err := svc.CreateHold(ctx, walletID, 100, time.Time{}); !errors.Is(err, domain.ErrWalletFrozen) {
+		t.Errorf("expected ErrWalletFrozen for new hold, got %v", err)
+	}
+	if _, err := svc.CaptureHold(ctx, hold.ID, 0); !errors.Is(err, domain.ErrWalletFrozen) {
+		t.Errorf("expected ErrWalletFrozen for capture, got %v", err)
+	}
+	// Отмена блокировки возвращает средства и на замороженном кошельке
+	if _, err := svc.VoidHold(ctx, hold.ID); err != nil {
+		t.Errorf("expected void on frozen wallet to succeed, got %v", err)
+	}
+}
+
+func TestOverdraftLimit(t *testing.T) {
+	repo := newMockWalletRepository()
+	holds := newMockHoldRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithHolds(holds))
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 100)
+
+	// Без кредитной линии баланс не уходит в минус
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 150}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds without overdraft, got %v", err)
+	}
+	if _, err := svc.SetOverdraftLimit(ctx, walletID, -1); !errors.Is(err, domain.ErrInvalidAmount) {
+		t.Errorf("expected ErrInvalidAmount for negative limit, got %v", err)
+	}
+
+	wallet, err := svc.SetOverdraftLimit(ctx, walletID, 500)
+	if err != nil || wallet.OverdraftLimit != 500 {
+		t.Fatalf("unexpected result: %+v; error: %v", wallet, err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 400}); err != nil {
+		t.Fatalf("expected withdraw within overdraft to succeed, got %v", err)
+	}
+	wallet, _ = svc.GetWallet(ctx, walletID)
+	if wallet.Balance != -300 || wallet.RemainingCredit() != 200 {
+		t.Errorf("expected balance -300 with 200 credit left, got %+v (credit %d)", wallet, wallet.RemainingCredit())
+	}
+
+	// Блокировка тоже использует кредитную линию
+	if _, err := svc.CreateHold(ctx, walletID, 150, time.Time{}); err != nil {
+		t.Fatalf("could not create hold within overdraft: %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 51}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds beyond overdraft, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 50}); err != nil {
+		t.Errorf("expected withdraw down to -limit to succeed, got %v", err)
+	}
+
+	// Лимит нельзя уменьшить ниже использованного кредита
+	if _, err := svc.SetOverdraftLimit(ctx, walletID, 400); !errors.Is(err, domain.ErrOverdraftInUse) {
+		t.Errorf("expected ErrOverdraftInUse, got %v", err)
+	}
+	if _, err := svc.SetOverdraftLimit(ctx, uuid.New(), 100); !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+}
+
+func TestSpendingLimits(t *testing.T) {
+	repo := newMockWalletRepository()
+	repo.epoch = time.Now() // операции попадают в текущие окна лимитов
+	svc := usecase.NewWalletService(repo, usecase.WithSpendingLimits([]domain.SpendingLimit{
+		{Period: domain.PeriodDaily, MaxAmount: 1000},
+		{Period: domain.PeriodMonthly, MaxCount: 3},
+	}))
+	ctx := context.Background()
+
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	other, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 10000)
+
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 600}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Исходящий перевод расходует тот же лимит
+	_, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.TRANSFER, Amount: 500, DestinationWalletID: other})
+	if !errors.Is(err, domain.ErrSpendingLimitExceeded) {
+		t.Errorf("expected ErrSpendingLimitExceeded for transfer over daily amount, got %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.TRANSFER, Amount: 400, DestinationWalletID: other}); err != nil {
+		t.Errorf("expected transfer up to the daily amount to succeed, got %v", err)
+	}
+	// Зачисления лимиты не расходуют
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 5000}); err != nil {
+		t.Errorf("unexpected deposit error: %v", err)
+	}
+
+	// Лимит кошелька заменяет политику по умолчанию для своего периода
+	statuses, err := svc.SetSpendingLimits(ctx, walletID, []domain.SpendingLimit{{Period: "daily", MaxAmount: 5000}})
+	if err != nil {
+		t.Fatalf("could not set limits: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[0].Override || statuses[0].Used.Amount != 1000 || statuses[1].Override || statuses[1].Used.Count != 2 {
+		t.Errorf("unexpected limit statuses: %+v", statuses)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 1000}); err != nil {
+		t.Errorf("expected withdraw within overridden limit to succeed, got %v", err)
+	}
+	// Месячный лимит на число операций исчерпан
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 1}); !errors.Is(err, domain.ErrSpendingLimitExceeded) {
+		t.Errorf("expected ErrSpendingLimitExceeded for operation count, got %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 13000 {
+		t.Errorf("expected balance 13000, got %d", balance)
+	}
+
+	if _, err := svc.SetSpendingLimits(ctx, walletID, []domain.SpendingLimit{{Period: "HOURLY", MaxAmount: 1}}); !errors.Is(err, domain.ErrInvalidSpendingLimit) {
+		t.Errorf("expected ErrInvalidSpendingLimit, got %v", err)
+	}
+	if err := svc.ResetSpendingLimit(ctx, walletID, domain.PeriodDaily); err != nil {
+		t.Fatalf("could not reset limit: %v", err)
+	}
+	statuses, _ = svc.GetSpendingLimits(ctx, walletID)
+	if len(statuses) != 2 || statuses[0].Override || statuses[0].MaxAmount != 1000 {
+		t.Errorf("expected default daily limit after reset, got %+v", statuses)
+	}
+}
+
+func TestSpendingLimits_Holds(t *testing.T) {
+	repo := newMockWalletRepository()
+	repo.epoch = time.Now()
+	holds := newMockHoldRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithHolds(holds), usecase.WithSpendingLimits([]domain.SpendingLimit{
+		{Period: domain.PeriodDaily, MaxAmount: 1000},
+	}))
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, walletID, 10000)
+
+	// Блокировка сверх лимита отклоняется, блокировка в пределах лимита расходует его
+	if _, err := svc.CreateHold(ctx, walletID, 1001, time.Time{}); !errors.Is(err, domain.ErrSpendingLimitExceeded) {
+		t.Errorf("expected ErrSpendingLimitExceeded for hold over daily amount, got %v", err)
+	}
+	hold, err := svc.CreateHold(ctx, walletID, 700, time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 400}); !errors.Is(err, domain.ErrSpendingLimitExceeded) {
+		t.Errorf("expected reserved amount to count toward the limit, got %v", err)
+	}
+	if _, err := svc.CreateHold(ctx, walletID, 400, time.Time{}); !errors.Is(err, domain.ErrSpendingLimitExceeded) {
+		t.Errorf("expected second hold over the limit to be rejected, got %v", err)
+	}
+
+	// Захват проверяет лимит заново: лимит снижен после создания блокировки
+	if _, err := svc.SetSpendingLimits(ctx, walletID, []domain.SpendingLimit{{Period: domain.PeriodDaily, MaxAmount: 500}}); err != nil {
+		t.Fatalf("could not set limits: %v", err)
+	}
+	if _, err := svc.CaptureHold(ctx, hold.ID, 0); !errors.Is(err, domain.ErrSpendingLimitExceeded) {
+		t.Errorf("expected ErrSpendingLimitExceeded for capture over the limit, got %v", err)
+	}
+	captured, err := svc.CaptureHold(ctx, hold.ID, 500)
+	if err != nil || captured.CapturedAmount != 500 {
+		t.Fatalf("expected capture within the limit, got %+v; error:%v", captured, err)
+	}
+	if balance, _ := repo.GetBalance(ctx, walletID); balance != 9500 {
+		t.Errorf("expected balance 9500, got %d", balance)
+	}
+
+	// Блокировка, созданная до начала окна, лимит этого окна не расходует
+	other, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, other, 10000)
+	old := domain.Hold{ID: uuid.New(), WalletID: other, Amount: 900, Status: domain.HoldActive, CreatedAt: time.Now().AddDate(0, -1, 0)}
+	holds.holds[old.ID] = old
+	repo.reserved[other] = old.Amount
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: other, OperationType: usecase.WITHDRAW, Amount: 1000}); err != nil {
+		t.Errorf("expected hold from a previous window to be ignored, got %v", err)
+	}
+
+	// Каждая блокировка окна считается операцией для лимита числа операций
+	if _, err := svc.SetSpendingLimits(ctx, other, []domain.SpendingLimit{{Period: domain.PeriodDaily, MaxCount: 2}}); err != nil {
+		t.Fatalf("could not set limits: %v", err)
+	}
+	if _, err := svc.CreateHold(ctx, other, 10, time.Time{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := svc.CreateHold(ctx, other, 10, time.Time{}); !errors.Is(err, domain.ErrSpendingLimitExceeded) {
+		t.Errorf("expected holds to count toward the operation limit, got %v", err)
+	}
+}
+
+func TestOwners(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithOwners(repo))
+	ctx := context.Background()
+
+	if _, err := svc.CreateOwner(ctx, domain.Owner{Name: "  "}); !errors.Is(err, domain.ErrInvalidOwner) {
+		t.Errorf("expected ErrInvalidOwner for blank name, got %v", err)
+	}
+	owner, err := svc.CreateOwner(ctx, domain.Owner{Name: " Acme LLC ", Email: "billing@acme.example"})
+	if err != nil || owner.ID == uuid.Nil || owner.Name != "Acme LLC" {
+		t.Fatalf("unexpected owner: %+v; error: %v", owner, err)
+	}
+
+	rub, err := svc.CreateWallet(ctx, domain.Wallet{OwnerID: &owner.ID})
+	if err != nil {
+		t.Fatalf("could not create wallet: %v", err)
+	}
+	usd, _ := svc.CreateWallet(ctx, domain.Wallet{Currency: "usd", OwnerID: &owner.ID})
+	svc.CreateWallet(ctx, domain.Wallet{}) // анонимный кошелёк не попадает в список
+	repo.UpdateBalance(ctx, usd, 250)
+
+	missing := uuid.New()
+	if _, err := svc.CreateWallet(ctx, domain.Wallet{OwnerID: &missing}); !errors.Is(err, domain.ErrOwnerNotFound) {
+		t.Errorf("expected ErrOwnerNotFound for unknown owner, got %v", err)
+	}
+
+	wallets, err := svc.ListOwnerWallets(ctx, owner.ID)
+	if err != nil {
+		t.Fatalf("could not list owner wallets: %v", err)
+	}
+	if len(wallets) != 2 || wallets[0].ID != rub || wallets[1].ID != usd {
+		t.Fatalf("expected owner's two wallets in creation order, got %+v", wallets)
+	}
+	if wallets[0].Currency != "RUB" || wallets[1].Currency != "USD" || wallets[1].Balance != 250 || *wallets[1].OwnerID != owner.ID {
+		t.Errorf("unexpected owner wallets: %+v", wallets)
+	}
+
+	if _, err := svc.ListOwnerWallets(ctx, missing); !errors.Is(err, domain.ErrOwnerNotFound) {
+		t.Errorf("expected ErrOwnerNotFound, got %v", err)
+	}
+}
+
+func TestWalletMetadata(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	gold, err := svc.CreateWallet(ctx, domain.Wallet{DisplayName: "  Payroll ", Metadata: map[string]string{"tier": "gold", "crm": "acc-1"}})
+	if err != nil {
+		t.Fatalf("could not create wallet: %v", err)
+	}
+	svc.CreateWallet(ctx, domain.Wallet{Metadata: map[string]string{"tier": "silver"}})
+	if _, err := svc.CreateWallet(ctx, domain.Wallet{Metadata: map[string]string{"a:b": "c"}}); !errors.Is(err, domain.ErrInvalidMetadata) {
+		t.Errorf("expected ErrInvalidMetadata, got %v", err)
+	}
+
+	wallet, _ := svc.GetWallet(ctx, gold)
+	if wallet.DisplayName != "Payroll" || wallet.Metadata["crm"] != "acc-1" {
+		t.Errorf("unexpected wallet details: %+v", wallet)
+	}
+
+	name, ref := "Payroll EUR", "acc-2"
+	wallet, err = svc.UpdateWallet(ctx, gold, domain.WalletUpdate{DisplayName: &name, Metadata: map[string]*string{"crm": &ref, "tier": nil}})
+	if err != nil {
+		t.Fatalf("could not update wallet: %v", err)
+	}
+	if wallet.DisplayName != name || len(wallet.Metadata) != 1 || wallet.Metadata["crm"] != ref {
+		t.Errorf("unexpected updated wallet: %+v", wallet)
+	}
+	if stored, _ := repo.GetWallet(ctx, gold); stored.Metadata["crm"] != ref || stored.DisplayName != name {
+		t.Errorf("update was not persisted: %+v", stored)
+	}
+	if _, err := svc.UpdateWallet(ctx, uuid.New(), domain.WalletUpdate{DisplayName: &name}); !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+
+	wallets, err := svc.ListWallets(ctx, domain.WalletFilter{Labels: map[string]string{"tier": "silver"}})
+	if err != nil || len(wallets) != 1 || wallets[0].Metadata["tier"] != "silver" {
+		t.Errorf("unexpected wallets by label: %+v; error: %v", wallets, err)
+	}
+	if repo.lastFilter.Limit != usecase.DefaultWalletsLimit {
+		t.Errorf("expected default limit %d, got %d", usecase.DefaultWalletsLimit, repo.lastFilter.Limit)
+	}
+	svc.ListWallets(ctx, domain.WalletFilter{Limit: 10000})
+	if repo.lastFilter.Limit != usecase.MaxWalletsLimit {
+		t.Errorf("expected limit capped at %d, got %d", usecase.MaxWalletsLimit, repo.lastFilter.Limit)
+	}
+	if _, err := svc.ListWallets(ctx, domain.WalletFilter{Labels: map[string]string{"": "x"}}); !errors.Is(err, domain.ErrInvalidMetadata) {
+		t.Errorf("expected ErrInvalidMetadata for empty label key, got %v", err)
+	}
+}
+
+func TestReverseTransaction(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000})
+	deposit := repo.transactions[0]
+
+	// Частичный возврат депозита
+	reversal, err := svc.ReverseTransaction(ctx, deposit.ID, 300)
+	if err != nil {
+		t.Fatalf("could not reverse deposit: %v", err)
+	}
+	if reversal.TransactionID != deposit.ID || reversal.Amount != 300 || reversal.Remaining != 700 || len(reversal.Transactions) != 1 {
+		t.Fatalf("unexpected reversal: %+v", reversal)
+	}
+	compensating := reversal.Transactions[0]
+	if compensating.Amount != -300 || compensating.OperationType != usecase.REVERSAL || *compensating.ReversedTransactionID != deposit.ID {
+		t.Errorf("unexpected compensating entry: %+v", compensating)
+	}
+	if repo.transactions[0].Amount != 1000 {
+		t.Errorf("original transaction must not change, got %+v", repo.transactions[0])
+	}
+
+	if _, err := svc.ReverseTransaction(ctx, deposit.ID, 701); !errors.Is(err, domain.ErrRefundExceedsAmount) {
+		t.Errorf("expected ErrRefundExceedsAmount, got %v", err)
+	}
+	// Без суммы возвращается остаток
+	if reversal, err = svc.ReverseTransaction(ctx, deposit.ID, 0); err != nil || reversal.Amount != 700 || reversal.Remaining != 0 {
+		t.Fatalf("unexpected full reversal: %+v; error: %v", reversal, err)
+	}
+	if _, err := svc.ReverseTransaction(ctx, deposit.ID, 0); !errors.Is(err, domain.ErrAlreadyReversed) {
+		t.Errorf("expected ErrAlreadyReversed, got %v", err)
+	}
+	if _, err := svc.ReverseTransaction(ctx, reversal.Transactions[0].ID, 0); !errors.Is(err, domain.ErrNotReversible) {
+		t.Errorf("expected ErrNotReversible for a reversal, got %v", err)
+	}
+	if _, err := svc.ReverseTransaction(ctx, uuid.New(), 0); !errors.Is(err, domain.ErrTransactionNotFound) {
+		t.Errorf("expected ErrTransactionNotFound, got %v", err)
+	}
+	if balance, _ := svc.GetBalance(ctx, walletID); balance != 0 {
+		t.Errorf("expected balance 0 after full reversal, got %d", balance)
+	}
+
+	// Возврат снятия зачисляет средства
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 500})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 200})
+	withdrawal := repo.transactions[len(repo.transactions)-1]
+	if reversal, err = svc.ReverseTransaction(ctx, withdrawal.ID, 0); err != nil || reversal.Transactions[0].Amount != 200 {
+		t.Fatalf("unexpected withdrawal refund: %+v; error: %v", reversal, err)
+	}
+	if balance, _ := svc.GetBalance(ctx, walletID); balance != 500 {
+		t.Errorf("expected balance 500 after refund, got %d", balance)
+	}
+
+	// Потраченный депозит вернуть нельзя
+	topUp := repo.transactions[len(repo.transactions)-3]
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 400})
+	if _, err := svc.ReverseTransaction(ctx, topUp.ID, 0); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+
+	summaries, _ := svc.GetLedgerSummary(ctx)
+	for _, summary := range summaries {
+		if !summary.Balanced {
+			t.Errorf("ledger is not balanced after reversals: %+v", summary)
+		}
+	}
+}
+
+func TestReverseTransaction_Transfer(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	from, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	to, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	repo.UpdateBalance(ctx, from, 1000)
+	svc.PerformOperation(ctx, domain.Operation{WalletID: from, OperationType: usecase.TRANSFER, Amount: 600, DestinationWalletID: to})
+	debit, credit := repo.transactions[0], repo.transactions[1]
+
+	// id зачисления тоже отменяет перевод
+	reversal, err := svc.ReverseTransaction(ctx, credit.ID, 250)
+	if err != nil {
+		t.Fatalf("could not reverse transfer: %v", err)
+	}
+	if reversal.TransactionID != debit.ID || reversal.Remaining != 350 || len(reversal.Transactions) != 2 {
+		t.Fatalf("unexpected reversal: %+v", reversal)
+	}
+	refundDebit, refundCredit := reversal.Transactions[0], reversal.Transactions[1]
+	if refundDebit.WalletID != to || refundDebit.Amount != -250 || *refundDebit.ReversedTransactionID != credit.ID {
+		t.Errorf("unexpected recipient entry: %+v", refundDebit)
+	}
+	if refundCredit.WalletID != from || refundCredit.Amount != 250 || *refundCredit.RelatedTransactionID != refundDebit.ID {
+		t.Errorf("unexpected sender entry: %+v", refundCredit)
+	}
+
+	if _, err := svc.ReverseTransaction(ctx, debit.ID, 351); !errors.Is(err, domain.ErrRefundExceedsAmount) {
+		t.Errorf("expected ErrRefundExceedsAmount, got %v", err)
+	}
+	// Закрытый кошелёк получателя не даёт вернуть перевод
+	repo.statuses[to] = domain.WalletClosed
+	if _, err := svc.ReverseTransaction(ctx, debit.ID, 0); !errors.Is(err, domain.ErrWalletClosed) {
+		t.Errorf("expected ErrWalletClosed, got %v", err)
+	}
+	repo.statuses[to] = domain.WalletFrozen // исправление проходит и для замороженного кошелька
+	if _, err := svc.ReverseTransaction(ctx, debit.ID, 0); err != nil {
+		t.Fatalf("could not reverse the rest of transfer: %v", err)
+	}
+	fromBalance, _ := repo.GetBalance(ctx, from)
+	toBalance, _ := repo.GetBalance(ctx, to)
+	if fromBalance != 1000 || toBalance != 0 {
+		t.Errorf("expected balances 1000 and 0, got %d and %d", fromBalance, toBalance)
+	}
+	if _, err := svc.ReverseTransaction(ctx, credit.ID, 0); !errors.Is(err, domain.ErrAlreadyReversed) {
+		t.Errorf("expected ErrAlreadyReversed, got %v", err)
+	}
+}
+
+func TestReverseTransaction_CrossCurrencyTransfer(t *testing.T) {
+	repo := newMockWalletRepository()
+	rates := newMockExchangeRateRepository()
+	svc := usecase.NewWalletService(repo, usecase.WithExchangeRates(rates), usecase.WithRoundingMode(domain.RoundDown))
+	ctx := context.Background()
+
+	usd, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	rub, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, usd, 10000)
+	svc.AddExchangeRates(ctx, []domain.ExchangeRate{{BaseCurrency: "USD", QuoteCurrency: "RUB", Rate: "90.555"}})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: usd, OperationType: usecase.TRANSFER, Amount: 1001, DestinationWalletID: rub})
+
+	// 90645 * 333 / 1001 = 30154.9..., округление вниз до 30154
+	reversal, err := svc.ReverseTransaction(ctx, repo.transactions[0].ID, 333)
+	if err != nil {
+		t.Fatalf("could not reverse transfer: %v", err)
+	}
+	if reversal.Transactions[0].Amount != -30154 || *reversal.Transactions[0].ExchangeRate != "90.555" {
+		t.Errorf("unexpected prorated recipient entry: %+v", reversal.Transactions[0])
+	}
+	// Последний возврат забирает точный остаток зачисления
+	if reversal, err = svc.ReverseTransaction(ctx, repo.transactions[0].ID, 0); err != nil || reversal.Transactions[0].Amount != -(90645 - 30154) {
+		t.Fatalf("unexpected final reversal: %+v; error: %v", reversal, err)
+	}
+	usdBalance, _ := repo.GetBalance(ctx, usd)
+	rubBalance, _ := repo.GetBalance(ctx, rub)
+	if usdBalance != 10000 || rubBalance != 0 {
+		t.Errorf("expected balances 10000 USD and 0 RUB, got %d and %d", usdBalance, rubBalance)
+	}
+}
+
+func TestGetBalanceAt(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := svc.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	// Мок записывает операции с шагом в секунду начиная с epoch
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 300})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 50})
+
+	tests := []struct {
+		asOf     time.Time
+		expected int64
+	}{
+		{repo.epoch.Add(-time.Second), 0},
+		{repo.epoch, 1000},
+		{repo.epoch.Add(1500 * time.Millisecond), 700},
+		{repo.epoch.Add(time.Hour), 750},
+	}
+	for _, tt := range tests {
+		snapshot, err := svc.GetBalanceAt(ctx, walletID, tt.asOf)
+		if err != nil || snapshot.Balance != tt.expected || snapshot.Currency != "USD" || !snapshot.AsOf.Equal(tt.asOf) {
+			t.Errorf("as of %s: expected %d, got %+v; error: %v", tt.asOf, tt.expected, snapshot, err)
+		}
+	}
+
+	if _, err := svc.GetBalanceAt(ctx, uuid.New(), repo.epoch); !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+}
+
+// запись выписки в память для проверки
+type recordedStatement struct {
+	header  domain.StatementHeader
+	lines   []domain.StatementLine
+	summary *domain.StatementSummary
+	onBegin func() // вызывается после заголовка, до чтения строк
+}
+
+func (r *recordedStatement) Begin(header domain.StatementHeader) error {
+	r.header = header
+	if r.onBegin != nil {
+		r.onBegin()
+	}
+	return nil
+}
+
+func (r *recordedStatement) Line(line domain.StatementLine) error {
+	r.lines = append(r.lines, line)
+	return nil
+}
+
+func (r *recordedStatement) End(summary domain.StatementSummary) error {
+	r.summary = &summary
+	return nil
+}
+
+func TestWriteStatement(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	walletID, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	other, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	// Операции записываются в epoch, epoch+1s, ...
+	for _, op := range []domain.Operation{
+		{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 1000},
+		{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 300},
+		{WalletID: other, OperationType: usecase.DEPOSIT, Amount: 999},
+		{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 50},
+		{WalletID: walletID, OperationType: usecase.WITHDRAW, Amount: 20},
+	} {
+		if _, err := svc.PerformOperation(ctx, op); err != nil {
+			t.Fatalf("could not perform operation: %v", err)
+		}
+	}
+
+	out := &recordedStatement{}
+	from, to := repo.epoch.Add(time.Second), repo.epoch.Add(4*time.Second)
+	if err := svc.WriteStatement(ctx, walletID, from, to, out); err != nil {
+		t.Fatalf("could not write statement: %v", err)
+	}
+	if out.header.OpeningBalance != 1000 || out.header.Currency != "RUB" || !out.header.From.Equal(from) {
+		t.Errorf("unexpected header: %+v", out.header)
+	}
+	if len(out.lines) != 2 || out.lines[0].Amount != -300 || out.lines[0].Balance != 700 || out.lines[1].Balance != 750 {
+		t.Fatalf("unexpected lines: %+v", out.lines)
+	}
+	expected := domain.StatementSummary{ClosingBalance: 750, TotalCredits: 50, TotalDebits: 300, Count: 2}
+	if out.summary == nil || *out.summary != expected {
+		t.Errorf("expected summary %+v, got %+v", expected, out.summary)
+	}
+
+	for _, period := range [][2]time.Time{{to, from}, {from, from}, {time.Time{}, to}} {
+		out := &recordedStatement{}
+		if err := svc.WriteStatement(ctx, walletID, period[0], period[1], out); !errors.Is(err, domain.ErrInvalidPeriod) || out.summary != nil {
+			t.Errorf("period %v: expected ErrInvalidPeriod before writing, got %v", period, err)
+		}
+	}
+	if err := svc.WriteStatement(ctx, uuid.New(), from, to, &recordedStatement{}); !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+
+	// Операция, выполненная во время выгрузки, в выписку не попадает: остатки и строки из одного снимка
+	out = &recordedStatement{onBegin: func() {
+		if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: walletID, OperationType: usecase.DEPOSIT, Amount: 5}); err != nil {
+			t.Fatalf("could not perform operation: %v", err)
+		}
+	}}
+	to = repo.epoch.Add(time.Hour)
+	if err := svc.WriteStatement(ctx, walletID, from, to, out); err != nil {
+		t.Fatalf("could not write statement: %v", err)
+	}
+	if len(out.lines) != 3 || out.summary == nil || out.summary.ClosingBalance != 730 {
+		t.Errorf("expected statement without the concurrent operation, got %+v and %+v", out.lines, out.summary)
+	}
+}
+
+func TestReconcileBalances(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+
+	consistent, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	drifted, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	unrepairable, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	ledger, _ := svc.CreateWallet(ctx, domain.Wallet{})
+	for _, id := range []uuid.UUID{consistent, drifted, unrepairable, ledger} {
+		svc.PerformOperation(ctx, domain.Operation{WalletID: id, OperationType: usecase.DEPOSIT, Amount: 100})
+	}
+	// Баланс изменён в обход операций
+	repo.wallets[drifted] += 50
+	repo.wallets[unrepairable] = 0
+	repo.transactions = append(repo.transactions, domain.Transaction{WalletID: unrepairable, Amount: -300})
+	repo.ledgerDrift = map[uuid.UUID]int64{ledger: 7}
+
+	report, err := svc.ReconcileBalances(ctx, false)
+	if err != nil {
+		t.Fatalf("could not reconcile: %v", err)
+	}
+	if report.Checked != 4 || report.Drifted != 3 || report.Repaired != 0 || report.Consistent || report.Repair {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	checks := make(map[uuid.UUID]domain.BalanceCheck)
+	for _, check := range report.Wallets {
+		checks[check.WalletID] = check
+	}
+	if check := checks[drifted]; check.StoredBalance != 150 || check.ExpectedBalance != 100 || check.Drift != 50 || check.Repaired {
+		t.Errorf("unexpected drift: %+v", check)
+	}
+	if check := checks[ledger]; check.Drift != 0 || check.LedgerDrift != 7 {
+		t.Errorf("unexpected ledger drift: %+v", check)
+	}
+	if repo.wallets[drifted] != 150 {
+		t.Error("expected balances to be left alone without repair")
+	}
+
+	report, err = svc.ReconcileBalances(ctx, true)
+	if err != nil {
+		t.Fatalf("could not reconcile: %v", err)
+	}
+	checks = make(map[uuid.UUID]domain.BalanceCheck)
+	for _, check := range report.Wallets {
+		checks[check.WalletID] = check
+	}
+	if report.Repaired != 1 || repo.wallets[drifted] != 100 || !checks[drifted].Repaired || checks[drifted].Drift != 50 {
+		t.Errorf("expected drifted wallet to be repaired, got %+v", checks[drifted])
+	}
+	// Ожидаемый баланс -200 нарушает ограничение кредитной линии: баланс не меняется
+	if check := checks[unrepairable]; check.Repaired || check.RepairError == "" || repo.wallets[unrepairable] != 0 {
+		t.Errorf("expected repair error, got %+v", check)
+	}
+	// Расхождение главной книги не исправляется
+	if report.Consistent || checks[ledger].Repaired {
+		t.Errorf("expected ledger drift to remain, got %+v", report)
+	}
+
+	delete(repo.ledgerDrift, ledger)
+	repo.overdrafts[unrepairable] = 500
+	if report, _ := svc.ReconcileBalances(ctx, true); !report.Consistent || report.Repaired != 1 {
+		t.Errorf("expected all drift to be repaired, got %+v", report)
+	}
+	if report, _ := svc.ReconcileBalances(ctx, false); !report.Consistent || report.Drifted != 0 || len(report.Wallets) != 0 {
+		t.Errorf("expected consistent balances, got %+v", report)
+	}
+}
+
+// Баланс меняется только записями операций: после любых операций он равен сумме записей кошелька
+func TestBalanceFollowsLedger(t *testing.T) {
+	repo := newMockWalletRepository()
+	ctx := context.Background()
+	revenue, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc := usecase.NewWalletService(repo, usecase.WithHolds(newMockHoldRepository()), usecase.WithFees(domain.FeeSchedule{
+		RevenueWallets: map[string]uuid.UUID{"RUB": revenue},
+		Rules:          []domain.FeeRule{{Name: "withdraw", OperationType: usecase.WITHDRAW, Flat: 3, Rounding: domain.RoundHalfEven}},
+	}))
+	a, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	b, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	deposit, _ := svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.DEPOSIT, Amount: 1000})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.WITHDRAW, Amount: 100})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: a, OperationType: usecase.TRANSFER, Amount: 250, DestinationWalletID: b})
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: b, OperationType: usecase.WITHDRAW, Amount: 5000}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+	hold, _ := svc.CreateHold(ctx, b, 200, time.Time{})
+	svc.CaptureHold(ctx, hold.ID, 150)
+	svc.ReverseTransaction(ctx, deposit.Transactions[0].ID, 100)
+
+	for _, walletID := range []uuid.UUID{revenue, a, b} {
+		balance, _ := repo.GetBalance(ctx, walletID)
+		ledger, _ := repo.GetBalanceAt(ctx, walletID, time.Now().AddDate(100, 0, 0))
+		if balance != ledger {
+			t.Errorf("wallet %s: balance %d differs from its records %d", walletID, balance, ledger)
+		}
+	}
+	if balance, _ := repo.GetBalance(ctx, a); balance != 1000-100-3-250-100 {
+		t.Errorf("expected balance 547, got %d", balance)
+	}
+	if report, _ := svc.ReconcileBalances(ctx, false); !report.Consistent {
+		t.Errorf("expected consistent balances, got %+v", report)
+	}
+}
+
+func TestPerformOperation_Fees(t *testing.T) {
+	repo := newMockWalletRepository()
+	ctx := context.Background()
+	revenue, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc := usecase.NewWalletService(repo, usecase.WithFees(domain.FeeSchedule{
+		RevenueWallets: map[string]uuid.UUID{"RUB": revenue},
+		Rules: []domain.FeeRule{
+			{Name: "gold", OperationType: usecase.WITHDRAW, Tier: "GOLD"},
+			{Name: "withdraw", OperationType: usecase.WITHDRAW, Flat: 10, Percent: "1", Rounding: domain.RoundUp},
+			{Name: "transfer", OperationType: usecase.TRANSFER, Percent: "2", MinFee: 5, Rounding: domain.RoundHalfEven},
+		},
+	}))
+
+	wallet, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	other, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	usd, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	repo.UpdateBalance(ctx, wallet, 1000)
+	repo.UpdateBalance(ctx, usd, 1000)
+
+	// Пополнение без правила - без комиссии
+	result, err := svc.PerformOperation(ctx, domain.Operation{WalletID: wallet, OperationType: usecase.DEPOSIT, Amount: 100})
+	if err != nil || len(result.Transactions) != 1 || len(result.Fees) != 0 {
+		t.Fatalf("expected deposit without fees, got %+v; error:%v", result, err)
+	}
+
+	// Списание 150: 10 + 1% (1.5 вверх = 2)
+	result, err = svc.PerformOperation(ctx, domain.Operation{WalletID: wallet, OperationType: usecase.WITHDRAW, Amount: 150})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Fees) != 1 {
+		t.Fatalf("expected 1 fee, got %+v", result.Fees)
+	}
+	fee := result.Fees[0]
+	if fee.Rule != "withdraw" || fee.Amount != 12 || fee.Flat != 10 || fee.Percentage != 2 || fee.RevenueWalletID != revenue || fee.WalletID != wallet {
+		t.Errorf("unexpected fee: %+v", fee)
+	}
+	if balance, _ := repo.GetBalance(ctx, wallet); balance != 938 {
+		t.Errorf("expected balance 938, got %d", balance)
+	}
+	if balance, _ := repo.GetBalance(ctx, revenue); balance != 12 {
+		t.Errorf("expected revenue balance 12, got %d", balance)
+	}
+
+	// Пара записей FEE: списание ссылается на операцию, зачисление - на списание
+	n := len(repo.transactions)
+	operation, debit, credit := repo.transactions[n-3], repo.transactions[n-2], repo.transactions[n-1]
+	if debit.ID != fee.TransactionID || debit.OperationType != usecase.FEE || debit.Amount != -12 || *debit.CounterpartyWalletID != revenue || *debit.RelatedTransactionID != operation.ID {
+		t.Errorf("unexpected fee debit: %+v", debit)
+	}
+	if credit.WalletID != revenue || credit.OperationType != usecase.FEE || credit.Amount != 12 || *credit.CounterpartyWalletID != wallet || *credit.RelatedTransactionID != debit.ID {
+		t.Errorf("unexpected fee credit: %+v", credit)
+	}
+
+	// Перевод 100: 2% = 2, но не меньше 5; комиссию платит отправитель
+	result, err = svc.PerformOperation(ctx, domain.Operation{WalletID: wallet, OperationType: usecase.TRANSFER, Amount: 100, DestinationWalletID: other})
+	if err != nil || len(result.Transactions) != 2 || len(result.Fees) != 1 || result.Fees[0].Amount != 5 || result.Fees[0].WalletID != wallet {
+		t.Fatalf("unexpected transfer result %+v; error:%v", result, err)
+	}
+	if *repo.transactions[len(repo.transactions)-2].RelatedTransactionID != result.Transactions[0].ID {
+		t.Error("expected transfer fee to reference the debit record")
+	}
+	walletBalance, _ := repo.GetBalance(ctx, wallet)
+	otherBalance, _ := repo.GetBalance(ctx, other)
+	revenueBalance, _ := repo.GetBalance(ctx, revenue)
+	if walletBalance != 833 || otherBalance != 100 || revenueBalance != 17 {
+		t.Errorf("expected balances 833/100/17, got %d/%d/%d", walletBalance, otherBalance, revenueBalance)
+	}
+
+	// Не хватает на комиссию - операция откатывается целиком
+	records := len(repo.transactions)
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: wallet, OperationType: usecase.WITHDRAW, Amount: 830}); !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Errorf("expected ErrInsufficientFunds, got %v", err)
+	}
+	if balance, _ := repo.GetBalance(ctx, wallet); balance != 833 || len(repo.transactions) != records {
+		t.Errorf("expected no changes, got balance %d and %d records", balance, len(repo.transactions)-records)
+	}
+
+	// Тариф GOLD попадает в бесплатное правило
+	if _, err := svc.SetWalletTier(ctx, wallet, "gold"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result, err := svc.PerformOperation(ctx, domain.Operation{WalletID: wallet, OperationType: usecase.WITHDRAW, Amount: 830}); err != nil || len(result.Fees) != 0 {
+		t.Errorf("expected withdraw without fees, got %+v; error:%v", result, err)
+	}
+
+	// Без кошелька доходов в валюте и с самого кошелька доходов комиссия не берётся
+	if result, err := svc.PerformOperation(ctx, domain.Operation{WalletID: usd, OperationType: usecase.WITHDRAW, Amount: 100}); err != nil || len(result.Fees) != 0 {
+		t.Errorf("expected USD withdraw without fees, got %+v; error:%v", result, err)
+	}
+	if result, err := svc.PerformOperation(ctx, domain.Operation{WalletID: revenue, OperationType: usecase.WITHDRAW, Amount: 10}); err != nil || len(result.Fees) != 0 {
+		t.Errorf("expected revenue withdraw without fees, got %+v; error:%v", result, err)
+	}
+
+	// Записи FEE не входят в расход по лимитам
+	usage, _ := repo.GetSpendingUsage(ctx, wallet, time.Time{})
+	if usage.Amount != 150+100+830 {
+		t.Errorf("expected spending usage without fees, got %d", usage.Amount)
+	}
+}
+
+func TestPerformOperation_FeeRevenueWalletErrors(t *testing.T) {
+	repo := newMockWalletRepository()
+	ctx := context.Background()
+	revenue, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "USD"})
+	wallet, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, wallet, 1000)
+	rules := []domain.FeeRule{{Name: "withdraw", OperationType: usecase.WITHDRAW, Flat: 10, Rounding: domain.RoundHalfEven}}
+
+	for name, revenueID := range map[string]uuid.UUID{"Wrong currency": revenue, "Unknown wallet": uuid.New()} {
+		t.Run(name, func(t *testing.T) {
+			svc := usecase.NewWalletService(repo, usecase.WithFees(domain.FeeSchedule{RevenueWallets: map[string]uuid.UUID{"RUB": revenueID}, Rules: rules}))
+			_, err := svc.PerformOperation(ctx, domain.Operation{WalletID: wallet, OperationType: usecase.WITHDRAW, Amount: 100})
+			if err == nil || errors.Is(err, domain.ErrWalletNotFound) {
+				t.Errorf("expected configuration error, got %v", err)
+			}
+			if balance, _ := repo.GetBalance(ctx, wallet); balance != 1000 {
+				t.Errorf("expected balance 1000, got %d", balance)
+			}
+		})
+	}
+}
+
+func TestPerformOperation_FeeLocks(t *testing.T) {
+	repo := newMockWalletRepository()
+	ctx := context.Background()
+	revenue, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc := usecase.NewWalletService(repo, usecase.WithFees(domain.FeeSchedule{
+		RevenueWallets: map[string]uuid.UUID{"RUB": revenue},
+		Rules: []domain.FeeRule{
+			{Name: "deposit", OperationType: usecase.DEPOSIT, Flat: 5, Rounding: domain.RoundHalfEven},
+			{Name: "transfer", OperationType: usecase.TRANSFER, Flat: 5, Rounding: domain.RoundHalfEven},
+		},
+	}))
+	wallet, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	other, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, wallet, 1000)
+
+	// Кошелёк доходов блокируется одним вызовом с участниками, а не после них
+	repo.locks = nil
+	if _, err := svc.PerformOperation(ctx, domain.Operation{WalletID: wallet, OperationType: usecase.TRANSFER, Amount: 100, DestinationWalletID: other}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.locks) != 1 || len(repo.locks[0]) != 3 {
+		t.Errorf("expected participants and revenue wallet to be locked together, got %v", repo.locks)
+	}
+
+	// С замороженного кошелька комиссия за пополнение не списывается
+	if _, err := svc.ChangeWalletStatus(ctx, other, domain.WalletFrozen, "check", "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := svc.PerformOperation(ctx, domain.Operation{WalletID: other, OperationType: usecase.DEPOSIT, Amount: 100})
+	if err != nil || len(result.Fees) != 0 {
+		t.Fatalf("expected deposit to frozen wallet without fees, got %+v; error:%v", result, err)
+	}
+	if balance, _ := repo.GetBalance(ctx, other); balance != 200 {
+		t.Errorf("expected balance 200, got %d", balance)
+	}
+	if balance, _ := repo.GetBalance(ctx, revenue); balance != 5 {
+		t.Errorf("expected revenue balance 5, got %d", balance)
+	}
+}
+
+func TestSetWalletTier(t *testing.T) {
+	repo := newMockWalletRepository()
+	svc := usecase.NewWalletService(repo)
+	ctx := context.Background()
+	walletID, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+
+	if wallet, _ := svc.GetWallet(ctx, walletID); wallet.Tier != domain.DefaultWalletTier {
+		t.Errorf("expected default tier, got %q", wallet.Tier)
+	}
+	wallet, err := svc.SetWalletTier(ctx, walletID, " premium ")
+	if err != nil || wallet.Tier != "PREMIUM" {
+		t.Fatalf("expected tier PREMIUM, got %q; error:%v", wallet.Tier, err)
+	}
+	if wallet, _ := svc.GetWallet(ctx, walletID); wallet.Tier != "PREMIUM" {
+		t.Errorf("expected stored tier PREMIUM, got %q", wallet.Tier)
+	}
+	if _, err := svc.SetWalletTier(ctx, walletID, "no spaces"); !errors.Is(err, domain.ErrInvalidTier) {
+		t.Errorf("expected ErrInvalidTier, got %v", err)
+	}
+	if _, err := svc.SetWalletTier(ctx, uuid.New(), "GOLD"); !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected ErrWalletNotFound, got %v", err)
+	}
+	schedule := svc.GetFeeSchedule(ctx)
+	if schedule.Rules == nil || schedule.RevenueWallets == nil {
+		t.Errorf("expected empty schedule, got %+v", schedule)
+	}
+}
+
+// mockInterestRepository хранит начисления процентов в памяти; остатки и выплаты берёт из мока кошельков
+type mockInterestRepository struct {
+	wallets     *mockWalletRepository
+	accruals    []domain.InterestAccrual
+	paidBy      map[int]uuid.UUID                // выплата, в которую вошло начисление с индексом i
+	runs        map[time.Time]domain.InterestRun // итоги по дням
+	failSaveRun bool                             // имитация сбоя после выплат
+}
+
+func newMockInterestRepository(wallets *mockWalletRepository) *mockInterestRepository {
+	return &mockInterestRepository{wallets: wallets, paidBy: make(map[int]uuid.UUID), runs: make(map[time.Time]domain.InterestRun)}
+}
+
+func (m *mockInterestRepository) StreamEndOfDayBalances(ctx context.Context, end time.Time, fn func(domain.EndOfDayBalance) error) error {
+	for _, walletID := range m.wallets.walletIDs {
+		wallet, _ := m.wallets.GetWallet(ctx, walletID)
+		balance, _ := m.wallets.GetBalanceAt(ctx, walletID, end.Add(-time.Nanosecond))
+		err := fn(domain.EndOfDayBalance{WalletID: walletID, Currency: wallet.Currency, Tier: wallet.Tier, Status: wallet.Status, Balance: balance})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockInterestRepository) CreateInterestAccruals(ctx context.Context, accruals []domain.InterestAccrual) (int64, error) {
+	var created int64
+	for _, accrual := range accruals {
+		exists := false
+		for _, saved := range m.accruals {
+			exists = exists || (saved.WalletID == accrual.WalletID && saved.Date.Equal(accrual.Date))
+		}
+		if !exists {
+			m.accruals = append(m.accruals, accrual)
+			created++
+		}
+	}
+	return created, nil
+}
+
+func (m *mockInterestRepository) ListInterestPayees(ctx context.Context, through time.Time) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool)
+	payees := []uuid.UUID{}
+	for i, accrual := range m.accruals {
+		if _, paid := m.paidBy[i]; !paid && !accrual.Date.After(through) && !seen[accrual.WalletID] {
+			seen[accrual.WalletID] = true
+			payees = append(payees, accrual.WalletID)
+		}
+	}
+	return payees, nil
+}
+
+func (m *mockInterestRepository) GetAccruedInterest(ctx context.Context, walletID uuid.UUID, through time.Time) (domain.AccruedInterest, error) {
+	accrued := new(big.Rat)
+	for _, accrual := range m.accruals {
+		if accrual.WalletID == walletID && !accrual.Date.After(through) {
+			amount, _ := new(big.Rat).SetString(accrual.Amount)
+			accrued.Add(accrued, amount)
+		}
+	}
+	interest := domain.AccruedInterest{WalletID: walletID, Accrued: accrued.FloatString(domain.InterestScale)}
+	for _, tx := range m.wallets.transactions {
+		if tx.WalletID == walletID && tx.OperationType == usecase.INTEREST {
+			interest.Paid += tx.Amount
+		}
+	}
+	return interest, nil
+}
+
+func (m *mockInterestRepository) MarkInterestPaid(ctx context.Context, walletID uuid.UUID, through time.Time, transactionID uuid.UUID) error {
+	for i, accrual := range m.accruals {
+		if _, paid := m.paidBy[i]; !paid && accrual.WalletID == walletID && !accrual.Date.After(through) {
+			m.paidBy[i] = transactionID
+		}
+	}
+	return nil
+}
+
+func (m *mockInterestRepository) GetLastInterestRun(ctx context.Context) (time.Time, error) {
+	var last time.Time
+	for date := range m.runs {
+		if date.After(last) {
+			last = date
+		}
+	}
+	return last, nil
+}
+
+func (m *mockInterestRepository) SaveInterestRun(ctx context.Context, run domain.InterestRun) error {
+	if m.failSaveRun {
+		return errors.New("insert failed")
+	}
+	saved := m.runs[run.Date]
+	run.Accrued += saved.Accrued
+	m.runs[run.Date] = run
+	return nil
+}
+
+func TestAccrueInterest(t *testing.T) {
+	repo := newMockWalletRepository()
+	interest := newMockInterestRepository(repo)
+	svc := usecase.NewWalletService(repo, usecase.WithInterest(interest, domain.InterestSchedule{
+		Rates: []domain.InterestRate{
+			{Tier: "GOLD", Currency: "RUB", AnnualRate: "36.5"}, // 0.1% в день
+			{Tier: "STANDARD", AnnualRate: "3.65"},              // 0.01% в день
+		},
+		DayCount: 365,
+		Payout:   domain.PayoutMonthly,
+	}))
+	ctx := context.Background()
+
+	gold, _ := svc.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	standard, _ := svc.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	empty, _ := svc.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	closed, _ := svc.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc.SetWalletTier(ctx, gold, "GOLD")
+	svc.PerformOperation(ctx, domain.Operation{WalletID: gold, OperationType: usecase.DEPOSIT, Amount: 100000})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: standard, OperationType: usecase.DEPOSIT, Amount: 12345})
+	svc.PerformOperation(ctx, domain.Operation{WalletID: closed, OperationType: usecase.DEPOSIT, Amount: 500})
+	repo.statuses[closed] = domain.WalletClosed
+
+	jan30 := time.Date(2026, 1, 30, 15, 0, 0, 0, time.UTC)
+	run, err := svc.AccrueInterest(ctx, jan30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !run.Date.Equal(domain.AccrualDate(jan30)) || run.Accrued != 2 || run.AlreadyAccrued != 0 || len(run.Payouts) != 0 {
+		t.Errorf("unexpected run: %+v", run)
+	}
+	for _, accrual := range interest.accruals {
+		if accrual.WalletID == empty || accrual.WalletID == closed {
+			t.Errorf("unexpected accrual: %+v", accrual)
+		}
+		if accrual.WalletID == standard && (accrual.Amount != "1.234500000000" || accrual.AnnualRate != "3.65" || accrual.Balance != 12345) {
+			t.Errorf("unexpected standard accrual: %+v", accrual)
+		}
+	}
+
+	// Повторный запуск за тот же день ничего не начисляет
+	if run, err := svc.AccrueInterest(ctx, jan30); err != nil || run.Accrued != 0 || run.AlreadyAccrued != 2 {
+		t.Errorf("expected rerun without accruals, got %+v; error:%v", run, err)
+	}
+
+	// Последний день месяца: выплата целой части начислений; сбой после выплат
+	interest.failSaveRun = true
+	if _, err := svc.AccrueInterest(ctx, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Fatal("expected error")
+	}
+	goldBalance, _ := repo.GetBalance(ctx, gold)
+	standardBalance, _ := repo.GetBalance(ctx, standard)
+	if goldBalance != 100200 || standardBalance != 12347 {
+		t.Errorf("expected balances 100200 and 12347, got %d and %d", goldBalance, standardBalance)
+	}
+
+	// Перезапуск после сбоя не выплачивает повторно
+	interest.failSaveRun = false
+	run, err = svc.AccrueInterest(ctx, time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC))
+	if err != nil || run.Accrued != 0 || run.AlreadyAccrued != 2 || len(run.Payouts) != 0 {
+		t.Errorf("expected rerun without payouts, got %+v; error:%v", run, err)
+	}
+	if balance, _ := repo.GetBalance(ctx, gold); balance != 100200 {
+		t.Errorf("expected balance 100200, got %d", balance)
+	}
+
+	// Пропущенные дни февраля начисляются по порядку; дробный остаток января переходит в выплату февраля
+	runs, err := svc.RunInterestAccrual(ctx, time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC))
+	if err != nil || len(runs) != 28 {
+		t.Fatalf("expected 28 runs, got %d; error:%v", len(runs), err)
+	}
+	last := runs[len(runs)-1]
+	if !last.Date.Equal(time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)) || len(last.Payouts) != 2 {
+		t.Fatalf("unexpected last run: %+v", last)
+	}
+	for _, payout := range last.Payouts {
+		if (payout.WalletID == gold && payout.Amount != 2805) || (payout.WalletID == standard && payout.Amount != 35) || payout.Currency != "RUB" {
+			t.Errorf("unexpected payout: %+v", payout)
+		}
+	}
+	if balance, _ := repo.GetBalance(ctx, standard); balance != 12382 {
+		t.Errorf("expected balance 12382, got %d", balance)
+	}
+	payoutTx := repo.transactions[len(repo.transactions)-1]
+	if payoutTx.OperationType != usecase.INTEREST || payoutTx.ID != last.Payouts[len(last.Payouts)-1].TransactionID {
+		t.Errorf("unexpected payout record: %+v", payoutTx)
+	}
+	for i, accrual := range interest.accruals {
+		if interest.paidBy[i] == uuid.Nil {
+			t.Errorf("expected accrual to be paid: %+v", accrual)
+		}
+	}
+
+	// Выплаты процентов сходятся в главной книге
+	summaries, _ := svc.GetLedgerSummary(ctx)
+	if len(summaries) != 1 || !summaries[0].Balanced || summaries[0].InterestPaid != 200+2+2805+35 {
+		t.Errorf("unexpected ledger summary: %+v", summaries)
+	}
+
+	if _, err := svc.AccrueInterest(ctx, time.Now()); !errors.Is(err, domain.ErrInvalidAccrualDate) {
+		t.Errorf("expected ErrInvalidAccrualDate, got %v", err)
+	}
+	if _, err := usecase.NewWalletService(repo).AccrueInterest(ctx, jan30); err == nil {
+		t.Error("expected error without interest store")
+	}
+}
+
+// Тестирование пакета операций: все операции выполняются вместе или не выполняется ни одна
+func TestPerformBatch(t *testing.T) {
+	repo := newMockWalletRepository()
+	ctx := context.Background()
+	revenue, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	svc := usecase.NewWalletService(repo, usecase.WithFees(domain.FeeSchedule{
+		RevenueWallets: map[string]uuid.UUID{"RUB": revenue},
+		Rules:          []domain.FeeRule{{Name: "withdraw", OperationType: usecase.WITHDRAW, Flat: 10}},
+	}))
+
+	first, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	second, _ := repo.CreateWallet(ctx, domain.Wallet{Currency: "RUB"})
+	repo.UpdateBalance(ctx, first, 1000)
+
+	results, err := svc.PerformBatch(ctx, []domain.Operation{
+		{WalletID: first, OperationType: usecase.TRANSFER, Amount: 300, DestinationWalletID: second},
+		{WalletID: second, OperationType: usecase.WITHDRAW, Amount: 100},
+		{WalletID: first, OperationType: usecase.DEPOSIT, Amount: 50},
+	})
+	if err != nil || len(results) != 3 {
+		t.Fatalf("expected 3 results, got %+v; error:%v", results, err)
+	}
+	if len(results[0].Transactions) != 2 || len(results[1].Fees) != 1 || results[2].Transactions[0].Amount != 50 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	balances := func() (int64, int64, int64) {
+		a, _ := repo.GetBalance(ctx, first)
+		b, _ := repo.GetBalance(ctx, second)
+		c, _ := repo.GetBalance(ctx, revenue)
+		return a, b, c
+	}
+	if a, b, c := balances(); a != 750 || b != 190 || c != 10 {
+		t.Errorf("expected balances 750, 190, 10, got %d, %d, %d", a, b, c)
+	}
+
+	// Ошибка третьей операции откатывает первые две
+	n := len(repo.transactions)
+	_, err = svc.PerformBatch(ctx, []domain.Operation{
+		{WalletID: second, OperationType: usecase.DEPOSIT, Amount: 500},
+		{WalletID: first, OperationType: usecase.WITHDRAW, Amount: 100},
+		{WalletID: second, OperationType: usecase.WITHDRAW, Amount: 10000},
+	})
+	var batchErr *domain.BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 2 || !errors.Is(err, domain.ErrInsufficientFunds) {
+		t.Fatalf("expected insufficient funds for operation 2, got %v", err)
+	}
+	if a, b, c := balances(); a != 750 || b != 190 || c != 10 || len(repo.transactions) != n {
+		t.Errorf("expected batch to be rolled back, got balances %d, %d, %d and %d records", a, b, c, len(repo.transactions)-n)
+	}
+
+	// Неизвестный кошелёк не мешает блокировке остальных, ошибка приходится на его операцию
+	_, err = svc.PerformBatch(ctx, []domain.Operation{
+		{WalletID: first, OperationType: usecase.DEPOSIT, Amount: 1},
+		{WalletID: uuid.New(), OperationType: usecase.DEPOSIT, Amount: 1},
+	})
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, domain.ErrWalletNotFound) {
+		t.Errorf("expected wallet not found for operation 1, got %v", err)
+	}
+
+	if _, err := svc.PerformBatch(ctx, nil); !errors.Is(err, domain.ErrInvalidBatch) {
+		t.Errorf("expected ErrInvalidBatch for empty batch, got %v", err)
+	}
+	if _, err := svc.PerformBatch(ctx, make([]domain.Operation, 1001)); !errors.Is(err, domain.ErrInvalidBatch) {
+		t.Errorf("expected ErrInvalidBatch for oversized batch, got %v", err)
+	}
+}
@@ -2,9 +2,9 @@ package tests
 
 import (
 	"context"
-	"errors"
 
 	"github.com/google/uuid"
+	"WalletApp/internal/domain"
 )
 
 // MockWalletService мок-сервис для тестирования
@@ -30,7 +30,7 @@ func (m *MockWalletService) CreateWallet(ctx context.Context) (uuid.UUID, error)
 func (m *MockWalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (int64, error) {
 	balance, exists := m.Balances[walletID]
 	if !exists {
-		return 0, errors.New("wallet not found")
+		return 0, domain.ErrWalletNotFound
 	}
 	return balance, nil
 }
@@ -42,11 +42,11 @@ func (m *MockWalletService) PerformOperation(ctx context.Context, walletID uuid.
 		m.Balances[walletID] += amount
 	case "WITHDRAW":
 		if m.Balances[walletID] < amount {
-			return errors.New("insufficient funds")
+			return domain.ErrInsufficientFunds
 		}
 		m.Balances[walletID] -= amount
 	default:
-		return errors.New("invalid operation type")
+		return domain.ErrInvalidOperation
 	}
 	return nil
 }
\ No newline at end of file